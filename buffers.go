@@ -0,0 +1,217 @@
+package gore
+
+import "image"
+
+// Auxiliary buffers rendered alongside each frame, for agents and vision
+// tests: the depth of each pixel of the 3D view, what produced it, and the
+// automap.
+
+// DoomBuffersFrontend can optionally be implemented by a DoomFrontend to
+// receive the auxiliary buffers for each frame. Implementing it, or setting
+// Options.Buffers, enables them; they aren't rendered otherwise.
+type DoomBuffersFrontend interface {
+	// DrawBuffers is called just before DrawFrame, with the buffers for the
+	// same frame. They are reused, so must be copied if they are to be
+	// retained.
+	DrawBuffers(buffers *FrameBuffers)
+}
+
+// LabelKind is the type of thing which produced a pixel
+type LabelKind uint8
+
+const (
+	LabelNone    LabelKind = iota // Outside the 3D view, ie: the status bar
+	LabelWall                     // Index is into Level.Lines
+	LabelFloor                    // Index is the flat number, in the WAD's flat order
+	LabelCeiling                  // Index is the flat number, in the WAD's flat order
+	LabelSky
+	LabelThing  // Index is the Mobj.ID
+	LabelWeapon // The player's weapon
+)
+
+const labelKindShift = 28
+
+// Label identifies what produced a pixel in the 3D view
+type Label uint32
+
+func makeLabel(kind LabelKind, index int32) Label {
+	return Label(kind)<<labelKindShift | Label(index)&(1<<labelKindShift-1)
+}
+
+// Kind returns the type of thing which produced the pixel
+func (l Label) Kind() LabelKind {
+	return LabelKind(l >> labelKindShift)
+}
+
+// Index returns the index of the wall, flat or thing which produced the
+// pixel, as described by its Kind
+func (l Label) Index() int {
+	return int(l & (1<<labelKindShift - 1))
+}
+
+// LabelBuffer holds the Label of every pixel of the screen
+type LabelBuffer struct {
+	Pix           []Label
+	Width, Height int
+}
+
+// At returns the Label of a pixel
+func (b *LabelBuffer) At(x, y int) Label {
+	return b.Pix[y*b.Width+x]
+}
+
+// FrameBuffers are the auxiliary buffers for a frame. The Depth and Labels
+// cover the whole screen, matching the image passed to DrawFrame, but only
+// pixels drawn by the 3D view are set; they are empty when the view isn't
+// being shown, such as in the menus or on the automap.
+type FrameBuffers struct {
+	// Depth is the distance from the viewer of each pixel, in map units. It
+	// is 0 outside the 3D view and for the player's weapon, and 65535 for
+	// the sky.
+	Depth  *image.Gray16
+	Labels *LabelBuffer
+	// Automap is the automap, drawn around the player whether or not it is
+	// being shown. It is black when not in a level.
+	Automap *image.RGBA
+}
+
+var aux_enabled bool
+var aux_frontend DoomBuffersFrontend
+var aux_buffers *FrameBuffers
+var aux_depthbuf []uint16
+var aux_labelbuf []Label
+var aux_automapbuf []byte
+
+// aux_depth and aux_label are written by the column and span drawers, for
+// whatever they are currently drawing
+var aux_depth uint16
+var aux_label Label
+
+// i_InitAuxBuffers allocates the auxiliary buffers, if they are enabled
+func i_InitAuxBuffers() {
+	aux_frontend, _ = dg_frontend.(DoomBuffersFrontend)
+	if aux_frontend != nil {
+		aux_enabled = true
+	}
+	if !aux_enabled {
+		return
+	}
+	width, height := int(screenwidth), int(screenheight)
+	mapHeight := height - int(v_StatusBarHeight())
+	aux_depthbuf = make([]uint16, width*height)
+	aux_labelbuf = make([]Label, width*height)
+	aux_automapbuf = make([]byte, width*mapHeight)
+	aux_buffers = &FrameBuffers{
+		Depth:   image.NewGray16(image.Rect(0, 0, width, height)),
+		Labels:  &LabelBuffer{Pix: make([]Label, width*height), Width: width, Height: height},
+		Automap: image.NewRGBA(image.Rect(0, 0, width, mapHeight)),
+	}
+}
+
+// i_UpdateAuxBuffers fills in the exported buffers for the frame which has
+// just been drawn, and clears the working ones ready for the next
+func i_UpdateAuxBuffers() {
+	if !aux_enabled {
+		return
+	}
+	depth := aux_buffers.Depth.Pix
+	for i, d := range aux_depthbuf {
+		depth[i*2] = uint8(d >> 8)
+		depth[i*2+1] = uint8(d)
+	}
+	copy(aux_buffers.Labels.Pix, aux_labelbuf)
+	clear(aux_depthbuf)
+	clear(aux_labelbuf)
+
+	am_DrawBuffer(aux_automapbuf)
+	pix := aux_buffers.Automap.Pix
+	for i, c := range aux_automapbuf {
+		col := colors[c]
+		pix[i*4] = col.R
+		pix[i*4+1] = col.G
+		pix[i*4+2] = col.B
+		pix[i*4+3] = 0xff
+	}
+	if aux_frontend != nil {
+		i_Callback(func() { aux_frontend.DrawBuffers(aux_buffers) })
+	}
+}
+
+// r_AuxColumn records the depth and label of a column being drawn
+func r_AuxColumn(x, yl, yh int32) {
+	if !aux_enabled {
+		return
+	}
+	for y := yl; y <= yh; y++ {
+		i := ylookup[y] + columnofs[x]
+		aux_depthbuf[i] = aux_depth
+		aux_labelbuf[i] = aux_label
+	}
+}
+
+// r_AuxSpan records the depth and label of a span being drawn
+func r_AuxSpan(y, x1, x2 int32) {
+	if !aux_enabled {
+		return
+	}
+	for x := x1; x <= x2; x++ {
+		i := ylookup[y] + columnofs[x]
+		aux_depthbuf[i] = aux_depth
+		aux_labelbuf[i] = aux_label
+	}
+}
+
+// r_AuxDistance converts a distance to the units of the depth buffer
+func r_AuxDistance(dist fixed_t) uint16 {
+	return uint16(max(1, min(0xffff, dist>>FRACBITS)))
+}
+
+// r_AuxScale converts a projected scale, as used for walls and sprites, to
+// the units of the depth buffer
+func r_AuxScale(scale fixed_t) uint16 {
+	return r_AuxDistance(fixedDiv(projection, scale))
+}
+
+// r_AuxWallLabel returns the label for a wall
+func r_AuxWallLabel(ld *line_t) Label {
+	return makeLabel(LabelWall, ld.Findex)
+}
+
+// am_DrawBuffer draws the automap into buf, regardless of whether it is
+// active. When it is, the view is the same as shown on screen; otherwise it
+// is centered on the player at the default scale.
+func am_DrawBuffer(buf []byte) {
+	if gamestate != gs_LEVEL || players[consoleplayer].Fmo == nil {
+		clear(buf)
+		return
+	}
+	savedFB, savedPlr := fb, plr
+	savedX, savedY, savedX2, savedY2, savedW, savedH := m_x, m_y, m_x2, m_y2, m_w, m_h
+	if automapactive == 0 {
+		if lastlevel != gamemap || lastepisode != gameepisode {
+			am_LevelInit()
+			lastlevel = gamemap
+			lastepisode = gameepisode
+		}
+		plr = &players[consoleplayer]
+		m_w = fixedMul(f_w<<16, scale_ftom)
+		m_h = fixedMul(f_h<<16, scale_ftom)
+		m_x = plr.Fmo.Fx - m_w/2
+		m_y = plr.Fmo.Fy - m_h/2
+		m_x2 = m_x + m_w
+		m_y2 = m_y + m_h
+	}
+	fb = buf
+	am_clearFB(BLACK)
+	if grid {
+		am_drawGrid(6*16 + GRAYSRANGE/2)
+	}
+	am_drawWalls()
+	am_drawPlayers()
+	if cheating == 2 {
+		am_drawThings(7*16, GREENRANGE)
+	}
+	am_drawCrosshair(6 * 16)
+	fb, plr = savedFB, savedPlr
+	m_x, m_y, m_x2, m_y2, m_w, m_h = savedX, savedY, savedX2, savedY2, savedW, savedH
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AndreRenaud/gore/wad"
+)
+
+// saveWriter builds savegames the same way as the saveg_write_* functions
+type saveWriter struct {
+	bytes.Buffer
+}
+
+func (w *saveWriter) w8(v ...int) {
+	for _, b := range v {
+		w.WriteByte(byte(b))
+	}
+}
+
+func (w *saveWriter) w16(v ...int) {
+	for _, s := range v {
+		w.Write(binary.LittleEndian.AppendUint16(nil, uint16(s)))
+	}
+}
+
+func (w *saveWriter) w32(v ...int) {
+	for _, i := range v {
+		w.Write(binary.LittleEndian.AppendUint32(nil, uint32(i)))
+	}
+}
+
+func (w *saveWriter) pad() {
+	for w.Len()%4 != 0 {
+		w.WriteByte(0)
+	}
+}
+
+var testLevel = &Level{
+	Sectors: 2,
+	Sides:   [][2]bool{{true, false}, {true, true}},
+}
+
+// testSave returns a savegame of testLevel, with one player, one monster
+// and a couple of specials. If there are any PWADs, it's an extended
+// savegame.
+func testSave(pwads ...PWAD) []byte {
+	var w saveWriter
+	w.Write(append([]byte("test save"), make([]byte, saveStringSize-9)...))
+	w.Write(append([]byte("version 109"), make([]byte, versionSize-11)...))
+	if len(pwads) > 0 {
+		copy(w.Bytes()[saveStringSize:], extendedVersion)
+	}
+	w.w8(2, 1, 3)       // skill, episode, map
+	w.w8(1, 0, 0, 0)    // playeringame
+	w.w8(0x01, 0x23, 4) // leveltime
+	if len(pwads) > 0 {
+		w.w8(len(pwads))
+		for _, pwad := range pwads {
+			w.w8(len(pwad.Name))
+			w.WriteString(pwad.Name)
+			sum, _ := hex.DecodeString(pwad.SHA1)
+			w.Write(sum)
+		}
+	}
+
+	w.pad()
+	w.w32(0xdead, 0)            // mo, playerstate
+	w.w8(25, -10)               // forwardmove, sidemove
+	w.w16(-256, 7)              // angleturn, consistancy
+	w.w8(0, 1)                  // chatchar, buttons
+	w.w32(41<<16, 41<<16, 0, 0) // viewz, viewheight, deltaviewheight, bob
+	w.w32(87, 50, 1)            // health, armorpoints, armortype
+	w.w32(0, 0, 0, 0, 0, 0)     // powers
+	w.w32(1, 0, 0, 0, 0, 0)     // cards
+	w.w32(1)                    // backpack
+	w.w32(0, 0, 0, 0)           // frags
+	w.w32(3, 10)                // readyweapon, pendingweapon
+	w.w32(1, 1, 1, 1, 0, 0, 0, 0, 0)
+	w.w32(50, 20, 0, 0)        // ammo
+	w.w32(400, 100, 100, 600)  // maxammo
+	w.w32(0, 0, 0, 0, 5, 6, 1) // attackdown ... secretcount
+	w.w32(0xbeef, 0, 0, 0)     // message, damagecount, bonuscount, attacker
+	w.w32(0, 0, 0)             // extralight, fixedcolormap, colormap
+	w.w32(10, 1, 1<<16, 32<<16, 0, -1, 0, 0)
+	w.w32(1) // didsecret
+
+	// Sectors
+	w.w16(0, 128, 5, 6, 160, 0, 0)
+	w.w16(-24, 72, 5, 7, 255, 9, 3)
+	// Lines
+	w.w16(1, 0, 0)
+	w.w16(1, 2, 3, 4, 5)
+	w.w16(4, 1, 3)
+	w.w16(0, 0, 0, 0, 0)
+	w.w16(-8, 16, 10, 11, 12)
+
+	w.w8(tcMobj)
+	w.pad()
+	w.w32(0, 0, 0)                   // thinker
+	w.w32(64<<16, -32<<16, 0)        // x, y, z
+	w.w32(0, 0)                      // snext, sprev
+	w.w32(0x40000000, 31, 0x8002)    // angle, sprite, frame
+	w.w32(0, 0, 0)                   // bnext, bprev, subsector
+	w.w32(0, 72<<16, 20<<16, 56<<16) // floorz, ceilingz, radius, height
+	w.w32(1<<15, 0, 0, 0)            // momentum, validcount
+	w.w32(11, 0, 8, 442, 0x400006)   // type, info, tics, state, flags
+	w.w32(60, 8, 0, 0, 8, 0, 0, 0)   // health ... lastlook
+	w.w16(64, -32, 90, 3001, 7)      // spawnpoint
+	w.w32(0)                         // tracer
+	w.w8(tcEnd)
+
+	w.w8(tcFloor)
+	w.pad()
+	w.w32(0, 0, 0)
+	w.w32(1, 0, 1, 1, 0)
+	w.w16(5)
+	w.w32(64<<16, 1<<16)
+	w.w8(tcGlow)
+	w.pad()
+	w.w32(0, 0, 0)
+	w.w32(0, 128, 255, -1)
+	w.w8(tcEndSpecials)
+	w.w8(saveGameEOF)
+	return w.Bytes()
+}
+
+func TestDecodeSaveGame(t *testing.T) {
+	t.Parallel()
+	sg := DecodeSaveGame(testSave(), testLevel)
+	if len(sg.Problems) > 0 {
+		t.Fatalf("Unexpected problems: %v", sg.Problems)
+	}
+	wantHeader := Header{
+		Description:   "test save",
+		Version:       "version 109",
+		Skill:         2,
+		Episode:       1,
+		Map:           3,
+		PlayersInGame: [4]bool{true},
+		LevelTime:     0x012304,
+	}
+	if !reflect.DeepEqual(sg.Header, wantHeader) {
+		t.Errorf("Header = %+v, want %+v", sg.Header, wantHeader)
+	}
+
+	if len(sg.Players) != 1 {
+		t.Fatalf("Got %d players, want 1", len(sg.Players))
+	}
+	p := sg.Players[0]
+	if p.Health != 87 || p.ArmorPoints != 50 || !p.Backpack || !p.Cards[0] || p.Ammo[1] != 20 ||
+		p.MaxAmmo[3] != 600 || p.SecretCount != 1 || p.ViewZ != 41 || !p.DidSecret {
+		t.Errorf("Player decoded incorrectly: %+v", p)
+	}
+	if p.Cmd != (TicCmd{ForwardMove: 25, SideMove: -10, AngleTurn: -256, Consistancy: 7, Buttons: 1}) {
+		t.Errorf("Player command = %+v", p.Cmd)
+	}
+	if p.PSprites[1] != (PSprite{Tics: -1}) || p.PSprites[0].SY != 32 {
+		t.Errorf("Player sprites = %+v", p.PSprites)
+	}
+
+	if len(sg.Sectors) != 2 || sg.Sectors[1] != (Sector{Index: 1, FloorHeight: -24, CeilingHeight: 72, FloorPic: 5, CeilingPic: 7, LightLevel: 255, Special: 9, Tag: 3}) {
+		t.Errorf("Sectors = %+v", sg.Sectors)
+	}
+	if len(sg.Lines) != 2 || sg.Lines[0].Back != nil || sg.Lines[1].Back == nil ||
+		*sg.Lines[1].Back != (Side{TextureOffset: -8, RowOffset: 16, TopTexture: 10, BottomTexture: 11, MidTexture: 12}) {
+		t.Errorf("Lines = %+v", sg.Lines)
+	}
+
+	if len(sg.Mobjs) != 1 {
+		t.Fatalf("Got %d mobjs, want 1", len(sg.Mobjs))
+	}
+	mo := sg.Mobjs[0]
+	if mo.TypeName != "MT_TROOP" || mo.X != 64 || mo.Y != -32 || mo.Angle != 90 || mo.MomX != 0.5 ||
+		mo.State != 442 || mo.Health != 60 || mo.Player != -1 || mo.SpawnPoint.Type != 3001 {
+		t.Errorf("Mobj decoded incorrectly: %+v", mo)
+	}
+
+	wantSpecials := []any{
+		Floor{Class: "floor", Type: 1, Sector: 1, Direction: 1, Texture: 5, FloorDestHeight: 64, Speed: 1},
+		Glow{Class: "glow", MinLight: 128, MaxLight: 255, Direction: -1},
+	}
+	if !reflect.DeepEqual(sg.Specials, wantSpecials) {
+		t.Errorf("Specials = %+v, want %+v", sg.Specials, wantSpecials)
+	}
+}
+
+func TestDecodeSaveGameWithoutLevel(t *testing.T) {
+	t.Parallel()
+	sg := DecodeSaveGame(testSave(), nil)
+	if len(sg.Problems) > 0 {
+		t.Fatalf("Unexpected problems: %v", sg.Problems)
+	}
+	if len(sg.Players) != 1 || sg.Players[0].Health != 87 {
+		t.Errorf("Players = %+v", sg.Players)
+	}
+	if sg.Sectors != nil || sg.Mobjs != nil {
+		t.Errorf("World decoded without a level")
+	}
+}
+
+func TestDecodeSaveGameProblems(t *testing.T) {
+	t.Parallel()
+	hasProblem := func(sg *SaveGame, substr string) bool {
+		for _, p := range sg.Problems {
+			if strings.Contains(p, substr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	data := testSave()
+	data[len(data)-1] = 0x42
+	for _, level := range []*Level{testLevel, nil} {
+		if sg := DecodeSaveGame(data, level); !hasProblem(sg, "bad end of file marker 0x42") {
+			t.Errorf("Bad EOF marker not flagged: %v", sg.Problems)
+		}
+	}
+
+	data = append(testSave(), make([]byte, saveGameSize)...)
+	sg := DecodeSaveGame(data, testLevel)
+	if !hasProblem(sg, "over the Vanilla limit") || !hasProblem(sg, "bytes after the end of file marker") {
+		t.Errorf("Oversized file not flagged: %v", sg.Problems)
+	}
+
+	data = testSave()
+	sg = DecodeSaveGame(data[:len(data)-20], testLevel)
+	if !hasProblem(sg, "truncated") {
+		t.Errorf("Truncated file not flagged: %v", sg.Problems)
+	}
+}
+
+func TestDecodeExtendedSaveGame(t *testing.T) {
+	t.Parallel()
+	pwads := []PWAD{
+		{Name: "big.wad", SHA1: strings.Repeat("01", 20)},
+		{Name: "music.wad", SHA1: strings.Repeat("ef", 20)},
+	}
+	sg := DecodeSaveGame(testSave(pwads...), testLevel)
+	if len(sg.Problems) > 0 {
+		t.Fatalf("Unexpected problems: %v", sg.Problems)
+	}
+	if !sg.Header.Extended || !reflect.DeepEqual(sg.Header.PWADs, pwads) {
+		t.Errorf("Header = %+v", sg.Header)
+	}
+	if len(sg.Mobjs) != 1 || len(sg.Specials) != 2 {
+		t.Errorf("Decoded %d mobjs and %d specials, want 1 and 2", len(sg.Mobjs), len(sg.Specials))
+	}
+
+	// Extended savegames don't have a size limit
+	data := append(testSave(pwads...), make([]byte, saveGameSize)...)
+	sg = DecodeSaveGame(data, testLevel)
+	if len(sg.Problems) != 1 || !strings.Contains(sg.Problems[0], "after the end of file marker") {
+		t.Errorf("Problems = %v", sg.Problems)
+	}
+
+	if !samePWADs(pwads, []PWAD{{Name: "renamed.wad", SHA1: pwads[0].SHA1}, pwads[1]}) || samePWADs(pwads, pwads[:1]) {
+		t.Errorf("PWADs compared incorrectly")
+	}
+}
+
+func TestWADLevel(t *testing.T) {
+	t.Parallel()
+	var linedefs saveWriter
+	linedefs.w16(0, 1, 1, 0, 0, 0, -1)
+	linedefs.w16(1, 2, 4, 0, 0, 1, 2)
+	lumps := []struct {
+		name string
+		data []byte
+	}{
+		{"E1M3", nil},
+		{"THINGS", nil},
+		{"LINEDEFS", linedefs.Bytes()},
+		{"SIDEDEFS", nil},
+		{"VERTEXES", nil},
+		{"SEGS", nil},
+		{"SSECTORS", nil},
+		{"NODES", nil},
+		{"SECTORS", make([]byte, 2*mapSectorSize)},
+	}
+	var buf saveWriter
+	buf.WriteString("PWAD")
+	buf.w32(len(lumps), 12+2*mapSectorSize+linedefs.Len())
+	var dir saveWriter
+	for _, l := range lumps {
+		dir.w32(buf.Len(), len(l.data))
+		dir.Write(append([]byte(l.name), make([]byte, 8-len(l.name))...))
+		buf.Write(l.data)
+	}
+	buf.Write(dir.Bytes())
+	w, err := wad.Open(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Error opening WAD: %v", err)
+	}
+	level, err := findLevel([]*wad.WAD{w}, 1, 3)
+	if err != nil {
+		t.Fatalf("Error finding level: %v", err)
+	}
+	if !reflect.DeepEqual(level, testLevel) {
+		t.Errorf("Level = %+v, want %+v", level, testLevel)
+	}
+	if _, err := findLevel([]*wad.WAD{w}, 1, 4); err == nil {
+		t.Errorf("Found a level that doesn't exist")
+	}
+}
@@ -0,0 +1,125 @@
+// goresave decodes Doom savegames (.dsg files) into JSON, for looking into
+// saves that won't load.
+//
+// Usage:
+//
+//	goresave [-iwad doom1.wad] [-file pwad.wad]... savegame.dsg...
+//
+// The header and players can always be decoded. The sectors, lines, objects
+// and moving floors etc. depend on the map the game was saved on, so are only
+// decoded if the WADs it was played with are given. Both Vanilla savegames
+// and gore's extended ones, which record the PWADs they need, are understood.
+// Anything wrong with the file, such as being too large for Vanilla Doom,
+// having a bad end of file marker or needing different PWADs to the ones
+// given, is listed in "problems", and makes the exit status 1.
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/AndreRenaud/gore/wad"
+)
+
+// fileList is a flag that can be given more than once
+type fileList []string
+
+func (f *fileList) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *fileList) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// samePWADs reports whether two lists of PWADs have the same contents, in
+// the same order, whatever they're called
+func samePWADs(a, b []PWAD) bool {
+	return slices.EqualFunc(a, b, func(a, b PWAD) bool {
+		return a.SHA1 == b.SHA1
+	})
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("goresave: ")
+	iwad := flag.String("iwad", "", "IWAD the games were saved with")
+	var pwads fileList
+	flag.Var(&pwads, "file", "PWAD the games were saved with, can be repeated")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: goresave [-iwad file] [-file pwad]... savegame.dsg...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var wads []*wad.WAD
+	var pwadSums []PWAD
+	if *iwad == "" && len(pwads) > 0 {
+		log.Fatal("-file needs -iwad")
+	}
+	for i, name := range append([]string{*iwad}, pwads...) {
+		if name == "" {
+			continue
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w, err := wad.Open(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+		wads = append(wads, w)
+		if i > 0 {
+			sum := sha1.Sum(data)
+			pwadSums = append(pwadSums, PWAD{Name: filepath.Base(name), SHA1: hex.EncodeToString(sum[:])})
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	status := 0
+	for _, name := range flag.Args() {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			log.Print(err)
+			status = 1
+			continue
+		}
+		var level *Level
+		if len(wads) > 0 && len(data) >= headerSize {
+			// The map is in the header, which decodes without it
+			h := DecodeSaveGame(data, nil).Header
+			level, err = findLevel(wads, h.Episode, h.Map)
+			if err != nil {
+				log.Printf("%s: %v, only decoding the header and players", name, err)
+			}
+		}
+		sg := DecodeSaveGame(data, level)
+		sg.File = name
+		if level != nil && sg.Header.Extended && !samePWADs(sg.Header.PWADs, pwadSums) {
+			sg.problem("saved with different PWADs to the ones given, so the map may be wrong")
+		}
+		if len(sg.Problems) > 0 {
+			status = 1
+		}
+		if err := enc.Encode(sg); err != nil {
+			log.Fatal(err)
+		}
+	}
+	os.Exit(status)
+}
@@ -0,0 +1,710 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/AndreRenaud/gore"
+)
+
+// Layout of the savegames written by the saveg_write_* functions. Pointers
+// are written as 32-bit values, but are meaningless once loaded, so they're
+// skipped rather than decoded.
+const (
+	saveStringSize = 24     // SAVESTRINGSIZE
+	versionSize    = 16     // VERSIONSIZE
+	saveGameSize   = 180224 // SAVEGAMESIZE, the limit in Vanilla Doom
+	saveGameEOF    = 0x1d   // SAVEGAME_EOF
+	headerSize     = saveStringSize + versionSize + 3 + maxPlayers + 3
+
+	// Extended savegames have this version string, and the PWADs which were
+	// loaded after the header
+	extendedVersion = "gore save 1"
+
+	maxPlayers  = 4
+	numPowers   = 6
+	numCards    = 6
+	numWeapons  = 9
+	numAmmo     = 4
+	numPSprites = 2
+
+	tcEnd  = 0
+	tcMobj = 1
+
+	tcCeiling     = 0
+	tcDoor        = 1
+	tcFloor       = 2
+	tcPlat        = 3
+	tcFlash       = 4
+	tcStrobe      = 5
+	tcGlow        = 6
+	tcEndSpecials = 7
+
+	thinkerSize = 12 // prev, next and function pointers
+	fracUnit    = 1 << 16
+	angle360    = 1 << 32
+)
+
+// SaveGame is the decoded contents of a savegame
+type SaveGame struct {
+	File    string   `json:"file"`
+	Size    int      `json:"size"`
+	Header  Header   `json:"header"`
+	Players []Player `json:"players"`
+	// The world and thinkers can only be decoded with the map the game was
+	// saved on, so these are left out if it isn't available
+	Sectors  []Sector `json:"sectors,omitempty"`
+	Lines    []Line   `json:"lines,omitempty"`
+	Mobjs    []Mobj   `json:"mobjs,omitempty"`
+	Specials []any    `json:"specials,omitempty"`
+	// Problems found with the file, such as exceeding the Vanilla size limit
+	Problems []string `json:"problems,omitempty"`
+}
+
+type Header struct {
+	Description   string  `json:"description"`
+	Version       string  `json:"version"`
+	Extended      bool    `json:"extended"`
+	Skill         int     `json:"skill"`
+	Episode       int     `json:"episode"`
+	Map           int     `json:"map"`
+	PlayersInGame [4]bool `json:"playersInGame"`
+	LevelTime     int     `json:"levelTime"` // In tics
+	// The PWADs the game was played with, for extended savegames
+	PWADs []PWAD `json:"pwads,omitempty"`
+}
+
+type PWAD struct {
+	Name string `json:"name"`
+	SHA1 string `json:"sha1"`
+}
+
+type TicCmd struct {
+	ForwardMove int `json:"forwardMove"`
+	SideMove    int `json:"sideMove"`
+	AngleTurn   int `json:"angleTurn"`
+	Consistancy int `json:"consistancy"`
+	ChatChar    int `json:"chatChar"`
+	Buttons     int `json:"buttons"`
+}
+
+type PSprite struct {
+	State int     `json:"state"`
+	Tics  int     `json:"tics"`
+	SX    float64 `json:"sx"`
+	SY    float64 `json:"sy"`
+}
+
+type Player struct {
+	Index           int                  `json:"index"`
+	PlayerState     int                  `json:"playerState"`
+	Cmd             TicCmd               `json:"cmd"`
+	ViewZ           float64              `json:"viewZ"`
+	ViewHeight      float64              `json:"viewHeight"`
+	DeltaViewHeight float64              `json:"deltaViewHeight"`
+	Bob             float64              `json:"bob"`
+	Health          int                  `json:"health"`
+	ArmorPoints     int                  `json:"armorPoints"`
+	ArmorType       int                  `json:"armorType"`
+	Powers          [numPowers]int       `json:"powers"`
+	Cards           [numCards]bool       `json:"cards"`
+	Backpack        bool                 `json:"backpack"`
+	Frags           [maxPlayers]int      `json:"frags"`
+	ReadyWeapon     int                  `json:"readyWeapon"`
+	PendingWeapon   int                  `json:"pendingWeapon"`
+	WeaponOwned     [numWeapons]bool     `json:"weaponOwned"`
+	Ammo            [numAmmo]int         `json:"ammo"`
+	MaxAmmo         [numAmmo]int         `json:"maxAmmo"`
+	AttackDown      int                  `json:"attackDown"`
+	UseDown         int                  `json:"useDown"`
+	Cheats          int                  `json:"cheats"`
+	Refire          int                  `json:"refire"`
+	KillCount       int                  `json:"killCount"`
+	ItemCount       int                  `json:"itemCount"`
+	SecretCount     int                  `json:"secretCount"`
+	DamageCount     int                  `json:"damageCount"`
+	BonusCount      int                  `json:"bonusCount"`
+	ExtraLight      int                  `json:"extraLight"`
+	FixedColormap   int                  `json:"fixedColormap"`
+	Colormap        int                  `json:"colormap"`
+	PSprites        [numPSprites]PSprite `json:"psprites"`
+	DidSecret       bool                 `json:"didSecret"`
+}
+
+type Sector struct {
+	Index         int `json:"index"`
+	FloorHeight   int `json:"floorHeight"`
+	CeilingHeight int `json:"ceilingHeight"`
+	FloorPic      int `json:"floorPic"`
+	CeilingPic    int `json:"ceilingPic"`
+	LightLevel    int `json:"lightLevel"`
+	Special       int `json:"special"`
+	Tag           int `json:"tag"`
+}
+
+type Side struct {
+	TextureOffset int `json:"textureOffset"`
+	RowOffset     int `json:"rowOffset"`
+	TopTexture    int `json:"topTexture"`
+	BottomTexture int `json:"bottomTexture"`
+	MidTexture    int `json:"midTexture"`
+}
+
+type Line struct {
+	Index   int `json:"index"`
+	Flags   int `json:"flags"`
+	Special int `json:"special"`
+	Tag     int `json:"tag"`
+	// Front and back sides, nil if the line doesn't have one
+	Front *Side `json:"front,omitempty"`
+	Back  *Side `json:"back,omitempty"`
+}
+
+type MapThing struct {
+	X       int `json:"x"`
+	Y       int `json:"y"`
+	Angle   int `json:"angle"`
+	Type    int `json:"type"`
+	Options int `json:"options"`
+}
+
+type Mobj struct {
+	Type         int      `json:"type"`
+	TypeName     string   `json:"typeName"`
+	X            float64  `json:"x"`
+	Y            float64  `json:"y"`
+	Z            float64  `json:"z"`
+	Angle        float64  `json:"angle"` // Degrees anticlockwise from east
+	Sprite       int      `json:"sprite"`
+	Frame        int      `json:"frame"`
+	FloorZ       float64  `json:"floorZ"`
+	CeilingZ     float64  `json:"ceilingZ"`
+	Radius       float64  `json:"radius"`
+	Height       float64  `json:"height"`
+	MomX         float64  `json:"momX"`
+	MomY         float64  `json:"momY"`
+	MomZ         float64  `json:"momZ"`
+	ValidCount   int      `json:"validCount"`
+	Tics         int      `json:"tics"`
+	State        int      `json:"state"`
+	Flags        uint32   `json:"flags"`
+	Health       int      `json:"health"`
+	MoveDir      int      `json:"moveDir"`
+	MoveCount    int      `json:"moveCount"`
+	ReactionTime int      `json:"reactionTime"`
+	Threshold    int      `json:"threshold"`
+	Player       int      `json:"player"` // Index of the player, or -1
+	LastLook     int      `json:"lastLook"`
+	SpawnPoint   MapThing `json:"spawnPoint"`
+}
+
+type Ceiling struct {
+	Class        string  `json:"class"`
+	Type         int     `json:"type"`
+	Sector       int     `json:"sector"`
+	BottomHeight float64 `json:"bottomHeight"`
+	TopHeight    float64 `json:"topHeight"`
+	Speed        float64 `json:"speed"`
+	Crush        bool    `json:"crush"`
+	Direction    int     `json:"direction"`
+	Tag          int     `json:"tag"`
+	OldDirection int     `json:"oldDirection"`
+}
+
+type Door struct {
+	Class        string  `json:"class"`
+	Type         int     `json:"type"`
+	Sector       int     `json:"sector"`
+	TopHeight    float64 `json:"topHeight"`
+	Speed        float64 `json:"speed"`
+	Direction    int     `json:"direction"`
+	TopWait      int     `json:"topWait"`
+	TopCountdown int     `json:"topCountdown"`
+}
+
+type Floor struct {
+	Class           string  `json:"class"`
+	Type            int     `json:"type"`
+	Crush           bool    `json:"crush"`
+	Sector          int     `json:"sector"`
+	Direction       int     `json:"direction"`
+	NewSpecial      int     `json:"newSpecial"`
+	Texture         int     `json:"texture"`
+	FloorDestHeight float64 `json:"floorDestHeight"`
+	Speed           float64 `json:"speed"`
+}
+
+type Plat struct {
+	Class     string  `json:"class"`
+	Sector    int     `json:"sector"`
+	Speed     float64 `json:"speed"`
+	Low       float64 `json:"low"`
+	High      float64 `json:"high"`
+	Wait      int     `json:"wait"`
+	Count     int     `json:"count"`
+	Status    int     `json:"status"`
+	OldStatus int     `json:"oldStatus"`
+	Crush     bool    `json:"crush"`
+	Tag       int     `json:"tag"`
+	Type      int     `json:"type"`
+}
+
+type LightFlash struct {
+	Class    string `json:"class"`
+	Sector   int    `json:"sector"`
+	Count    int    `json:"count"`
+	MaxLight int    `json:"maxLight"`
+	MinLight int    `json:"minLight"`
+	MaxTime  int    `json:"maxTime"`
+	MinTime  int    `json:"minTime"`
+}
+
+type Strobe struct {
+	Class      string `json:"class"`
+	Sector     int    `json:"sector"`
+	Count      int    `json:"count"`
+	MinLight   int    `json:"minLight"`
+	MaxLight   int    `json:"maxLight"`
+	DarkTime   int    `json:"darkTime"`
+	BrightTime int    `json:"brightTime"`
+}
+
+type Glow struct {
+	Class     string `json:"class"`
+	Sector    int    `json:"sector"`
+	MinLight  int    `json:"minLight"`
+	MaxLight  int    `json:"maxLight"`
+	Direction int    `json:"direction"`
+}
+
+// Level is what's needed from the map to decode the world in a savegame
+type Level struct {
+	Sectors int
+	// Whether each line has a front and back side
+	Sides [][2]bool
+}
+
+// saveReader reads the little-endian values in a savegame. Reading past the
+// end returns zeros, and sets short.
+type saveReader struct {
+	data  []byte
+	pos   int
+	short bool
+}
+
+func (r *saveReader) read(n int) []byte {
+	if r.pos+n > len(r.data) {
+		r.pos = len(r.data)
+		r.short = true
+		return make([]byte, n)
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *saveReader) read8() int {
+	return int(r.read(1)[0])
+}
+
+func (r *saveReader) read16() int {
+	return int(int16(binary.LittleEndian.Uint16(r.read(2))))
+}
+
+func (r *saveReader) read32() int32 {
+	return int32(binary.LittleEndian.Uint32(r.read(4)))
+}
+
+func (r *saveReader) readInt() int {
+	return int(r.read32())
+}
+
+func (r *saveReader) readBool() bool {
+	return r.read32() != 0
+}
+
+func (r *saveReader) readFixed() float64 {
+	return float64(r.read32()) / fracUnit
+}
+
+// readPad skips to the next 4-byte boundary, as saveg_read_pad does
+func (r *saveReader) readPad() {
+	r.read((4 - r.pos&3) & 3)
+}
+
+func (r *saveReader) skipPointer() {
+	r.read(4)
+}
+
+// cString returns the NUL terminated string at the start of b
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// DecodeSaveGame decodes a savegame. If level is nil, only the header and
+// players are decoded.
+func DecodeSaveGame(data []byte, level *Level) *SaveGame {
+	r := &saveReader{data: data}
+	sg := &SaveGame{Size: len(data)}
+	h := &sg.Header
+	h.Description = cString(r.read(saveStringSize))
+	h.Version = cString(r.read(versionSize))
+	h.Extended = h.Version == extendedVersion
+	if !h.Extended && !strings.HasPrefix(h.Version, "version ") {
+		sg.problem("unrecognised version string %q", h.Version)
+	}
+	// Extended savegames don't have a size limit
+	if !h.Extended && len(data) > saveGameSize {
+		sg.problem("file is %d bytes, over the Vanilla limit of %d bytes", len(data), saveGameSize)
+	}
+	h.Skill = r.read8()
+	h.Episode = r.read8()
+	h.Map = r.read8()
+	for i := range h.PlayersInGame {
+		h.PlayersInGame[i] = r.read8() != 0
+	}
+	// leveltime is stored big-endian, in 3 bytes
+	for range 3 {
+		h.LevelTime = h.LevelTime<<8 | r.read8()
+	}
+	if h.Extended {
+		h.PWADs = make([]PWAD, r.read8())
+		for i := range h.PWADs {
+			h.PWADs[i].Name = string(r.read(r.read8()))
+			h.PWADs[i].SHA1 = hex.EncodeToString(r.read(sha1.Size))
+		}
+	}
+
+	for i, ingame := range h.PlayersInGame {
+		if ingame {
+			r.readPad()
+			sg.Players = append(sg.Players, readPlayer(r, i))
+		}
+	}
+	if r.short {
+		sg.problem("file is truncated")
+		return sg
+	}
+
+	if level == nil {
+		// Without the map, all that can be checked is the last byte
+		if last := data[len(data)-1]; last != saveGameEOF {
+			sg.problem("bad end of file marker 0x%02x, expected 0x%02x", last, saveGameEOF)
+		}
+		return sg
+	}
+
+	readWorld(r, sg, level)
+	if !readThinkers(r, sg) || !readSpecials(r, sg) {
+		return sg
+	}
+	if r.short {
+		sg.problem("file is truncated")
+		return sg
+	}
+	if eof := r.read8(); r.short {
+		sg.problem("file is truncated before the end of file marker")
+	} else if eof != saveGameEOF {
+		sg.problem("bad end of file marker 0x%02x at offset %d, expected 0x%02x", eof, r.pos-1, saveGameEOF)
+	} else if r.pos != len(data) {
+		sg.problem("%d bytes after the end of file marker", len(data)-r.pos)
+	}
+	return sg
+}
+
+func (sg *SaveGame) problem(format string, args ...any) {
+	sg.Problems = append(sg.Problems, fmt.Sprintf(format, args...))
+}
+
+func readPlayer(r *saveReader, index int) Player {
+	p := Player{Index: index}
+	r.skipPointer() // mo
+	p.PlayerState = r.readInt()
+	p.Cmd = TicCmd{
+		ForwardMove: int(int8(r.read8())),
+		SideMove:    int(int8(r.read8())),
+		AngleTurn:   r.read16(),
+		Consistancy: r.read16(),
+		ChatChar:    r.read8(),
+		Buttons:     r.read8(),
+	}
+	p.ViewZ = r.readFixed()
+	p.ViewHeight = r.readFixed()
+	p.DeltaViewHeight = r.readFixed()
+	p.Bob = r.readFixed()
+	p.Health = r.readInt()
+	p.ArmorPoints = r.readInt()
+	p.ArmorType = r.readInt()
+	for i := range p.Powers {
+		p.Powers[i] = r.readInt()
+	}
+	for i := range p.Cards {
+		p.Cards[i] = r.readBool()
+	}
+	p.Backpack = r.readBool()
+	for i := range p.Frags {
+		p.Frags[i] = r.readInt()
+	}
+	p.ReadyWeapon = r.readInt()
+	p.PendingWeapon = r.readInt()
+	for i := range p.WeaponOwned {
+		p.WeaponOwned[i] = r.readBool()
+	}
+	for i := range p.Ammo {
+		p.Ammo[i] = r.readInt()
+	}
+	for i := range p.MaxAmmo {
+		p.MaxAmmo[i] = r.readInt()
+	}
+	p.AttackDown = r.readInt()
+	p.UseDown = r.readInt()
+	p.Cheats = r.readInt()
+	p.Refire = r.readInt()
+	p.KillCount = r.readInt()
+	p.ItemCount = r.readInt()
+	p.SecretCount = r.readInt()
+	r.skipPointer() // message
+	p.DamageCount = r.readInt()
+	p.BonusCount = r.readInt()
+	r.skipPointer() // attacker
+	p.ExtraLight = r.readInt()
+	p.FixedColormap = r.readInt()
+	p.Colormap = r.readInt()
+	for i := range p.PSprites {
+		p.PSprites[i] = PSprite{
+			State: r.readInt(),
+			Tics:  r.readInt(),
+			SX:    r.readFixed(),
+			SY:    r.readFixed(),
+		}
+	}
+	p.DidSecret = r.readBool()
+	return p
+}
+
+func readWorld(r *saveReader, sg *SaveGame, level *Level) {
+	for i := range level.Sectors {
+		sg.Sectors = append(sg.Sectors, Sector{
+			Index:         i,
+			FloorHeight:   r.read16(),
+			CeilingHeight: r.read16(),
+			FloorPic:      r.read16(),
+			CeilingPic:    r.read16(),
+			LightLevel:    r.read16(),
+			Special:       r.read16(),
+			Tag:           r.read16(),
+		})
+	}
+	for i, sides := range level.Sides {
+		li := Line{
+			Index:   i,
+			Flags:   r.read16(),
+			Special: r.read16(),
+			Tag:     r.read16(),
+		}
+		for j, exists := range sides {
+			if !exists {
+				continue
+			}
+			side := &Side{
+				TextureOffset: r.read16(),
+				RowOffset:     r.read16(),
+				TopTexture:    r.read16(),
+				BottomTexture: r.read16(),
+				MidTexture:    r.read16(),
+			}
+			if j == 0 {
+				li.Front = side
+			} else {
+				li.Back = side
+			}
+		}
+		sg.Lines = append(sg.Lines, li)
+	}
+}
+
+// readThinkers reads the mobjs, returning false if the list isn't
+// terminated properly
+func readThinkers(r *saveReader, sg *SaveGame) bool {
+	for {
+		tclass := r.read8()
+		if r.short {
+			sg.problem("file is truncated in the thinkers")
+			return false
+		}
+		switch tclass {
+		case tcEnd:
+			return true
+		case tcMobj:
+			r.readPad()
+			sg.Mobjs = append(sg.Mobjs, readMobj(r))
+		default:
+			sg.problem("unknown thinker class %d at offset %d", tclass, r.pos-1)
+			return false
+		}
+	}
+}
+
+func readMobj(r *saveReader) Mobj {
+	var mo Mobj
+	r.read(thinkerSize)
+	mo.X = r.readFixed()
+	mo.Y = r.readFixed()
+	mo.Z = r.readFixed()
+	r.skipPointer() // snext
+	r.skipPointer() // sprev
+	mo.Angle = float64(uint32(r.read32())) * 360 / angle360
+	mo.Sprite = r.readInt()
+	mo.Frame = r.readInt()
+	r.skipPointer() // bnext
+	r.skipPointer() // bprev
+	r.skipPointer() // subsector
+	mo.FloorZ = r.readFixed()
+	mo.CeilingZ = r.readFixed()
+	mo.Radius = r.readFixed()
+	mo.Height = r.readFixed()
+	mo.MomX = r.readFixed()
+	mo.MomY = r.readFixed()
+	mo.MomZ = r.readFixed()
+	mo.ValidCount = r.readInt()
+	mo.Type = r.readInt()
+	mo.TypeName = gore.MobjTypeName(mo.Type)
+	r.skipPointer() // info
+	mo.Tics = r.readInt()
+	mo.State = r.readInt()
+	mo.Flags = uint32(r.read32())
+	mo.Health = r.readInt()
+	mo.MoveDir = r.readInt()
+	mo.MoveCount = r.readInt()
+	r.skipPointer() // target
+	mo.ReactionTime = r.readInt()
+	mo.Threshold = r.readInt()
+	mo.Player = r.readInt() - 1
+	mo.LastLook = r.readInt()
+	mo.SpawnPoint = MapThing{
+		X:       r.read16(),
+		Y:       r.read16(),
+		Angle:   r.read16(),
+		Type:    r.read16(),
+		Options: r.read16(),
+	}
+	r.skipPointer() // tracer
+	return mo
+}
+
+// readSpecials reads the moving floors, ceilings and lights, returning false
+// if the list isn't terminated properly
+func readSpecials(r *saveReader, sg *SaveGame) bool {
+	for {
+		offset := r.pos
+		tclass := r.read8()
+		if r.short {
+			sg.problem("file is truncated in the specials")
+			return false
+		}
+		if tclass == tcEndSpecials {
+			return true
+		}
+		r.readPad()
+		var special any
+		switch tclass {
+		case tcCeiling:
+			r.read(thinkerSize)
+			special = Ceiling{
+				Class:        "ceiling",
+				Type:         r.readInt(),
+				Sector:       r.readInt(),
+				BottomHeight: r.readFixed(),
+				TopHeight:    r.readFixed(),
+				Speed:        r.readFixed(),
+				Crush:        r.readBool(),
+				Direction:    r.readInt(),
+				Tag:          r.readInt(),
+				OldDirection: r.readInt(),
+			}
+		case tcDoor:
+			r.read(thinkerSize)
+			special = Door{
+				Class:        "door",
+				Type:         r.readInt(),
+				Sector:       r.readInt(),
+				TopHeight:    r.readFixed(),
+				Speed:        r.readFixed(),
+				Direction:    r.readInt(),
+				TopWait:      r.readInt(),
+				TopCountdown: r.readInt(),
+			}
+		case tcFloor:
+			r.read(thinkerSize)
+			special = Floor{
+				Class:           "floor",
+				Type:            r.readInt(),
+				Crush:           r.readBool(),
+				Sector:          r.readInt(),
+				Direction:       r.readInt(),
+				NewSpecial:      r.readInt(),
+				Texture:         r.read16(),
+				FloorDestHeight: r.readFixed(),
+				Speed:           r.readFixed(),
+			}
+		case tcPlat:
+			r.read(thinkerSize)
+			special = Plat{
+				Class:     "plat",
+				Sector:    r.readInt(),
+				Speed:     r.readFixed(),
+				Low:       r.readFixed(),
+				High:      r.readFixed(),
+				Wait:      r.readInt(),
+				Count:     r.readInt(),
+				Status:    r.readInt(),
+				OldStatus: r.readInt(),
+				Crush:     r.readBool(),
+				Tag:       r.readInt(),
+				Type:      r.readInt(),
+			}
+		case tcFlash:
+			r.read(thinkerSize)
+			special = LightFlash{
+				Class:    "flash",
+				Sector:   r.readInt(),
+				Count:    r.readInt(),
+				MaxLight: r.readInt(),
+				MinLight: r.readInt(),
+				MaxTime:  r.readInt(),
+				MinTime:  r.readInt(),
+			}
+		case tcStrobe:
+			r.read(thinkerSize)
+			special = Strobe{
+				Class:      "strobe",
+				Sector:     r.readInt(),
+				Count:      r.readInt(),
+				MinLight:   r.readInt(),
+				MaxLight:   r.readInt(),
+				DarkTime:   r.readInt(),
+				BrightTime: r.readInt(),
+			}
+		case tcGlow:
+			r.read(thinkerSize)
+			special = Glow{
+				Class:     "glow",
+				Sector:    r.readInt(),
+				MinLight:  r.readInt(),
+				MaxLight:  r.readInt(),
+				Direction: r.readInt(),
+			}
+		default:
+			sg.problem("unknown special class %d at offset %d", tclass, offset)
+			return false
+		}
+		sg.Specials = append(sg.Specials, special)
+	}
+}
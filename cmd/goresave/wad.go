@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/AndreRenaud/gore/wad"
+)
+
+const (
+	mapSectorSize  = 26
+	mapLinedefSize = 14
+)
+
+// findLevel returns what's needed to decode a savegame's world for a map,
+// from the last WAD which has it. Doom II style maps are used if there's a
+// MAP01.
+func findLevel(wads []*wad.WAD, episode, mapnum int) (*Level, error) {
+	name := fmt.Sprintf("E%dM%d", episode, mapnum)
+	for _, w := range wads {
+		if _, ok := w.Map("MAP01"); ok {
+			name = fmt.Sprintf("MAP%02d", mapnum)
+		}
+	}
+	for _, w := range slices.Backward(wads) {
+		if m, ok := w.Map(name); ok {
+			return readLevel(w, m)
+		}
+	}
+	return nil, fmt.Errorf("map %s not found", name)
+}
+
+func readLevel(w *wad.WAD, m wad.Map) (*Level, error) {
+	linedefs, ok1 := m.Lump("LINEDEFS")
+	sectors, ok2 := m.Lump("SECTORS")
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("map %s is missing its LINEDEFS or SECTORS", m.Name)
+	}
+	data, err := w.ReadLump(linedefs)
+	if err != nil {
+		return nil, err
+	}
+	level := &Level{Sectors: int(sectors.Size) / mapSectorSize}
+	for ld := range slices.Chunk(data, mapLinedefSize) {
+		if len(ld) < mapLinedefSize {
+			return nil, errors.New("truncated LINEDEFS")
+		}
+		level.Sides = append(level.Sides, [2]bool{
+			int16(binary.LittleEndian.Uint16(ld[10:])) != -1,
+			int16(binary.LittleEndian.Uint16(ld[12:])) != -1,
+		})
+	}
+	return level, nil
+}
@@ -0,0 +1,138 @@
+package gore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Demos recorded to, and played from, Go readers & writers rather than
+// files and WAD lumps.
+
+// DEMOHEADERSIZE is the size of the header of a v1.9 demo: the version,
+// skill, episode, map, deathmatch, respawn, fast, nomonsters and
+// consoleplayer, followed by which players are in the game
+const DEMOHEADERSIZE = 9 + MAXPLAYERS
+
+// demo_writer receives the demo being recorded when it finishes, rather than
+// it being written to demoname
+var demo_writer io.Writer
+
+// demo_err is the error from writing the demo to demo_writer
+var demo_err error
+
+// demo_data is the demo to play, rather than the lump defdemoname
+var demo_data []byte
+
+// demo_usergame is usergame from before RecordDemo, which is restored once
+// the demo finishes, as the game carries on
+var demo_usergame boolean
+
+// g_FinishDemo writes the demo which has just been recorded to demo_writer
+func g_FinishDemo() {
+	i_Callback(func() { _, demo_err = demo_writer.Write(demobuffer[:demo_pos]) })
+	demo_writer = nil
+	demobuffer = nil
+	demorecording = 0
+	usergame = demo_usergame
+}
+
+// RecordDemo restarts the current level, and records it as a demo, in the
+// same format as the -record command line parameter. Recording continues
+// until StopDemo is called, the demo quit key (q) is pressed, or the game is
+// closed, when the demo is written to w.
+func (g *Game) RecordDemo(w io.Writer) (err error) {
+	if err := g.lock(); err != nil {
+		return err
+	}
+	defer g.unlock()
+	if g.closed {
+		return ErrClosed
+	}
+	if g.err != nil {
+		return g.err
+	}
+	if demorecording != 0 {
+		return ErrDemoRecording
+	}
+	if gamestate != gs_LEVEL || demoplayback != 0 {
+		return ErrNoLevel
+	}
+	defer g.catch(&err)
+	demo_writer = w
+	demo_err = nil
+	demobuffer = nil
+	demorecording = 1
+	demo_usergame = usergame
+	usergame = 0
+	// Unlike g_DoNewGame, keep -respawn, -fast and -nomonsters, which are
+	// recorded in the demo, as -record does
+	g_InitNew(gameskill, gameepisode, gamemap)
+	gameaction = ga_nothing
+	g_BeginRecording()
+	return nil
+}
+
+// PlayDemo reads a demo from r, in the .lmp format used by the -playdemo
+// command line parameter, and plays it back from the next tic. Once it
+// finishes, the game returns to the title screen.
+func (g *Game) PlayDemo(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(data) < DEMOHEADERSIZE {
+		return errors.New("gore: demo is too short")
+	}
+	if player := data[8]; player >= MAXPLAYERS || data[9+int(player)] == 0 {
+		return fmt.Errorf("gore: demo's console player %d isn't in the game", player)
+	}
+	// End the demo early, rather than reading past the end, if it has been
+	// cut short
+	for range 5 {
+		data = append(data, DEMOMARKER)
+	}
+	if err := g.lock(); err != nil {
+		return err
+	}
+	defer g.unlock()
+	if g.closed {
+		return ErrClosed
+	}
+	if g.err != nil {
+		return g.err
+	}
+	if demorecording != 0 {
+		return ErrDemoRecording
+	}
+	if name := mapLumpName(int32(data[2]), int32(data[3])); w_CheckNumForName(name) < 0 {
+		return fmt.Errorf("gore: demo map %s not found", name)
+	}
+	demo_data = data
+	defdemoname = ""
+	gameaction = ga_playdemo
+	return nil
+}
+
+// StopDemo stops recording or playing back a demo. If a demo was being
+// recorded by RecordDemo, it is written out, and any error from writing it
+// is returned.
+func (g *Game) StopDemo() (err error) {
+	if err := g.lock(); err != nil {
+		return err
+	}
+	defer g.unlock()
+	if g.closed {
+		return ErrClosed
+	}
+	if g.err != nil {
+		return g.err
+	}
+	defer g.catch(&err)
+	if demorecording == 0 && demoplayback == 0 {
+		return nil
+	}
+	g_CheckDemoStatus()
+	err, demo_err = demo_err, nil
+	return err
+}
@@ -13,7 +13,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
-	"runtime/debug"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -22,10 +22,6 @@ import (
 
 var vfs fs.FS
 
-func init() {
-	vfs = os.DirFS(".")
-}
-
 // Stat is the stat function implemented with fs
 func fsStat(name string) (fs.FileInfo, error) {
 	if statfs, ok := vfs.(fs.StatFS); ok {
@@ -43,11 +39,18 @@ func fsStat(name string) (fs.FileInfo, error) {
 	return stat, nil
 }
 
-// SetVirtualFileSystem sets the virtual file system
+// SetVirtualFileSystem sets the virtual file system used by games which
+// don't provide their own via Options.FS
 func SetVirtualFileSystem(a fs.FS) {
-	vfs = a
+	engineLock.Lock()
+	defer engineLock.Unlock()
+	defaultVFS = a
 }
 
+// DoomFrontend displays a game and provides its input. Its methods are
+// called from the goroutine running the game, while the engine is locked, so
+// they can't call the Game's methods other than Stop; any others return
+// ErrReentrant.
 type DoomFrontend interface {
 	DrawFrame(img *image.RGBA)
 	SetTitle(title string)
@@ -111,18 +114,6 @@ func gostring_bytes(s []byte) string {
 	return string(s[:end])
 }
 
-func gostring(s uintptr) string {
-	if s == 0 {
-		return ""
-	}
-
-	p := s
-	for *(*byte)(unsafe.Pointer(p)) != 0 {
-		p++
-	}
-	return string(unsafe.Slice((*byte)(unsafe.Pointer(s)), p-s))
-}
-
 func gostring_n(s uintptr, n int) string {
 	if s == 0 || n <= 0 {
 		return ""
@@ -189,9 +180,7 @@ const PT_ADDLINES = 1
 const PT_ADDTHINGS = 2
 const PT_EARLYOUT = 4
 const REDRANGE = 16
-const SCREENHEIGHT = 200
 const SCREENHEIGHT_4_3 = 240
-const SCREENWIDTH = 320
 const SCREENWIDTH_4_3 = 256
 const SIL_BOTH = 3
 const SIL_BOTTOM = 1
@@ -1669,6 +1658,7 @@ const mf_TRANSSHIFT = 26
 
 type mobj_t struct {
 	degenmobj_t
+	Fid           uint32 // Unique ID, for Game.Mobjs
 	Fsnext        *mobj_t
 	Fsprev        *mobj_t
 	Fangle        angle_t
@@ -1700,6 +1690,11 @@ type mobj_t struct {
 	Flastlook     int32
 	Fspawnpoint   mapthing_t
 	Ftracer       *mobj_t
+	// Position at the start of the tic, for rendering between tics, if
+	// Finterpolate is set
+	Foldx, Foldy, Foldz fixed_t
+	Foldangle           angle_t
+	Finterpolate        boolean
 }
 
 type patch_t struct {
@@ -1768,6 +1763,9 @@ type sector_t struct {
 	Fspecialdata    any
 	Flinecount      int32
 	Flines          []*line_t
+	// Heights at the start of the tic, for rendering between tics
+	Foldfloorheight   fixed_t
+	Foldceilingheight fixed_t
 }
 
 type side_t struct {
@@ -1798,6 +1796,7 @@ type line_t struct {
 	Fbacksector  *sector_t
 	Fvalidcount  int32
 	Fspecialdata any
+	Findex       int32 // Position in lines, for the label buffer
 }
 
 type subsector_t struct {
@@ -1856,6 +1855,7 @@ type vissprite_t struct {
 	Fpatch      int32
 	Fcolormap   []lighttable_t
 	Fmobjflags  int32
+	Fid         uint32 // ID of the mobj, or 0 for the player's weapon
 }
 
 type spriteframe_t struct {
@@ -1875,12 +1875,8 @@ type visplane_t struct {
 	Flightlevel int32
 	Fminx       int32
 	Fmaxx       int32
-	Fpad1       uint8
-	Ftop        [320]uint8
-	Fpad2       uint8
-	Fpad3       uint8
-	Fbottom     [320]uint8
-	Fpad4       uint8
+	Ftop        []uint16 // Top row of each column, or 0xffff if unused
+	Fbottom     []uint16
 }
 
 type weaponinfo_t struct {
@@ -1964,6 +1960,7 @@ type player_t struct {
 	Fplayerstate     playerstate_t
 	Fcmd             ticcmd_t
 	Fviewz           fixed_t
+	Foldviewz        fixed_t // Fviewz at the start of the tic, for rendering between tics
 	Fviewheight      fixed_t
 	Fdeltaviewheight fixed_t
 	Fbob             fixed_t
@@ -2827,8 +2824,8 @@ func am_clearMarks() {
 func am_LevelInit() {
 	f_y = 0
 	f_x = 0
-	f_w = SCREENWIDTH
-	f_h = SCREENHEIGHT - 32
+	f_w = screenwidth
+	f_h = screenheight - v_StatusBarHeight()
 	am_clearMarks()
 	am_findMinMaxBoundaries()
 	scale_mtof = fixedDiv(min_scale_mtof, float2fixed(0.7))
@@ -3571,12 +3568,12 @@ func am_drawMarks() {
 		if markpoints[i].Fx != -1 {
 			//      w = SHORT(marknums[i]->width);
 			//      h = SHORT(marknums[i]->height);
-			w = 5 // because something's wrong with the wad, i guess
-			h = 6 // because something's wrong with the wad, i guess
+			w = v_ScaleX(5) // because something's wrong with the wad, i guess
+			h = v_ScaleY(6) // because something's wrong with the wad, i guess
 			fx = f_x + fixedMul(markpoints[i].Fx-m_x, scale_mtof)>>16
 			fy = f_y + (f_h - fixedMul(markpoints[i].Fy-m_y, scale_mtof)>>16)
 			if fx >= f_x && fx <= f_w-w && fy >= f_y && fy <= f_h-h {
-				v_DrawPatch(fx, fy, marknums[i])
+				v_DrawPatch(v_UnscaleX(fx), v_UnscaleY(fy), marknums[i])
 			}
 		}
 	}
@@ -4343,6 +4340,11 @@ func tryRunTics() {
 		if i_GetTime()/ticdup-entertic > 0 {
 			return
 		}
+		// With an uncapped framerate, draw a frame in between tics
+		// rather than waiting.
+		if interp_enabled && !dg_run_full_speed {
+			return
+		}
 		i_Sleep(1)
 	}
 	// run the count * ticdup dics
@@ -4423,9 +4425,9 @@ type sound_module_t struct {
 }
 
 type music_module_t struct {
-	Fsound_devices     uintptr
+	Fsound_devices     []snddevice_t
 	Fnum_sound_devices int32
-	FInit              func()
+	FInit              func() boolean
 	FShutdown          func()
 	FSetMusicVolume    func(volume int32)
 	FPauseMusic        func()
@@ -4434,7 +4436,7 @@ type music_module_t struct {
 	FUnRegisterSong    func(handle uintptr)
 	FPlaySong          func(handle uintptr, looping boolean) (r boolean)
 	FStopSong          func()
-	FMusicIsPlaying    uintptr
+	FMusicIsPlaying    func() boolean
 	FPoll              func()
 }
 
@@ -4623,13 +4625,17 @@ func d_Display() {
 	// save the current screen if about to wipe
 	if gamestate != wipegamestate {
 		wipe = 1
-		wipe_StartScreen(0, 0, SCREENWIDTH, SCREENHEIGHT)
+		wipe_StartScreen(0, 0, screenwidth, screenheight)
 	} else {
 		wipe = 0
 	}
 	if gamestate == gs_LEVEL && gametic != 0 {
 		hu_Erase()
 	}
+	// The 320x200 screens don't cover the sides in widescreen
+	if gamestate != gs_LEVEL {
+		v_FillPillars(nil, 0, ORIGHEIGHT)
+	}
 	// do buffered drawing
 	switch gamestate {
 	case gs_LEVEL:
@@ -4639,14 +4645,14 @@ func d_Display() {
 		if automapactive != 0 {
 			am_Drawer()
 		}
-		if wipe != 0 || viewheight != 200 && fullscreen != 0 {
+		if wipe != 0 || viewheight != screenheight && fullscreen != 0 {
 			redrawsbar = 1
 		}
 		if inhelpscreensstate != 0 && inhelpscreens == 0 {
 			redrawsbar = 1
 		} // just put away the help screen
-		st_Drawer(booluint32(viewheight == 200), redrawsbar)
-		fullscreen = booluint32(viewheight == 200)
+		st_Drawer(booluint32(viewheight == screenheight), redrawsbar)
+		fullscreen = booluint32(viewheight == screenheight)
 	case gs_INTERMISSION:
 		wi_Drawer()
 	case gs_FINALE:
@@ -4674,7 +4680,7 @@ func d_Display() {
 		r_FillBackScreen()  // draw the pattern into the back screen
 	}
 	// see if the border needs to be updated to the screen
-	if gamestate == gs_LEVEL && automapactive == 0 && scaledviewwidth != 320 {
+	if gamestate == gs_LEVEL && automapactive == 0 && scaledviewwidth != screenwidth {
 		if menuactive != 0 || menuactivestate != 0 || viewactivestate == 0 {
 			borderdrawcount = 3
 		}
@@ -4698,9 +4704,9 @@ func d_Display() {
 		if automapactive != 0 {
 			y = 4
 		} else {
-			y = viewwindowy + 4
+			y = v_UnscaleY(viewwindowy) + 4
 		}
-		v_DrawPatchDirect(viewwindowx+(scaledviewwidth-int32(68))/2, y, w_CacheLumpNameT("M_PAUSE"))
+		v_DrawPatchDirect(v_UnscaleX(viewwindowx)+(v_UnscaleX(scaledviewwidth)-int32(68))/2, y, w_CacheLumpNameT("M_PAUSE"))
 	}
 	// menus go directly to the screen
 	m_Drawer()  // menu is drawn even on top of everything
@@ -4711,7 +4717,7 @@ func d_Display() {
 		return
 	}
 	// wipe update
-	wipe_EndScreen(0, 0, SCREENWIDTH, SCREENHEIGHT)
+	wipe_EndScreen(0, 0, screenwidth, screenheight)
 	wipestart = i_GetTime() - 1
 	for cond := true; cond; cond = done == 0 {
 		for cond := true; cond; cond = tics <= 0 {
@@ -4720,7 +4726,7 @@ func d_Display() {
 			i_Sleep(1)
 		}
 		wipestart = nowtime
-		done = uint32(wipe_ScreenWipe(int32(wipe_Melt), 0, 0, SCREENWIDTH, SCREENHEIGHT, tics))
+		done = uint32(wipe_ScreenWipe(int32(wipe_Melt), 0, 0, screenwidth, screenheight, tics))
 		i_UpdateNoBlit()
 		m_Drawer()       // menu is drawn even on top of wipes
 		i_FinishUpdate() // page flip or blit buffer
@@ -4796,14 +4802,18 @@ func d_GrabMouseCallback() boolean {
 func doomgeneric_Tick() {
 	// frame syncronous IO operations
 	i_StartFrame()
-	tryRunTics() // will run at least one tic
+	tryRunTics() // will run at least one tic, unless uncapped
 	var dmo *degenmobj_t
 	if players[consoleplayer].Fmo != nil {
 		dmo = &players[consoleplayer].Fmo.degenmobj_t // console player
 	}
 	s_UpdateSounds(dmo) // move positional sounds
 	// Update display, next frame, with current state.
+	if !dg_run_full_speed {
+		r_BeginInterpolation(r_InterpolationFrac())
+	}
 	d_Display()
+	r_EndInterpolation()
 }
 
 // C documentation
@@ -5584,7 +5594,10 @@ func d_DoomMain() {
 	// Set the gamedescription string. This is only possible now that
 	// we've finished loading Dehacked patches.
 	d_SetGameDescription()
-	savegamedir = m_GetSaveGameDir(d_SaveGameIWADName(gamemission))
+	if save_store == nil {
+		savegamedir = m_GetSaveGameDir(d_SaveGameIWADName(gamemission))
+		save_store = NewDirSaveStore(savegamedir)
+	}
 	// Check for -file in shareware
 	if modifiedgame != 0 {
 		// These are the lumps that will be checked in IWAD,
@@ -5797,7 +5810,7 @@ func d_DoomMain() {
 		return
 	}
 	if startloadgame >= 0 {
-		g_LoadGame(p_SaveGameFile(startloadgame))
+		g_LoadGame(startloadgame)
 	}
 	if gameaction != ga_loadgame {
 		if autostart != 0 || netgame != 0 {
@@ -6293,20 +6306,8 @@ func f_TextWrite() {
 	var c, count, cx, cy, w int32
 	var pos int
 	// erase the entire screen to a tiled background
-	src := w_CacheLumpNameBytes(finaleflat)
-	destPos := 0
-	for y := 0; y < SCREENHEIGHT; y++ {
-		for x := 0; x < SCREENWIDTH/64; x++ {
-			copy(I_VideoBuffer[destPos:], src[uintptr(y&63<<6):uintptr(y&63<<6)+64])
-			destPos += 64
-		}
-		if SCREENWIDTH&63 != 0 {
-			length := SCREENWIDTH & 63
-			copy(I_VideoBuffer[destPos:destPos+length], src[y&63<<6:])
-			destPos += length
-		}
-	}
-	v_MarkRect(0, 0, SCREENWIDTH, SCREENHEIGHT)
+	v_TileFlat(I_VideoBuffer, w_CacheLumpNameBytes(finaleflat))
+	v_MarkRect(0, 0, ORIGWIDTH, ORIGHEIGHT)
 	// draw some of the text onto the screen
 	cx = 10
 	cy = 10
@@ -6332,7 +6333,7 @@ func f_TextWrite() {
 			continue
 		}
 		w = int32(hu_font[c].Fwidth)
-		if cx+w > SCREENWIDTH {
+		if cx+w > ORIGWIDTH {
 			break
 		}
 		v_DrawPatch(cx, cy, hu_font[c])
@@ -6658,20 +6659,7 @@ func f_CastDrawer() {
 //	// F_DrawPatchCol
 //	//
 func f_DrawPatchCol(x int32, patch *patch_t, col int32) {
-	var dest int32
-	var count int32
-	column := patch.GetColumn(col)
-	// step through the posts in a column
-	for int32(column.Ftopdelta) != 0xff {
-		source := column.Data()
-		dest = x + int32(column.Ftopdelta)*SCREENWIDTH
-		count = int32(column.Flength)
-		for i := int32(0); i < count; i++ {
-			I_VideoBuffer[dest] = source[i]
-			dest += SCREENWIDTH
-		}
-		column = column.Next()
-	}
+	v_DrawPatchColumn(I_VideoBuffer, x, 0, patch.GetColumn(col))
 }
 
 // C documentation
@@ -6683,7 +6671,7 @@ func f_BunnyScroll() {
 	var scrolled, stage int32
 	p1 := w_CacheLumpNameT("PFUB2")
 	p2 := w_CacheLumpNameT("PFUB1")
-	v_MarkRect(0, 0, SCREENWIDTH, SCREENHEIGHT)
+	v_MarkRect(0, 0, ORIGWIDTH, ORIGHEIGHT)
 	scrolled = 320 - (int32(finalecount)-int32(230))/2
 	if scrolled > 320 {
 		scrolled = 320
@@ -6691,7 +6679,7 @@ func f_BunnyScroll() {
 	if scrolled < 0 {
 		scrolled = 0
 	}
-	for x := int32(0); x < SCREENWIDTH; x++ {
+	for x := int32(0); x < ORIGWIDTH; x++ {
 		if x+scrolled < 320 {
 			f_DrawPatchCol(x, p1, x+scrolled)
 		} else {
@@ -6702,7 +6690,7 @@ func f_BunnyScroll() {
 		return
 	}
 	if finalecount < 1180 {
-		v_DrawPatch((SCREENWIDTH-13*8)/2, (SCREENHEIGHT-8*8)/2, w_CacheLumpNameT("END0"))
+		v_DrawPatch((ORIGWIDTH-13*8)/2, (ORIGHEIGHT-8*8)/2, w_CacheLumpNameT("END0"))
 		laststage = 0
 		return
 	}
@@ -6715,7 +6703,7 @@ func f_BunnyScroll() {
 		laststage = stage
 	}
 	name := fmt.Sprintf("END%d", stage)
-	v_DrawPatch((SCREENWIDTH-13*8)/2, (SCREENHEIGHT-8*8)/2, w_CacheLumpNameT(name))
+	v_DrawPatch((ORIGWIDTH-13*8)/2, (ORIGHEIGHT-8*8)/2, w_CacheLumpNameT(name))
 }
 
 var laststage int32
@@ -6870,10 +6858,12 @@ func wipe_doMelt(width int32, height int32, ticks int32) int32 {
 				done = 0
 			} else {
 				if y_screen[i] < height {
-					if y_screen[i] < 16 {
-						v3 = y_screen[i] + 1
+					// Speeds are scaled, so the wipe takes as long
+					// at any resolution.
+					if y_screen[i] < v_ScaleY(16) {
+						v3 = y_screen[i] + v_ScaleY(1)
 					} else {
-						v3 = 8
+						v3 = v_ScaleY(8)
 					}
 					dy = v3
 					if y_screen[i]+dy >= height {
@@ -6914,13 +6904,13 @@ func wipe_exitMelt(width int32, height int32, ticks int32) int32 {
 }
 
 func wipe_StartScreen(x int32, y int32, width int32, height int32) int32 {
-	wipe_scr_start = make([]byte, SCREENWIDTH*SCREENHEIGHT)
+	wipe_scr_start = make([]byte, screenwidth*screenheight)
 	i_ReadScreen(wipe_scr_start)
 	return 0
 }
 
 func wipe_EndScreen(x int32, y int32, width int32, height int32) int32 {
-	wipe_scr_end = make([]byte, SCREENWIDTH*SCREENHEIGHT)
+	wipe_scr_end = make([]byte, screenwidth*screenheight)
 	i_ReadScreen(wipe_scr_end)
 	v_DrawBlock(x, y, width, height, wipe_scr_start) // restore start scr.
 	return 0
@@ -7355,6 +7345,7 @@ func g_BuildTiccmd(cmd *ticcmd_t, maketic int32) {
 	}
 	cmd.Fforwardmove += int8(forward)
 	cmd.Fsidemove += int8(side)
+	g_ApplyTicOverride(cmd)
 	// special buttons
 	if sendpause != 0 {
 		sendpause = 0
@@ -7499,6 +7490,11 @@ func g_Responder(ev *event_t) boolean {
 		}
 		return 1
 	}
+	// screenshots can be taken at any time
+	if ev.Ftype1 == Ev_keydown && key_menu_screenshot != 0 && ev.Fdata1 == key_menu_screenshot {
+		g_ScreenShot()
+		return 1
+	}
 	// any other key pops up menu if in demos
 	if gameaction == ga_nothing && singledemo == 0 && (demoplayback != 0 || gamestate == gs_DEMOSCREEN) {
 		if ev.Ftype1 == Ev_keydown || ev.Ftype1 == Ev_mouse && ev.Fdata1 != 0 || ev.Ftype1 == Ev_joystick && ev.Fdata1 != 0 {
@@ -7605,6 +7601,14 @@ func g_Ticker() {
 			f_StartFinale()
 		case ga_worlddone:
 			g_DoWorldDone()
+		case ga_screenshot:
+			if err := v_ScreenShot(); err != nil {
+				fprintf_ccgo(os.Stderr, "Couldn't save screenshot: %v\n", err)
+				players[consoleplayer].Fmessage = "screen shot failed"
+			} else {
+				players[consoleplayer].Fmessage = "screen shot"
+			}
+			gameaction = ga_nothing
 		case ga_nothing:
 			break
 		}
@@ -8140,25 +8144,38 @@ func g_DoWorldDone() {
 	viewactive = 1
 }
 
-func g_LoadGame(name string) {
-	savename = name
+func g_LoadGame(slot int32) {
+	loadgameslot = slot
 	gameaction = ga_loadgame
 }
 
 func g_DoLoadGame() {
-	var savedleveltime int32
-	var err error
 	gameaction = ga_nothing
-	save_stream, err = os.Open(savename)
+	var data []byte
+	var err error
+	i_Callback(func() { data, err = save_store.Read(int(loadgameslot)) })
 	if err != nil {
-		log.Printf("g_DoLoadGame: error opening savegame file %s: %v\n", savename, err)
+		log.Printf("g_DoLoadGame: error reading savegame %d: %v\n", loadgameslot, err)
 		return
 	}
-	defer save_stream.Close()
+	if err := g_ReadSaveGame(data); err != nil {
+		log.Printf("g_DoLoadGame: error loading savegame %d: %v\n", loadgameslot, err)
+		if _, ok := err.(*WADMismatchError); ok {
+			players[consoleplayer].Fmessage = "savegame needs different wads."
+		}
+	}
+}
+
+// g_ReadSaveGame loads the level saved in data, returning ErrSaveVersion if
+// it isn't a savegame for this version, or a *WADMismatchError if it was
+// saved with different PWADs
+func g_ReadSaveGame(data []byte) error {
+	var savedleveltime int32
+	save_stream = &savestream_t{Fdata: data}
+	defer func() { save_stream = nil }()
 	savegame_error = 0
-	if p_ReadSaveGameHeader() == 0 {
-		save_stream.Close()
-		return
+	if err := p_ReadSaveGameHeader(); err != nil {
+		return err
 	}
 	savedleveltime = leveltime
 	// load a base level
@@ -8177,6 +8194,7 @@ func g_DoLoadGame() {
 	}
 	// draw the pattern into the back screen
 	r_FillBackScreen()
+	return nil
 }
 
 // C documentation
@@ -8193,57 +8211,42 @@ func g_SaveGame(slot int32, description string) {
 }
 
 func g_DoSaveGame() {
-	var recovery_savegame_file, savegame_file, temp_savegame_file string
-	recovery_savegame_file = ""
-	temp_savegame_file = p_TempSaveGameFile()
-	savegame_file = p_SaveGameFile(savegameslot)
-	// Open the savegame file for writing.  We write to a temporary file
-	// and then rename it at the end if it was successfully written.
-	// This prevents an existing savegame from being overwritten by
-	// a corrupted one, or if a savegame buffer overrun occurs.
-	var err error
-	save_stream, err = os.OpenFile(temp_savegame_file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	// The savegame is built up in memory, and only handed to the save
+	// store once it has been successfully written. This prevents an
+	// existing savegame from being overwritten by a corrupted one, or if a
+	// savegame buffer overrun occurs.
+	gameaction = ga_nothing
+	data, oversize, err := g_WriteSaveGame(savedescription)
+	if err == nil {
+		i_Callback(func() { err = save_store.Write(int(savegameslot), data) })
+	}
 	if err != nil {
-		log.Printf("g_DoSaveGame: error opening savegame file %s: %v\n", temp_savegame_file, err)
-		// Failed to save the game, so we're going to have to abort. But
-		// to be nice, save to somewhere else before we call i_Error().
-		recovery_savegame_file = m_TempFile("recovery.dsg")
-		save_stream, err = os.OpenFile(recovery_savegame_file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-		if err != nil {
-			log.Printf("g_DoSaveGame: error opening recovery savegame file %s: %v\n", recovery_savegame_file, err)
-			i_Error("Failed to open either '%s' or '%s' to write savegame.", temp_savegame_file, recovery_savegame_file)
-		}
+		log.Printf("g_DoSaveGame: error writing savegame %d: %v\n", savegameslot, err)
+		players[consoleplayer].Fmessage = "game save failed."
+		return
 	}
+	savedescription = ""
+	players[consoleplayer].Fmessage = "game saved."
+	if oversize {
+		players[consoleplayer].Fmessage = "game saved, too big for vanilla doom."
+	}
+	// draw the pattern into the back screen
+	r_FillBackScreen()
+}
+
+// g_WriteSaveGameFormat returns the current level as a savegame, in either
+// the Vanilla or extended format
+func g_WriteSaveGameFormat(description string, extended bool) []byte {
+	save_stream = &savestream_t{}
+	defer func() { save_stream = nil }()
 	savegame_error = 0
-	p_WriteSaveGameHeader(savedescription)
+	p_WriteSaveGameHeader(description, extended)
 	p_ArchivePlayers()
 	p_ArchiveWorld()
 	p_ArchiveThinkers()
 	p_ArchiveSpecials()
 	p_WriteSaveGameEOF()
-	// Enforce the same savegame size limit as in Vanilla Doom,
-	// except if the vanilla_savegame_limit setting is turned off.
-	pos, err := save_stream.Seek(0, io.SeekCurrent)
-	if vanilla_savegame_limit != 0 && pos > SAVEGAMESIZE {
-		i_Error("Savegame buffer overrun")
-	}
-	// Finish up, close the savegame file.
-	save_stream.Close()
-	if recovery_savegame_file != "" {
-		// We failed to save to the normal location, but we wrote a
-		// recovery file to the temp directory. Now we can bomb out
-		// with an error.
-		i_Error("Failed to open savegame file '%s' for writing.\nBut your game has been saved to '%s' for recovery.", temp_savegame_file, recovery_savegame_file)
-	}
-	// Now rename the temporary savegame file to the actual savegame
-	// file, overwriting the old savegame if there was one there.
-	os.Remove(savegame_file) // remove the old savegame file
-	os.Rename(temp_savegame_file, savegame_file)
-	gameaction = ga_nothing
-	savedescription = ""
-	players[consoleplayer].Fmessage = "game saved."
-	// draw the pattern into the back screen
-	r_FillBackScreen()
+	return save_stream.Fdata
 }
 
 func g_DeferedInitNew(skill skill_t, episode int32, map1 int32) {
@@ -8432,6 +8435,9 @@ func g_ReadDemoTiccmd(cmd *ticcmd_t) {
 func g_WriteDemoTiccmd(cmd *ticcmd_t) {
 	if gamekeydown[key_demo_quit] { // press q to end demo recording
 		g_CheckDemoStatus()
+		if demorecording == 0 {
+			return
+		}
 	}
 	demo_start := demo_pos
 	if len(demobuffer)-demo_pos < 6 {
@@ -8546,6 +8552,7 @@ func g_BeginRecording() {
 	demo_pos++
 	for i := range MAXPLAYERS {
 		demobuffer[demo_pos] = uint8(playeringame[i])
+		demo_pos++
 	}
 }
 
@@ -8589,11 +8596,17 @@ func g_DoPlayDemo() {
 	var demoversion, episode, map1 int32
 	var skill skill_t
 	gameaction = ga_nothing
-	num := w_GetNumForName(defdemoname)
-	length := w_LumpLength(uint32(num))
-	demodata := w_CacheLumpNum(num)
-	demobuffer = make([]byte, length)
-	copy(demobuffer, unsafe.Slice((*uint8)(unsafe.Pointer(demodata)), length))
+	if demo_data != nil {
+		// Given to PlayDemo
+		demobuffer = demo_data
+		demo_data = nil
+	} else {
+		num := w_GetNumForName(defdemoname)
+		length := w_LumpLength(uint32(num))
+		demodata := w_CacheLumpNum(num)
+		demobuffer = make([]byte, length)
+		copy(demobuffer, unsafe.Slice((*uint8)(unsafe.Pointer(demodata)), length))
+	}
 	demo_pos = 0
 	demoversion = int32(demobuffer[demo_pos])
 	demo_pos++
@@ -8628,6 +8641,9 @@ func g_DoPlayDemo() {
 		playeringame[i] = uint32(demobuffer[demo_pos])
 		demo_pos++
 	}
+	if consoleplayer >= MAXPLAYERS || playeringame[consoleplayer] == 0 {
+		i_Error("Demo's console player %d isn't in the game", consoleplayer)
+	}
 	if playeringame[1] != 0 || m_CheckParm("-solo-net") > 0 || m_CheckParm("-netdemo") > 0 {
 		netgame = 1
 		netdemo = 1
@@ -8682,7 +8698,9 @@ func g_CheckDemoStatus() {
 		i_Error("timed %d gametics in %d realtics (%f fps)", gametic, realtics, float64(fps))
 	}
 	if demoplayback != 0 {
-		w_ReleaseLumpName(defdemoname)
+		if defdemoname != "" {
+			w_ReleaseLumpName(defdemoname)
+		}
 		demoplayback = 0
 		netdemo = 0
 		netgame = 0
@@ -8706,6 +8724,11 @@ func g_CheckDemoStatus() {
 	if demorecording != 0 {
 		demobuffer[demo_pos] = uint8(DEMOMARKER)
 		demo_pos++
+		if demo_writer != nil {
+			// Recorded by RecordDemo, so the game carries on
+			g_FinishDemo()
+			return
+		}
 		m_WriteFile(demoname, demobuffer[:demo_pos])
 		demobuffer = nil
 		demorecording = 0
@@ -8788,20 +8811,20 @@ func hulib_drawTextLine(l *hu_textline_t, drawcursor boolean) {
 		c = uint8(xtoupper(int32(l.Fl[i])))
 		if int32(c) != ' ' && int32(c) >= l.Fsc && int32(c) <= '_' {
 			w = int32(l.Ff[int32(c)-l.Fsc].Fwidth)
-			if x+w > SCREENWIDTH {
+			if x+w > ORIGWIDTH {
 				break
 			}
 			v_DrawPatchDirect(x, l.Fy, l.Ff[int32(c)-l.Fsc])
 			x += w
 		} else {
 			x += 4
-			if x >= SCREENWIDTH {
+			if x >= ORIGWIDTH {
 				break
 			}
 		}
 	}
 	// draw the cursor if requested
-	if drawcursor != 0 && x+int32(l.Ff['_'-l.Fsc].Fwidth) <= SCREENWIDTH {
+	if drawcursor != 0 && x+int32(l.Ff['_'-l.Fsc].Fwidth) <= ORIGWIDTH {
 		v_DrawPatchDirect(x, l.Fy, l.Ff['_'-l.Fsc])
 	}
 }
@@ -8816,17 +8839,17 @@ func hulib_eraseTextLine(l *hu_textline_t) {
 	// (because of a recent change back from the automap)
 	if automapactive == 0 && viewwindowx != 0 && l.Fneedsupdate != 0 {
 		lh = int32(l.Ff[0].Fheight) + 1
-		y = l.Fy
-		yoffset = y * SCREENWIDTH
-		for ; y < l.Fy+lh; y++ {
+		y = v_ScaleY(l.Fy)
+		yoffset = y * screenwidth
+		for ; y < v_ScaleY(l.Fy+lh); y++ {
 			if y < viewwindowy || y >= viewwindowy+viewheight {
-				r_VideoErase(uint32(yoffset), SCREENWIDTH)
+				r_VideoErase(uint32(yoffset), screenwidth)
 			} else {
 				r_VideoErase(uint32(yoffset), viewwindowx) // erase left border
-				r_VideoErase(uint32(yoffset+viewwindowx+viewwidth), viewwindowx)
+				r_VideoErase(uint32(yoffset+viewwindowx+scaledviewwidth), screenwidth-viewwindowx-scaledviewwidth)
 				// erase right border
 			}
-			yoffset += SCREENWIDTH
+			yoffset += screenwidth
 		}
 	}
 	if l.Fneedsupdate != 0 {
@@ -17257,7 +17280,7 @@ func i_BindJoystickVariables() {
 }
 
 // 1x scale doesn't really do any scaling: it just copies the buffer
-// a line at a time for when pitch != SCREENWIDTH (!native_surface)
+// a line at a time for when pitch != screenwidth (!native_surface)
 
 func init() {
 	snd_samplerate = 44100
@@ -17299,7 +17322,12 @@ var snd_mport = 0
 
 // Compiled-in sound modules:
 
-var sound_modules = []sound_module_t{}
+var sound_modules = []sound_module_t{mixer_module}
+
+// Compiled-in music modules. The frontend's MIDI player also handles AdLib
+// and Sound Blaster music if the OPL emulator can't run.
+
+var music_modules = []music_module_t{opl_music_module, midi_music_module}
 
 // Check if a sound device is in the given list of devices
 
@@ -17333,6 +17361,18 @@ func initSfxModule(use_sfx_prefix boolean) {
 // Initialize music according to snd_musicdevice.
 
 func initMusicModule() {
+	for i := range music_modules {
+		m := &music_modules[i]
+		// Is the music device in the list of devices supported
+		// by this module?
+		if sndDeviceInList(snd_musicdevice, m.Fsound_devices, m.Fnum_sound_devices) != 0 {
+			// Initialize the module
+			if m.FInit() != 0 {
+				music_module = m
+				return
+			}
+		}
+	}
 }
 
 //
@@ -17456,9 +17496,6 @@ func i_PrecacheSounds(sounds []sfxinfo_t) {
 }
 
 func i_InitMusic() {
-	if music_module != nil {
-		music_module.FInit()
-	}
 }
 
 func i_ShutdownMusic() {
@@ -17484,7 +17521,7 @@ func i_ResumeSong() {
 
 func i_RegisterSong(data []byte) uintptr {
 	if music_module != nil {
-		music_module.FRegisterSong(data)
+		return music_module.FRegisterSong(data)
 	}
 	return 0
 }
@@ -17518,6 +17555,11 @@ func i_BindSoundVariables() {
 	m_BindVariable("snd_musiccmd", &snd_musiccmd)
 	m_BindVariable("snd_samplerate", &snd_samplerate)
 	m_BindVariable("snd_cachesize", &snd_cachesize)
+	// A frontend which plays MIDI itself presumably wants to, so make
+	// that the default music device rather than the OPL emulator
+	if _, ok := dg_frontend.(DoomMusicFrontend); ok {
+		snd_musicdevice = SNDDEVICE_GENMIDI
+	}
 	// Before SDL_mixer version 1.2.11, MIDI music caused the game
 	// to crash when it looped.  If this is an old SDL_mixer version,
 	// disable MIDI.
@@ -17594,6 +17636,8 @@ func i_Quit() {
 		// Call the exit function.
 		exit_funcs[i].Ffunc()
 	}
+	exit_funcs = nil
+	dg_exiting = true
 }
 
 //
@@ -17603,35 +17647,23 @@ func i_Quit() {
 var already_quitting = 0
 
 func i_Error(errStr string, args ...any) {
-	var exit_gui_popup boolean
+	err := newEngineError(fmt.Sprintf(errStr, args...))
+	fmt.Fprintf(os.Stderr, "%s\n\n", err.Message)
 	if already_quitting != 0 {
 		fprintf_ccgo(os.Stderr, "Warning: recursive call to i_Error detected.\n")
 	} else {
 		already_quitting = 1
-	}
-	fmt.Fprintf(os.Stderr, errStr, args...)
-	fprintf_ccgo(os.Stderr, "\n\n")
-
-	debug.PrintStack()
-
-	// Shutdown. Here might be other errors.
-	for i := len(exit_funcs) - 1; i >= 0; i-- {
-		// Call the exit function.
+		// Shutdown. Here might be other errors.
+		for i := len(exit_funcs) - 1; i >= 0; i-- {
+			// Call the exit function.
 
-		if exit_funcs[i].Frun_on_error != 0 {
-			exit_funcs[i].Ffunc()
+			if exit_funcs[i].Frun_on_error != 0 {
+				exit_funcs[i].Ffunc()
+			}
 		}
 	}
-	exit_gui_popup = booluint32(m_ParmExists("-nogui") == 0)
-	// Pop up a GUI dialog box to show the error message, if the
-	// game was not run from the console (and the user will
-	// therefore be unable to otherwise see the message).
-	if exit_gui_popup != 0 && i_ConsoleStdout() == 0 {
-		// TODO: Expose error message somehow?
-	}
-	// abort();
-	for 1 != 0 {
-	}
+	// Unwind back out to the Game, which returns this to the caller
+	panic(err)
 }
 
 //
@@ -18886,6 +18918,7 @@ func init() {
 	key_menu_qload = 0x80 + 0x43
 	key_menu_quit = 0x80 + 0x44
 	key_menu_gamma = 0x80 + 0x57
+	key_menu_screenshot = KEY_PRTSCR
 	key_menu_incscreen = int32(KEY_EQUALS1)
 	key_menu_decscreen = int32(KEY_MINUS1)
 	joybstrafe = 1
@@ -19484,19 +19517,26 @@ func init() {
 //	//  read the strings from the savegame files
 //	//
 func m_ReadSaveStrings() {
+	var slots []int
+	var err error
+	i_Callback(func() { slots, err = save_store.List() })
+	if err != nil {
+		log.Printf("m_ReadSaveStrings: error listing savegames: %v\n", err)
+	}
 	for i := range int32(load_end) {
-		var thisString [SAVESTRINGSIZE]byte
-		var err error
-		handle, err := os.Open(p_SaveGameFile(i))
-		if err != nil {
+		var data []byte
+		if slices.Contains(slots, int(i)) {
+			i_Callback(func() { data, err = save_store.Read(int(i)) })
+			if err != nil {
+				log.Printf("m_ReadSaveStrings: error reading savegame %d: %v\n", i, err)
+			}
+		}
+		if data == nil {
 			savegamestrings[i] = "empty slot"
 			LoadMenu[i].Fstatus = 0
 			continue
 		}
-
-		handle.Read(thisString[:])
-		savegamestrings[i] = gostring_bytes(thisString[:])
-		handle.Close()
+		savegamestrings[i] = gostring_bytes(data[:min(len(data), SAVESTRINGSIZE)])
 		LoadMenu[i].Fstatus = 1
 	}
 }
@@ -19534,7 +19574,7 @@ func m_DrawSaveLoadBorder(x int32, y int32) {
 //	// User wants to load this game
 //	//
 func m_LoadSelect(choice int32) {
-	g_LoadGame(p_SaveGameFile(choice))
+	g_LoadGame(choice)
 	m_ClearMenus()
 }
 
@@ -20141,7 +20181,7 @@ func m_WriteText(x int32, y int32, string1 string) {
 			continue
 		}
 		w = int32(hu_font[c].Fwidth)
-		if cx+w > SCREENWIDTH {
+		if cx+w > ORIGWIDTH {
 			break
 		}
 		v_DrawPatchDirect(cx, cy, hu_font[c])
@@ -20576,7 +20616,7 @@ func m_Drawer() {
 	// Horiz. & Vertically center string and print it.
 	if messageToPrint != 0 {
 		start = 0
-		y2 = int16(SCREENHEIGHT/2 - m_StringHeight(messageString)/2)
+		y2 = int16(ORIGHEIGHT/2 - m_StringHeight(messageString)/2)
 		for start < int32(len(messageString)) {
 			foundnewline = 0
 			for i := uint32(0); i < uint32(len(messageString[start:])); i++ {
@@ -20591,7 +20631,7 @@ func m_Drawer() {
 				bp = messageString[start:]
 				start = int32(uint64(start) + uint64(len(bp)))
 			}
-			x = int16(SCREENWIDTH/2 - m_StringWidth(bp)/2)
+			x = int16(ORIGWIDTH/2 - m_StringWidth(bp)/2)
 			m_WriteText(int32(x), int32(y2), bp)
 			y2 = int16(int32(y2) + int32(hu_font[0].Fheight))
 		}
@@ -20742,15 +20782,6 @@ func m_WriteFile(name string, source []byte) boolean {
 	return 1
 }
 
-// Returns the path to a temporary file of the given name, stored
-// inside the system temporary directory.
-//
-// The returned value must be freed with z_Free after use.
-
-func m_TempFile(s string) string {
-	return "/tmp" + "/" + s
-}
-
 func m_ExtractFileBase(path string, dest []byte) {
 	src := filepath.Base(path)
 	// Copy up to eight characters
@@ -24756,6 +24787,7 @@ func p_TeleportMove(thing *mobj_t, x fixed_t, y fixed_t) boolean {
 	thing.Fx = x
 	thing.Fy = y
 	p_SetThingPosition(thing)
+	thing.Finterpolate = 0 // Don't draw it sliding across the map
 	return 1
 }
 
@@ -26771,6 +26803,7 @@ func p_SpawnMobj(x fixed_t, y fixed_t, z fixed_t, type1 mobjtype_t) (r *mobj_t)
 	var info *mobjinfo_t
 	mobj := &mobj_t{}
 	info = &mobjinfo[type1]
+	mobj.Fid = p_NewMobjID()
 	mobj.Ftype1 = type1
 	mobj.Finfo = info
 	mobj.Fx = x
@@ -27997,25 +28030,6 @@ func p_MovePsprites(player *player_t) {
 
 const SAVEGAME_EOF = 29
 
-// Get the filename of a temporary file to write the savegame to.  After
-// the file has been successfully saved, it will be renamed to the
-// real file.
-
-func p_TempSaveGameFile() string {
-	if filename == "" {
-		filename = savegamedir + "temp.dsg"
-	}
-	return filename
-}
-
-var filename string
-
-// Get the filename of the save game file to use for the specified slot.
-
-func p_SaveGameFile(slot int32) string {
-	return fmt.Sprintf("%sdgsave%d.dsg", savegamedir, slot)
-}
-
 // Endian-safe integer read/write functions
 
 func saveg_read8() uint8 {
@@ -28478,7 +28492,9 @@ func saveg_read_player_t(str *player_t) {
 	// int secretcount;
 	str.Fsecretcount = saveg_read32()
 	// char* message;
-	str.Fmessage = gostring(saveg_readp())
+	// The saved pointer is meaningless once loaded, so skip it
+	saveg_readp()
+	str.Fmessage = ""
 	// int damagecount;
 	str.Fdamagecount = saveg_read32()
 	// int bonuscount;
@@ -28910,7 +28926,7 @@ func saveg_write_glow_t(str *glow_t) {
 // Write the header for a savegame
 //
 
-func p_WriteSaveGameHeader(description string) {
+func p_WriteSaveGameHeader(description string, extended bool) {
 	for i := 0; i < SAVESTRINGSIZE; i++ {
 		if i < len(description) {
 			saveg_write8(uint8(description[i]))
@@ -28919,6 +28935,9 @@ func p_WriteSaveGameHeader(description string) {
 		}
 	}
 	bp := fmt.Sprintf("version %d", g_VanillaVersionCode())
+	if extended {
+		bp = p_ExtendedVersion()
+	}
 	for i := range VERSIONSIZE {
 		if i < len(bp) {
 			saveg_write8(uint8(bp[i]))
@@ -28935,13 +28954,16 @@ func p_WriteSaveGameHeader(description string) {
 	saveg_write8(uint8(leveltime >> int32(16) & 0xff))
 	saveg_write8(uint8(leveltime >> 8 & 0xff))
 	saveg_write8(uint8(leveltime & 0xff))
+	if extended {
+		p_WriteSaveGamePWADs()
+	}
 }
 
 //
 // Read the header for a savegame
 //
 
-func p_ReadSaveGameHeader() boolean {
+func p_ReadSaveGameHeader() error {
 	var a, b, c uint8
 	// skip the description field
 	for range SAVESTRINGSIZE {
@@ -28952,21 +28974,33 @@ func p_ReadSaveGameHeader() boolean {
 		bp[i] = saveg_read8()
 	}
 	vanilla := fmt.Sprintf("version %d", g_VanillaVersionCode())
-	if vanilla != gostring_bytes(bp[:]) {
-		return 0
+	extended := p_ExtendedVersion() == gostring_bytes(bp[:])
+	if !extended && vanilla != gostring_bytes(bp[:]) {
+		return ErrSaveVersion
 	} // bad version
-	gameskill = skill_t(saveg_read8())
-	gameepisode = int32(saveg_read8())
-	gamemap = int32(saveg_read8())
+	skill := skill_t(saveg_read8())
+	episode := int32(saveg_read8())
+	map1 := int32(saveg_read8())
+	var ingame [MAXPLAYERS]boolean
 	for i := range MAXPLAYERS {
-		playeringame[i] = uint32(saveg_read8())
+		ingame[i] = uint32(saveg_read8())
 	}
 	// get the times
 	a = saveg_read8()
 	b = saveg_read8()
 	c = saveg_read8()
+	// Check the PWADs before changing anything
+	if extended {
+		if err := p_ReadSaveGamePWADs(); err != nil {
+			return err
+		}
+	}
+	gameskill = skill
+	gameepisode = episode
+	gamemap = map1
+	playeringame = ingame
 	leveltime = int32(a)<<int32(16) + int32(b)<<8 + int32(c)
-	return 1
+	return nil
 }
 
 //
@@ -29153,6 +29187,7 @@ func p_UnArchiveThinkers() {
 			saveg_read_pad()
 			mobj = &mobj_t{}
 			saveg_read_mobj_t(mobj)
+			mobj.Fid = p_NewMobjID()
 			mobj.Ftarget = nil
 			mobj.Ftracer = nil
 			p_SetThingPosition(mobj)
@@ -29561,6 +29596,7 @@ func p_LoadLineDefs(lump int32) {
 	for i = 0; i < numlines; i++ {
 		ld := &lines[i]
 		mld := ml[i]
+		ld.Findex = i
 		ld.Fflags = mld.Fflags
 		ld.Fspecial = mld.Fspecial
 		ld.Ftag = mld.Ftag
@@ -29848,6 +29884,7 @@ func p_SetupLevel(episode int32, map1 int32, playermask int32, skill skill_t) {
 		bp = string([]byte{'E', '0' + byte(episode), 'M', '0' + byte(map1)})
 	}
 	lumpnum = w_GetNumForName(bp)
+	level_maplump = lumpnum
 	leveltime = 0
 	// note: most of this ordering is important
 	p_LoadBlockMap(lumpnum + ml_BLOCKMAP)
@@ -32144,6 +32181,7 @@ func p_RunThinkers() {
 //
 
 func p_Ticker() {
+	p_RecordInterpolation()
 	// run the tic
 	if paused != 0 {
 		return
@@ -33479,13 +33517,14 @@ func r_DrawColumn() {
 	if count < 0 {
 		return
 	}
-	if uint32(dc_x) >= SCREENWIDTH || dc_yl < 0 || dc_yh >= SCREENHEIGHT {
+	if uint32(dc_x) >= uint32(screenwidth) || dc_yl < 0 || dc_yh >= screenheight {
 		i_Error("r_DrawColumn: %d to %d at %d", dc_yl, dc_yh, dc_x)
 	}
 	// Framebuffer destination address.
 	// Use ylookup LUT to avoid multiply with ScreenWidth.
 	// Use columnofs LUT for subwindows?
 	dest = ylookup[dc_yl] + (columnofs[dc_x])
+	r_AuxColumn(dc_x, dc_yl, dc_yh)
 	// Determine scaling,
 	//  which is the only mapping to be done.
 	fracstep = dc_iscale
@@ -33497,7 +33536,7 @@ func r_DrawColumn() {
 		// Re-map color indices from wall texture column
 		//  using a lighting/special effects LUT.
 		I_VideoBuffer[dest] = dc_colormap[*(*uint8)(unsafe.Pointer(dc_source + uintptr(frac>>FRACBITS&int32(127))))]
-		dest += SCREENWIDTH
+		dest += screenwidth
 		frac += fracstep
 	}
 }
@@ -33515,7 +33554,7 @@ func r_DrawColumnLow() {
 	if count < 0 {
 		return
 	}
-	if uint32(dc_x) >= SCREENWIDTH || dc_yl < 0 || dc_yh >= SCREENHEIGHT {
+	if uint32(dc_x) >= uint32(screenwidth) || dc_yl < 0 || dc_yh >= screenheight {
 		i_Error("r_DrawColumn: %d to %d at %d", dc_yl, dc_yh, dc_x)
 	}
 	//	dccount++;
@@ -33523,6 +33562,8 @@ func r_DrawColumnLow() {
 	x = dc_x << 1
 	dest = ylookup[dc_yl] + (columnofs[x])
 	dest2 = ylookup[dc_yl] + (columnofs[x+1])
+	r_AuxColumn(x, dc_yl, dc_yh)
+	r_AuxColumn(x+1, dc_yl, dc_yh)
 	fracstep = dc_iscale
 	frac = dc_texturemid + (dc_yl-centery)*fracstep
 	for {
@@ -33530,8 +33571,8 @@ func r_DrawColumnLow() {
 		v3 = dc_colormap[*(*uint8)(unsafe.Pointer(dc_source + uintptr(frac>>FRACBITS&int32(127))))]
 		I_VideoBuffer[dest] = v3
 		I_VideoBuffer[dest2] = v3
-		dest += SCREENWIDTH
-		dest2 += SCREENWIDTH
+		dest += screenwidth
+		dest2 += screenwidth
 		frac += fracstep
 		goto _2
 	_2:
@@ -33546,56 +33587,56 @@ func r_DrawColumnLow() {
 
 func init() {
 	fuzzoffset = [50]int32{
-		0:  SCREENWIDTH,
-		1:  -SCREENWIDTH,
-		2:  SCREENWIDTH,
-		3:  -SCREENWIDTH,
-		4:  SCREENWIDTH,
-		5:  SCREENWIDTH,
-		6:  -SCREENWIDTH,
-		7:  SCREENWIDTH,
-		8:  SCREENWIDTH,
-		9:  -SCREENWIDTH,
-		10: SCREENWIDTH,
-		11: SCREENWIDTH,
-		12: SCREENWIDTH,
-		13: -SCREENWIDTH,
-		14: SCREENWIDTH,
-		15: SCREENWIDTH,
-		16: SCREENWIDTH,
-		17: -SCREENWIDTH,
-		18: -SCREENWIDTH,
-		19: -SCREENWIDTH,
-		20: -SCREENWIDTH,
-		21: SCREENWIDTH,
-		22: -SCREENWIDTH,
-		23: -SCREENWIDTH,
-		24: SCREENWIDTH,
-		25: SCREENWIDTH,
-		26: SCREENWIDTH,
-		27: SCREENWIDTH,
-		28: -SCREENWIDTH,
-		29: SCREENWIDTH,
-		30: -SCREENWIDTH,
-		31: SCREENWIDTH,
-		32: SCREENWIDTH,
-		33: -SCREENWIDTH,
-		34: -SCREENWIDTH,
-		35: SCREENWIDTH,
-		36: SCREENWIDTH,
-		37: -SCREENWIDTH,
-		38: -SCREENWIDTH,
-		39: -SCREENWIDTH,
-		40: -SCREENWIDTH,
-		41: SCREENWIDTH,
-		42: SCREENWIDTH,
-		43: SCREENWIDTH,
-		44: SCREENWIDTH,
-		45: -SCREENWIDTH,
-		46: SCREENWIDTH,
-		47: SCREENWIDTH,
-		48: -SCREENWIDTH,
-		49: SCREENWIDTH,
+		0:  1,
+		1:  -1,
+		2:  1,
+		3:  -1,
+		4:  1,
+		5:  1,
+		6:  -1,
+		7:  1,
+		8:  1,
+		9:  -1,
+		10: 1,
+		11: 1,
+		12: 1,
+		13: -1,
+		14: 1,
+		15: 1,
+		16: 1,
+		17: -1,
+		18: -1,
+		19: -1,
+		20: -1,
+		21: 1,
+		22: -1,
+		23: -1,
+		24: 1,
+		25: 1,
+		26: 1,
+		27: 1,
+		28: -1,
+		29: 1,
+		30: -1,
+		31: 1,
+		32: 1,
+		33: -1,
+		34: -1,
+		35: 1,
+		36: 1,
+		37: -1,
+		38: -1,
+		39: -1,
+		40: -1,
+		41: 1,
+		42: 1,
+		43: 1,
+		44: 1,
+		45: -1,
+		46: 1,
+		47: 1,
+		48: -1,
+		49: 1,
 	}
 }
 
@@ -33626,10 +33667,11 @@ func r_DrawFuzzColumn() {
 	if count < 0 {
 		return
 	}
-	if uint32(dc_x) >= SCREENWIDTH || dc_yl < 0 || dc_yh >= SCREENHEIGHT {
+	if uint32(dc_x) >= uint32(screenwidth) || dc_yl < 0 || dc_yh >= screenheight {
 		i_Error("r_DrawFuzzColumn: %d to %d at %d", dc_yl, dc_yh, dc_x)
 	}
 	dest = ylookup[dc_yl] + (columnofs[dc_x])
+	r_AuxColumn(dc_x, dc_yl, dc_yh)
 	// Looks familiar.
 	fracstep = dc_iscale
 	frac = dc_texturemid + (dc_yl-centery)*fracstep
@@ -33641,14 +33683,14 @@ func r_DrawFuzzColumn() {
 		//  a pixel that is either one column
 		//  left or right of the current one.
 		// Add index from colormap to index.
-		I_VideoBuffer[dest] = colormaps[6*256+int(I_VideoBuffer[dest+fuzzoffset[fuzzpos]])]
+		I_VideoBuffer[dest] = colormaps[6*256+int(I_VideoBuffer[dest+fuzzoffset[fuzzpos]*screenwidth])]
 		// Clamp table lookup index.
 		fuzzpos++
 		v3 = fuzzpos
 		if v3 == FUZZTABLE {
 			fuzzpos = 0
 		}
-		dest += SCREENWIDTH
+		dest += screenwidth
 		frac += fracstep
 		goto _2
 	_2:
@@ -33681,11 +33723,13 @@ func r_DrawFuzzColumnLow() {
 	}
 	// low detail mode, need to multiply by 2
 	x = dc_x << 1
-	if uint32(x) >= SCREENWIDTH || dc_yl < 0 || dc_yh >= SCREENHEIGHT {
+	if uint32(x) >= uint32(screenwidth) || dc_yl < 0 || dc_yh >= screenheight {
 		i_Error("r_DrawFuzzColumn: %d to %d at %d", dc_yl, dc_yh, dc_x)
 	}
 	dest = ylookup[dc_yl] + (columnofs[x])
 	dest2 = ylookup[dc_yl] + (columnofs[x+1])
+	r_AuxColumn(x, dc_yl, dc_yh)
+	r_AuxColumn(x+1, dc_yl, dc_yh)
 	// Looks familiar.
 	fracstep = dc_iscale
 	frac = dc_texturemid + (dc_yl-centery)*fracstep
@@ -33697,16 +33741,16 @@ func r_DrawFuzzColumnLow() {
 		//  a pixel that is either one column
 		//  left or right of the current one.
 		// Add index from colormap to index.
-		I_VideoBuffer[dest] = colormaps[6*256+int(I_VideoBuffer[dest+fuzzoffset[fuzzpos]])]
-		I_VideoBuffer[dest2] = colormaps[6*256+int(I_VideoBuffer[dest2+fuzzoffset[fuzzpos]])]
+		I_VideoBuffer[dest] = colormaps[6*256+int(I_VideoBuffer[dest+fuzzoffset[fuzzpos]*screenwidth])]
+		I_VideoBuffer[dest2] = colormaps[6*256+int(I_VideoBuffer[dest2+fuzzoffset[fuzzpos]*screenwidth])]
 		// Clamp table lookup index.
 		fuzzpos++
 		v3 = fuzzpos
 		if v3 == FUZZTABLE {
 			fuzzpos = 0
 		}
-		dest += SCREENWIDTH
-		dest2 += SCREENWIDTH
+		dest += screenwidth
+		dest2 += screenwidth
 		frac += fracstep
 		goto _2
 	_2:
@@ -33727,10 +33771,11 @@ func r_DrawTranslatedColumn() {
 	if count < 0 {
 		return
 	}
-	if uint32(dc_x) >= SCREENWIDTH || dc_yl < 0 || dc_yh >= SCREENHEIGHT {
+	if uint32(dc_x) >= uint32(screenwidth) || dc_yl < 0 || dc_yh >= screenheight {
 		i_Error("r_DrawColumn: %d to %d at %d", dc_yl, dc_yh, dc_x)
 	}
 	dest = ylookup[dc_yl] + (columnofs[dc_x])
+	r_AuxColumn(dc_x, dc_yl, dc_yh)
 	// Looks familiar.
 	fracstep = dc_iscale
 	frac = dc_texturemid + (dc_yl-centery)*fracstep
@@ -33742,7 +33787,7 @@ func r_DrawTranslatedColumn() {
 		// Thus the "green" ramp of the player 0 sprite
 		//  is mapped to gray, red, black/indigo.
 		I_VideoBuffer[dest] = dc_colormap[dc_translation[*(*uint8)(unsafe.Pointer(dc_source + uintptr(frac>>FRACBITS)))]]
-		dest += SCREENWIDTH
+		dest += screenwidth
 		frac += fracstep
 		goto _2
 	_2:
@@ -33765,11 +33810,13 @@ func r_DrawTranslatedColumnLow() {
 	}
 	// low detail, need to scale by 2
 	x = dc_x << 1
-	if uint32(x) >= SCREENWIDTH || dc_yl < 0 || dc_yh >= SCREENHEIGHT {
+	if uint32(x) >= uint32(screenwidth) || dc_yl < 0 || dc_yh >= screenheight {
 		i_Error("r_DrawColumn: %d to %d at %d", dc_yl, dc_yh, x)
 	}
 	dest = ylookup[dc_yl] + (columnofs[x])
 	dest2 = ylookup[dc_yl] + (columnofs[x+1])
+	r_AuxColumn(x, dc_yl, dc_yh)
+	r_AuxColumn(x+1, dc_yl, dc_yh)
 	// Looks familiar.
 	fracstep = dc_iscale
 	frac = dc_texturemid + (dc_yl-centery)*fracstep
@@ -33782,8 +33829,8 @@ func r_DrawTranslatedColumnLow() {
 		//  is mapped to gray, red, black/indigo.
 		I_VideoBuffer[dest] = dc_colormap[dc_translation[*(*uint8)(unsafe.Pointer(dc_source + uintptr(frac>>FRACBITS)))]]
 		I_VideoBuffer[dest2] = dc_colormap[dc_translation[*(*uint8)(unsafe.Pointer(dc_source + uintptr(frac>>FRACBITS)))]]
-		dest += SCREENWIDTH
-		dest2 += SCREENWIDTH
+		dest += screenwidth
+		dest2 += screenwidth
 		frac += fracstep
 		goto _2
 	_2:
@@ -33830,7 +33877,7 @@ func r_InitTranslationTables() {
 func r_DrawSpan() {
 	var count, spot, v1, dest int32
 	var position, step, xtemp, ytemp uint32
-	if ds_x2 < ds_x1 || ds_x1 < 0 || ds_x2 >= SCREENWIDTH || uint32(ds_y) > SCREENHEIGHT {
+	if ds_x2 < ds_x1 || ds_x1 < 0 || ds_x2 >= screenwidth || uint32(ds_y) > uint32(screenheight) {
 		i_Error("r_DrawSpan: %d to %d at %d", ds_x1, ds_x2, ds_y)
 	}
 	//	dscount++;
@@ -33841,6 +33888,7 @@ func r_DrawSpan() {
 	position = uint32(ds_xfrac<<10)&0xffff0000 | uint32(ds_yfrac>>6&0x0000ffff)
 	step = uint32(ds_xstep<<10)&0xffff0000 | uint32(ds_ystep>>6&0x0000ffff)
 	dest = ylookup[ds_y] + columnofs[ds_x1]
+	r_AuxSpan(ds_y, ds_x1, ds_x2)
 	// We do not check for zero spans here?
 	count = ds_x2 - ds_x1
 	for {
@@ -33875,7 +33923,7 @@ func r_DrawSpan() {
 func r_DrawSpanLow() {
 	var count, spot, v1, dest int32
 	var position, step, xtemp, ytemp uint32
-	if ds_x2 < ds_x1 || ds_x1 < 0 || ds_x2 >= SCREENWIDTH || uint32(ds_y) > SCREENHEIGHT {
+	if ds_x2 < ds_x1 || ds_x1 < 0 || ds_x2 >= screenwidth || uint32(ds_y) > uint32(screenheight) {
 		i_Error("r_DrawSpan: %d to %d at %d", ds_x1, ds_x2, ds_y)
 	}
 	//	dscount++;
@@ -33886,6 +33934,7 @@ func r_DrawSpanLow() {
 	ds_x1 <<= 1
 	ds_x2 <<= 1
 	dest = ylookup[ds_y] + columnofs[ds_x1]
+	r_AuxSpan(ds_y, ds_x1, ds_x2+1)
 	for {
 		// Calculate current texture index in u,v.
 		ytemp = position >> 4 & 0x0fc0
@@ -33922,20 +33971,27 @@ func r_InitBuffer(width int32, height int32) {
 	// Handle resize,
 	//  e.g. smaller view windows
 	//  with border and/or status bar.
-	viewwindowx = (SCREENWIDTH - width) >> 1
+	// The window is positioned in the original 320x200 layout, so that it
+	//  lines up with the border patches.
+	ox, oy, _, _ := r_OrigViewWindow()
+	if width == screenwidth {
+		viewwindowx = 0
+	} else {
+		viewwindowx = v_ScaleX(ox)
+	}
 	// Column offset. For windows.
 	for i := range width {
 		columnofs[i] = viewwindowx + i
 	}
 	// Samw with base row offset.
-	if width == SCREENWIDTH {
+	if width == screenwidth {
 		viewwindowy = 0
 	} else {
-		viewwindowy = (SCREENHEIGHT - SBARHEIGHT - height) >> 1
+		viewwindowy = v_ScaleY(oy)
 	}
 	// Preclaculate all row offsets.
 	for i := range height {
-		ylookup[i] = (i + viewwindowy) * SCREENWIDTH
+		ylookup[i] = (i + viewwindowy) * screenwidth
 	}
 }
 
@@ -33948,17 +34004,11 @@ func r_InitBuffer(width int32, height int32) {
 //	// Also draws a beveled edge.
 //	//
 func r_FillBackScreen() {
-	var src []byte
-	var name, name1, name2 string
 	var patch *patch_t
 	var x, y int32
-	// DOOM border patch.
-	name1 = "FLOOR7_2"
-	// DOOM II border patch.
-	name2 = "GRNROCK"
 	// If we are running full screen, there is no need to do any of this,
 	// and the background buffer can be freed if it was previously in use.
-	if scaledviewwidth == SCREENWIDTH {
+	if scaledviewwidth == screenwidth {
 		if background_buffer != nil {
 			background_buffer = nil
 		}
@@ -33966,51 +34016,20 @@ func r_FillBackScreen() {
 	}
 	// Allocate the background buffer if necessary
 	if background_buffer == nil {
-		background_buffer = make([]byte, SCREENWIDTH*(SCREENHEIGHT-SBARHEIGHT))
-	}
-	if gamemode == commercial {
-		name = name2
-	} else {
-		name = name1
-	}
-	src = w_CacheLumpNameBytes(name)
-	destPos := 0
-	y = 0
-	for {
-		if y >= SCREENHEIGHT-SBARHEIGHT {
-			break
-		}
-		x = 0
-		for {
-			if x >= SCREENWIDTH/64 {
-				break
-			}
-			copy(background_buffer[destPos:destPos+64], src[uintptr(y&63<<6):])
-			destPos += 64
-			goto _2
-		_2:
-			;
-			x++
-		}
-		if SCREENWIDTH&63 != 0 {
-			length := SCREENWIDTH & 63
-			copy(background_buffer[destPos:destPos+length], src[uintptr(y&63<<6):])
-			destPos += length
-		}
-		goto _1
-	_1:
-		;
-		y++
+		background_buffer = make([]byte, screenwidth*(screenheight-v_StatusBarHeight()))
 	}
+	v_TileFlat(background_buffer, v_BackgroundFlat())
 	// Draw screen and bezel; this is done to a separate screen buffer.
+	// The patches are positioned in the original 320x200 layout.
+	ox, oy, ow, oh := r_OrigViewWindow()
 	v_UseBuffer(background_buffer)
 	patch = w_CacheLumpNameT("brdr_t")
 	x = 0
 	for {
-		if x >= scaledviewwidth {
+		if x >= ow {
 			break
 		}
-		v_DrawPatch(viewwindowx+x, viewwindowy-8, patch)
+		v_DrawPatch(ox+x, oy-8, patch)
 		goto _3
 	_3:
 		;
@@ -34019,10 +34038,10 @@ func r_FillBackScreen() {
 	patch = w_CacheLumpNameT("brdr_b")
 	x = 0
 	for {
-		if x >= scaledviewwidth {
+		if x >= ow {
 			break
 		}
-		v_DrawPatch(viewwindowx+x, viewwindowy+viewheight, patch)
+		v_DrawPatch(ox+x, oy+oh, patch)
 		goto _4
 	_4:
 		;
@@ -34031,10 +34050,10 @@ func r_FillBackScreen() {
 	patch = w_CacheLumpNameT("brdr_l")
 	y = 0
 	for {
-		if y >= viewheight {
+		if y >= oh {
 			break
 		}
-		v_DrawPatch(viewwindowx-8, viewwindowy+y, patch)
+		v_DrawPatch(ox-8, oy+y, patch)
 		goto _5
 	_5:
 		;
@@ -34043,20 +34062,20 @@ func r_FillBackScreen() {
 	patch = w_CacheLumpNameT("brdr_r")
 	y = 0
 	for {
-		if y >= viewheight {
+		if y >= oh {
 			break
 		}
-		v_DrawPatch(viewwindowx+scaledviewwidth, viewwindowy+y, patch)
+		v_DrawPatch(ox+ow, oy+y, patch)
 		goto _6
 	_6:
 		;
 		y += 8
 	}
 	// Draw beveled edge.
-	v_DrawPatch(viewwindowx-8, viewwindowy-8, w_CacheLumpNameT("brdr_tl"))
-	v_DrawPatch(viewwindowx+scaledviewwidth, viewwindowy-8, w_CacheLumpNameT("brdr_tr"))
-	v_DrawPatch(viewwindowx-8, viewwindowy+viewheight, w_CacheLumpNameT("brdr_bl"))
-	v_DrawPatch(viewwindowx+scaledviewwidth, viewwindowy+viewheight, w_CacheLumpNameT("brdr_br"))
+	v_DrawPatch(ox-8, oy-8, w_CacheLumpNameT("brdr_tl"))
+	v_DrawPatch(ox+ow, oy-8, w_CacheLumpNameT("brdr_tr"))
+	v_DrawPatch(ox-8, oy+oh, w_CacheLumpNameT("brdr_bl"))
+	v_DrawPatch(ox+ow, oy+oh, w_CacheLumpNameT("brdr_br"))
 	v_RestoreBuffer()
 }
 
@@ -34084,34 +34103,36 @@ func r_VideoErase(ofs uint32, count int32) {
 //	//  for different size windows?
 //	//
 func r_DrawViewBorder() {
-	var i, ofs, side, top int32
-	if scaledviewwidth == SCREENWIDTH {
+	var bottom, i, ofs, right, side, top int32
+	if scaledviewwidth == screenwidth {
 		return
 	}
-	top = (SCREENHEIGHT - SBARHEIGHT - viewheight) / 2
-	side = (SCREENWIDTH - scaledviewwidth) / 2
+	top = viewwindowy
+	side = viewwindowx
+	right = screenwidth - side - scaledviewwidth
+	bottom = screenheight - v_StatusBarHeight() - top - viewheight
 	// copy top and one line of left side
-	r_VideoErase(0, top*SCREENWIDTH+side)
+	r_VideoErase(0, top*screenwidth+side)
 	// copy one line of right side and bottom
-	ofs = (viewheight+top)*SCREENWIDTH - side
-	r_VideoErase(uint32(ofs), top*SCREENWIDTH+side)
+	ofs = (viewheight+top)*screenwidth - right
+	r_VideoErase(uint32(ofs), bottom*screenwidth+right)
 	// copy sides using wraparound
-	ofs = top*SCREENWIDTH + SCREENWIDTH - side
-	side <<= 1
+	ofs = top*screenwidth + side + scaledviewwidth
+	side += right
 	i = 1
 	for {
 		if i >= viewheight {
 			break
 		}
 		r_VideoErase(uint32(ofs), side)
-		ofs += SCREENWIDTH
+		ofs += screenwidth
 		goto _1
 	_1:
 		;
 		i++
 	}
 	// ?
-	v_MarkRect(0, 0, SCREENWIDTH, SCREENHEIGHT-SBARHEIGHT)
+	v_MarkRect(0, 0, ORIGWIDTH, ORIGHEIGHT-SBARHEIGHT)
 }
 
 const ANG18011 = 2147483648
@@ -34366,8 +34387,8 @@ func r_InitTextureMapping() {
 	//  after the view angle.
 	//
 	// Calc focallength
-	//  so FIELDOFVIEW angles covers SCREENWIDTH.
-	focallength = fixedDiv(centerxfrac, finetangent[FINEANGLES/4+FIELDOFVIEW/2])
+	//  so FIELDOFVIEW angles covers fovwidth.
+	focallength = fixedDiv(projection, finetangent[FINEANGLES/4+FIELDOFVIEW/2])
 	for i := 0; i < FINEANGLES/2; i++ {
 		if finetangent[i] > 1<<FRACBITS*2 {
 			t = -1
@@ -34426,7 +34447,7 @@ func r_InitLightTables() {
 	for i := range int32(LIGHTLEVELS) {
 		startmap = (LIGHTLEVELS - 1 - i) * 2 * NUMCOLORMAPS / LIGHTLEVELS
 		for j := range int32(MAXLIGHTZ) {
-			scale = fixedDiv(SCREENWIDTH/2*(1<<FRACBITS), (j+1)<<LIGHTZSHIFT)
+			scale = fixedDiv(ORIGWIDTH/2*(1<<FRACBITS), (j+1)<<LIGHTZSHIFT)
 			scale >>= LIGHTSCALESHIFT
 			level = startmap - scale/DISTMAP
 			if level < 0 {
@@ -34456,11 +34477,16 @@ func r_ExecuteSetViewSize() {
 	var level, startmap int32
 	setsizeneeded = 0
 	if setblocks == 11 {
-		scaledviewwidth = SCREENWIDTH
-		viewheight = SCREENHEIGHT
+		scaledviewwidth = screenwidth
+		viewheight = screenheight
+	} else if setblocks == 10 {
+		// Fills the width of the screen, even in widescreen
+		scaledviewwidth = screenwidth
+		viewheight = v_ScaleY(ORIGHEIGHT - SBARHEIGHT)
 	} else {
-		scaledviewwidth = setblocks * 32
-		viewheight = setblocks * 168 / 10 & ^7
+		x, y, width, height := r_OrigViewWindow()
+		scaledviewwidth = v_ScaleX(x+width) - v_ScaleX(x)
+		viewheight = v_ScaleY(y+height) - v_ScaleY(y)
 	}
 	detailshift = setdetail
 	viewwidth = scaledviewwidth >> detailshift
@@ -34468,7 +34494,12 @@ func r_ExecuteSetViewSize() {
 	centerx = viewwidth / 2
 	centerxfrac = centerx << FRACBITS
 	centeryfrac = centery << FRACBITS
-	projection = centerxfrac
+	if scaledviewwidth == screenwidth {
+		fovwidth = viewwidth * v_AreaWidth() / screenwidth
+	} else {
+		fovwidth = viewwidth
+	}
+	projection = fovwidth / 2 << FRACBITS
 	if detailshift == 0 {
 		basecolfunc = r_DrawColumn
 		colfunc = r_DrawColumn
@@ -34485,8 +34516,8 @@ func r_ExecuteSetViewSize() {
 	r_InitBuffer(scaledviewwidth, viewheight)
 	r_InitTextureMapping()
 	// psprite scales
-	pspritescale = 1 << FRACBITS * viewwidth / SCREENWIDTH
-	pspriteiscale = 1 << FRACBITS * SCREENWIDTH / viewwidth
+	pspritescale = 1 << FRACBITS * fovwidth / ORIGWIDTH
+	pspriteiscale = 1 << FRACBITS * ORIGWIDTH / fovwidth
 	// thing clipping
 	for i := range viewwidth {
 		screenheightarray[i] = int16(viewheight)
@@ -34495,7 +34526,7 @@ func r_ExecuteSetViewSize() {
 	for i := range viewheight {
 		dy = (i-viewheight/2)<<FRACBITS + 1<<FRACBITS/2
 		dy = xabs(dy)
-		yslope[i] = fixedDiv(viewwidth<<detailshift/2*(1<<FRACBITS), dy)
+		yslope[i] = fixedDiv(fovwidth<<detailshift/2*(1<<FRACBITS), dy)
 	}
 	for i := range viewwidth {
 		cosadj = xabs(finecosine[xtoviewangle[i]>>ANGLETOFINESHIFT])
@@ -34506,7 +34537,7 @@ func r_ExecuteSetViewSize() {
 	for i := range int32(LIGHTLEVELS) {
 		startmap = (LIGHTLEVELS - 1 - i) * 2 * NUMCOLORMAPS / LIGHTLEVELS
 		for j := range int32(MAXLIGHTSCALE) {
-			level = startmap - j*SCREENWIDTH/(viewwidth<<detailshift)/DISTMAP
+			level = startmap - j*v_AreaWidth()/(fovwidth<<detailshift)/DISTMAP
 			if level < 0 {
 				level = 0
 			}
@@ -34676,6 +34707,7 @@ func r_MapPlane(y int32, x1 int32, x2 int32) {
 	ds_y = y
 	ds_x1 = x1
 	ds_x2 = x2
+	aux_depth = r_AuxDistance(distance)
 	// high or low detail
 	spanfunc()
 }
@@ -34694,14 +34726,14 @@ func r_ClearPlanes() {
 		ceilingclip[i] = int16(-1)
 	}
 	lastvisplane_index = 0
-	lastopening = uintptr(unsafe.Pointer(&openings))
+	lastopening = uintptr(unsafe.Pointer(&openings[screenwidth]))
 	// texture calculation
 	clear(cachedheight[:])
 	// left to right mapping
 	angle = (viewangle - uint32(ANG909)) >> ANGLETOFINESHIFT
-	// scale will be unit scale at SCREENWIDTH/2 distance
-	basexscale = fixedDiv(finecosine[angle], centerxfrac)
-	baseyscale = -fixedDiv(finesine[angle], centerxfrac)
+	// scale will be unit scale at fovwidth/2 distance
+	basexscale = fixedDiv(finecosine[angle], projection)
+	baseyscale = -fixedDiv(finesine[angle], projection)
 }
 
 // C documentation
@@ -34727,10 +34759,10 @@ func r_FindPlane(height fixed_t, picnum int32, lightlevel int32) *visplane_t {
 	check.Fheight = height
 	check.Fpicnum = picnum
 	check.Flightlevel = lightlevel
-	check.Fminx = SCREENWIDTH
+	check.Fminx = screenwidth
 	check.Fmaxx = -1
-	for i := 0; i < 320; i++ {
-		check.Ftop[i] = 0xff
+	for i := range check.Ftop {
+		check.Ftop[i] = 0xffff
 	}
 	lastvisplane_index++
 	return check
@@ -34762,7 +34794,7 @@ func r_CheckPlane(pl *visplane_t, start int32, stop int32) *visplane_t {
 		if !(x <= intrh) {
 			break
 		}
-		if pl.Ftop[x] != 0xff {
+		if pl.Ftop[x] != 0xffff {
 			break
 		}
 		goto _1
@@ -34783,8 +34815,8 @@ func r_CheckPlane(pl *visplane_t, start int32, stop int32) *visplane_t {
 	newPl.Flightlevel = pl.Flightlevel
 	newPl.Fminx = start
 	newPl.Fmaxx = stop
-	for i := 0; i < 320; i++ {
-		newPl.Ftop[i] = 0xff
+	for i := range newPl.Ftop {
+		newPl.Ftop[i] = 0xffff
 	}
 	lastvisplane_index++
 	return newPl
@@ -34804,8 +34836,8 @@ func r_DrawPlanes() {
 	if lastvisplane_index >= len(visplanes)-1 {
 		i_Error("r_DrawPlanes: visplane overflow (%d)", lastvisplane_index)
 	}
-	if (int64(lastopening)-int64(uintptr(unsafe.Pointer(&openings))))/2 > int64(SCREENWIDTH*64) {
-		i_Error("r_DrawPlanes: opening overflow (%d)", (int64(lastopening)-int64(uintptr(unsafe.Pointer(&openings))))/2)
+	if (int64(lastopening)-int64(uintptr(unsafe.Pointer(&openings[screenwidth]))))/2 > int64(screenwidth*64) {
+		i_Error("r_DrawPlanes: opening overflow (%d)", (int64(lastopening)-int64(uintptr(unsafe.Pointer(&openings[screenwidth]))))/2)
 	}
 	for i := 0; i < lastvisplane_index; i++ {
 		pl := &visplanes[i]
@@ -34821,6 +34853,8 @@ func r_DrawPlanes() {
 			//  by INVUL inverse mapping.
 			dc_colormap = colormaps
 			dc_texturemid = skytexturemid
+			aux_depth = 0xffff
+			aux_label = makeLabel(LabelSky, 0)
 			x = pl.Fminx
 			for {
 				if !(x <= pl.Fmaxx) {
@@ -34842,6 +34876,11 @@ func r_DrawPlanes() {
 			continue
 		}
 		// regular flat
+		if pl.Fheight < viewz {
+			aux_label = makeLabel(LabelFloor, pl.Fpicnum)
+		} else {
+			aux_label = makeLabel(LabelCeiling, pl.Fpicnum)
+		}
 		lumpnum = firstflat + flattranslation[pl.Fpicnum]
 		ds_source = w_CacheLumpNumBytes(lumpnum)
 		planeheight = xabs(pl.Fheight - viewz)
@@ -34854,10 +34893,10 @@ func r_DrawPlanes() {
 		}
 		planezlight = zlight[light][:]
 		if int(pl.Fmaxx+1) < len(pl.Ftop) {
-			pl.Ftop[pl.Fmaxx+1] = 0xff
+			pl.Ftop[pl.Fmaxx+1] = 0xffff
 		}
 		if pl.Fminx-1 >= 0 {
-			pl.Ftop[pl.Fminx-1] = 0xff
+			pl.Ftop[pl.Fminx-1] = 0xffff
 		}
 		stop = pl.Fmaxx + 1
 		x = pl.Fminx
@@ -34869,14 +34908,14 @@ func r_DrawPlanes() {
 				t1 = int32(pl.Ftop[x-1])
 				b1 = int32(pl.Fbottom[x-1])
 			} else {
-				t1 = 0xff
+				t1 = 0xffff
 				b1 = -1
 			}
 			if x < int32(len(pl.Ftop)) {
 				t2 = int32(pl.Ftop[x])
 				b2 = int32(pl.Fbottom[x])
 			} else {
-				t2 = 0xff
+				t2 = 0xffff
 				b2 = -1
 			}
 			for t1 < t2 && t1 <= b1 {
@@ -34945,6 +34984,7 @@ func r_RenderMaskedSegRange(ds *drawseg_t, x1 int32, x2 int32) {
 	}
 	maskedtexturecol = ds.Fmaskedtexturecol
 	rw_scalestep = ds.Fscalestep
+	aux_label = r_AuxWallLabel(curline.Flinedef)
 	spryscale = ds.Fscale1 + (x1-ds.Fx1)*rw_scalestep
 	mfloorclip = ds.Fsprbottomclip
 	mceilingclip = ds.Fsprtopclip
@@ -34979,7 +35019,7 @@ func r_RenderMaskedSegRange(ds *drawseg_t, x1 int32, x2 int32) {
 		// calculate lighting
 		if int32(*(*int16)(unsafe.Pointer(maskedtexturecol + uintptr(dc_x)*2))) != int32(SHRT_MAX1) {
 			if fixedcolormap == nil {
-				index = uint32(spryscale >> LIGHTSCALESHIFT)
+				index = uint32(spryscale >> LIGHTSCALESHIFT * ORIGWIDTH / v_AreaWidth())
 				if index >= MAXLIGHTSCALE {
 					index = uint32(MAXLIGHTSCALE - 1)
 				}
@@ -34987,6 +35027,7 @@ func r_RenderMaskedSegRange(ds *drawseg_t, x1 int32, x2 int32) {
 			}
 			sprtopscreen = centeryfrac - fixedMul(dc_texturemid, spryscale)
 			dc_iscale = int32(0xffffffff / uint32(spryscale))
+			aux_depth = r_AuxScale(spryscale)
 			// draw the texture
 			col = (*column_t)(unsafe.Pointer(r_GetColumn(texnum, int32(*(*int16)(unsafe.Pointer(maskedtexturecol + uintptr(dc_x)*2)))) - uintptr(3)))
 			r_DrawMaskedColumn(col)
@@ -35017,6 +35058,7 @@ func r_RenderSegLoop() {
 	var texturecolumn fixed_t
 	ceilingclip_pos = rw_x
 	floorclip_pos = rw_x
+	aux_label = r_AuxWallLabel(curline.Flinedef)
 	for {
 		if rw_x >= rw_stopx {
 			break
@@ -35034,8 +35076,8 @@ func r_RenderSegLoop() {
 				bottom = int32(floorclip[floorclip_pos]) - 1
 			}
 			if top <= bottom {
-				ceilingplane.Ftop[rw_x] = uint8(top)
-				ceilingplane.Fbottom[rw_x] = uint8(bottom)
+				ceilingplane.Ftop[rw_x] = uint16(top)
+				ceilingplane.Fbottom[rw_x] = uint16(bottom)
 			}
 		}
 		yh = bottomfrac >> HEIGHTBITS
@@ -35049,8 +35091,8 @@ func r_RenderSegLoop() {
 				top = int32(ceilingclip[ceilingclip_pos]) + 1
 			}
 			if top <= bottom {
-				floorplane.Ftop[rw_x] = uint8(top)
-				floorplane.Fbottom[rw_x] = uint8(bottom)
+				floorplane.Ftop[rw_x] = uint16(top)
+				floorplane.Fbottom[rw_x] = uint16(bottom)
 			}
 		}
 		// texturecolumn and lighting are independent of wall tiers
@@ -35063,13 +35105,14 @@ func r_RenderSegLoop() {
 			texturecolumn = rw_offset - fixedMul(finetangent[angle], rw_distance)
 			texturecolumn >>= FRACBITS
 			// calculate lighting
-			index = uint32(rw_scale >> LIGHTSCALESHIFT)
+			index = uint32(rw_scale >> LIGHTSCALESHIFT * ORIGWIDTH / v_AreaWidth())
 			if index >= MAXLIGHTSCALE {
 				index = uint32(MAXLIGHTSCALE - 1)
 			}
 			dc_colormap = walllights[index]
 			dc_x = rw_x
 			dc_iscale = int32(0xffffffff / uint32(rw_scale))
+			aux_depth = r_AuxScale(rw_scale)
 		} else {
 			// purely to shut up the compiler
 			texturecolumn = 0
@@ -35412,13 +35455,13 @@ func r_StoreWallRange(start int32, stop int32) {
 	r_RenderSegLoop()
 	// save sprite clipping info
 	if (drawsegs[ds_index].Fsilhouette&SIL_TOP != 0 || maskedtexture != 0) && drawsegs[ds_index].Fsprtopclip == nil {
-		xmemcpy(lastopening, uintptr(unsafe.Pointer(&ceilingclip))+uintptr(start)*2, uint64(2*(rw_stopx-start)))
-		drawsegs[ds_index].Fsprtopclip = unsafe.Slice((*int16)(unsafe.Pointer((lastopening - uintptr(start)*2))), 320)
+		xmemcpy(lastopening, uintptr(unsafe.Pointer(&ceilingclip[0]))+uintptr(start)*2, uint64(2*(rw_stopx-start)))
+		drawsegs[ds_index].Fsprtopclip = unsafe.Slice((*int16)(unsafe.Pointer((lastopening - uintptr(start)*2))), screenwidth)
 		lastopening += uintptr(rw_stopx-start) * 2
 	}
 	if (drawsegs[ds_index].Fsilhouette&SIL_BOTTOM != 0 || maskedtexture != 0) && drawsegs[ds_index].Fsprbottomclip == nil {
-		xmemcpy(lastopening, uintptr(unsafe.Pointer(&floorclip))+uintptr(start)*2, uint64(2*(rw_stopx-start)))
-		drawsegs[ds_index].Fsprbottomclip = unsafe.Slice((*int16)(unsafe.Pointer((lastopening - uintptr(start)*2))), 320)
+		xmemcpy(lastopening, uintptr(unsafe.Pointer(&floorclip[0]))+uintptr(start)*2, uint64(2*(rw_stopx-start)))
+		drawsegs[ds_index].Fsprbottomclip = unsafe.Slice((*int16)(unsafe.Pointer((lastopening - uintptr(start)*2))), screenwidth)
 		lastopening += uintptr(rw_stopx-start) * 2
 	}
 	if maskedtexture != 0 && drawsegs[ds_index].Fsilhouette&SIL_TOP == 0 {
@@ -35596,7 +35639,7 @@ func r_InitSpriteDefs(namelist []string) {
 //	// Called at program start.
 //	//
 func r_InitSprites(namelist []string) {
-	for i := range SCREENWIDTH {
+	for i := range screenwidth {
 		negonearray[i] = int16(-1)
 	}
 	r_InitSpriteDefs(namelist)
@@ -35672,6 +35715,13 @@ func r_DrawVisSprite(vis *vissprite_t, x1 int32, x2 int32) {
 	}
 	dc_iscale = xabs(vis.Fxiscale) >> detailshift
 	dc_texturemid = vis.Ftexturemid
+	if vis.Fid != 0 {
+		aux_depth = r_AuxScale(vis.Fscale)
+		aux_label = makeLabel(LabelThing, int32(vis.Fid))
+	} else {
+		aux_depth = 0
+		aux_label = makeLabel(LabelWeapon, 0)
+	}
 	frac = vis.Fstartfrac
 	spryscale = vis.Fscale
 	sprtopscreen = centeryfrac - fixedMul(dc_texturemid, spryscale)
@@ -35755,6 +35805,7 @@ func r_ProjectSprite(thing *mobj_t) {
 	}
 	// store information in a vissprite
 	vis = r_NewVisSprite()
+	vis.Fid = thing.Fid
 	vis.Fmobjflags = thing.Fflags
 	vis.Fscale = xscale << detailshift
 	vis.Fgx = thing.Fx
@@ -35801,7 +35852,7 @@ func r_ProjectSprite(thing *mobj_t) {
 				vis.Fcolormap = colormaps
 			} else {
 				// diminished light
-				index = xscale >> (LIGHTSCALESHIFT - detailshift)
+				index = xscale >> (LIGHTSCALESHIFT - detailshift) * ORIGWIDTH / v_AreaWidth()
 				if index >= MAXLIGHTSCALE {
 					index = MAXLIGHTSCALE - 1
 				}
@@ -36013,8 +36064,8 @@ func r_SortVisSprites() {
 //	//
 //	// R_DrawSprite
 //	//
-var clipbot [320]int16
-var cliptop [320]int16
+var clipbot []int16
+var cliptop []int16
 
 func r_DrawSprite(spr *vissprite_t) {
 	var lowscale, scale fixed_t
@@ -37235,10 +37286,10 @@ func stlib_drawNum(n *st_number_t, refresh boolean) {
 	}
 	// clear the area
 	x = n.Fx - numdigits*w
-	if n.Fy-(SCREENHEIGHT-st_HEIGHT) < 0 {
+	if n.Fy-(ORIGHEIGHT-st_HEIGHT) < 0 {
 		i_Error("drawNum: n->y - st_Y < 0")
 	}
-	v_CopyRect(x, n.Fy-(SCREENHEIGHT-st_HEIGHT), st_backing_screen, w*numdigits, h, x, n.Fy)
+	v_CopyRect(x, n.Fy, st_backing_screen, w*numdigits, h, x, n.Fy)
 	// if non-number, do not draw it
 	if num == 1994 {
 		return
@@ -37308,10 +37359,10 @@ func stlib_updateMultIcon(mi *st_multicon_t, refresh boolean) {
 			y = mi.Fy - int32(mi.Fp[mi.Foldinum].Ftopoffset)
 			w = int32(mi.Fp[mi.Foldinum].Fwidth)
 			h = int32(mi.Fp[mi.Foldinum].Fheight)
-			if y-(SCREENHEIGHT-st_HEIGHT) < 0 {
+			if y-(ORIGHEIGHT-st_HEIGHT) < 0 {
 				i_Error("updateMultIcon: y - st_Y < 0")
 			}
-			v_CopyRect(x, y-(SCREENHEIGHT-st_HEIGHT), st_backing_screen, w, h, x, y)
+			v_CopyRect(x, y, st_backing_screen, w, h, x, y)
 		}
 		v_DrawPatch(mi.Fx, mi.Fy, mi.Fp[*mi.Finum])
 		mi.Foldinum = *mi.Finum
@@ -37334,13 +37385,13 @@ func stlib_updateBinIcon(bi *st_binicon_t, refresh boolean) {
 		y = bi.Fy - int32(bi.Fp.Ftopoffset)
 		w = int32(bi.Fp.Fwidth)
 		h = int32(bi.Fp.Fheight)
-		if y-(SCREENHEIGHT-st_HEIGHT) < 0 {
+		if y-(ORIGHEIGHT-st_HEIGHT) < 0 {
 			i_Error("updateBinIcon: y - st_Y < 0")
 		}
 		if *bi.Fval != 0 {
 			v_DrawPatch(bi.Fx, bi.Fy, bi.Fp)
 		} else {
-			v_CopyRect(x, y-(SCREENHEIGHT-st_HEIGHT), st_backing_screen, w, h, x, y)
+			v_CopyRect(x, y, st_backing_screen, w, h, x, y)
 		}
 		bi.Foldval = *bi.Fval
 	}
@@ -37684,13 +37735,18 @@ func init() {
 
 func st_refreshBackground() {
 	if st_statusbaron != 0 {
+		// The backing screen is the size of the whole screen, so that
+		//  it scales the same way. In widescreen, the sides are filled
+		//  with the background flat.
+		v_TileFlat(st_backing_screen, v_BackgroundFlat())
 		v_UseBuffer(st_backing_screen)
-		v_DrawPatch(st_X, 0, sbar)
+		v_DrawPatch(st_X, ORIGHEIGHT-st_HEIGHT, sbar)
 		if netgame != 0 {
-			v_DrawPatch(st_FX, 0, faceback)
+			v_DrawPatch(st_FX, ORIGHEIGHT-st_HEIGHT, faceback)
 		}
 		v_RestoreBuffer()
-		v_CopyRect(st_X, 0, st_backing_screen, SCREENWIDTH, st_HEIGHT, st_X, SCREENHEIGHT-st_HEIGHT)
+		v_CopyRect(st_X, ORIGHEIGHT-st_HEIGHT, st_backing_screen, ORIGWIDTH, st_HEIGHT, st_X, ORIGHEIGHT-st_HEIGHT)
+		v_FillPillars(st_backing_screen, ORIGHEIGHT-st_HEIGHT, st_HEIGHT)
 	}
 }
 
@@ -38356,7 +38412,7 @@ func st_Stop() {
 
 func st_Init() {
 	st_loadData()
-	st_backing_screen = make([]byte, SCREENWIDTH*st_HEIGHT)
+	st_backing_screen = make([]byte, screenwidth*screenheight)
 }
 
 const NORM_SEP = 128
@@ -38857,16 +38913,18 @@ func v_MarkRect(x int32, y int32, width int32, height int32) {
 //	// V_CopyRect
 //	//
 func v_CopyRect(srcx int32, srcy int32, source []byte, width int32, height int32, destx int32, desty int32) {
-	if srcx < 0 || srcx+width > SCREENWIDTH || srcy < 0 || srcy+height > SCREENHEIGHT || destx < 0 || destx+width > SCREENWIDTH || desty < 0 || desty+height > SCREENHEIGHT {
+	if srcx < 0 || srcx+width > ORIGWIDTH || srcy < 0 || srcy+height > ORIGHEIGHT || destx < 0 || destx+width > ORIGWIDTH || desty < 0 || desty+height > ORIGHEIGHT {
 		i_Error("Bad v_CopyRect")
 	}
 	v_MarkRect(destx, desty, width, height)
-	srcPos := SCREENWIDTH*srcy + srcx
-	destPos := SCREENWIDTH*desty + destx
+	srcPos := screenwidth*v_ScaleY(srcy) + v_ScaleX(srcx)
+	destPos := screenwidth*v_ScaleY(desty) + v_ScaleX(destx)
+	width = v_ScaleX(destx+width) - v_ScaleX(destx)
+	height = v_ScaleY(desty+height) - v_ScaleY(desty)
 	for ; height > 0; height-- {
 		copy(dest_screen[destPos:destPos+width], source[srcPos:srcPos+width])
-		srcPos += SCREENWIDTH
-		destPos += SCREENWIDTH
+		srcPos += screenwidth
+		destPos += screenwidth
 	}
 }
 
@@ -38878,22 +38936,12 @@ func v_CopyRect(srcx int32, srcy int32, source []byte, width int32, height int32
 func v_DrawPatch(x int32, y int32, patch *patch_t) {
 	y -= int32(patch.Ftopoffset)
 	x -= int32(patch.Fleftoffset)
-	if x < 0 || x+int32(patch.Fwidth) > SCREENWIDTH || y < 0 || y+int32(patch.Fheight) > SCREENHEIGHT {
+	if x < 0 || x+int32(patch.Fwidth) > ORIGWIDTH || y < 0 || y+int32(patch.Fheight) > ORIGHEIGHT {
 		i_Error("Bad v_DrawPatch x=%d y=%d patch.width=%d patch.height=%d topoffset=%d leftoffset=%d", x, y, int32(patch.Fwidth), int32(patch.Fheight), int32(patch.Ftopoffset), int32(patch.Fleftoffset))
 	}
 	v_MarkRect(x, y, int32(patch.Fwidth), int32(patch.Fheight))
 	for col := range int32(patch.Fwidth) {
-		column := patch.GetColumn(col)
-		// step through the posts in a column
-		for int32(column.Ftopdelta) != 0xff {
-			source := column.Data()
-			pos := (y * SCREENWIDTH) + x + col + int32(column.Ftopdelta)*SCREENWIDTH
-			for i := int32(0); i < int32(column.Flength); i++ {
-				dest_screen[pos] = source[i]
-				pos += SCREENWIDTH
-			}
-			column = column.Next()
-		}
+		v_DrawPatchColumn(dest_screen, x+col, y, patch.GetColumn(col))
 	}
 }
 
@@ -38905,27 +38953,15 @@ func v_DrawPatch(x int32, y int32, patch *patch_t) {
 
 func v_DrawPatchFlipped(x int32, y int32, patch *patch_t) {
 	var w int32
-	//var dest, desttop uintptr
 	y -= int32(patch.Ftopoffset)
 	x -= int32(patch.Fleftoffset)
-	if x < 0 || x+int32(patch.Fwidth) > SCREENWIDTH || y < 0 || y+int32(patch.Fheight) > SCREENHEIGHT {
+	if x < 0 || x+int32(patch.Fwidth) > ORIGWIDTH || y < 0 || y+int32(patch.Fheight) > ORIGHEIGHT {
 		i_Error("Bad v_DrawPatchFlipped")
 	}
 	v_MarkRect(x, y, int32(patch.Fwidth), int32(patch.Fheight))
-	destTop := y*SCREENWIDTH + x
 	w = int32(patch.Fwidth)
 	for col := range w {
-		column := patch.GetColumn(w - 1 - col)
-		// step through the posts in a column
-		for int32(column.Ftopdelta) != 0xff {
-			source := column.Data()
-			destPos := destTop + int32(column.Ftopdelta)*SCREENWIDTH
-			for i := uint8(0); i < column.Flength; i++ {
-				dest_screen[destPos+SCREENWIDTH*int32(i)] = source[i]
-			}
-			column = column.Next()
-		}
-		destTop++
+		v_DrawPatchColumn(dest_screen, x+col, y, patch.GetColumn(w-1-col))
 	}
 }
 
@@ -38945,42 +38981,28 @@ func v_DrawPatchDirect(x int32, y int32, patch *patch_t) {
 
 func v_DrawBlock(x int32, y int32, width int32, height int32, src []byte) {
 	var pos int32
-	if x < 0 || x+width > SCREENWIDTH || y < 0 || y+height > SCREENHEIGHT {
+	if x < 0 || x+width > screenwidth || y < 0 || y+height > screenheight {
 		i_Error("Bad v_DrawBlock")
 	}
 	v_MarkRect(x, y, width, height)
-	destPos := y*SCREENWIDTH + x
+	destPos := y*screenwidth + x
 	for ; height <= 0; height-- {
 		copy(dest_screen[destPos:destPos+width], src)
 		pos += width
-		destPos += SCREENWIDTH
+		destPos += screenwidth
 	}
 }
 
 func v_DrawFilledBox(x int32, y int32, w int32, h int32, c int32) {
-	var x1 int32
-	pos := SCREENWIDTH*y + x
-	for y1 := int32(0); y1 < h; y1++ {
-		for x := int32(0); x < w; x++ {
-			I_VideoBuffer[pos+x1] = uint8(c)
-		}
-		pos += SCREENWIDTH
-	}
+	v_FillRect(I_VideoBuffer, x, y, w, h, uint8(c))
 }
 
 func v_DrawHorizLine(x int32, y int32, w int32, c int32) {
-	pos := SCREENWIDTH*y + x
-	for x1 := int32(0); x1 < w; x1++ {
-		I_VideoBuffer[pos+x1] = uint8(c)
-	}
+	v_FillRect(I_VideoBuffer, x, y, w, 1, uint8(c))
 }
 
 func v_DrawVertLine(x int32, y int32, h int32, c int32) {
-	pos := SCREENWIDTH*y + x
-	for y1 := int32(0); y1 < h; y1++ {
-		I_VideoBuffer[pos] = uint8(c)
-		pos += SCREENWIDTH
-	}
+	v_FillRect(I_VideoBuffer, x, y, 1, h, uint8(c))
 }
 
 func v_DrawBox(x int32, y int32, w int32, h int32, c int32) {
@@ -39029,7 +39051,7 @@ func v_DrawMouseSpeedBox(speed int32) {
 		return
 	}
 	// Calculate box position
-	box_x = SCREENWIDTH - MOUSE_SPEED_BOX_WIDTH - 10
+	box_x = ORIGWIDTH - MOUSE_SPEED_BOX_WIDTH - 10
 	box_y = 15
 	v_DrawFilledBox(box_x, box_y, MOUSE_SPEED_BOX_WIDTH, MOUSE_SPEED_BOX_HEIGHT, bgcolor)
 	v_DrawBox(box_x, box_y, MOUSE_SPEED_BOX_WIDTH, MOUSE_SPEED_BOX_HEIGHT, bordercolor)
@@ -39669,10 +39691,10 @@ func wi_drawLF() {
 	y = WI_TITLEY
 	if gamemode != commercial || wbs.Flast < NUMCMAPS {
 		// draw <LevelName>
-		v_DrawPatch((SCREENWIDTH-int32(lnames[wbs.Flast].Fwidth))/2, y, lnames[wbs.Flast])
+		v_DrawPatch((ORIGWIDTH-int32(lnames[wbs.Flast].Fwidth))/2, y, lnames[wbs.Flast])
 		// draw "Finished!"
 		y += 5 * int32(lnames[wbs.Flast].Fheight) / 4
-		v_DrawPatch((SCREENWIDTH-int32(finished.Fwidth))/2, y, finished)
+		v_DrawPatch((ORIGWIDTH-int32(finished.Fwidth))/2, y, finished)
 	} else {
 		if wbs.Flast == NUMCMAPS {
 			// MAP33 - nothing is displayed!
@@ -39683,8 +39705,8 @@ func wi_drawLF() {
 				// bits of memory at this point, but let's try to be accurate
 				// anyway.  This deliberately triggers a v_DrawPatch error.
 				bp := patch_t{
-					Fwidth:      int16(SCREENWIDTH),
-					Fheight:     int16(SCREENHEIGHT),
+					Fwidth:      int16(ORIGWIDTH),
+					Fheight:     int16(ORIGHEIGHT),
 					Fleftoffset: 1,
 					Ftopoffset:  1,
 				}
@@ -39701,10 +39723,10 @@ func wi_drawEL() {
 	var y int32
 	y = WI_TITLEY
 	// draw "Entering"
-	v_DrawPatch((SCREENWIDTH-int32(entering.Fwidth))/2, y, entering)
+	v_DrawPatch((ORIGWIDTH-int32(entering.Fwidth))/2, y, entering)
 	// draw level
 	y += 5 * int32(lnames[wbs.Fnext].Fheight) / 4
-	v_DrawPatch((SCREENWIDTH-int32(lnames[wbs.Fnext].Fwidth))/2, y, lnames[wbs.Fnext])
+	v_DrawPatch((ORIGWIDTH-int32(lnames[wbs.Fnext].Fwidth))/2, y, lnames[wbs.Fnext])
 }
 
 func wi_drawOnLnode(n int32, c []*patch_t) {
@@ -39717,7 +39739,7 @@ func wi_drawOnLnode(n int32, c []*patch_t) {
 		top = lnodes[wbs.Fepsd][n].Fy - int32(c[i].Ftopoffset)
 		right = left + int32(c[i].Fwidth)
 		bottom = top + int32(c[i].Fheight)
-		if left >= 0 && right < SCREENWIDTH && top >= 0 && bottom < SCREENHEIGHT {
+		if left >= 0 && right < ORIGWIDTH && top >= 0 && bottom < ORIGHEIGHT {
 			fits = 1
 		} else {
 			i++
@@ -40458,16 +40480,16 @@ func wi_drawStats() {
 	wi_drawAnimatedBack()
 	wi_drawLF()
 	v_DrawPatch(SP_STATSX, SP_STATSY, kills)
-	wi_drawPercent(SCREENWIDTH-SP_STATSX, SP_STATSY, cnt_kills[0])
+	wi_drawPercent(ORIGWIDTH-SP_STATSX, SP_STATSY, cnt_kills[0])
 	v_DrawPatch(SP_STATSX, SP_STATSY+lh, items)
-	wi_drawPercent(SCREENWIDTH-SP_STATSX, SP_STATSY+lh, cnt_items[0])
+	wi_drawPercent(ORIGWIDTH-SP_STATSX, SP_STATSY+lh, cnt_items[0])
 	v_DrawPatch(SP_STATSX, SP_STATSY+2*lh, sp_secret)
-	wi_drawPercent(SCREENWIDTH-SP_STATSX, SP_STATSY+2*lh, cnt_secret[0])
-	v_DrawPatch(SP_TIMEX, SCREENHEIGHT-32, timepatch)
-	wi_drawTime(SCREENWIDTH/2-SP_TIMEX, SCREENHEIGHT-32, cnt_time)
+	wi_drawPercent(ORIGWIDTH-SP_STATSX, SP_STATSY+2*lh, cnt_secret[0])
+	v_DrawPatch(SP_TIMEX, ORIGHEIGHT-32, timepatch)
+	wi_drawTime(ORIGWIDTH/2-SP_TIMEX, ORIGHEIGHT-32, cnt_time)
 	if wbs.Fepsd < 3 {
-		v_DrawPatch(SCREENWIDTH/2+SP_TIMEX, SCREENHEIGHT-32, par)
-		wi_drawTime(SCREENWIDTH-SP_TIMEX, SCREENHEIGHT-32, cnt_par)
+		v_DrawPatch(ORIGWIDTH/2+SP_TIMEX, ORIGHEIGHT-32, par)
+		wi_drawTime(ORIGWIDTH-SP_TIMEX, ORIGHEIGHT-32, cnt_par)
 	}
 }
 
@@ -40809,7 +40831,7 @@ func w_ParseCommandLine() boolean {
 			}
 			filename = d_TryFindWADByName(myargs[p])
 			fprintf_ccgo(os.Stdout, " adding %s\n", filename)
-			w_AddFile(filename)
+			w_AddPWAD(filename)
 		}
 	}
 	//    W_PrintDirectory();
@@ -40923,15 +40945,33 @@ func w_AddFile(filename string) fs.File {
 	for i := startlump; i < numlumps; i++ {
 		lump_p := &lumpinfo[i]
 		lump_p.Fwad_file = wad_file
-		lump_p.Fposition = fileinfo[i].Ffilepos
-		lump_p.Fsize = fileinfo[i].Fsize
+		lump_p.Fposition = fileinfo[i-startlump].Ffilepos
+		lump_p.Fsize = fileinfo[i-startlump].Fsize
 		lump_p.Fcache = nil
-		lump_p.Fname = fileinfo[i].Fname
+		lump_p.Fname = fileinfo[i-startlump].Fname
 	}
 	lumphash = nil
 	return wad_file
 }
 
+// w_CloseFiles closes all of the files opened by w_AddFile
+
+func w_CloseFiles() {
+	var last fs.File
+	for i := range numlumps {
+		lump_p := &lumpinfo[i]
+		// Lumps from the same file are contiguous
+		if lump_p.Fwad_file != nil && lump_p.Fwad_file != last {
+			last = lump_p.Fwad_file
+			last.Close()
+		}
+		lump_p.Fwad_file = nil
+		lump_p.Fcache = nil
+	}
+	numlumps = 0
+	lumphash = nil
+}
+
 //
 // W_CheckNumForName
 // Returns -1 if name not found.
@@ -41384,60 +41424,71 @@ type DoomEvent struct {
 var lastMouse DoomEvent
 
 func i_GetEvent() {
-	var newEvent event_t
 	var event DoomEvent
-	for dg_frontend.GetEvent(&event) {
-		if event.Type == Ev_keydown || event.Type == Ev_keyup {
-			pressed := int32(0)
-			if event.Type == Ev_keydown {
-				pressed = 1
-			}
-			updateShiftStatus(pressed, event.Key)
-			// process event
-			if event.Type == Ev_keydown {
-				// data1 has the key pressed, data2 has the character
-				// (shift-translated, etc)
-				newEvent.Ftype1 = Ev_keydown
-				newEvent.Fdata1 = int32(event.Key)
-				newEvent.Fdata2 = int32(getTypedChar(event.Key))
-				if newEvent.Fdata1 != 0 {
-					d_PostEvent(&newEvent)
-				}
-			} else {
-				newEvent.Ftype1 = Ev_keyup
-				newEvent.Fdata1 = int32(event.Key)
-				// data2 is just initialized to zero for ev_keyup.
-				// For ev_keydown it's the shifted Unicode character
-				// that was typed, but if something wants to detect
-				// key releases it should do so based on data1
-				// (key ID), not the printable char.
-				newEvent.Fdata2 = 0
-				if newEvent.Fdata1 != 0 {
-					d_PostEvent(&newEvent)
-				}
-			}
+	more := true
+	for {
+		i_Callback(func() { more = dg_frontend.GetEvent(&event) })
+		if !more {
+			break
 		}
-		if event.Type == Ev_mouse {
-			if lastMouse.Type == 0 {
-				lastMouse = event
-			}
-			newEvent.Ftype1 = Ev_mouse
-			if event.Mouse.Button1 {
-				newEvent.Fdata1 |= 1
-			}
-			if event.Mouse.Button2 {
-				newEvent.Fdata1 |= 2
-			}
-			newEvent.Fdata2 = int32((event.Mouse.XPos - lastMouse.Mouse.XPos) * SCREENWIDTH * 100)
-			newEvent.Fdata3 = int32((lastMouse.Mouse.YPos - event.Mouse.YPos) * SCREENHEIGHT * 100)
-			if newEvent.Fdata2 < 5 && newEvent.Fdata2 > -5 &&
-				newEvent.Fdata3 < 5 && newEvent.Fdata3 > -5 {
-				// Ignore small mouse movements.
-				continue
+		i_PostDoomEvent(&event)
+	}
+}
+
+// i_PostDoomEvent converts an event from the frontend into the engine's
+// representation, and queues it for processing
+func i_PostDoomEvent(event *DoomEvent) {
+	var newEvent event_t
+	if event.Type == Ev_keydown || event.Type == Ev_keyup {
+		pressed := int32(0)
+		if event.Type == Ev_keydown {
+			pressed = 1
+		}
+		updateShiftStatus(pressed, event.Key)
+		// process event
+		if event.Type == Ev_keydown {
+			// data1 has the key pressed, data2 has the character
+			// (shift-translated, etc)
+			newEvent.Ftype1 = Ev_keydown
+			newEvent.Fdata1 = int32(event.Key)
+			newEvent.Fdata2 = int32(getTypedChar(event.Key))
+			if newEvent.Fdata1 != 0 {
+				d_PostEvent(&newEvent)
 			}
-			lastMouse = event
-			d_PostEvent(&newEvent)
+		} else {
+			newEvent.Ftype1 = Ev_keyup
+			newEvent.Fdata1 = int32(event.Key)
+			// data2 is just initialized to zero for ev_keyup.
+			// For ev_keydown it's the shifted Unicode character
+			// that was typed, but if something wants to detect
+			// key releases it should do so based on data1
+			// (key ID), not the printable char.
+			newEvent.Fdata2 = 0
+			if newEvent.Fdata1 != 0 {
+				d_PostEvent(&newEvent)
+			}
+		}
+	}
+	if event.Type == Ev_mouse {
+		if lastMouse.Type == 0 {
+			lastMouse = *event
+		}
+		newEvent.Ftype1 = Ev_mouse
+		if event.Mouse.Button1 {
+			newEvent.Fdata1 |= 1
+		}
+		if event.Mouse.Button2 {
+			newEvent.Fdata1 |= 2
+		}
+		newEvent.Fdata2 = int32((event.Mouse.XPos - lastMouse.Mouse.XPos) * ORIGWIDTH * 100)
+		newEvent.Fdata3 = int32((lastMouse.Mouse.YPos - event.Mouse.YPos) * ORIGHEIGHT * 100)
+		if newEvent.Fdata2 < 5 && newEvent.Fdata2 > -5 &&
+			newEvent.Fdata3 < 5 && newEvent.Fdata3 > -5 {
+			// Ignore small mouse movements.
+			return
 		}
+		lastMouse = *event
+		d_PostEvent(&newEvent)
 	}
 }
 
@@ -41455,7 +41506,8 @@ func init() {
 
 func i_InitGraphics() {
 	/* Allocate screen to draw to */
-	I_VideoBuffer = make([]byte, SCREENWIDTH*SCREENHEIGHT) // For DOOM to draw on
+	I_VideoBuffer = make([]byte, screenwidth*screenheight) // For DOOM to draw on
+	i_InitPalettedScreen()
 	i_InitInput()
 }
 
@@ -41474,20 +41526,30 @@ func i_UpdateNoBlit() {
 //
 
 func i_FinishUpdate() {
+	i_CallFrameHooks()
+	if pal_enabled {
+		i_UpdateAuxBuffers()
+		if pal_frontend != nil {
+			i_Callback(func() { pal_frontend.DrawPalettedFrame(pal_screen) })
+		}
+		return
+	}
 	var line_in_pos = 0
-	for y := SCREENHEIGHT - 1; y >= 0; y-- {
-		for i := 0; i < SCREENWIDTH; i++ {
+	width, height := int(screenwidth), int(screenheight)
+	for y := height - 1; y >= 0; y-- {
+		for i := 0; i < width; i++ {
 			inRaw := I_VideoBuffer[line_in_pos+i]
 			col := colors[inRaw]
-			pos := SCREENWIDTH*4*int(SCREENHEIGHT-y-1) + i*4
+			pos := width*4*(height-y-1) + i*4
 			DG_ScreenBuffer.Pix[pos] = col.R
 			DG_ScreenBuffer.Pix[pos+1] = col.G
 			DG_ScreenBuffer.Pix[pos+2] = col.B
 			DG_ScreenBuffer.Pix[pos+3] = 0xff
 		}
-		line_in_pos += SCREENWIDTH
+		line_in_pos += width
 	}
-	dg_frontend.DrawFrame(DG_ScreenBuffer)
+	i_UpdateAuxBuffers()
+	i_Callback(func() { dg_frontend.DrawFrame(DG_ScreenBuffer) })
 }
 
 // C documentation
@@ -41509,6 +41571,7 @@ func i_SetPalette(palette []byte) {
 		colors[i].G = palette[i*3+1]
 		colors[i].B = palette[i*3+2]
 	}
+	i_UpdatePalettedScreen()
 }
 
 // Given an RGB value, find the closest matching palette index.
@@ -41535,7 +41598,7 @@ func i_GetPaletteIndex(r int32, g int32, b int32) (r1 int32) {
 }
 
 func i_SetWindowTitle(title string) {
-	dg_frontend.SetTitle(title)
+	i_Callback(func() { dg_frontend.SetTitle(title) })
 }
 
 func i_GraphicsCheckCommandLine() {
@@ -41561,31 +41624,14 @@ func doomgeneric_Create(args []string) {
 	myargs = args
 	m_FindResponseFile()
 
-	DG_ScreenBuffer = image.NewRGBA(image.Rect(0, 0, SCREENWIDTH, SCREENHEIGHT))
+	i_CheckResolutionParms()
+	i_CheckUncappedParm()
+	i_InitResolution()
+	DG_ScreenBuffer = image.NewRGBA(image.Rect(0, 0, int(screenwidth), int(screenheight)))
+	i_InitAuxBuffers()
 	d_DoomMain()
 }
 
-func Run(fg DoomFrontend, args []string) {
-	if dg_frontend != nil {
-		log.Printf("Run called twice, ignoring second call")
-	}
-	dg_frontend = fg
-	dg_exiting = false
-	start_time = time.Now()
-
-	// Convert command line arguments to C strings.
-	args = append([]string{"doom"}, args...) // prepend "doom" as argv[0]
-	doomgeneric_Create(args)
-	for !dg_exiting {
-		doomgeneric_Tick()
-	}
-	dg_frontend = nil
-}
-
-func Stop() {
-	dg_exiting = true
-}
-
 var DG_ScreenBuffer *image.RGBA
 
 var EpiDef menu_t
@@ -41882,13 +41928,13 @@ var bulletslope fixed_t
 
 var buttonlist [MAXBUTTONS]button_t
 
-var cacheddistance [200]fixed_t
+var cacheddistance []fixed_t
 
-var cachedheight [200]fixed_t
+var cachedheight []fixed_t
 
-var cachedxstep [200]fixed_t
+var cachedxstep []fixed_t
 
-var cachedystep [200]fixed_t
+var cachedystep []fixed_t
 
 var castattacking boolean
 
@@ -41906,7 +41952,7 @@ var caststate *state_t
 
 var casttics int32
 
-var ceilingclip [320]int16
+var ceilingclip []int16
 
 // C documentation
 //
@@ -41998,7 +42044,7 @@ var colfunc func()
 
 var colormaps []lighttable_t
 
-var columnofs [1120]int32
+var columnofs []int32
 
 //
 // This is used to get the local FILE:LINE info from CPP
@@ -42178,7 +42224,7 @@ var dirtybox box_t
 
 var displayplayer int32
 
-var distscale [320]fixed_t
+var distscale []fixed_t
 
 var doom1_endmsg [NUM_QUITMESSAGES]string
 
@@ -42286,10 +42332,10 @@ var floatok boolean
 //
 //	//
 //	// Clip values are the solid pixel bounding the range.
-//	//  floorclip starts out SCREENHEIGHT
+//	//  floorclip starts out screenheight
 //	//  ceilingclip starts out -1
 //	//
-var floorclip [320]int16
+var floorclip []int16
 
 var floorplane *visplane_t
 
@@ -42819,7 +42865,7 @@ var myargs []string
 //
 //	// constant arrays
 //	//  used for psprite clipping and initializing clipping
-var negonearray [320]int16
+var negonearray []int16
 
 /* Support signed or unsigned plain-char */
 
@@ -42953,7 +42999,7 @@ var openbottom fixed_t
 // C documentation
 //
 //	// ?
-var openings [20480]int16
+var openings []int16
 
 var openrange fixed_t
 
@@ -43146,7 +43192,7 @@ var saveStringEnter int32
 //	Refresh/render internal state variables (global).
 //
 
-var save_stream *os.File
+var save_stream *savestream_t
 
 var savegame_error boolean
 
@@ -43156,7 +43202,7 @@ var savegamedir string
 
 var savegamestrings [10]string
 
-var savename string
+var loadgameslot int32
 
 var scaledviewwidth int32
 
@@ -43171,7 +43217,7 @@ var screenSize int32
 
 var screenblocks int32
 
-var screenheightarray [320]int16
+var screenheightarray []int16
 
 // If true, game is running as a screensaver
 
@@ -43356,7 +43402,7 @@ var spanfunc func()
 //	// spanstart holds the start of a plane span
 //	// initialized to 0 at start
 //	//
-var spanstart [200]int32
+var spanstart []int32
 
 // keep track of special lines as they are hit,
 // but don't process them until the move is proven valid
@@ -43723,7 +43769,7 @@ var viewactive boolean
 
 var viewangle angle_t
 
-// Fineangles in the SCREENWIDTH wide window.
+// Fineangles in the screenwidth wide window.
 
 var viewangleoffset uint32
 
@@ -43838,11 +43884,11 @@ var xspeed [8]fixed_t
 //	// The xtoviewangleangle[] table maps a screen pixel
 //	// to the lowest viewangle that maps back to x ranges
 //	// from clipangle to -clipangle.
-var xtoviewangle [321]angle_t
+var xtoviewangle []angle_t
 
-var ylookup [832]int32
+var ylookup []int32
 
-var yslope [200]fixed_t
+var yslope []fixed_t
 
 var yspeed [8]fixed_t
 
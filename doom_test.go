@@ -1,19 +1,27 @@
 package gore
 
 import (
-	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"image"
+	"image/color"
 	"image/draw"
+	"image/gif"
 	"image/png"
-	"io"
+	"io/fs"
 	"math/rand"
 	"os"
-	"os/exec"
+	"reflect"
+	"slices"
 	"sync"
 	"testing"
+	"testing/fstest"
 	"time"
 
+	"github.com/AndreRenaud/gore/recorder"
 	diff "github.com/olegfedoseev/image-diff"
 )
 
@@ -28,79 +36,105 @@ type doomTestHeadless struct {
 	t             *testing.T
 	keys          []delayedEvent
 	lastEventTick int32
-	outputFile    io.WriteCloser
+	recorder      *recorder.Recorder
+	frames        int // Number of frames drawn
 	lock          sync.Mutex
 	lastImage     *image.RGBA
+	game          *Game
 }
 
-func (d *doomTestHeadless) Close() {
-	if err := d.outputFile.Close(); err != nil {
-		d.t.Errorf("Error closing output file: %v", err)
+// run starts a new game using d as the frontend, and runs it until it is
+// stopped or the player quits
+func (d *doomTestHeadless) run(opts Options) {
+	d.t.Helper()
+	opts.Args = []string{"-iwad", "doom1.wad"}
+	opts.FullSpeed = true
+	game, err := New(d, opts)
+	if err != nil {
+		d.t.Fatalf("Error creating game: %v", err)
+	}
+	d.lock.Lock()
+	d.game = game
+	d.lock.Unlock()
+	if err := game.Run(); err != nil {
+		d.t.Errorf("Error running game: %v", err)
 	}
 }
 
-type bufferedWriteCloser struct {
-	*bufio.Writer
-	io.Closer
+// Stop stops the running game
+func (d *doomTestHeadless) Stop() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.game != nil {
+		d.game.Stop()
+	}
 }
 
-func (b *bufferedWriteCloser) Close() error {
-	if err := b.Writer.Flush(); err != nil {
-		return fmt.Errorf("error flushing buffer: %w", err)
+// withState runs f with the engine state for g loaded
+func (g *Game) withState(f func()) {
+	if err := g.lock(); err != nil {
+		panic(err)
 	}
-	return b.Closer.Close()
+	defer g.unlock()
+	f()
 }
 
-func ffmpegSaver(filename string) (io.WriteCloser, error) {
-	args := []string{
-		"ffmpeg",
-		"-y", // Overwrite output file if it exists
-		"-loglevel", "error",
-		"-hide_banner",
-		"-f", "rawvideo",
-		"-s", fmt.Sprintf("%dx%d", SCREENWIDTH, SCREENHEIGHT),
-		"-r", "29", // Frame rate - 35ms ticks?
-		"-pix_fmt", "rgba",
-		"-i", "-",
-		"-crf", "27",
-		"-preset", "veryfast",
-		"-c:v", "libx264",
-		"-pix_fmt", "yuv420p",
-		"-flush_packets", "1",
-		"-movflags", "+faststart",
-		filename,
+// allMobjs returns all of the mobjs in the game's level, failing the test if
+// they can't be got
+func allMobjs(t *testing.T, game *Game) []Mobj {
+	t.Helper()
+	var mobjs []Mobj
+	for mo, err := range game.Mobjs() {
+		if err != nil {
+			t.Fatalf("Error getting mobjs: %v", err)
+		}
+		mobjs = append(mobjs, mo)
 	}
-	cmd := exec.Command(args[0], args[1:]...)
-	cmd.Stderr = os.Stderr
+	return mobjs
+}
 
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return nil, err
+// wipeRunning reports whether the game is yet to start, or is in the middle of
+// a screen wipe
+func (d *doomTestHeadless) wipeRunning() bool {
+	d.lock.Lock()
+	game := d.game
+	d.lock.Unlock()
+	if game == nil {
+		return true
 	}
+	// The game can't be looked at while it's in a frontend callback, in which
+	// case it's treated as still running, to be checked again
+	if err := game.lock(); err != nil {
+		return true
+	}
+	defer game.unlock()
+	return wipe_running != 0
+}
 
-	if err := cmd.Start(); err != nil {
-		return nil, err
+func (d *doomTestHeadless) Close() {
+	if d.recorder == nil {
+		return
+	}
+	if err := d.recorder.Close(); err != nil {
+		d.t.Errorf("Error closing recording: %v", err)
 	}
-	// We don't want ffmpeg compression to slow down the game, so just use a lot of memory instead
-	return &bufferedWriteCloser{
-		Writer: bufio.NewWriterSize(stdin, 256*1024*1024),
-		Closer: stdin,
-	}, nil
 }
 
 func (d *doomTestHeadless) DrawFrame(frame *image.RGBA) {
 	d.lock.Lock()
 	defer d.lock.Unlock()
-	if d.outputFile == nil {
+	if d.recorder == nil {
 		var err error
-		name := fmt.Sprintf("doom_test_%s.mp4", d.t.Name())
-		d.outputFile, err = ffmpegSaver(name)
+		name := fmt.Sprintf("doom_test_%s.png", d.t.Name())
+		d.recorder, err = recorder.Create(name)
 		if err != nil {
-			d.t.Fatalf("Error starting ffmpeg: %v", err)
+			d.t.Fatalf("Error creating recording: %v", err)
 		}
 		d.t.Logf("Saving output to %s", name)
 	}
-	d.outputFile.Write(frame.Pix)
+	// Each frame is drawn after a single tic
+	d.recorder.WriteFrame(frame, d.frames)
+	d.frames++
 	if d.lastImage == nil {
 		d.lastImage = image.NewRGBA(frame.Rect)
 	}
@@ -221,7 +255,7 @@ func (d *doomTestHeadless) InsertKeyChange(Key uint8, pressed bool) {
 
 // Run the demo at super speed to make sure it all goes ok
 func TestDoomDemo(t *testing.T) {
-	dg_run_full_speed = true
+	t.Parallel()
 	game := &doomTestHeadless{
 		t: t,
 	}
@@ -230,9 +264,9 @@ func TestDoomDemo(t *testing.T) {
 		time.Sleep(2 * time.Second)
 
 		// Quit
-		Stop()
+		game.Stop()
 	}()
-	Run(game, []string{"-iwad", "doom1.wad"})
+	game.run(Options{})
 }
 
 func savePNG(filename string, img image.Image) error {
@@ -264,7 +298,7 @@ func loadPNG(filename string) (image.Image, error) {
 }
 
 func TestLoadSave(t *testing.T) {
-	dg_run_full_speed = true
+	t.Parallel()
 	var imgPlayedGame, imgNewGame, imgLoadedGame *image.RGBA
 	game := &doomTestHeadless{
 		t: t,
@@ -355,15 +389,15 @@ func TestLoadSave(t *testing.T) {
 					t.Errorf("New game screenshot matches the original: %f%% difference", percent)
 				}
 			}},
-			{ticks: 1000, callback: func(d *doomTestHeadless) { Stop() }},
+			{ticks: 1000, callback: func(d *doomTestHeadless) { d.Stop() }},
 		},
 	}
 	defer game.Close()
-	Run(game, []string{"-iwad", "doom1.wad"})
+	game.run(Options{})
 }
 
 func TestDoomRandom(t *testing.T) {
-	dg_run_full_speed = true
+	t.Parallel()
 	game := &doomTestHeadless{
 		t: t,
 	}
@@ -404,13 +438,13 @@ func TestDoomRandom(t *testing.T) {
 		game.InsertKey(KEY_ENTER)    // Confirm quit
 		game.InsertKey('y')          // Confirm exit
 	}()
-	Run(game, []string{"-iwad", "doom1.wad"})
+	game.run(Options{})
 }
 
 func compareScreen(game *doomTestHeadless, testdataPrefix string, percentOk float64) {
 	screen := game.GetScreen()
 	if screen == nil {
-		game.t.Errorf("No screen captured for %s", filename)
+		game.t.Errorf("No screen captured for %s", testdataPrefix)
 		return
 	}
 	// Save the screenshot for debugging
@@ -437,7 +471,7 @@ func compareScreen(game *doomTestHeadless, testdataPrefix string, percentOk floa
 }
 
 func TestDoomLevels(t *testing.T) {
-	dg_run_full_speed = true
+	t.Parallel()
 	var game *doomTestHeadless
 	game = &doomTestHeadless{
 		t: t,
@@ -476,12 +510,12 @@ func TestDoomLevels(t *testing.T) {
 		}...)
 	}
 	// Quit the game
-	game.keys = append(game.keys, delayedEvent{ticks: 1000, callback: func(d *doomTestHeadless) { Stop() }})
-	Run(game, []string{"-iwad", "doom1.wad"})
+	game.keys = append(game.keys, delayedEvent{ticks: 1000, callback: func(d *doomTestHeadless) { d.Stop() }})
+	game.run(Options{})
 }
 
 func TestDoomMap(t *testing.T) {
-	dg_run_full_speed = true
+	t.Parallel()
 	game := &doomTestHeadless{
 		t: t,
 	}
@@ -515,11 +549,11 @@ func TestDoomMap(t *testing.T) {
 		game.InsertKey(KEY_ENTER)    // Confirm quit
 		game.InsertKey('y')          // Confirm exit
 	}()
-	Run(game, []string{"-iwad", "doom1.wad"})
+	game.run(Options{})
 }
 
 func TestWeapons(t *testing.T) {
-	dg_run_full_speed = true
+	t.Parallel()
 	game := &doomTestHeadless{
 		t: t,
 		keys: []delayedEvent{
@@ -559,9 +593,9 @@ func TestWeapons(t *testing.T) {
 		}...)
 	}
 	// Quit the game
-	game.keys = append(game.keys, delayedEvent{ticks: 1000, callback: func(d *doomTestHeadless) { Stop() }})
+	game.keys = append(game.keys, delayedEvent{ticks: 1000, callback: func(d *doomTestHeadless) { d.Stop() }})
 	defer game.Close()
-	Run(game, []string{"-iwad", "doom1.wad"})
+	game.run(Options{})
 }
 
 func confirmMenu(t *testing.T, game *doomTestHeadless, name string) {
@@ -597,18 +631,16 @@ func confirmMenu(t *testing.T, game *doomTestHeadless, name string) {
 
 // TestMenus walks through the menus and checks the screenshots
 func TestMenus(t *testing.T) {
-	dg_run_full_speed = true
+	t.Parallel()
 	game := &doomTestHeadless{
 		t: t,
 	}
 	defer game.Close()
-	// Disable the demo playback, since it messes with the screenshots
-	dont_run_demo = true
 
 	go func() {
 		// Wait for screen wipe
 		time.Sleep(5 * time.Millisecond)
-		for wipe_running != 0 {
+		for game.wipeRunning() {
 			time.Sleep(1 * time.Millisecond)
 		}
 		time.Sleep(5 * time.Millisecond)
@@ -629,7 +661,1573 @@ func TestMenus(t *testing.T) {
 		confirmMenu(t, game, "load")
 
 		// Quit
-		Stop()
+		game.Stop()
 	}()
-	Run(game, []string{"-iwad", "doom1.wad"})
+	// Disable the demo playback, since it messes with the screenshots
+	game.run(Options{dontRunDemo: true})
+}
+
+// TestStep confirms that two games driven by Step with the same inputs
+// produce identical output
+func TestStep(t *testing.T) {
+	t.Parallel()
+	var frames [2][]byte
+	for i := range frames {
+		headless := &doomTestHeadless{t: t}
+		game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad"}})
+		if err != nil {
+			t.Fatalf("Error creating game: %v", err)
+		}
+		start := []DoomEvent{{Type: Ev_keydown, Key: KEY_ESCAPE}, {Type: Ev_keyup, Key: KEY_ESCAPE}}
+		for range 3 {
+			start = append(start, DoomEvent{Type: Ev_keydown, Key: KEY_ENTER}, DoomEvent{Type: Ev_keyup, Key: KEY_ENTER})
+		}
+		for _, ev := range start {
+			if _, err := game.Step(ev); err != nil {
+				t.Fatalf("Error stepping game: %v", err)
+			}
+		}
+		if _, err := game.Step(DoomEvent{Type: Ev_keydown, Key: KEY_UPARROW1}); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+		var frame Frame
+		for range 200 {
+			if frame, err = game.Step(); err != nil {
+				t.Fatalf("Error stepping game: %v", err)
+			}
+		}
+		if frame.Exited {
+			t.Fatalf("Game exited unexpectedly")
+		}
+		frames[i] = append([]byte(nil), frame.Image.Pix...)
+		if err := game.Close(); err != nil {
+			t.Errorf("Error closing game: %v", err)
+		}
+		headless.Close()
+	}
+	if !bytes.Equal(frames[0], frames[1]) {
+		t.Errorf("Stepped games produced different frames")
+	}
+}
+
+// TestMissingIWAD confirms that a fatal engine error is returned to the
+// caller, and that it doesn't stop other games from being created
+func TestMissingIWAD(t *testing.T) {
+	t.Parallel()
+	for range 2 {
+		headless := &doomTestHeadless{t: t}
+		_, err := New(headless, Options{Args: []string{"-iwad", "missing.wad"}})
+		var engineErr *EngineError
+		if !errors.As(err, &engineErr) {
+			t.Fatalf("Expected an EngineError, got %v", err)
+		}
+		if engineErr.Subsystem != "main" {
+			t.Errorf("Unexpected subsystem %q for error %q", engineErr.Subsystem, engineErr.Message)
+		}
+	}
+}
+
+// TestRunContext confirms that cancelling the context stops the game, and that
+// a new game can then be started
+func TestRunContext(t *testing.T) {
+	t.Parallel()
+	for range 2 {
+		headless := &doomTestHeadless{t: t}
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		err := RunContext(ctx, headless, []string{"-iwad", "doom1.wad"})
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the context deadline to end the game, got %v", err)
+		}
+		headless.Close()
+	}
+
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad"}})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	game.Stop()
+	if err := game.Run(); err != nil {
+		t.Errorf("Error running game: %v", err)
+	}
+	if err := game.Run(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed when re-running a game, got %v", err)
+	}
+}
+
+type doomTestAudio struct {
+	doomTestHeadless
+	samples []int16
+}
+
+func (d *doomTestAudio) PlayAudio(samples []int16) {
+	d.samples = append(d.samples, samples...)
+}
+
+// TestSound confirms that the mixer produces audio in step with the tics
+func TestSound(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestAudio{doomTestHeadless: doomTestHeadless{t: t}}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad"}})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	// Opening the menu plays a sound
+	frame, err := game.Step(DoomEvent{Type: Ev_keydown, Key: KEY_ESCAPE})
+	if err != nil {
+		t.Fatalf("Error stepping game: %v", err)
+	}
+	start, startSamples := frame.Tic, len(headless.samples)
+	for range 35 {
+		if frame, err = game.Step(); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+	}
+	if got, want := len(headless.samples)-startSamples, (frame.Tic-start)*AudioSampleRate/TICRATE*2; got != want {
+		t.Errorf("Got %d samples, expected %d", got, want)
+	}
+	silent := true
+	for _, s := range headless.samples {
+		if s != 0 {
+			silent = false
+			break
+		}
+	}
+	if silent {
+		t.Errorf("No sound was produced")
+	}
+}
+
+type doomTestMusic struct {
+	doomTestHeadless
+	songs   [][]byte
+	looping bool
+	stopped int
+	volume  int
+}
+
+func (d *doomTestMusic) PlayMusic(midi []byte, looping bool) {
+	d.songs = append(d.songs, midi)
+	d.looping = looping
+}
+
+func (d *doomTestMusic) StopMusic() {
+	d.stopped++
+}
+
+func (d *doomTestMusic) SetMusicVolume(volume int) {
+	d.volume = volume
+}
+
+// PlayAudio makes the OPL synthesizer available, so that TestMusic can check
+// it is only used when chosen
+func (d *doomTestMusic) PlayAudio(samples []int16) {
+}
+
+// TestMusic confirms that the level music is converted to MIDI, rather than
+// played on the OPL synthesizer, and silenced while paused
+func TestMusic(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestMusic{doomTestHeadless: doomTestHeadless{t: t}}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	if _, err := game.Step(); err != nil {
+		t.Fatalf("Error stepping game: %v", err)
+	}
+	if len(headless.songs) == 0 {
+		t.Fatalf("No music was played")
+	}
+	if song := headless.songs[len(headless.songs)-1]; !bytes.HasPrefix(song, []byte("MThd")) {
+		t.Errorf("Music isn't MIDI: % x", song[:min(len(song), 16)])
+	}
+	if !headless.looping {
+		t.Errorf("Level music isn't looping")
+	}
+	if headless.volume == 0 {
+		t.Errorf("Music volume is 0")
+	}
+	for _, ev := range []Evtype_t{Ev_keydown, Ev_keyup} {
+		if _, err := game.Step(DoomEvent{Type: ev, Key: KEY_PAUSE1}); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+	}
+	if headless.volume != 0 {
+		t.Errorf("Music volume is %d while paused", headless.volume)
+	}
+	// Choosing the Sound Blaster plays it on the OPL synthesizer instead
+	game.withState(func() {
+		if opl_chip != nil {
+			t.Errorf("OPL synthesizer is running with General MIDI")
+		}
+		music_module.FShutdown()
+		snd_musicdevice = SNDDEVICE_SB
+		initMusicModule()
+		if opl_chip == nil {
+			t.Errorf("OPL synthesizer isn't running with the Sound Blaster")
+		}
+	})
+}
+
+func TestMus2Mid(t *testing.T) {
+	t.Parallel()
+	mus := []byte{
+		'M', 'U', 'S', 0x1a,
+		10, 0, // Score length
+		16, 0, // Score start
+		1, 0, // Primary channels
+		0, 0, // Secondary channels
+		0, 0, // Instruments
+		0, 0, // Padding
+		0x40, 0, 30, // Change instrument to 30
+		0x90, 0xbc, 100, // Press key 60 at volume 100
+		0x0a,     // Delay 10 ticks
+		0x00, 60, // Release key 60
+		0x60, // Score end
+	}
+	want := []byte{
+		'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 0, 0, 1, 0, 70,
+		'M', 'T', 'r', 'k', 0, 0, 0, 19,
+		0x00, 0xb0, 0x7b, 0x00, // All notes off
+		0x00, 0xc0, 30, // Change instrument
+		0x00, 0x90, 60, 100, // Note on
+		0x0a, 0x80, 60, 0, // Note off
+		0x00, 0xff, 0x2f, 0x00, // End of track
+	}
+	if got := mus2mid(mus); !bytes.Equal(got, want) {
+		t.Errorf("mus2mid gave\n% x\nexpected\n% x", got, want)
+	}
+	if got := mus2mid(mus[:len(mus)-1]); got != nil {
+		t.Errorf("mus2mid accepted a truncated score: % x", got)
+	}
+}
+
+// TestOPL plays a sustained sine wave on the OPL emulator, and checks its
+// frequency and level
+func TestOPL(t *testing.T) {
+	t.Parallel()
+	chip := newOPLChip()
+	chip.writeReg(0x20, 0x01) // Modulator: multiplier 1
+	chip.writeReg(0x40, 0x3f) // Modulator: minimum level
+	chip.writeReg(0x23, 0x21) // Carrier: sustained, multiplier 1
+	chip.writeReg(0x43, 0x00) // Carrier: maximum level
+	chip.writeReg(0x63, 0xf0) // Carrier: instant attack
+	chip.writeReg(0x83, 0x0f) // Carrier: no decay
+	// 440Hz is an F-number of 580 in block 4
+	chip.writeReg(0xa0, 580&0xff)
+	chip.writeReg(0xb0, 580>>8|4<<2|0x20)
+	crossings := 0
+	var prev, peak int32
+	for range opl_RATE {
+		left, right := chip.generate()
+		if left != right {
+			t.Fatalf("Left %d and right %d differ", left, right)
+		}
+		if prev < 0 && left >= 0 {
+			crossings++
+		}
+		prev = left
+		peak = max(peak, left)
+	}
+	if crossings < 439 || crossings > 441 {
+		t.Errorf("Got %dHz, expected 440Hz", crossings)
+	}
+	if peak < 4000 {
+		t.Errorf("Peak level of %d is too quiet", peak)
+	}
+}
+
+func TestParseMIDI(t *testing.T) {
+	t.Parallel()
+	midi := []byte{
+		'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 1, 0, 2, 0, 70,
+		'M', 'T', 'r', 'k', 0, 0, 0, 11,
+		0x00, 0xff, 0x51, 0x03, 0x07, 0xa1, 0x20, // Tempo
+		0x00, 0xff, 0x2f, 0x00, // End of track
+		'M', 'T', 'r', 'k', 0, 0, 0, 14,
+		0x00, 0x90, 60, 100, // Note on
+		0x0a, 60, 0, // Note on with running status and velocity 0
+		0x00, 0xc0, 5, // Change instrument
+		0x00, 0xff, 0x2f, 0x00, // End of track
+	}
+	song := parseMIDI(midi)
+	if song == nil {
+		t.Fatalf("Failed to parse MIDI")
+	}
+	want := []midi_event_t{
+		{Ftick: 0, Fstatus: 0xff, Ftempo: 500000},
+		{Ftick: 0, Fstatus: 0x90, Fdata1: 60, Fdata2: 100},
+		{Ftick: 10, Fstatus: 0x90, Fdata1: 60, Fdata2: 0},
+		{Ftick: 10, Fstatus: 0xc0, Fdata1: 5},
+	}
+	if song.Fdivision != 70 || !slices.Equal(song.Fevents, want) {
+		t.Errorf("Got %+v, expected %+v", song, want)
+	}
+	if parseMIDI(midi[:len(midi)-5]) != nil {
+		t.Errorf("Parsed a truncated MIDI file")
+	}
+}
+
+// TestOPLMusic confirms that the level music is played on the OPL emulator
+// when the frontend only supports PCM audio
+func TestOPLMusic(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestAudio{doomTestHeadless: doomTestHeadless{t: t}}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	for range 35 {
+		if _, err := game.Step(); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+	}
+	game.withState(func() {
+		if opl_song == nil {
+			t.Errorf("No OPL music is playing")
+		}
+	})
+	silent := true
+	for _, s := range headless.samples {
+		if s != 0 {
+			silent = false
+			break
+		}
+	}
+	if silent {
+		t.Errorf("No music was produced")
+	}
+}
+
+func TestState(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "3", "-skill", "4"}})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	if _, err := game.Step(); err != nil {
+		t.Fatalf("Error stepping game: %v", err)
+	}
+	state, err := game.State()
+	if err != nil {
+		t.Fatalf("Error getting state: %v", err)
+	}
+	if !state.InLevel || state.Episode != 1 || state.Map != 3 || state.Skill != 3 {
+		t.Errorf("Expected to be in E1M3 on skill 3, got %+v", state)
+	}
+	if state.TotalKills == 0 || state.TotalItems == 0 || state.TotalSecrets == 0 {
+		t.Errorf("Level totals are missing: %+v", state)
+	}
+	p := state.Player
+	if !p.Alive || p.Health != 100 || p.Ammo[AmmoBullets] != 50 || p.Weapon != WeaponPistol {
+		t.Errorf("Unexpected starting player state: %+v", p)
+	}
+	if !p.Weapons[WeaponFist] || !p.Weapons[WeaponPistol] || p.Weapons[WeaponShotgun] {
+		t.Errorf("Unexpected starting weapons: %v", p.Weapons)
+	}
+	// Run forwards for a bit
+	for range 10 {
+		if _, err := game.Step(DoomEvent{Type: Ev_keydown, Key: KEY_UPARROW1}); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+	}
+	moved, err := game.State()
+	if err != nil {
+		t.Fatalf("Error getting state: %v", err)
+	}
+	if moved.Player.Position == p.Position || moved.Player.Momentum == (Vec3{}) {
+		t.Errorf("Player didn't move from %+v: %+v", p.Position, moved.Player)
+	}
+	if moved.LevelTime <= state.LevelTime {
+		t.Errorf("Level time didn't advance: %d -> %d", state.LevelTime, moved.LevelTime)
+	}
+	game.Close()
+	if _, err := game.State(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed from a closed game, got %v", err)
+	}
+}
+
+func TestMobjs(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	if _, err := game.Step(); err != nil {
+		t.Fatalf("Error stepping game: %v", err)
+	}
+	state, err := game.State()
+	if err != nil {
+		t.Fatalf("Error getting state: %v", err)
+	}
+	monsters := map[uint32]Mobj{}
+	var player *Mobj
+	for _, mo := range allMobjs(t, game) {
+		if mo.Player == 0 {
+			player = &mo
+		}
+		if mo.Flags&MobjCountKill != 0 {
+			monsters[mo.ID] = mo
+		}
+	}
+	if player == nil || player.Type != "MT_PLAYER" || player.Sprite != "PLAY" || player.Position != state.Player.Position {
+		t.Errorf("Player object is wrong: %+v", player)
+	}
+	if len(monsters) != state.TotalKills {
+		t.Errorf("Found %d monsters, expected %d", len(monsters), state.TotalKills)
+	}
+	for range 35 {
+		if _, err := game.Step(); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+	}
+	for _, mo := range allMobjs(t, game) {
+		if mo.Flags&MobjCountKill == 0 {
+			continue
+		}
+		if before, ok := monsters[mo.ID]; !ok || before.Type != mo.Type {
+			t.Errorf("Monster %d changed from %+v to %+v", mo.ID, before, mo)
+		}
+		delete(monsters, mo.ID)
+	}
+	if len(monsters) != 0 {
+		t.Errorf("Monsters disappeared: %+v", monsters)
+	}
+	game.Close()
+	errs := 0
+	for _, err := range game.Mobjs() {
+		if err != ErrClosed {
+			t.Errorf("Mobjs of a closed game returned %v, expected ErrClosed", err)
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Errorf("Mobjs of a closed game returned %d errors, expected 1", errs)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	if _, err := game.Step(); err != nil {
+		t.Fatalf("Error stepping game: %v", err)
+	}
+	level, err := game.Level()
+	if err != nil {
+		t.Fatalf("Error getting level: %v", err)
+	}
+	if level.Name != "E1M1" {
+		t.Errorf("Level name is %q, expected E1M1", level.Name)
+	}
+	if len(level.Vertices) == 0 || len(level.Lines) == 0 || len(level.Sectors) == 0 || len(level.Nodes) == 0 {
+		t.Fatalf("Level is missing geometry: %d vertices, %d lines, %d sectors, %d nodes",
+			len(level.Vertices), len(level.Lines), len(level.Sectors), len(level.Nodes))
+	}
+	for i, l := range level.Lines {
+		if l.Front < 0 || l.Front >= len(level.Sides) {
+			t.Errorf("Line %d has invalid front side %d", i, l.Front)
+		}
+		if (l.Back >= 0) != (l.Flags&LineTwoSided != 0) {
+			t.Errorf("Line %d has back side %d, but flags %#x", i, l.Back, l.Flags)
+		}
+	}
+	for i, s := range level.Sectors {
+		if s.Ceiling < s.Floor || s.FloorFlat == "" || s.CeilingFlat == "" {
+			t.Errorf("Sector %d is wrong: %+v", i, s)
+		}
+	}
+	starts := 0
+	for _, th := range level.Things {
+		if th.Type == 1 {
+			starts++
+			if th.X != 1056 || th.Y != -3616 {
+				t.Errorf("Player 1 start is at %v,%v", th.X, th.Y)
+			}
+		}
+	}
+	if starts != 1 {
+		t.Errorf("Found %d player 1 starts", starts)
+	}
+	if want := level.Blockmap.Width * level.Blockmap.Height; len(level.Blockmap.Lines) != want {
+		t.Errorf("Blockmap has %d blocks, expected %d", len(level.Blockmap.Lines), want)
+	}
+
+	// The level's geometry is still loaded during the intermission, but
+	// isn't being played
+	game.withState(g_ExitLevel)
+	if _, err := game.Step(); err != nil {
+		t.Fatalf("Error stepping game: %v", err)
+	}
+	if _, err := game.Level(); err != ErrNoLevel {
+		t.Errorf("Level during the intermission returned %v, expected ErrNoLevel", err)
+	}
+}
+
+func TestInput(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	step := func() State {
+		t.Helper()
+		if _, err := game.Step(); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+		state, err := game.State()
+		if err != nil {
+			t.Fatalf("Error getting state: %v", err)
+		}
+		return state
+	}
+	start := step()
+
+	// The player starts facing north, so turning 90 degrees faces west
+	if err := game.SetTicCommand(0, 0, 90, 0); err != nil {
+		t.Fatalf("Error setting tic command: %v", err)
+	}
+	turned := step()
+	if turned.Player.Angle != start.Player.Angle+90 {
+		t.Errorf("Player angle is %v after turning, expected %v", turned.Player.Angle, start.Player.Angle+90)
+	}
+
+	for range 5 {
+		if err := game.SetTicCommand(50, 0, 0, 0); err != nil {
+			t.Fatalf("Error setting tic command: %v", err)
+		}
+		step()
+	}
+	moved := step()
+	if moved.Player.Position.X >= turned.Player.Position.X {
+		t.Errorf("Player didn't move west: %v -> %v", turned.Player.Position, moved.Player.Position)
+	}
+
+	if err := game.PressKey(KEY_UPARROW1); err != nil {
+		t.Fatalf("Error pressing key: %v", err)
+	}
+	for range 5 {
+		step()
+	}
+	if err := game.ReleaseKey(KEY_UPARROW1); err != nil {
+		t.Fatalf("Error releasing key: %v", err)
+	}
+	walked := step()
+	if walked.Player.Position.X >= moved.Player.Position.X {
+		t.Errorf("Player didn't walk west: %v -> %v", moved.Player.Position, walked.Player.Position)
+	}
+
+	if err := game.MoveMouse(-100, 0); err != nil {
+		t.Fatalf("Error moving mouse: %v", err)
+	}
+	if looked := step(); looked.Player.Angle <= walked.Player.Angle {
+		t.Errorf("Player didn't turn left with the mouse: %v -> %v", walked.Player.Angle, looked.Player.Angle)
+	}
+}
+
+func TestBuffers(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}, Buffers: true})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	var frame Frame
+	for range 5 {
+		if frame, err = game.Step(); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+	}
+	b := frame.Buffers
+	if b == nil {
+		t.Fatalf("No buffers returned")
+	}
+	ids := map[int]bool{}
+	for _, mo := range allMobjs(t, game) {
+		ids[int(mo.ID)] = true
+	}
+	kinds := map[LabelKind]int{}
+	for y := range b.Labels.Height {
+		for x := range b.Labels.Width {
+			label := b.Labels.At(x, y)
+			kinds[label.Kind()]++
+			depth := b.Depth.Gray16At(x, y).Y
+			switch label.Kind() {
+			case LabelNone, LabelWeapon:
+				if depth != 0 {
+					t.Fatalf("Pixel %d,%d has label %v but depth %d", x, y, label.Kind(), depth)
+				}
+			case LabelThing:
+				if !ids[label.Index()] {
+					t.Fatalf("Pixel %d,%d is labelled with unknown object %d", x, y, label.Index())
+				}
+			default:
+				if depth == 0 {
+					t.Fatalf("Pixel %d,%d has label %v but no depth", x, y, label.Kind())
+				}
+			}
+		}
+	}
+	for _, kind := range []LabelKind{LabelNone, LabelWall, LabelFloor, LabelCeiling, LabelWeapon} {
+		if kinds[kind] == 0 {
+			t.Errorf("No pixels labelled with %v: %v", kind, kinds)
+		}
+	}
+	// The status bar isn't part of the 3D view
+	if label := b.Labels.At(b.Labels.Width/2, b.Labels.Height-1); label.Kind() != LabelNone {
+		t.Errorf("Status bar is labelled as %v", label.Kind())
+	}
+	lit := 0
+	for i := 0; i < len(b.Automap.Pix); i += 4 {
+		if b.Automap.Pix[i] != 0 || b.Automap.Pix[i+1] != 0 || b.Automap.Pix[i+2] != 0 {
+			lit++
+		}
+	}
+	if lit == 0 {
+		t.Errorf("Automap is blank")
+	}
+}
+
+// renderFrame starts a game on E1M1 with the given options, and returns the
+// frame after a few tics
+func renderFrame(t *testing.T, headless *doomTestHeadless, opts Options) *image.RGBA {
+	opts.Args = []string{"-iwad", "doom1.wad", "-warp", "1", "1"}
+	game, err := New(headless, opts)
+	if err != nil {
+		t.Fatalf("Error creating %dx%d game: %v", opts.Width, opts.Height, err)
+	}
+	defer game.Close()
+	var frame Frame
+	for range 5 {
+		if frame, err = game.Step(); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+	}
+	img := image.NewRGBA(frame.Image.Rect)
+	copy(img.Pix, frame.Image.Pix)
+	return img
+}
+
+func TestResolution(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	if _, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad"}, Width: 100, Height: 100}); err == nil {
+		t.Errorf("Expected an error for an unsupported resolution")
+	}
+	small := renderFrame(t, headless, Options{})
+	large := renderFrame(t, headless, Options{Width: ORIGWIDTH * 2, Height: ORIGHEIGHT * 2})
+	if size := large.Bounds().Size(); size.X != ORIGWIDTH*2 || size.Y != ORIGHEIGHT*2 {
+		t.Fatalf("Frame is %v, expected %dx%d", size, ORIGWIDTH*2, ORIGHEIGHT*2)
+	}
+	// The ammo count on the status bar should be scaled up exactly
+	for y := ORIGHEIGHT - 32; y < ORIGHEIGHT; y++ {
+		for x := range 48 {
+			if small.RGBAAt(x, y) != large.RGBAAt(x*2+1, y*2+1) {
+				t.Fatalf("Status bar pixel %d,%d is %v at 320x200, but %v at 640x400", x, y, small.RGBAAt(x, y), large.RGBAAt(x*2+1, y*2+1))
+			}
+		}
+	}
+}
+
+func TestWidescreen(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	narrow := renderFrame(t, headless, Options{Width: 480, Height: 360})
+	wide := renderFrame(t, headless, Options{Width: 640, Height: 360, Aspect: Aspect16x9})
+	// The status bar should be the same, but centered
+	for y := 302; y < 360; y++ {
+		for x := range 72 {
+			if narrow.RGBAAt(x, y) != wide.RGBAAt(x+80, y) {
+				t.Fatalf("Status bar pixel %d,%d is %v at 4:3, but %v at 16:9", x, y, narrow.RGBAAt(x, y), wide.RGBAAt(x+80, y))
+			}
+		}
+	}
+	// with the background flat either side of it
+	black := color.RGBA{A: 0xff}
+	filled := 0
+	for y := 302; y < 360; y++ {
+		for x := range 80 {
+			if wide.RGBAAt(x, y) != black {
+				filled++
+			}
+		}
+	}
+	if filled == 0 {
+		t.Errorf("Status bar border isn't filled")
+	}
+	// The default size is widened to match the aspect ratio
+	if size := renderFrame(t, headless, Options{Aspect: Aspect16x10}).Bounds().Size(); size.X != 384 || size.Y != ORIGHEIGHT {
+		t.Errorf("16:10 frame is %v, expected 384x200", size)
+	}
+}
+
+// TestAreaWidth confirms that the default resolution for each aspect ratio
+// fits the 2D graphics exactly
+func TestAreaWidth(t *testing.T) {
+	t.Parallel()
+	game := &Game{state: enginePristine}
+	game.withState(func() {
+		for aspect := AspectVanilla; aspect <= Aspect16x9; aspect++ {
+			if err := i_SetResolution(0, 0, aspect); err != nil {
+				t.Errorf("Error setting the default resolution for aspect %d: %v", aspect, err)
+				continue
+			}
+			if area := v_AreaWidth(); area != ORIGWIDTH {
+				t.Errorf("%dx%d screen at aspect %d has a 4:3 area %d wide, expected %d", screenwidth, screenheight, aspect, area, ORIGWIDTH)
+			}
+		}
+	})
+}
+
+func TestInterpolation(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}, Uncapped: true})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	for range 5 {
+		if err := game.SetTicCommand(50, 0, 0, 0); err != nil {
+			t.Fatalf("Error setting tic command: %v", err)
+		}
+		if _, err := game.Step(); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+	}
+
+	game.lock()
+	defer game.unlock()
+	mo := players[consoleplayer].Fmo
+	oldy, y := mo.Foldy, mo.Fy
+	if oldy == y {
+		t.Fatalf("Player didn't move during the last tic")
+	}
+	r_BeginInterpolation(FRACUNIT / 2)
+	if expected := oldy + (y-oldy)/2; mo.Fy != expected {
+		t.Errorf("Halfway between %v and %v is %v, expected %v", oldy, y, mo.Fy, expected)
+	}
+	r_EndInterpolation()
+	if mo.Fy != y {
+		t.Errorf("Player y is %v after drawing, expected %v", mo.Fy, y)
+	}
+
+	// 20ms is 0.7 of a tic, even after the game has been running for a day
+	saved := dg_fake_tics
+	defer func() { dg_fake_tics = saved }()
+	dg_fake_tics = uint64(basetime) + 24*60*60*1000 + 20
+	if frac := r_InterpolationFrac(); frac != 7*FRACUNIT/10 {
+		t.Errorf("Interpolation fraction after a day is %v, expected %v", frac, 7*FRACUNIT/10)
+	}
+}
+
+func TestPaletted(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	rgba := renderFrame(t, headless, Options{})
+
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}, Paletted: true})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	var frame Frame
+	for range 5 {
+		if frame, err = game.Step(); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+	}
+	if frame.Image != nil {
+		t.Errorf("RGBA frame returned when paletted")
+	}
+	if frame.Paletted.Bounds() != rgba.Bounds() {
+		t.Fatalf("Paletted frame is %v, expected %v", frame.Paletted.Bounds(), rgba.Bounds())
+	}
+	for i, c := range frame.Paletted.Palette {
+		if _, _, _, a := c.RGBA(); a != 0xffff {
+			t.Fatalf("Palette colour %d is %v, expected it to be opaque", i, c)
+		}
+	}
+	for y := range rgba.Rect.Dy() {
+		for x := range rgba.Rect.Dx() {
+			if c := color.RGBAModel.Convert(frame.Paletted.At(x, y)); c != rgba.RGBAAt(x, y) {
+				t.Fatalf("Pixel %d,%d is %v, expected %v", x, y, c, rgba.RGBAAt(x, y))
+			}
+		}
+	}
+}
+
+func TestFrameHook(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	var tics []int
+	remove, err := game.AddFrameHook(func(img *image.Paletted, tic int) {
+		tics = append(tics, tic)
+	})
+	if err != nil {
+		t.Fatalf("Error adding frame hook: %v", err)
+	}
+	for range 3 {
+		if _, err := game.Step(); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+	}
+	if err := remove(); err != nil {
+		t.Errorf("Error removing frame hook: %v", err)
+	}
+	if _, err := game.Step(); err != nil {
+		t.Fatalf("Error stepping game: %v", err)
+	}
+	if len(tics) != 3 || tics[1] != tics[0]+1 || tics[2] != tics[1]+1 {
+		t.Errorf("Frame hook called for tics %v, expected 3 in a row", tics)
+	}
+}
+
+// reentrantHeadless calls the game's methods from DrawFrame
+type reentrantHeadless struct {
+	*doomTestHeadless
+	errs []error
+}
+
+func (d *reentrantHeadless) DrawFrame(frame *image.RGBA) {
+	d.doomTestHeadless.DrawFrame(frame)
+	_, err := d.game.State()
+	d.errs = append(d.errs, err)
+	d.errs = append(d.errs, d.game.NewGame(1, 1, 2))
+}
+
+func TestReentrant(t *testing.T) {
+	t.Parallel()
+	headless := &reentrantHeadless{doomTestHeadless: &doomTestHeadless{t: t}}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	headless.game = game
+	if _, err := game.Step(); err != nil {
+		t.Fatalf("Error stepping game: %v", err)
+	}
+	if len(headless.errs) == 0 {
+		t.Fatalf("DrawFrame wasn't called")
+	}
+	for _, err := range headless.errs {
+		if !errors.Is(err, ErrReentrant) {
+			t.Errorf("Calling the game from DrawFrame returned %v, expected ErrReentrant", err)
+		}
+	}
+	var remove func() error
+	var removeErr error
+	remove, err = game.AddFrameHook(func(*image.Paletted, int) { removeErr = remove() })
+	if err != nil {
+		t.Fatalf("Error adding frame hook: %v", err)
+	}
+	if _, err := game.Step(); err != nil {
+		t.Fatalf("Error stepping game: %v", err)
+	}
+	if removeErr != ErrReentrant {
+		t.Errorf("Removing a frame hook from within it returned %v, expected ErrReentrant", removeErr)
+	}
+	// The game is still usable afterwards
+	if _, err := game.State(); err != nil {
+		t.Errorf("Error getting state: %v", err)
+	}
+}
+
+func TestRuntimeError(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad"}})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	// A demo with only a version, so reading the header goes out of range
+	game.withState(func() {
+		demo_data = []byte{109}
+		gameaction = ga_playdemo
+	})
+	var engineErr *EngineError
+	if _, err := game.Step(); !errors.As(err, &engineErr) {
+		t.Fatalf("Playing a truncated demo returned %v, expected an EngineError", err)
+	}
+	if engineErr.Func != "g_DoPlayDemo" || engineErr.Stack == "" {
+		t.Errorf("Error is from %q with stack %q, expected g_DoPlayDemo", engineErr.Func, engineErr.Stack)
+	}
+	if _, err := game.Step(); err != engineErr {
+		t.Errorf("Stepping after the error returned %v, expected %v", err, engineErr)
+	}
+}
+
+func TestRecordGame(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	var gifData, apngData bytes.Buffer
+	gifRecorder, err := recorder.New(&gifData, recorder.GIF)
+	if err != nil {
+		t.Fatalf("Error creating GIF recorder: %v", err)
+	}
+	apngRecorder, err := recorder.New(&apngData, recorder.APNG)
+	if err != nil {
+		t.Fatalf("Error creating APNG recorder: %v", err)
+	}
+	for _, rec := range []*recorder.Recorder{gifRecorder, apngRecorder} {
+		if err := rec.Attach(game); err != nil {
+			t.Fatalf("Error attaching recorder: %v", err)
+		}
+	}
+	var frames []*image.RGBA
+	for range 3 {
+		frame, err := game.Step()
+		if err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+		img := image.NewRGBA(frame.Image.Rect)
+		copy(img.Pix, frame.Image.Pix)
+		frames = append(frames, img)
+	}
+	if err := gifRecorder.Close(); err != nil {
+		t.Fatalf("Error closing GIF recorder: %v", err)
+	}
+	if err := apngRecorder.Close(); err != nil {
+		t.Fatalf("Error closing APNG recorder: %v", err)
+	}
+
+	same := func(name string, got image.Image, want *image.RGBA) {
+		t.Helper()
+		if got.Bounds() != want.Bounds() {
+			t.Fatalf("%s is %v, expected %v", name, got.Bounds(), want.Bounds())
+		}
+		for y := range want.Rect.Dy() {
+			for x := range want.Rect.Dx() {
+				if c := color.RGBAModel.Convert(got.At(x, y)); c != want.RGBAAt(x, y) {
+					t.Fatalf("%s pixel %d,%d is %v, expected %v", name, x, y, c, want.RGBAAt(x, y))
+				}
+			}
+		}
+	}
+	anim, err := gif.DecodeAll(&gifData)
+	if err != nil {
+		t.Fatalf("Error decoding GIF: %v", err)
+	}
+	same("First GIF frame", anim.Image[0], frames[0])
+	same("Last GIF frame", anim.Image[len(anim.Image)-1], frames[len(frames)-1])
+	// Decoding an APNG as a PNG gives its first frame
+	img, err := png.Decode(&apngData)
+	if err != nil {
+		t.Fatalf("Error decoding APNG: %v", err)
+	}
+	same("First APNG frame", img, frames[0])
+}
+
+// doomTestScreenshot keeps the screenshots the game saves
+type doomTestScreenshot struct {
+	doomTestHeadless
+	shots []*image.Paletted
+}
+
+func (d *doomTestScreenshot) SaveScreenshot(img *image.Paletted) error {
+	d.shots = append(d.shots, img)
+	return nil
+}
+
+func TestScreenshot(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestScreenshot{doomTestHeadless: doomTestHeadless{t: t}}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	var frame Frame
+	for range 5 {
+		if frame, err = game.Step(); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+	}
+	shot, err := game.Screenshot()
+	if err != nil {
+		t.Fatalf("Error taking screenshot: %v", err)
+	}
+	if !bytes.Equal(shot.Pix, frame.Paletted.Pix) || !slices.Equal(shot.Palette, frame.Paletted.Palette) {
+		t.Errorf("Screenshot doesn't match the frame")
+	}
+	// Keep the RGBA frame to compare the saved screenshot with, as the next
+	// Step overwrites it
+	want := image.NewRGBA(frame.Image.Bounds())
+	copy(want.Pix, frame.Image.Pix)
+
+	// The screenshot key saves the frame before the one it was pressed in
+	if _, err := game.Step(DoomEvent{Type: Ev_keydown, Key: KEY_PRTSCR}); err != nil {
+		t.Fatalf("Error stepping game: %v", err)
+	}
+	if _, err := game.Step(DoomEvent{Type: Ev_keyup, Key: KEY_PRTSCR}); err != nil {
+		t.Fatalf("Error stepping game: %v", err)
+	}
+	if len(headless.shots) != 1 {
+		t.Fatalf("%d screenshots were saved, expected 1", len(headless.shots))
+	}
+	img := headless.shots[0]
+	if img.Bounds() != want.Bounds() {
+		t.Fatalf("Screenshot is %v, expected %v", img.Bounds(), want.Bounds())
+	}
+	for y := range want.Rect.Dy() {
+		for x := range want.Rect.Dx() {
+			if c := color.RGBAModel.Convert(img.At(x, y)); c != want.RGBAAt(x, y) {
+				t.Fatalf("Screenshot pixel %d,%d is %v, expected %v", x, y, c, want.RGBAAt(x, y))
+			}
+		}
+	}
+}
+
+func TestDemo(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	newGame := func() *Game {
+		game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1", "-fast"}})
+		if err != nil {
+			t.Fatalf("Error creating game: %v", err)
+		}
+		return game
+	}
+	step := func(game *Game) State {
+		t.Helper()
+		if _, err := game.Step(); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+		state, err := game.State()
+		if err != nil {
+			t.Fatalf("Error getting state: %v", err)
+		}
+		return state
+	}
+
+	game := newGame()
+	defer game.Close()
+	step(game)
+	var demo bytes.Buffer
+	if err := game.RecordDemo(&demo); err != nil {
+		t.Fatalf("Error recording demo: %v", err)
+	}
+	if err := game.RecordDemo(&demo); err != ErrDemoRecording {
+		t.Errorf("Recording a second demo returned %v, expected ErrDemoRecording", err)
+	}
+	var recorded State
+	for range 20 {
+		if err := game.SetTicCommand(50, 0, 2, 0); err != nil {
+			t.Fatalf("Error setting tic command: %v", err)
+		}
+		recorded = step(game)
+	}
+	if err := game.StopDemo(); err != nil {
+		t.Fatalf("Error stopping demo: %v", err)
+	}
+	data := demo.Bytes()
+	if len(data) != DEMOHEADERSIZE+20*4+1 || data[len(data)-1] != DEMOMARKER {
+		t.Fatalf("Demo is %d bytes, expected 20 tics", len(data))
+	}
+	if data[2] != 1 || data[3] != 1 {
+		t.Errorf("Demo is of E%dM%d, expected E1M1", data[2], data[3])
+	}
+	if data[6] != 1 {
+		t.Errorf("Demo was recorded without -fast")
+	}
+	game.withState(func() {
+		if usergame == 0 {
+			t.Errorf("Game can't be saved after recording a demo")
+		}
+	})
+
+	// Playing it back should end up in the same place
+	player := newGame()
+	defer player.Close()
+	if err := player.PlayDemo(bytes.NewReader(data[:5])); err == nil {
+		t.Errorf("No error playing a truncated demo")
+	}
+	bad := bytes.Clone(data)
+	for _, p := range []byte{MAXPLAYERS, 1} {
+		bad[8] = p
+		if err := player.PlayDemo(bytes.NewReader(bad)); err == nil {
+			t.Errorf("No error playing a demo as player %d", p)
+		}
+	}
+	// The engine shouldn't trust the header either, ie: for demo lumps
+	broken := newGame()
+	defer broken.Close()
+	broken.withState(func() {
+		demo_data = bad
+		gameaction = ga_playdemo
+	})
+	var engineErr *EngineError
+	if _, err := broken.Step(); !errors.As(err, &engineErr) {
+		t.Errorf("Playing a demo with a bad player returned %v, expected an EngineError", err)
+	}
+	if err := player.PlayDemo(bytes.NewReader(data)); err != nil {
+		t.Fatalf("Error playing demo: %v", err)
+	}
+	for range 25 {
+		if state := step(player); state.Player.Position == recorded.Player.Position && state.Player.Angle == recorded.Player.Angle {
+			return
+		}
+	}
+	t.Errorf("Demo playback never reached %v", recorded.Player.Position)
+}
+
+func TestSaveStores(t *testing.T) {
+	t.Parallel()
+	stores := map[string]SaveStore{
+		"dir":    NewDirSaveStore(t.TempDir() + "/saves"),
+		"memory": NewMemorySaveStore(),
+	}
+	for name, store := range stores {
+		if slots, err := store.List(); err != nil || len(slots) != 0 {
+			t.Errorf("%s: new store has slots %v, %v", name, slots, err)
+		}
+		if _, err := store.Read(1); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s: reading an empty slot returned %v", name, err)
+		}
+		for _, slot := range []int{3, 1} {
+			if err := store.Write(slot, []byte{byte(slot)}); err != nil {
+				t.Fatalf("%s: error writing slot %d: %v", name, slot, err)
+			}
+		}
+		if err := store.Write(1, []byte("replaced")); err != nil {
+			t.Fatalf("%s: error replacing slot 1: %v", name, err)
+		}
+		if slots, err := store.List(); err != nil || !slices.Equal(slots, []int{1, 3}) {
+			t.Errorf("%s: slots are %v, %v, expected [1 3]", name, slots, err)
+		}
+		if data, err := store.Read(1); err != nil || string(data) != "replaced" {
+			t.Errorf("%s: slot 1 is %q, %v", name, data, err)
+		}
+		if err := store.Delete(3); err != nil {
+			t.Errorf("%s: error deleting slot 3: %v", name, err)
+		}
+		if err := store.Delete(3); err != nil {
+			t.Errorf("%s: error deleting empty slot 3: %v", name, err)
+		}
+		if slots, err := store.List(); err != nil || !slices.Equal(slots, []int{1}) {
+			t.Errorf("%s: slots are %v, %v after deleting, expected [1]", name, slots, err)
+		}
+	}
+}
+
+func TestSaveGame(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	store := NewMemorySaveStore()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}, SaveStore: store})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	step := func() {
+		t.Helper()
+		if _, err := game.Step(); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+	}
+	step()
+	game.withState(func() { g_SaveGame(2, "test save") })
+	step()
+	step()
+	data, err := store.Read(2)
+	if err != nil {
+		t.Fatalf("Error reading save: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("test save\x00")) {
+		t.Errorf("Save doesn't start with its description: %q", data[:SAVESTRINGSIZE])
+	}
+	game.withState(func() {
+		m_ReadSaveStrings()
+		if savegamestrings[2] != "test save" || LoadMenu[2].Fstatus != 1 || LoadMenu[0].Fstatus != 0 {
+			t.Errorf("Save slots read as %q", savegamestrings)
+		}
+		g_LoadGame(2)
+	})
+	step()
+}
+
+// TestOversizeSaveGame saves a level with too many things for Vanilla Doom's
+// savegame buffer
+func TestOversizeSaveGame(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	store := NewMemorySaveStore()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}, SaveStore: store})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	step := func() {
+		t.Helper()
+		if _, err := game.Step(); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+	}
+	step()
+	const barrels = 2000
+	game.withState(func() {
+		mo := players[consoleplayer].Fmo
+		barrel := mobjtype_t(slices.Index(mobjTypeNames[:], "MT_BARREL"))
+		for range barrels {
+			p_SpawnMobj(mo.Fx+128*FRACUNIT, mo.Fy, mo.Fz, barrel)
+		}
+	})
+	save := func(slot int) ([]byte, string) {
+		t.Helper()
+		game.withState(func() { g_SaveGame(int32(slot), "big save") })
+		step()
+		step()
+		data, err := store.Read(slot)
+		if err != nil {
+			t.Fatalf("Error reading save: %v", err)
+		}
+		var message string
+		game.withState(func() { message = players[consoleplayer].Fmessage })
+		return data, message
+	}
+
+	// Too big for Vanilla, so saved in the extended format
+	data, message := save(0)
+	if len(data) <= SAVEGAMESIZE {
+		t.Fatalf("Save is only %d bytes", len(data))
+	}
+	if version := data[SAVESTRINGSIZE:]; !bytes.HasPrefix(version, []byte("gore save 1\x00")) {
+		t.Errorf("Oversize game saved with version %q", version[:VERSIONSIZE])
+	}
+	if message != "game saved, too big for vanilla doom." {
+		t.Errorf("Saving an oversize game showed %q", message)
+	}
+
+	// Without the limit, it's a Vanilla save which is too big for Vanilla
+	game.withState(func() { vanilla_savegame_limit = 0 })
+	data, message = save(1)
+	if version := data[SAVESTRINGSIZE:]; !bytes.HasPrefix(version, []byte("version 109\x00")) || message != "game saved." {
+		t.Errorf("Saved with version %q, showing %q", version[:VERSIONSIZE], message)
+	}
+
+	// Both load back with all of the barrels
+	for slot := range 2 {
+		game.withState(func() { g_LoadGame(int32(slot)) })
+		step()
+		n := 0
+		for _, mo := range allMobjs(t, game) {
+			if mo.Type == "MT_BARREL" {
+				n++
+			}
+		}
+		if n < barrels {
+			t.Errorf("Loaded %d barrels from slot %d, expected at least %d", n, slot, barrels)
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	play := func() State {
+		t.Helper()
+		for range 20 {
+			if err := game.SetTicCommand(50, 0, 3, ButtonAttack); err != nil {
+				t.Fatalf("Error setting tic command: %v", err)
+			}
+			if _, err := game.Step(); err != nil {
+				t.Fatalf("Error stepping game: %v", err)
+			}
+		}
+		state, err := game.State()
+		if err != nil {
+			t.Fatalf("Error getting state: %v", err)
+		}
+		return state
+	}
+	if _, err := game.Step(); err != nil {
+		t.Fatalf("Error stepping game: %v", err)
+	}
+	snapshot, err := game.Snapshot()
+	if err != nil {
+		t.Fatalf("Error taking snapshot: %v", err)
+	}
+	first := play()
+	if err := game.Restore(snapshot); err != nil {
+		t.Fatalf("Error restoring snapshot: %v", err)
+	}
+	// The game should play out exactly the same the second time around,
+	// apart from the tics which have passed
+	second := play()
+	second.GameTic = first.GameTic
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Game differs after restoring:\n%+v\n%+v", first, second)
+	}
+
+	if err := game.Restore([]byte("not a snapshot at all")); err == nil {
+		t.Errorf("No error restoring a bad snapshot")
+	}
+	// A corrupt trailer should be caught before the level is reloaded
+	extra := int(binary.LittleEndian.Uint32(snapshot[len(snapshot)-4:]))
+	trailer := len(snapshot) - extra
+	for _, corrupt := range []struct {
+		name  string
+		at    int
+		value uint32
+	}{
+		{"mobj count", trailer + 3*4, 1},
+		{"mobj target", trailer + 4*4 + 2*4, 1 << 20},
+		{"brain target count", len(snapshot) - (1+len(braintargets)+2)*4, uint32(len(braintargets)) + 1},
+	} {
+		bad := bytes.Clone(snapshot)
+		binary.LittleEndian.PutUint32(bad[corrupt.at:], corrupt.value)
+		if err := game.Restore(bad); err == nil {
+			t.Errorf("No error restoring a snapshot with a bad %s", corrupt.name)
+		}
+	}
+	if _, err := game.Step(); err != nil {
+		t.Errorf("Error stepping after bad snapshots: %v", err)
+	}
+}
+
+// TestSaveGameMessage loads a game saved while a message was showing, as the
+// message is saved as a pointer which can't be followed once loaded
+func TestSaveGameMessage(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	if _, err := game.Step(); err != nil {
+		t.Fatalf("Error stepping game: %v", err)
+	}
+	game.withState(func() { players[consoleplayer].Fmessage = "picked up a stimpack." })
+	snapshot, err := game.Snapshot()
+	if err != nil {
+		t.Fatalf("Error taking snapshot: %v", err)
+	}
+	if err := game.Restore(snapshot); err != nil {
+		t.Fatalf("Error restoring snapshot: %v", err)
+	}
+	var message string
+	game.withState(func() { message = players[consoleplayer].Fmessage })
+	if message != "" {
+		t.Errorf("Message after loading is %q, expected none", message)
+	}
+}
+
+// TestSnapshotCombat restores a snapshot taken while monsters are attacking,
+// and checks that everything plays out the same afterwards
+func TestSnapshotCombat(t *testing.T) {
+	t.Parallel()
+	headless := &doomTestHeadless{t: t}
+	defer headless.Close()
+	game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}})
+	if err != nil {
+		t.Fatalf("Error creating game: %v", err)
+	}
+	defer game.Close()
+	tic := func(i int) {
+		t.Helper()
+		// Shoot, which wakes the monsters, while turning back and forth
+		turn := 2.0
+		if i%70 >= 35 {
+			turn = -2
+		}
+		if err := game.SetTicCommand(0, 0, turn, ButtonAttack); err != nil {
+			t.Fatalf("Error setting tic command: %v", err)
+		}
+		if _, err := game.Step(); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+	}
+	hunting := func(mobjs []Mobj) bool {
+		for _, mo := range mobjs {
+			if mo.Flags&MobjCountKill != 0 && mo.Health > 0 && mo.Target != 0 {
+				return true
+			}
+		}
+		return false
+	}
+	var i int
+	for i = 0; !hunting(allMobjs(t, game)); i++ {
+		if i == 35*20 {
+			t.Fatalf("No monsters woke up")
+		}
+		tic(i)
+	}
+	snapshot, err := game.Snapshot()
+	if err != nil {
+		t.Fatalf("Error taking snapshot: %v", err)
+	}
+	before := allMobjs(t, game)
+	play := func() ([]Mobj, State) {
+		t.Helper()
+		for j := range 100 {
+			tic(i + j)
+		}
+		state, err := game.State()
+		if err != nil {
+			t.Fatalf("Error getting state: %v", err)
+		}
+		state.GameTic = 0
+		return allMobjs(t, game), state
+	}
+	firstMobjs, firstState := play()
+
+	if err := game.Restore(snapshot); err != nil {
+		t.Fatalf("Error restoring snapshot: %v", err)
+	}
+	if restored := allMobjs(t, game); !slices.Equal(before, restored) {
+		t.Errorf("Objects differ after restoring:\n%+v\n%+v", before, restored)
+	}
+	secondMobjs, secondState := play()
+	if !slices.Equal(firstMobjs, secondMobjs) {
+		t.Errorf("Objects differ after playing on from the snapshot:\n%+v\n%+v", firstMobjs, secondMobjs)
+	}
+	if !reflect.DeepEqual(firstState, secondState) {
+		t.Errorf("Game differs after playing on from the snapshot:\n%+v\n%+v", firstState, secondState)
+	}
+}
+
+// testPWAD returns a PWAD with a single lump
+func testPWAD(content string) []byte {
+	var wad bytes.Buffer
+	wad.WriteString("PWAD")
+	binary.Write(&wad, binary.LittleEndian, []int32{1, int32(12 + len(content))})
+	wad.WriteString(content)
+	binary.Write(&wad, binary.LittleEndian, []int32{12, int32(len(content))})
+	wad.WriteString("GORETEST")
+	return wad.Bytes()
+}
+
+func TestExtendedSaveGame(t *testing.T) {
+	t.Parallel()
+	iwad, err := os.ReadFile("doom1.wad")
+	if err != nil {
+		t.Fatalf("Error reading IWAD: %v", err)
+	}
+	files := fstest.MapFS{
+		"doom1.wad":   {Data: iwad},
+		"a.wad":       {Data: testPWAD("first")},
+		"b.wad":       {Data: testPWAD("second")},
+		"renamed.wad": {Data: testPWAD("first")},
+	}
+	// The shareware IWAD refuses -file, so the PWADs are added once the
+	// game has started
+	newGame := func(pwads ...string) *Game {
+		t.Helper()
+		headless := &doomTestHeadless{t: t}
+		t.Cleanup(headless.Close)
+		game, err := New(headless, Options{Args: []string{"-iwad", "doom1.wad", "-warp", "1", "1"}, FS: files})
+		if err != nil {
+			t.Fatalf("Error creating game: %v", err)
+		}
+		t.Cleanup(func() { game.Close() })
+		if _, err := game.Step(); err != nil {
+			t.Fatalf("Error stepping game: %v", err)
+		}
+		game.withState(func() {
+			for _, pwad := range pwads {
+				w_AddPWAD(pwad)
+				if lump := w_CheckNumForName("GORETEST"); lump < 0 || lumpinfo[lump].Fsize != int32(len(files[pwad].Data)-28) {
+					t.Errorf("Lump from %s not added correctly", pwad)
+				}
+			}
+		})
+		return game
+	}
+	snapshot := func(game *Game) []byte {
+		t.Helper()
+		data, err := game.Snapshot()
+		if err != nil {
+			t.Fatalf("Error taking snapshot: %v", err)
+		}
+		return data
+	}
+
+	// Without any PWADs, games are saved in the Vanilla format
+	vanilla := snapshot(newGame())
+	if version := vanilla[SAVESTRINGSIZE:]; !bytes.HasPrefix(version, []byte("version 109\x00")) {
+		t.Errorf("Game without PWADs saved with version %q", version[:VERSIONSIZE])
+	}
+
+	extended := snapshot(newGame("a.wad"))
+	if version := extended[SAVESTRINGSIZE:]; !bytes.HasPrefix(version, []byte("gore save 1\x00")) {
+		t.Errorf("Game with PWADs saved with version %q", version[:VERSIONSIZE])
+	}
+
+	var mismatch *WADMismatchError
+	err = newGame("b.wad").Restore(extended)
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Restoring with the wrong PWAD gave %v, want a WADMismatchError", err)
+	}
+	if len(mismatch.Saved) != 1 || mismatch.Saved[0].Name != "a.wad" || len(mismatch.Loaded) != 1 || mismatch.Loaded[0].Name != "b.wad" {
+		t.Errorf("Mismatch error has saved %v, loaded %v", mismatch.Saved, mismatch.Loaded)
+	}
+	if err := newGame().Restore(extended); !errors.As(err, &mismatch) {
+		t.Errorf("Restoring without the PWAD gave %v, want a WADMismatchError", err)
+	}
+	if err := newGame("renamed.wad").Restore(extended); err != nil {
+		t.Errorf("Error restoring with the same PWAD under another name: %v", err)
+	}
+
+	// The number of PWADs is saved in a byte
+	game := newGame()
+	game.withState(func() { pwad_files = make([]WADFile, 256) })
+	if _, err := game.Snapshot(); err == nil {
+		t.Errorf("No error saving with 256 PWADs")
+	}
 }
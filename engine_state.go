@@ -0,0 +1,2901 @@
+// Code generated by gen_engine_state.go; DO NOT EDIT.
+
+package gore
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// engineState is a copy of all of the package level engine variables
+type engineState struct {
+	aux_enabled        bool
+	aux_frontend       DoomBuffersFrontend
+	aux_buffers        *FrameBuffers
+	aux_depthbuf       []uint16
+	aux_labelbuf       []Label
+	aux_automapbuf     []byte
+	aux_depth          uint16
+	aux_label          Label
+	demo_writer        io.Writer
+	demo_err           error
+	demo_data          []byte
+	demo_usergame      boolean
+	vfs                fs.FS
+	dg_frontend        DoomFrontend
+	dg_run_full_speed  bool
+	dg_fake_tics       uint64
+	dg_exiting         bool
+	start_time         time.Time
+	cheating           int32
+	grid               bool
+	f_x                int32
+	f_y                int32
+	f_w                int32
+	f_h                int32
+	lightlev           int32
+	fb                 []byte
+	m_paninc           mpoint_t
+	mtof_zoommul       fixed_t
+	ftom_zoommul       fixed_t
+	m_x                fixed_t
+	m_y                fixed_t
+	m_x2               fixed_t
+	m_y2               fixed_t
+	m_w                fixed_t
+	m_h                fixed_t
+	min_x              fixed_t
+	min_y              fixed_t
+	max_x              fixed_t
+	max_y              fixed_t
+	max_w              fixed_t
+	max_h              fixed_t
+	min_scale_mtof     fixed_t
+	max_scale_mtof     fixed_t
+	old_m_w            fixed_t
+	old_m_h            fixed_t
+	old_m_x            fixed_t
+	old_m_y            fixed_t
+	f_oldloc           mpoint_t
+	scale_mtof         fixed_t
+	scale_ftom         fixed_t
+	plr                *player_t
+	marknums           [10]*patch_t
+	markpoints         [10]mpoint_t
+	markpointnum       int32
+	followplayer       int32
+	stopped            int32
+	st_notify          event_t
+	st_notify1         event_t
+	lastlevel          int32
+	lastepisode        int32
+	bigstate           int32
+	fuck               int32
+	fl                 fline_t
+	l                  mline_t
+	their_colors       [4]int32
+	events             [64]event_t
+	eventhead          int32
+	eventtail          int32
+	iwads              [14]iwad_t
+	iwad_dirs          []string
+	ticdata            [128]ticcmd_set_t
+	maketic            int32
+	recvtic            int32
+	localplayer        int32
+	skiptics           int32
+	new_sync           uint32
+	loop_interface     *loop_interface_t
+	local_playeringame [8]boolean
+	player_class       int32
+	frameon            int32
+	frameskip          [4]int32
+	oldnettics         int32
+	oldentertics       int32
+	viewactivestate    boolean
+	menuactivestate    boolean
+	inhelpscreensstate boolean
+	fullscreen         boolean
+	oldgamestate1      gamestate_t
+	borderdrawcount    int32
+	banners            [7]string
+	packs              [3]struct {
+		Fname    string
+		Fmission gamemission_t
+	}
+	copyright_banners [3]string
+	gameversions      [10]struct {
+		Fdescription string
+		Fcmdline     string
+		Fversion     gameversion_t
+	}
+	exitmsg             string
+	doom_loop_interface loop_interface_t
+	textscreens         [22]textscreen_t
+	laststage           int32
+	wipe_running        int
+	wipe_scr_start      []byte
+	wipe_scr_end        []byte
+	wipe_scr            []byte
+	y_screen            []int32
+	wipes               [6]func(int32, int32, int32) int32
+	weapon_keys         [8]*int32
+	next_weapon         weapontype_t
+	weapon_order_table  [9]struct {
+		Fweapon     weapontype_t
+		Fweapon_num weapontype_t
+	}
+	gamekeydown                [256]bool
+	turnheld                   int32
+	mousearray                 [9]bool
+	dclicktime                 int32
+	dclickstate                bool
+	dclicks                    int32
+	dclicktime2                int32
+	dclickstate2               boolean
+	dclicks2                   int32
+	joyxmove                   int32
+	joyymove                   int32
+	joystrafemove              int32
+	joyarray                   [21]bool
+	savegameslot               int32
+	savedescription            string
+	carry                      int16
+	plr1                       *player_t
+	w_title                    hu_textline_t
+	w_chat                     hu_itext_t
+	always_off                 boolean
+	chat_dest                  [4]int8
+	w_inputbuffer              [4]hu_itext_t
+	message_on                 boolean
+	message_nottobefuckedwith  boolean
+	w_message                  hu_stext_t
+	message_counter            int32
+	headsupactive              int
+	chatchars                  [128]int8
+	head                       int
+	tail                       int
+	lastmessage                string
+	altdown                    boolean
+	num_nobrainers             int32
+	usejoystick                int
+	joystick_index             int
+	joystick_x_axis            int
+	joystick_x_invert          int
+	joystick_y_axis            int32
+	joystick_y_invert          int32
+	joystick_strafe_axis       int
+	joystick_strafe_invert     int
+	joystick_physical_buttons  [10]int32
+	sound_module               *sound_module_t
+	music_module               *music_module_t
+	snd_sbport                 int
+	snd_sbirq                  int
+	snd_sbdma                  int
+	snd_mport                  int
+	sound_modules              []sound_module_t
+	music_modules              []music_module_t
+	exit_funcs                 []atexit_listentry_t
+	already_quitting           int
+	mem_dump_dos622            [10]uint8
+	mem_dump_win98             [10]uint8
+	mem_dump_dosbox            [10]uint8
+	mem_dump_custom            [10]uint8
+	dos_mem_dump               []byte
+	firsttime                  int
+	basetime                   uint32
+	last_tick                  int32
+	default_main_config        string
+	default_extra_config       string
+	doom_defaults_list         [76]default_t
+	doom_defaults              default_collection_t
+	extra_defaults_list        [119]default_t
+	extra_defaults             default_collection_t
+	detailNames                [2]string
+	msgNames                   [2]string
+	M_QuitResponse             func(key int32)
+	joywait                    int32
+	mousewait                  int32
+	mousey1                    int32
+	lasty                      int32
+	mousex1                    int32
+	lastx                      int32
+	x                          int16
+	y2                         int16
+	rndtable                   [256]uint8
+	easy                       int32
+	baseaddr                   int32
+	intercepts_overrun         [23]intercepts_overrun_t
+	dummy_mobj                 mobj_t
+	totallines                 int32
+	null_sector_is_initialized boolean
+	null_sector                sector_t
+	first                      int
+	tmp_s3_floorheight         int32
+	tmp_s3_floorpic            int32
+	background_buffer          []byte
+	clipbot                    []int16
+	cliptop                    []int16
+	captured_stats             [32]wbstartstruct_t
+	num_captured_stats         int32
+	plyr                       *player_t
+	st_firsttime               boolean
+	lu_palette                 int32
+	st_statusbaron             boolean
+	st_notdeathmatch           boolean
+	st_armson                  boolean
+	st_fragson                 boolean
+	sbar                       *patch_t
+	tallnum                    [10]*patch_t
+	tallpercent                *patch_t
+	shortnum                   [10]*patch_t
+	keys                       [6]*patch_t
+	faces                      [42]*patch_t
+	faceback                   *patch_t
+	armsbg                     *patch_t
+	arms                       [6][2]*patch_t
+	w_ready                    st_number_t
+	w_frags                    st_number_t
+	w_health                   st_percent_t
+	w_armsbg                   st_binicon_t
+	w_arms                     [6]st_multicon_t
+	w_faces                    st_multicon_t
+	w_keyboxes                 [3]st_multicon_t
+	w_armor                    st_percent_t
+	w_ammo                     [4]st_number_t
+	w_maxammo                  [4]st_number_t
+	st_fragscount              int32
+	st_oldhealth               int32
+	oldweaponsowned            [9]boolean
+	st_facecount               int32
+	st_faceindex               int32
+	keyboxes                   [3]int32
+	st_randomnumber            int32
+	lastcalc                   int32
+	oldhealth                  int32
+	lastattackdown             int32
+	priority                   int32
+	largeammo                  int32
+	st_palette                 int32
+	st_stopped                 int32
+	channels                   []channel_t
+	snd_SfxVolume              int32
+	mus_paused                 boolean
+	mus_playing                *musicinfo_t
+	dest_screen                []byte
+	lnodes                     [4][9]point_t
+	epsd0animinfo              [10]anim_t1
+	epsd1animinfo              [9]anim_t1
+	epsd2animinfo              [6]anim_t1
+	NUMANIMS                   [4]int32
+	anims1                     [4][]anim_t1
+	acceleratestage            int32
+	me                         int32
+	state                      stateenum_t
+	wbs                        *wbstartstruct_t
+	plrs                       []wbplayerstruct_t
+	cnt                        int32
+	bcnt                       int32
+	cnt_kills                  [4]int32
+	cnt_items                  [4]int32
+	cnt_secret                 [4]int32
+	cnt_time                   int32
+	cnt_par                    int32
+	cnt_pause                  int32
+	NUMCMAPS                   int32
+	yah                        [3]*patch_t
+	splat                      [2]*patch_t
+	percent                    *patch_t
+	colon                      *patch_t
+	num                        [10]*patch_t
+	wiminus                    *patch_t
+	finished                   *patch_t
+	entering                   *patch_t
+	sp_secret                  *patch_t
+	kills                      *patch_t
+	secret                     *patch_t
+	items                      *patch_t
+	frags                      *patch_t
+	timepatch                  *patch_t
+	par                        *patch_t
+	sucks                      *patch_t
+	killers                    *patch_t
+	victims                    *patch_t
+	total                      *patch_t
+	star                       *patch_t
+	bstar                      *patch_t
+	p                          [4]*patch_t
+	bp                         [4]*patch_t
+	lnames                     []*patch_t
+	background                 *patch_t
+	snl_pointeron              uint32
+	dm_state                   int32
+	dm_frags                   [4][4]int32
+	dm_totals                  [4]int32
+	cnt_frags                  [4]int32
+	dofrags                    int32
+	ng_state                   int32
+	sp_state                   int32
+	open_wadfiles              []fs.File
+	lumphash                   []*lumpinfo_t
+	unique_lumps               [4]struct {
+		Fmission  gamemission_t
+		Flumpname string
+	}
+	shiftdown                int32
+	shiftxform               [128]uint8
+	lastMouse                DoomEvent
+	colors                   [256]color.RGBA
+	DG_ScreenBuffer          *image.RGBA
+	EpiDef                   menu_t
+	EpisodeMenu              [4]menuitem_t
+	I_VideoBuffer            []byte
+	LoadDef                  menu_t
+	LoadMenu                 [6]menuitem_t
+	MainDef                  menu_t
+	MainMenu                 [6]menuitem_t
+	NewDef                   menu_t
+	NewGameMenu              [5]menuitem_t
+	OptionsDef               menu_t
+	OptionsMenu              [8]menuitem_t
+	ReadDef1                 menu_t
+	ReadDef2                 menu_t
+	ReadMenu1                [1]menuitem_t
+	ReadMenu2                [1]menuitem_t
+	S_music                  [68]musicinfo_t
+	S_sfx                    [109]sfxinfo_t
+	SaveDef                  menu_t
+	SaveMenu                 [6]menuitem_t
+	SoundDef                 menu_t
+	SoundMenu                [4]menuitem_t
+	TRACEANGLE               angle_t
+	activeceilings           [30]*ceiling_t
+	activeplats              [30]*plat_t
+	dont_run_demo            bool
+	advancedemo              boolean
+	aimslope                 fixed_t
+	alphSwitchList           [41]switchlist_t
+	angleturn                [3]fixed_t
+	animdefs                 [23]animdef_t
+	anims                    [32]anim_t
+	attackrange              fixed_t
+	automapactive            boolean
+	autostart                boolean
+	backsector               *sector_t
+	basecolfunc              func()
+	basexscale               fixed_t
+	baseyscale               fixed_t
+	bestslidefrac            fixed_t
+	bestslideline            *line_t
+	bfgedition               boolean
+	blocklinks               []*mobj_t
+	blockmap                 []int16
+	blockmaplump             []int16
+	bmapheight               int32
+	bmaporgx                 fixed_t
+	bmaporgy                 fixed_t
+	bmapwidth                int32
+	bodyque                  [32]*mobj_t
+	bodyqueslot              int32
+	bombdamage               int32
+	bombsource               *mobj_t
+	bombspot                 *mobj_t
+	bottomfrac               fixed_t
+	bottomslope              fixed_t
+	bottomstep               fixed_t
+	bottomtexture            int32
+	braintargeton            int32
+	braintargets             [32]*mobj_t
+	bulletslope              fixed_t
+	buttonlist               [16]button_t
+	cacheddistance           []fixed_t
+	cachedheight             []fixed_t
+	cachedxstep              []fixed_t
+	cachedystep              []fixed_t
+	castattacking            boolean
+	castdeath                boolean
+	castframes               int32
+	castnum                  int32
+	castonmelee              int32
+	castorder                [18]castinfo_t
+	caststate                *state_t
+	casttics                 int32
+	ceilingclip              []int16
+	ceilingline              *line_t
+	ceilingplane             *visplane_t
+	centerx                  int32
+	centerxfrac              fixed_t
+	centery                  int32
+	centeryfrac              fixed_t
+	chat_macros              [10]string
+	chat_on                  boolean
+	cheat_amap               cheatseq_t
+	cheat_ammo               cheatseq_t
+	cheat_ammonokey          cheatseq_t
+	cheat_choppers           cheatseq_t
+	cheat_clev               cheatseq_t
+	cheat_commercial_noclip  cheatseq_t
+	cheat_god                cheatseq_t
+	cheat_mus                cheatseq_t
+	cheat_mypos              cheatseq_t
+	cheat_noclip             cheatseq_t
+	cheat_player_arrow       [16]mline_t
+	cheat_powerup            [7]cheatseq_t
+	checkcoord               [12][4]int32
+	clipammo                 [4]int32
+	clipangle                angle_t
+	colfunc                  func()
+	colormaps                []lighttable_t
+	columnofs                []int32
+	configdir                string
+	consistancy              [4][128]uint8
+	consoleplayer            int32
+	corpsehit                *mobj_t
+	cpars                    [32]int32
+	crushchange              boolean
+	curline                  *seg_t
+	currentMenu              *menu_t
+	d_episode                int32
+	d_map                    int32
+	d_skill                  skill_t
+	dc_colormap              []lighttable_t
+	dc_iscale                fixed_t
+	dc_source                uintptr
+	dc_texturemid            fixed_t
+	dc_translation           []byte
+	dc_x                     int32
+	dc_yh                    int32
+	dc_yl                    int32
+	dclick_use               int32
+	deathmatch               int32
+	deathmatch_pos           int
+	deathmatchstarts         [10]mapthing_t
+	defdemoname              string
+	demo_pos                 int
+	demobuffer               []byte
+	demoname                 string
+	demoplayback             boolean
+	demorecording            boolean
+	demosequence             int32
+	detailLevel              int32
+	detailshift              int32
+	devparm                  boolean
+	diags                    [4]dirtype_t
+	dirtybox                 box_t
+	displayplayer            int32
+	distscale                []fixed_t
+	doom1_endmsg             [8]string
+	doom2_endmsg             [8]string
+	drawsegs                 [256]drawseg_t
+	drone                    boolean
+	ds_colormap              []lighttable_t
+	ds_index                 int
+	ds_source                []byte
+	ds_x1                    int32
+	ds_x2                    int32
+	ds_xfrac                 fixed_t
+	ds_xstep                 fixed_t
+	ds_y                     int32
+	ds_yfrac                 fixed_t
+	ds_ystep                 fixed_t
+	earlyout                 boolean
+	endstring                string
+	epi                      int32
+	extralight               int32
+	fastparm                 boolean
+	finalecount              uint32
+	finaleflat               string
+	finalestage              finalestage_t
+	finaletext               string
+	finecosine               []fixed_t
+	finesine                 [10240]fixed_t
+	finetangent              [4096]fixed_t
+	firstflat                int32
+	firstspritelump          int32
+	fixedcolormap            []lighttable_t
+	flattranslation          []int32
+	floatok                  boolean
+	floorclip                []int16
+	floorplane               *visplane_t
+	forwardmove              [2]fixed_t
+	frontsector              *sector_t
+	fuzzcolfunc              func()
+	fuzzoffset               [50]int32
+	fuzzpos                  int32
+	gameaction               gameaction_t
+	gamedescription          string
+	gameepisode              int32
+	gamemap                  int32
+	gamemission              gamemission_t
+	gamemode                 gamemode_t
+	gameskill                skill_t
+	gamestate                gamestate_t
+	gametic                  int32
+	gameversion              gameversion_t
+	gammamsg                 [5]string
+	hu_font                  [63]*patch_t
+	inhelpscreens            boolean
+	intercept_pos            int32
+	intercepts               [189]intercept_t
+	iquehead                 int32
+	iquetail                 int32
+	itemOn                   int16
+	itemrespawnque           [128]mapthing_t
+	itemrespawntime          [128]int32
+	iwadfile                 string
+	joybfire                 int32
+	joybjump                 int32
+	joybmenu                 int32
+	joybnextweapon           int32
+	joybprevweapon           int32
+	joybspeed                int32
+	joybstrafe               int32
+	joybstrafeleft           int32
+	joybstraferight          int32
+	joybuse                  int32
+	key_arti_all             int32
+	key_arti_blastradius     int32
+	key_arti_egg             int32
+	key_arti_health          int32
+	key_arti_invulnerability int32
+	key_arti_poisonbag       int32
+	key_arti_teleport        int32
+	key_arti_teleportother   int32
+	key_demo_quit            int32
+	key_down                 int32
+	key_fire                 int32
+	key_flycenter            int32
+	key_flydown              int32
+	key_flyup                int32
+	key_invdrop              int32
+	key_invend               int32
+	key_invhome              int32
+	key_invkey               int32
+	key_invleft              int32
+	key_invpop               int32
+	key_invquery             int32
+	key_invright             int32
+	key_invuse               int32
+	key_jump                 int32
+	key_left                 int32
+	key_lookcenter           int32
+	key_lookdown             int32
+	key_lookup               int32
+	key_map_clearmark        int32
+	key_map_east             int32
+	key_map_follow           int32
+	key_map_grid             int32
+	key_map_mark             int32
+	key_map_maxzoom          int32
+	key_map_north            int32
+	key_map_south            int32
+	key_map_toggle           int32
+	key_map_west             int32
+	key_map_zoomin           int32
+	key_map_zoomout          int32
+	key_menu_abort           int32
+	key_menu_activate        int32
+	key_menu_back            int32
+	key_menu_confirm         int32
+	key_menu_decscreen       int32
+	key_menu_detail          int32
+	key_menu_down            int32
+	key_menu_endgame         int32
+	key_menu_forward         int32
+	key_menu_gamma           int32
+	key_menu_help            int32
+	key_menu_incscreen       int32
+	key_menu_left            int32
+	key_menu_load            int32
+	key_menu_messages        int32
+	key_menu_qload           int32
+	key_menu_qsave           int32
+	key_menu_quit            int32
+	key_menu_right           int32
+	key_menu_save            int32
+	key_menu_screenshot      int32
+	key_menu_up              int32
+	key_menu_volume          int32
+	key_message_refresh      int32
+	key_mission              int32
+	key_multi_msg            int32
+	key_multi_msgplayer      [8]int32
+	key_nextweapon           int32
+	key_pause                int32
+	key_prevweapon           int32
+	key_right                int32
+	key_speed                int32
+	key_spy                  int32
+	key_strafe               int32
+	key_strafeleft           int32
+	key_straferight          int32
+	key_up                   int32
+	key_use                  int32
+	key_useartifact          int32
+	key_usehealth            int32
+	key_weapon1              int32
+	key_weapon2              int32
+	key_weapon3              int32
+	key_weapon4              int32
+	key_weapon5              int32
+	key_weapon6              int32
+	key_weapon7              int32
+	key_weapon8              int32
+	la_damage                int32
+	lastanim                 *anim_t
+	lastflat                 int32
+	lastopening              uintptr
+	lastspritelump           int32
+	lasttime                 int32
+	lastvisplane_index       int
+	levelTimeCount           int32
+	levelTimer               boolean
+	leveltime                int32
+	linedef                  *line_t
+	lines                    []line_t
+	linespeciallist          [64]*line_t
+	linetarget               *mobj_t
+	longtics                 boolean
+	lowfloor                 fixed_t
+	lowres_turn              boolean
+	lumpinfo                 [4096]lumpinfo_t
+	main_loop_started        boolean
+	mapnames                 [45]string
+	mapnames_commercial      [96]string
+	markceiling              boolean
+	markfloor                boolean
+	maskedtexture            boolean
+	maskedtexturecol         uintptr
+	maxammo                  [4]int32
+	maxframe                 int32
+	mceilingclip             []int16
+	menuactive               boolean
+	messageLastMenuActive    int32
+	messageNeedsInput        boolean
+	messageRoutine           *func(int32)
+	messageString            string
+	messageToPrint           int32
+	message_dontfuckwithme   boolean
+	mfloorclip               []int16
+	midtexture               int32
+	mobjinfo                 [137]mobjinfo_t
+	modifiedgame             boolean
+	mouseSensitivity         int32
+	mouse_acceleration       float32
+	mouse_threshold          int32
+	mousebbackward           int32
+	mousebfire               int32
+	mousebforward            int32
+	mousebjump               int32
+	mousebnextweapon         int32
+	mousebprevweapon         int32
+	mousebstrafe             int32
+	mousebstrafeleft         int32
+	mousebstraferight        int32
+	mousebuse                int32
+	mousex                   int32
+	mousey                   int32
+	musicVolume              int32
+	myargs                   []string
+	negonearray              []int16
+	net_client_connected     boolean
+	netcmds                  []ticcmd_t
+	netdemo                  boolean
+	netgame                  boolean
+	newend                   int
+	nodes                    []node_t
+	nodrawers                boolean
+	nofit                    boolean
+	nomonsters               boolean
+	numbraintargets          int32
+	numflats                 int32
+	numlines                 int32
+	numlinespecials          int16
+	numlumps                 uint32
+	numnodes                 int32
+	numsectors               int32
+	numsegs                  int32
+	numsides                 int32
+	numspechit               int32
+	numspritelumps           int32
+	numsprites               int32
+	numsubsectors            int32
+	numswitches              int32
+	numtextures              int32
+	numvertexes              int32
+	offsetms                 fixed_t
+	oldgamestate             gamestate_t
+	onground                 boolean
+	openbottom               fixed_t
+	openings                 []int16
+	openrange                fixed_t
+	opentop                  fixed_t
+	opposite                 [9]dirtype_t
+	overflowsprite           vissprite_t
+	pagename                 string
+	pagetic                  int32
+	pars                     [4][10]int32
+	paused                   boolean
+	pixhigh                  fixed_t
+	pixhighstep              fixed_t
+	pixlow                   fixed_t
+	pixlowstep               fixed_t
+	planeheight              fixed_t
+	planezlight              [][]lighttable_t
+	player_arrow             [7]mline_t
+	player_names             [4]string
+	playeringame             [4]boolean
+	players                  [4]player_t
+	playerstarts             [4]mapthing_t
+	precache                 boolean
+	prndindex                int32
+	projection               fixed_t
+	pspriteiscale            fixed_t
+	pspritescale             fixed_t
+	quickSaveSlot            int32
+	quitsounds               [8]int32
+	quitsounds2              [8]int32
+	rejectmatrix             []byte
+	respawnmonsters          boolean
+	respawnparm              boolean
+	rndindex                 int32
+	rw_angle1                int32
+	rw_bottomtexturemid      fixed_t
+	rw_centerangle           angle_t
+	rw_distance              fixed_t
+	rw_midtexturemid         fixed_t
+	rw_normalangle           angle_t
+	rw_offset                fixed_t
+	rw_scale                 fixed_t
+	rw_scalestep             fixed_t
+	rw_stopx                 int32
+	rw_toptexturemid         fixed_t
+	rw_x                     int32
+	saveCharIndex            int
+	saveOldString            string
+	saveSlot                 int32
+	saveStringEnter          int32
+	save_stream              *savestream_t
+	savegame_error           boolean
+	savegamedir              string
+	savegamestrings          [10]string
+	loadgameslot             int32
+	scaledviewwidth          int32
+	scalelight               [16][48][]lighttable_t
+	scalelightfixed          [48][]lighttable_t
+	screenSize               int32
+	screenblocks             int32
+	screenheightarray        []int16
+	screensaver_mode         boolean
+	secretexit               boolean
+	sectors                  []sector_t
+	segs                     []seg_t
+	segtextured              boolean
+	sendpause                boolean
+	sendsave                 boolean
+	setblocks                int32
+	setdetail                int32
+	setsizeneeded            boolean
+	sfxVolume                int32
+	shootthing               *mobj_t
+	shootz                   fixed_t
+	showMessages             int32
+	show_endoom              int32
+	sidedef                  *side_t
+	sidemove                 [2]fixed_t
+	sides                    []side_t
+	sightcounts              [2]int32
+	sightzstart              fixed_t
+	singledemo               boolean
+	singletics               boolean
+	skullAnimCounter         int16
+	skullName                [2]string
+	skyflatnum               int32
+	skytexture               int32
+	skytexturemid            int32
+	slidemo                  *mobj_t
+	snd_cachesize            int32
+	snd_channels             int32
+	snd_maxslicetime_ms      int32
+	snd_musiccmd             string
+	snd_musicdevice          int32
+	snd_samplerate           int32
+	snd_sfxdevice            int32
+	solidsegs                [32]cliprange_t
+	soundtarget              *mobj_t
+	spanfunc                 func()
+	spanstart                []int32
+	spechit                  [20]*line_t
+	spritelights             [48][]lighttable_t
+	spriteoffset             []fixed_t
+	sprites                  []spritedef_t
+	spritetopoffset          []fixed_t
+	spritewidth              []fixed_t
+	sprnames                 []string
+	sprtemp                  [29]spriteframe_t
+	sprtopscreen             fixed_t
+	spryscale                fixed_t
+	st_backing_screen        []byte
+	startepisode             int32
+	startloadgame            int32
+	startmap                 int32
+	startskill               skill_t
+	starttime                int32
+	states                   [967]state_t
+	storedemo                boolean
+	strace                   divline_t
+	sttminus                 *patch_t
+	subsectors               []subsector_t
+	switchlist               [100]int32
+	t2x                      fixed_t
+	t2y                      fixed_t
+	tantoangle               [2049]angle_t
+	testcontrols             boolean
+	testcontrols_mousespeed  int32
+	texturecolumnlump        [][]int16
+	texturecolumnofs         [][]uint16
+	texturecomposite         [][]byte
+	texturecompositesize     []int32
+	textureheight            []fixed_t
+	textures                 []*texture_t
+	textures_hashtable       []*texture_t
+	texturetranslation       []int32
+	texturewidthmask         []int32
+	thinkercap               thinker_t
+	thintriangle_guy         [3]mline_t
+	ticdup                   int32
+	timelimit                int32
+	timingdemo               boolean
+	tmbbox                   box_t
+	tmceilingz               fixed_t
+	tmdropoffz               fixed_t
+	tmflags                  int32
+	tmfloorz                 fixed_t
+	tmthing                  *mobj_t
+	tmx                      fixed_t
+	tmxmove                  fixed_t
+	tmy                      fixed_t
+	tmymove                  fixed_t
+	topfrac                  fixed_t
+	topslope                 fixed_t
+	topstep                  fixed_t
+	toptexture               int32
+	totalitems               int32
+	totalkills               int32
+	totalsecret              int32
+	trace                    divline_t
+	transcolfunc             func()
+	translationtables        []byte
+	turbodetected            [4]boolean
+	usegamma                 int32
+	usemouse                 int32
+	usergame                 boolean
+	usething                 *mobj_t
+	validcount               int32
+	vanilla_demo_limit       int32
+	vanilla_keyboard_mapping int32
+	vanilla_savegame_limit   int32
+	vertexes                 []vertex_t
+	viewactive               boolean
+	viewangle                angle_t
+	viewangleoffset          uint32
+	viewangletox             [4096]int32
+	viewcos                  fixed_t
+	viewheight               int32
+	viewplayer               *player_t
+	viewsin                  fixed_t
+	viewwidth                int32
+	viewwindowx              int32
+	viewwindowy              int32
+	viewx                    fixed_t
+	viewy                    fixed_t
+	viewz                    fixed_t
+	viletryx                 fixed_t
+	viletryy                 fixed_t
+	visplanes                [128]visplane_t
+	vissprite_n              int
+	vissprites               [128]vissprite_t
+	vsprsortedhead           vissprite_t
+	walllights               [48][]lighttable_t
+	weaponinfo               [9]weaponinfo_t
+	whichSkull               int16
+	wipegamestate            gamestate_t
+	wminfo                   wbstartstruct_t
+	worldbottom              int32
+	worldhigh                int32
+	worldlow                 int32
+	worldtop                 int32
+	xspeed                   [8]fixed_t
+	xtoviewangle             []angle_t
+	ylookup                  []int32
+	yslope                   []fixed_t
+	yspeed                   [8]fixed_t
+	zlight                   [16][128][]lighttable_t
+	pwad_files               []WADFile
+	tic_override             ticcmd_t
+	tic_override_set         bool
+	interp_enabled           bool
+	interp_tic               int32
+	interp_active            bool
+	interp_savedmobjs        []interp_savedmobj_t
+	interp_savedsectors      []fixed_t
+	level_maplump            int32
+	mobj_next_id             uint32
+	mobjTypeNames            [137]string
+	music_frontend           DoomMusicFrontend
+	music_songs              map[uintptr][]byte
+	music_next_handle        uintptr
+	music_volume             int32
+	music_paused             boolean
+	music_playing            boolean
+	midi_music_module        music_module_t
+	mus_controller_map       [15]byte
+	opl_logsin               [256]int32
+	opl_exp                  [256]int32
+	opl_mult                 [16]uint32
+	opl_kslrom               [16]int32
+	opl_kslshift             [4]byte
+	opl_eg_incstep           [4][4]byte
+	opl_voice_operators      [2][9]uint16
+	opl_volume_mapping_table [128]uint32
+	opl_chip                 *opl_chip_t
+	opl_main_instrs          []genmidi_instr_t
+	opl_percussion_instrs    []genmidi_instr_t
+	opl_voices               [18]opl_voice_t
+	opl_voice_free           []int32
+	opl_voice_alloced        []int32
+	opl_channels             [16]opl_channel_data_t
+	opl_songs                map[uintptr]*midi_song_t
+	opl_next_handle          uintptr
+	opl_song                 *midi_song_t
+	opl_song_pos             int
+	opl_song_tick            uint32
+	opl_song_acc             uint64
+	opl_tempo                uint32
+	opl_looping              boolean
+	opl_paused               boolean
+	opl_music_volume         int32
+	opl_resample_pos         uint32
+	opl_prev                 [2]int32
+	opl_next                 [2]int32
+	opl_buffer               []int16
+	opl_music_module         music_module_t
+	pal_enabled              bool
+	pal_frontend             DoomPalettedFrontend
+	pal_screen               *image.Paletted
+	pal_hooks                []*pal_hook_t
+	save_store               SaveStore
+	mixer_frontend           DoomSoundFrontend
+	mixer_use_prefix         boolean
+	mixer_channels           [16]mixer_channel_t
+	mixer_sounds             map[int32]*mixer_sound_t
+	mixer_frames             uint64
+	mixer_start_tic          int32
+	mixer_buffer             []int16
+	mixer_module             sound_module_t
+	screenwidth              int32
+	screenheight             int32
+	screenaspect             Aspect
+	fovwidth                 int32
+}
+
+// save copies the current package level engine variables into s
+func (s *engineState) save() {
+	s.aux_enabled = aux_enabled
+	s.aux_frontend = aux_frontend
+	s.aux_buffers = aux_buffers
+	s.aux_depthbuf = aux_depthbuf
+	s.aux_labelbuf = aux_labelbuf
+	s.aux_automapbuf = aux_automapbuf
+	s.aux_depth = aux_depth
+	s.aux_label = aux_label
+	s.demo_writer = demo_writer
+	s.demo_err = demo_err
+	s.demo_data = demo_data
+	s.demo_usergame = demo_usergame
+	s.vfs = vfs
+	s.dg_frontend = dg_frontend
+	s.dg_run_full_speed = dg_run_full_speed
+	s.dg_fake_tics = dg_fake_tics
+	s.dg_exiting = dg_exiting
+	s.start_time = start_time
+	s.cheating = cheating
+	s.grid = grid
+	s.f_x = f_x
+	s.f_y = f_y
+	s.f_w = f_w
+	s.f_h = f_h
+	s.lightlev = lightlev
+	s.fb = fb
+	s.m_paninc = m_paninc
+	s.mtof_zoommul = mtof_zoommul
+	s.ftom_zoommul = ftom_zoommul
+	s.m_x = m_x
+	s.m_y = m_y
+	s.m_x2 = m_x2
+	s.m_y2 = m_y2
+	s.m_w = m_w
+	s.m_h = m_h
+	s.min_x = min_x
+	s.min_y = min_y
+	s.max_x = max_x
+	s.max_y = max_y
+	s.max_w = max_w
+	s.max_h = max_h
+	s.min_scale_mtof = min_scale_mtof
+	s.max_scale_mtof = max_scale_mtof
+	s.old_m_w = old_m_w
+	s.old_m_h = old_m_h
+	s.old_m_x = old_m_x
+	s.old_m_y = old_m_y
+	s.f_oldloc = f_oldloc
+	s.scale_mtof = scale_mtof
+	s.scale_ftom = scale_ftom
+	s.plr = plr
+	s.marknums = marknums
+	s.markpoints = markpoints
+	s.markpointnum = markpointnum
+	s.followplayer = followplayer
+	s.stopped = stopped
+	s.st_notify = st_notify
+	s.st_notify1 = st_notify1
+	s.lastlevel = lastlevel
+	s.lastepisode = lastepisode
+	s.bigstate = bigstate
+	s.fuck = fuck
+	s.fl = fl
+	s.l = l
+	s.their_colors = their_colors
+	s.events = events
+	s.eventhead = eventhead
+	s.eventtail = eventtail
+	s.iwads = iwads
+	s.iwad_dirs = iwad_dirs
+	s.ticdata = ticdata
+	s.maketic = maketic
+	s.recvtic = recvtic
+	s.localplayer = localplayer
+	s.skiptics = skiptics
+	s.new_sync = new_sync
+	s.loop_interface = loop_interface
+	s.local_playeringame = local_playeringame
+	s.player_class = player_class
+	s.frameon = frameon
+	s.frameskip = frameskip
+	s.oldnettics = oldnettics
+	s.oldentertics = oldentertics
+	s.viewactivestate = viewactivestate
+	s.menuactivestate = menuactivestate
+	s.inhelpscreensstate = inhelpscreensstate
+	s.fullscreen = fullscreen
+	s.oldgamestate1 = oldgamestate1
+	s.borderdrawcount = borderdrawcount
+	s.banners = banners
+	s.packs = packs
+	s.copyright_banners = copyright_banners
+	s.gameversions = gameversions
+	s.exitmsg = exitmsg
+	s.doom_loop_interface = doom_loop_interface
+	s.textscreens = textscreens
+	s.laststage = laststage
+	s.wipe_running = wipe_running
+	s.wipe_scr_start = wipe_scr_start
+	s.wipe_scr_end = wipe_scr_end
+	s.wipe_scr = wipe_scr
+	s.y_screen = y_screen
+	s.wipes = wipes
+	s.weapon_keys = weapon_keys
+	s.next_weapon = next_weapon
+	s.weapon_order_table = weapon_order_table
+	s.gamekeydown = gamekeydown
+	s.turnheld = turnheld
+	s.mousearray = mousearray
+	s.dclicktime = dclicktime
+	s.dclickstate = dclickstate
+	s.dclicks = dclicks
+	s.dclicktime2 = dclicktime2
+	s.dclickstate2 = dclickstate2
+	s.dclicks2 = dclicks2
+	s.joyxmove = joyxmove
+	s.joyymove = joyymove
+	s.joystrafemove = joystrafemove
+	s.joyarray = joyarray
+	s.savegameslot = savegameslot
+	s.savedescription = savedescription
+	s.carry = carry
+	s.plr1 = plr1
+	s.w_title = w_title
+	s.w_chat = w_chat
+	s.always_off = always_off
+	s.chat_dest = chat_dest
+	s.w_inputbuffer = w_inputbuffer
+	s.message_on = message_on
+	s.message_nottobefuckedwith = message_nottobefuckedwith
+	s.w_message = w_message
+	s.message_counter = message_counter
+	s.headsupactive = headsupactive
+	s.chatchars = chatchars
+	s.head = head
+	s.tail = tail
+	s.lastmessage = lastmessage
+	s.altdown = altdown
+	s.num_nobrainers = num_nobrainers
+	s.usejoystick = usejoystick
+	s.joystick_index = joystick_index
+	s.joystick_x_axis = joystick_x_axis
+	s.joystick_x_invert = joystick_x_invert
+	s.joystick_y_axis = joystick_y_axis
+	s.joystick_y_invert = joystick_y_invert
+	s.joystick_strafe_axis = joystick_strafe_axis
+	s.joystick_strafe_invert = joystick_strafe_invert
+	s.joystick_physical_buttons = joystick_physical_buttons
+	s.sound_module = sound_module
+	s.music_module = music_module
+	s.snd_sbport = snd_sbport
+	s.snd_sbirq = snd_sbirq
+	s.snd_sbdma = snd_sbdma
+	s.snd_mport = snd_mport
+	s.sound_modules = sound_modules
+	s.music_modules = music_modules
+	s.exit_funcs = exit_funcs
+	s.already_quitting = already_quitting
+	s.mem_dump_dos622 = mem_dump_dos622
+	s.mem_dump_win98 = mem_dump_win98
+	s.mem_dump_dosbox = mem_dump_dosbox
+	s.mem_dump_custom = mem_dump_custom
+	s.dos_mem_dump = dos_mem_dump
+	s.firsttime = firsttime
+	s.basetime = basetime
+	s.last_tick = last_tick
+	s.default_main_config = default_main_config
+	s.default_extra_config = default_extra_config
+	s.doom_defaults_list = doom_defaults_list
+	s.doom_defaults = doom_defaults
+	s.extra_defaults_list = extra_defaults_list
+	s.extra_defaults = extra_defaults
+	s.detailNames = detailNames
+	s.msgNames = msgNames
+	s.M_QuitResponse = M_QuitResponse
+	s.joywait = joywait
+	s.mousewait = mousewait
+	s.mousey1 = mousey1
+	s.lasty = lasty
+	s.mousex1 = mousex1
+	s.lastx = lastx
+	s.x = x
+	s.y2 = y2
+	s.rndtable = rndtable
+	s.easy = easy
+	s.baseaddr = baseaddr
+	s.intercepts_overrun = intercepts_overrun
+	s.dummy_mobj = dummy_mobj
+	s.totallines = totallines
+	s.null_sector_is_initialized = null_sector_is_initialized
+	s.null_sector = null_sector
+	s.first = first
+	s.tmp_s3_floorheight = tmp_s3_floorheight
+	s.tmp_s3_floorpic = tmp_s3_floorpic
+	s.background_buffer = background_buffer
+	s.clipbot = clipbot
+	s.cliptop = cliptop
+	s.captured_stats = captured_stats
+	s.num_captured_stats = num_captured_stats
+	s.plyr = plyr
+	s.st_firsttime = st_firsttime
+	s.lu_palette = lu_palette
+	s.st_statusbaron = st_statusbaron
+	s.st_notdeathmatch = st_notdeathmatch
+	s.st_armson = st_armson
+	s.st_fragson = st_fragson
+	s.sbar = sbar
+	s.tallnum = tallnum
+	s.tallpercent = tallpercent
+	s.shortnum = shortnum
+	s.keys = keys
+	s.faces = faces
+	s.faceback = faceback
+	s.armsbg = armsbg
+	s.arms = arms
+	s.w_ready = w_ready
+	s.w_frags = w_frags
+	s.w_health = w_health
+	s.w_armsbg = w_armsbg
+	s.w_arms = w_arms
+	s.w_faces = w_faces
+	s.w_keyboxes = w_keyboxes
+	s.w_armor = w_armor
+	s.w_ammo = w_ammo
+	s.w_maxammo = w_maxammo
+	s.st_fragscount = st_fragscount
+	s.st_oldhealth = st_oldhealth
+	s.oldweaponsowned = oldweaponsowned
+	s.st_facecount = st_facecount
+	s.st_faceindex = st_faceindex
+	s.keyboxes = keyboxes
+	s.st_randomnumber = st_randomnumber
+	s.lastcalc = lastcalc
+	s.oldhealth = oldhealth
+	s.lastattackdown = lastattackdown
+	s.priority = priority
+	s.largeammo = largeammo
+	s.st_palette = st_palette
+	s.st_stopped = st_stopped
+	s.channels = channels
+	s.snd_SfxVolume = snd_SfxVolume
+	s.mus_paused = mus_paused
+	s.mus_playing = mus_playing
+	s.dest_screen = dest_screen
+	s.lnodes = lnodes
+	s.epsd0animinfo = epsd0animinfo
+	s.epsd1animinfo = epsd1animinfo
+	s.epsd2animinfo = epsd2animinfo
+	s.NUMANIMS = NUMANIMS
+	s.anims1 = anims1
+	s.acceleratestage = acceleratestage
+	s.me = me
+	s.state = state
+	s.wbs = wbs
+	s.plrs = plrs
+	s.cnt = cnt
+	s.bcnt = bcnt
+	s.cnt_kills = cnt_kills
+	s.cnt_items = cnt_items
+	s.cnt_secret = cnt_secret
+	s.cnt_time = cnt_time
+	s.cnt_par = cnt_par
+	s.cnt_pause = cnt_pause
+	s.NUMCMAPS = NUMCMAPS
+	s.yah = yah
+	s.splat = splat
+	s.percent = percent
+	s.colon = colon
+	s.num = num
+	s.wiminus = wiminus
+	s.finished = finished
+	s.entering = entering
+	s.sp_secret = sp_secret
+	s.kills = kills
+	s.secret = secret
+	s.items = items
+	s.frags = frags
+	s.timepatch = timepatch
+	s.par = par
+	s.sucks = sucks
+	s.killers = killers
+	s.victims = victims
+	s.total = total
+	s.star = star
+	s.bstar = bstar
+	s.p = p
+	s.bp = bp
+	s.lnames = lnames
+	s.background = background
+	s.snl_pointeron = snl_pointeron
+	s.dm_state = dm_state
+	s.dm_frags = dm_frags
+	s.dm_totals = dm_totals
+	s.cnt_frags = cnt_frags
+	s.dofrags = dofrags
+	s.ng_state = ng_state
+	s.sp_state = sp_state
+	s.open_wadfiles = open_wadfiles
+	s.lumphash = lumphash
+	s.unique_lumps = unique_lumps
+	s.shiftdown = shiftdown
+	s.shiftxform = shiftxform
+	s.lastMouse = lastMouse
+	s.colors = colors
+	s.DG_ScreenBuffer = DG_ScreenBuffer
+	s.EpiDef = EpiDef
+	s.EpisodeMenu = EpisodeMenu
+	s.I_VideoBuffer = I_VideoBuffer
+	s.LoadDef = LoadDef
+	s.LoadMenu = LoadMenu
+	s.MainDef = MainDef
+	s.MainMenu = MainMenu
+	s.NewDef = NewDef
+	s.NewGameMenu = NewGameMenu
+	s.OptionsDef = OptionsDef
+	s.OptionsMenu = OptionsMenu
+	s.ReadDef1 = ReadDef1
+	s.ReadDef2 = ReadDef2
+	s.ReadMenu1 = ReadMenu1
+	s.ReadMenu2 = ReadMenu2
+	s.S_music = S_music
+	s.S_sfx = S_sfx
+	s.SaveDef = SaveDef
+	s.SaveMenu = SaveMenu
+	s.SoundDef = SoundDef
+	s.SoundMenu = SoundMenu
+	s.TRACEANGLE = TRACEANGLE
+	s.activeceilings = activeceilings
+	s.activeplats = activeplats
+	s.dont_run_demo = dont_run_demo
+	s.advancedemo = advancedemo
+	s.aimslope = aimslope
+	s.alphSwitchList = alphSwitchList
+	s.angleturn = angleturn
+	s.animdefs = animdefs
+	s.anims = anims
+	s.attackrange = attackrange
+	s.automapactive = automapactive
+	s.autostart = autostart
+	s.backsector = backsector
+	s.basecolfunc = basecolfunc
+	s.basexscale = basexscale
+	s.baseyscale = baseyscale
+	s.bestslidefrac = bestslidefrac
+	s.bestslideline = bestslideline
+	s.bfgedition = bfgedition
+	s.blocklinks = blocklinks
+	s.blockmap = blockmap
+	s.blockmaplump = blockmaplump
+	s.bmapheight = bmapheight
+	s.bmaporgx = bmaporgx
+	s.bmaporgy = bmaporgy
+	s.bmapwidth = bmapwidth
+	s.bodyque = bodyque
+	s.bodyqueslot = bodyqueslot
+	s.bombdamage = bombdamage
+	s.bombsource = bombsource
+	s.bombspot = bombspot
+	s.bottomfrac = bottomfrac
+	s.bottomslope = bottomslope
+	s.bottomstep = bottomstep
+	s.bottomtexture = bottomtexture
+	s.braintargeton = braintargeton
+	s.braintargets = braintargets
+	s.bulletslope = bulletslope
+	s.buttonlist = buttonlist
+	s.cacheddistance = cacheddistance
+	s.cachedheight = cachedheight
+	s.cachedxstep = cachedxstep
+	s.cachedystep = cachedystep
+	s.castattacking = castattacking
+	s.castdeath = castdeath
+	s.castframes = castframes
+	s.castnum = castnum
+	s.castonmelee = castonmelee
+	s.castorder = castorder
+	s.caststate = caststate
+	s.casttics = casttics
+	s.ceilingclip = ceilingclip
+	s.ceilingline = ceilingline
+	s.ceilingplane = ceilingplane
+	s.centerx = centerx
+	s.centerxfrac = centerxfrac
+	s.centery = centery
+	s.centeryfrac = centeryfrac
+	s.chat_macros = chat_macros
+	s.chat_on = chat_on
+	s.cheat_amap = cheat_amap
+	s.cheat_ammo = cheat_ammo
+	s.cheat_ammonokey = cheat_ammonokey
+	s.cheat_choppers = cheat_choppers
+	s.cheat_clev = cheat_clev
+	s.cheat_commercial_noclip = cheat_commercial_noclip
+	s.cheat_god = cheat_god
+	s.cheat_mus = cheat_mus
+	s.cheat_mypos = cheat_mypos
+	s.cheat_noclip = cheat_noclip
+	s.cheat_player_arrow = cheat_player_arrow
+	s.cheat_powerup = cheat_powerup
+	s.checkcoord = checkcoord
+	s.clipammo = clipammo
+	s.clipangle = clipangle
+	s.colfunc = colfunc
+	s.colormaps = colormaps
+	s.columnofs = columnofs
+	s.configdir = configdir
+	s.consistancy = consistancy
+	s.consoleplayer = consoleplayer
+	s.corpsehit = corpsehit
+	s.cpars = cpars
+	s.crushchange = crushchange
+	s.curline = curline
+	s.currentMenu = currentMenu
+	s.d_episode = d_episode
+	s.d_map = d_map
+	s.d_skill = d_skill
+	s.dc_colormap = dc_colormap
+	s.dc_iscale = dc_iscale
+	s.dc_source = dc_source
+	s.dc_texturemid = dc_texturemid
+	s.dc_translation = dc_translation
+	s.dc_x = dc_x
+	s.dc_yh = dc_yh
+	s.dc_yl = dc_yl
+	s.dclick_use = dclick_use
+	s.deathmatch = deathmatch
+	s.deathmatch_pos = deathmatch_pos
+	s.deathmatchstarts = deathmatchstarts
+	s.defdemoname = defdemoname
+	s.demo_pos = demo_pos
+	s.demobuffer = demobuffer
+	s.demoname = demoname
+	s.demoplayback = demoplayback
+	s.demorecording = demorecording
+	s.demosequence = demosequence
+	s.detailLevel = detailLevel
+	s.detailshift = detailshift
+	s.devparm = devparm
+	s.diags = diags
+	s.dirtybox = dirtybox
+	s.displayplayer = displayplayer
+	s.distscale = distscale
+	s.doom1_endmsg = doom1_endmsg
+	s.doom2_endmsg = doom2_endmsg
+	s.drawsegs = drawsegs
+	s.drone = drone
+	s.ds_colormap = ds_colormap
+	s.ds_index = ds_index
+	s.ds_source = ds_source
+	s.ds_x1 = ds_x1
+	s.ds_x2 = ds_x2
+	s.ds_xfrac = ds_xfrac
+	s.ds_xstep = ds_xstep
+	s.ds_y = ds_y
+	s.ds_yfrac = ds_yfrac
+	s.ds_ystep = ds_ystep
+	s.earlyout = earlyout
+	s.endstring = endstring
+	s.epi = epi
+	s.extralight = extralight
+	s.fastparm = fastparm
+	s.finalecount = finalecount
+	s.finaleflat = finaleflat
+	s.finalestage = finalestage
+	s.finaletext = finaletext
+	s.finecosine = finecosine
+	s.finesine = finesine
+	s.finetangent = finetangent
+	s.firstflat = firstflat
+	s.firstspritelump = firstspritelump
+	s.fixedcolormap = fixedcolormap
+	s.flattranslation = flattranslation
+	s.floatok = floatok
+	s.floorclip = floorclip
+	s.floorplane = floorplane
+	s.forwardmove = forwardmove
+	s.frontsector = frontsector
+	s.fuzzcolfunc = fuzzcolfunc
+	s.fuzzoffset = fuzzoffset
+	s.fuzzpos = fuzzpos
+	s.gameaction = gameaction
+	s.gamedescription = gamedescription
+	s.gameepisode = gameepisode
+	s.gamemap = gamemap
+	s.gamemission = gamemission
+	s.gamemode = gamemode
+	s.gameskill = gameskill
+	s.gamestate = gamestate
+	s.gametic = gametic
+	s.gameversion = gameversion
+	s.gammamsg = gammamsg
+	s.hu_font = hu_font
+	s.inhelpscreens = inhelpscreens
+	s.intercept_pos = intercept_pos
+	s.intercepts = intercepts
+	s.iquehead = iquehead
+	s.iquetail = iquetail
+	s.itemOn = itemOn
+	s.itemrespawnque = itemrespawnque
+	s.itemrespawntime = itemrespawntime
+	s.iwadfile = iwadfile
+	s.joybfire = joybfire
+	s.joybjump = joybjump
+	s.joybmenu = joybmenu
+	s.joybnextweapon = joybnextweapon
+	s.joybprevweapon = joybprevweapon
+	s.joybspeed = joybspeed
+	s.joybstrafe = joybstrafe
+	s.joybstrafeleft = joybstrafeleft
+	s.joybstraferight = joybstraferight
+	s.joybuse = joybuse
+	s.key_arti_all = key_arti_all
+	s.key_arti_blastradius = key_arti_blastradius
+	s.key_arti_egg = key_arti_egg
+	s.key_arti_health = key_arti_health
+	s.key_arti_invulnerability = key_arti_invulnerability
+	s.key_arti_poisonbag = key_arti_poisonbag
+	s.key_arti_teleport = key_arti_teleport
+	s.key_arti_teleportother = key_arti_teleportother
+	s.key_demo_quit = key_demo_quit
+	s.key_down = key_down
+	s.key_fire = key_fire
+	s.key_flycenter = key_flycenter
+	s.key_flydown = key_flydown
+	s.key_flyup = key_flyup
+	s.key_invdrop = key_invdrop
+	s.key_invend = key_invend
+	s.key_invhome = key_invhome
+	s.key_invkey = key_invkey
+	s.key_invleft = key_invleft
+	s.key_invpop = key_invpop
+	s.key_invquery = key_invquery
+	s.key_invright = key_invright
+	s.key_invuse = key_invuse
+	s.key_jump = key_jump
+	s.key_left = key_left
+	s.key_lookcenter = key_lookcenter
+	s.key_lookdown = key_lookdown
+	s.key_lookup = key_lookup
+	s.key_map_clearmark = key_map_clearmark
+	s.key_map_east = key_map_east
+	s.key_map_follow = key_map_follow
+	s.key_map_grid = key_map_grid
+	s.key_map_mark = key_map_mark
+	s.key_map_maxzoom = key_map_maxzoom
+	s.key_map_north = key_map_north
+	s.key_map_south = key_map_south
+	s.key_map_toggle = key_map_toggle
+	s.key_map_west = key_map_west
+	s.key_map_zoomin = key_map_zoomin
+	s.key_map_zoomout = key_map_zoomout
+	s.key_menu_abort = key_menu_abort
+	s.key_menu_activate = key_menu_activate
+	s.key_menu_back = key_menu_back
+	s.key_menu_confirm = key_menu_confirm
+	s.key_menu_decscreen = key_menu_decscreen
+	s.key_menu_detail = key_menu_detail
+	s.key_menu_down = key_menu_down
+	s.key_menu_endgame = key_menu_endgame
+	s.key_menu_forward = key_menu_forward
+	s.key_menu_gamma = key_menu_gamma
+	s.key_menu_help = key_menu_help
+	s.key_menu_incscreen = key_menu_incscreen
+	s.key_menu_left = key_menu_left
+	s.key_menu_load = key_menu_load
+	s.key_menu_messages = key_menu_messages
+	s.key_menu_qload = key_menu_qload
+	s.key_menu_qsave = key_menu_qsave
+	s.key_menu_quit = key_menu_quit
+	s.key_menu_right = key_menu_right
+	s.key_menu_save = key_menu_save
+	s.key_menu_screenshot = key_menu_screenshot
+	s.key_menu_up = key_menu_up
+	s.key_menu_volume = key_menu_volume
+	s.key_message_refresh = key_message_refresh
+	s.key_mission = key_mission
+	s.key_multi_msg = key_multi_msg
+	s.key_multi_msgplayer = key_multi_msgplayer
+	s.key_nextweapon = key_nextweapon
+	s.key_pause = key_pause
+	s.key_prevweapon = key_prevweapon
+	s.key_right = key_right
+	s.key_speed = key_speed
+	s.key_spy = key_spy
+	s.key_strafe = key_strafe
+	s.key_strafeleft = key_strafeleft
+	s.key_straferight = key_straferight
+	s.key_up = key_up
+	s.key_use = key_use
+	s.key_useartifact = key_useartifact
+	s.key_usehealth = key_usehealth
+	s.key_weapon1 = key_weapon1
+	s.key_weapon2 = key_weapon2
+	s.key_weapon3 = key_weapon3
+	s.key_weapon4 = key_weapon4
+	s.key_weapon5 = key_weapon5
+	s.key_weapon6 = key_weapon6
+	s.key_weapon7 = key_weapon7
+	s.key_weapon8 = key_weapon8
+	s.la_damage = la_damage
+	s.lastanim = lastanim
+	s.lastflat = lastflat
+	s.lastopening = lastopening
+	s.lastspritelump = lastspritelump
+	s.lasttime = lasttime
+	s.lastvisplane_index = lastvisplane_index
+	s.levelTimeCount = levelTimeCount
+	s.levelTimer = levelTimer
+	s.leveltime = leveltime
+	s.linedef = linedef
+	s.lines = lines
+	s.linespeciallist = linespeciallist
+	s.linetarget = linetarget
+	s.longtics = longtics
+	s.lowfloor = lowfloor
+	s.lowres_turn = lowres_turn
+	s.lumpinfo = lumpinfo
+	s.main_loop_started = main_loop_started
+	s.mapnames = mapnames
+	s.mapnames_commercial = mapnames_commercial
+	s.markceiling = markceiling
+	s.markfloor = markfloor
+	s.maskedtexture = maskedtexture
+	s.maskedtexturecol = maskedtexturecol
+	s.maxammo = maxammo
+	s.maxframe = maxframe
+	s.mceilingclip = mceilingclip
+	s.menuactive = menuactive
+	s.messageLastMenuActive = messageLastMenuActive
+	s.messageNeedsInput = messageNeedsInput
+	s.messageRoutine = messageRoutine
+	s.messageString = messageString
+	s.messageToPrint = messageToPrint
+	s.message_dontfuckwithme = message_dontfuckwithme
+	s.mfloorclip = mfloorclip
+	s.midtexture = midtexture
+	s.mobjinfo = mobjinfo
+	s.modifiedgame = modifiedgame
+	s.mouseSensitivity = mouseSensitivity
+	s.mouse_acceleration = mouse_acceleration
+	s.mouse_threshold = mouse_threshold
+	s.mousebbackward = mousebbackward
+	s.mousebfire = mousebfire
+	s.mousebforward = mousebforward
+	s.mousebjump = mousebjump
+	s.mousebnextweapon = mousebnextweapon
+	s.mousebprevweapon = mousebprevweapon
+	s.mousebstrafe = mousebstrafe
+	s.mousebstrafeleft = mousebstrafeleft
+	s.mousebstraferight = mousebstraferight
+	s.mousebuse = mousebuse
+	s.mousex = mousex
+	s.mousey = mousey
+	s.musicVolume = musicVolume
+	s.myargs = myargs
+	s.negonearray = negonearray
+	s.net_client_connected = net_client_connected
+	s.netcmds = netcmds
+	s.netdemo = netdemo
+	s.netgame = netgame
+	s.newend = newend
+	s.nodes = nodes
+	s.nodrawers = nodrawers
+	s.nofit = nofit
+	s.nomonsters = nomonsters
+	s.numbraintargets = numbraintargets
+	s.numflats = numflats
+	s.numlines = numlines
+	s.numlinespecials = numlinespecials
+	s.numlumps = numlumps
+	s.numnodes = numnodes
+	s.numsectors = numsectors
+	s.numsegs = numsegs
+	s.numsides = numsides
+	s.numspechit = numspechit
+	s.numspritelumps = numspritelumps
+	s.numsprites = numsprites
+	s.numsubsectors = numsubsectors
+	s.numswitches = numswitches
+	s.numtextures = numtextures
+	s.numvertexes = numvertexes
+	s.offsetms = offsetms
+	s.oldgamestate = oldgamestate
+	s.onground = onground
+	s.openbottom = openbottom
+	s.openings = openings
+	s.openrange = openrange
+	s.opentop = opentop
+	s.opposite = opposite
+	s.overflowsprite = overflowsprite
+	s.pagename = pagename
+	s.pagetic = pagetic
+	s.pars = pars
+	s.paused = paused
+	s.pixhigh = pixhigh
+	s.pixhighstep = pixhighstep
+	s.pixlow = pixlow
+	s.pixlowstep = pixlowstep
+	s.planeheight = planeheight
+	s.planezlight = planezlight
+	s.player_arrow = player_arrow
+	s.player_names = player_names
+	s.playeringame = playeringame
+	s.players = players
+	s.playerstarts = playerstarts
+	s.precache = precache
+	s.prndindex = prndindex
+	s.projection = projection
+	s.pspriteiscale = pspriteiscale
+	s.pspritescale = pspritescale
+	s.quickSaveSlot = quickSaveSlot
+	s.quitsounds = quitsounds
+	s.quitsounds2 = quitsounds2
+	s.rejectmatrix = rejectmatrix
+	s.respawnmonsters = respawnmonsters
+	s.respawnparm = respawnparm
+	s.rndindex = rndindex
+	s.rw_angle1 = rw_angle1
+	s.rw_bottomtexturemid = rw_bottomtexturemid
+	s.rw_centerangle = rw_centerangle
+	s.rw_distance = rw_distance
+	s.rw_midtexturemid = rw_midtexturemid
+	s.rw_normalangle = rw_normalangle
+	s.rw_offset = rw_offset
+	s.rw_scale = rw_scale
+	s.rw_scalestep = rw_scalestep
+	s.rw_stopx = rw_stopx
+	s.rw_toptexturemid = rw_toptexturemid
+	s.rw_x = rw_x
+	s.saveCharIndex = saveCharIndex
+	s.saveOldString = saveOldString
+	s.saveSlot = saveSlot
+	s.saveStringEnter = saveStringEnter
+	s.save_stream = save_stream
+	s.savegame_error = savegame_error
+	s.savegamedir = savegamedir
+	s.savegamestrings = savegamestrings
+	s.loadgameslot = loadgameslot
+	s.scaledviewwidth = scaledviewwidth
+	s.scalelight = scalelight
+	s.scalelightfixed = scalelightfixed
+	s.screenSize = screenSize
+	s.screenblocks = screenblocks
+	s.screenheightarray = screenheightarray
+	s.screensaver_mode = screensaver_mode
+	s.secretexit = secretexit
+	s.sectors = sectors
+	s.segs = segs
+	s.segtextured = segtextured
+	s.sendpause = sendpause
+	s.sendsave = sendsave
+	s.setblocks = setblocks
+	s.setdetail = setdetail
+	s.setsizeneeded = setsizeneeded
+	s.sfxVolume = sfxVolume
+	s.shootthing = shootthing
+	s.shootz = shootz
+	s.showMessages = showMessages
+	s.show_endoom = show_endoom
+	s.sidedef = sidedef
+	s.sidemove = sidemove
+	s.sides = sides
+	s.sightcounts = sightcounts
+	s.sightzstart = sightzstart
+	s.singledemo = singledemo
+	s.singletics = singletics
+	s.skullAnimCounter = skullAnimCounter
+	s.skullName = skullName
+	s.skyflatnum = skyflatnum
+	s.skytexture = skytexture
+	s.skytexturemid = skytexturemid
+	s.slidemo = slidemo
+	s.snd_cachesize = snd_cachesize
+	s.snd_channels = snd_channels
+	s.snd_maxslicetime_ms = snd_maxslicetime_ms
+	s.snd_musiccmd = snd_musiccmd
+	s.snd_musicdevice = snd_musicdevice
+	s.snd_samplerate = snd_samplerate
+	s.snd_sfxdevice = snd_sfxdevice
+	s.solidsegs = solidsegs
+	s.soundtarget = soundtarget
+	s.spanfunc = spanfunc
+	s.spanstart = spanstart
+	s.spechit = spechit
+	s.spritelights = spritelights
+	s.spriteoffset = spriteoffset
+	s.sprites = sprites
+	s.spritetopoffset = spritetopoffset
+	s.spritewidth = spritewidth
+	s.sprnames = sprnames
+	s.sprtemp = sprtemp
+	s.sprtopscreen = sprtopscreen
+	s.spryscale = spryscale
+	s.st_backing_screen = st_backing_screen
+	s.startepisode = startepisode
+	s.startloadgame = startloadgame
+	s.startmap = startmap
+	s.startskill = startskill
+	s.starttime = starttime
+	s.states = states
+	s.storedemo = storedemo
+	s.strace = strace
+	s.sttminus = sttminus
+	s.subsectors = subsectors
+	s.switchlist = switchlist
+	s.t2x = t2x
+	s.t2y = t2y
+	s.tantoangle = tantoangle
+	s.testcontrols = testcontrols
+	s.testcontrols_mousespeed = testcontrols_mousespeed
+	s.texturecolumnlump = texturecolumnlump
+	s.texturecolumnofs = texturecolumnofs
+	s.texturecomposite = texturecomposite
+	s.texturecompositesize = texturecompositesize
+	s.textureheight = textureheight
+	s.textures = textures
+	s.textures_hashtable = textures_hashtable
+	s.texturetranslation = texturetranslation
+	s.texturewidthmask = texturewidthmask
+	s.thinkercap = thinkercap
+	s.thintriangle_guy = thintriangle_guy
+	s.ticdup = ticdup
+	s.timelimit = timelimit
+	s.timingdemo = timingdemo
+	s.tmbbox = tmbbox
+	s.tmceilingz = tmceilingz
+	s.tmdropoffz = tmdropoffz
+	s.tmflags = tmflags
+	s.tmfloorz = tmfloorz
+	s.tmthing = tmthing
+	s.tmx = tmx
+	s.tmxmove = tmxmove
+	s.tmy = tmy
+	s.tmymove = tmymove
+	s.topfrac = topfrac
+	s.topslope = topslope
+	s.topstep = topstep
+	s.toptexture = toptexture
+	s.totalitems = totalitems
+	s.totalkills = totalkills
+	s.totalsecret = totalsecret
+	s.trace = trace
+	s.transcolfunc = transcolfunc
+	s.translationtables = translationtables
+	s.turbodetected = turbodetected
+	s.usegamma = usegamma
+	s.usemouse = usemouse
+	s.usergame = usergame
+	s.usething = usething
+	s.validcount = validcount
+	s.vanilla_demo_limit = vanilla_demo_limit
+	s.vanilla_keyboard_mapping = vanilla_keyboard_mapping
+	s.vanilla_savegame_limit = vanilla_savegame_limit
+	s.vertexes = vertexes
+	s.viewactive = viewactive
+	s.viewangle = viewangle
+	s.viewangleoffset = viewangleoffset
+	s.viewangletox = viewangletox
+	s.viewcos = viewcos
+	s.viewheight = viewheight
+	s.viewplayer = viewplayer
+	s.viewsin = viewsin
+	s.viewwidth = viewwidth
+	s.viewwindowx = viewwindowx
+	s.viewwindowy = viewwindowy
+	s.viewx = viewx
+	s.viewy = viewy
+	s.viewz = viewz
+	s.viletryx = viletryx
+	s.viletryy = viletryy
+	s.visplanes = visplanes
+	s.vissprite_n = vissprite_n
+	s.vissprites = vissprites
+	s.vsprsortedhead = vsprsortedhead
+	s.walllights = walllights
+	s.weaponinfo = weaponinfo
+	s.whichSkull = whichSkull
+	s.wipegamestate = wipegamestate
+	s.wminfo = wminfo
+	s.worldbottom = worldbottom
+	s.worldhigh = worldhigh
+	s.worldlow = worldlow
+	s.worldtop = worldtop
+	s.xspeed = xspeed
+	s.xtoviewangle = xtoviewangle
+	s.ylookup = ylookup
+	s.yslope = yslope
+	s.yspeed = yspeed
+	s.zlight = zlight
+	s.pwad_files = pwad_files
+	s.tic_override = tic_override
+	s.tic_override_set = tic_override_set
+	s.interp_enabled = interp_enabled
+	s.interp_tic = interp_tic
+	s.interp_active = interp_active
+	s.interp_savedmobjs = interp_savedmobjs
+	s.interp_savedsectors = interp_savedsectors
+	s.level_maplump = level_maplump
+	s.mobj_next_id = mobj_next_id
+	s.mobjTypeNames = mobjTypeNames
+	s.music_frontend = music_frontend
+	s.music_songs = music_songs
+	s.music_next_handle = music_next_handle
+	s.music_volume = music_volume
+	s.music_paused = music_paused
+	s.music_playing = music_playing
+	s.midi_music_module = midi_music_module
+	s.mus_controller_map = mus_controller_map
+	s.opl_logsin = opl_logsin
+	s.opl_exp = opl_exp
+	s.opl_mult = opl_mult
+	s.opl_kslrom = opl_kslrom
+	s.opl_kslshift = opl_kslshift
+	s.opl_eg_incstep = opl_eg_incstep
+	s.opl_voice_operators = opl_voice_operators
+	s.opl_volume_mapping_table = opl_volume_mapping_table
+	s.opl_chip = opl_chip
+	s.opl_main_instrs = opl_main_instrs
+	s.opl_percussion_instrs = opl_percussion_instrs
+	s.opl_voices = opl_voices
+	s.opl_voice_free = opl_voice_free
+	s.opl_voice_alloced = opl_voice_alloced
+	s.opl_channels = opl_channels
+	s.opl_songs = opl_songs
+	s.opl_next_handle = opl_next_handle
+	s.opl_song = opl_song
+	s.opl_song_pos = opl_song_pos
+	s.opl_song_tick = opl_song_tick
+	s.opl_song_acc = opl_song_acc
+	s.opl_tempo = opl_tempo
+	s.opl_looping = opl_looping
+	s.opl_paused = opl_paused
+	s.opl_music_volume = opl_music_volume
+	s.opl_resample_pos = opl_resample_pos
+	s.opl_prev = opl_prev
+	s.opl_next = opl_next
+	s.opl_buffer = opl_buffer
+	s.opl_music_module = opl_music_module
+	s.pal_enabled = pal_enabled
+	s.pal_frontend = pal_frontend
+	s.pal_screen = pal_screen
+	s.pal_hooks = pal_hooks
+	s.save_store = save_store
+	s.mixer_frontend = mixer_frontend
+	s.mixer_use_prefix = mixer_use_prefix
+	s.mixer_channels = mixer_channels
+	s.mixer_sounds = mixer_sounds
+	s.mixer_frames = mixer_frames
+	s.mixer_start_tic = mixer_start_tic
+	s.mixer_buffer = mixer_buffer
+	s.mixer_module = mixer_module
+	s.screenwidth = screenwidth
+	s.screenheight = screenheight
+	s.screenaspect = screenaspect
+	s.fovwidth = fovwidth
+}
+
+// load replaces the package level engine variables with the contents of s
+func (s *engineState) load() {
+	aux_enabled = s.aux_enabled
+	aux_frontend = s.aux_frontend
+	aux_buffers = s.aux_buffers
+	aux_depthbuf = s.aux_depthbuf
+	aux_labelbuf = s.aux_labelbuf
+	aux_automapbuf = s.aux_automapbuf
+	aux_depth = s.aux_depth
+	aux_label = s.aux_label
+	demo_writer = s.demo_writer
+	demo_err = s.demo_err
+	demo_data = s.demo_data
+	demo_usergame = s.demo_usergame
+	vfs = s.vfs
+	dg_frontend = s.dg_frontend
+	dg_run_full_speed = s.dg_run_full_speed
+	dg_fake_tics = s.dg_fake_tics
+	dg_exiting = s.dg_exiting
+	start_time = s.start_time
+	cheating = s.cheating
+	grid = s.grid
+	f_x = s.f_x
+	f_y = s.f_y
+	f_w = s.f_w
+	f_h = s.f_h
+	lightlev = s.lightlev
+	fb = s.fb
+	m_paninc = s.m_paninc
+	mtof_zoommul = s.mtof_zoommul
+	ftom_zoommul = s.ftom_zoommul
+	m_x = s.m_x
+	m_y = s.m_y
+	m_x2 = s.m_x2
+	m_y2 = s.m_y2
+	m_w = s.m_w
+	m_h = s.m_h
+	min_x = s.min_x
+	min_y = s.min_y
+	max_x = s.max_x
+	max_y = s.max_y
+	max_w = s.max_w
+	max_h = s.max_h
+	min_scale_mtof = s.min_scale_mtof
+	max_scale_mtof = s.max_scale_mtof
+	old_m_w = s.old_m_w
+	old_m_h = s.old_m_h
+	old_m_x = s.old_m_x
+	old_m_y = s.old_m_y
+	f_oldloc = s.f_oldloc
+	scale_mtof = s.scale_mtof
+	scale_ftom = s.scale_ftom
+	plr = s.plr
+	marknums = s.marknums
+	markpoints = s.markpoints
+	markpointnum = s.markpointnum
+	followplayer = s.followplayer
+	stopped = s.stopped
+	st_notify = s.st_notify
+	st_notify1 = s.st_notify1
+	lastlevel = s.lastlevel
+	lastepisode = s.lastepisode
+	bigstate = s.bigstate
+	fuck = s.fuck
+	fl = s.fl
+	l = s.l
+	their_colors = s.their_colors
+	events = s.events
+	eventhead = s.eventhead
+	eventtail = s.eventtail
+	iwads = s.iwads
+	iwad_dirs = s.iwad_dirs
+	ticdata = s.ticdata
+	maketic = s.maketic
+	recvtic = s.recvtic
+	localplayer = s.localplayer
+	skiptics = s.skiptics
+	new_sync = s.new_sync
+	loop_interface = s.loop_interface
+	local_playeringame = s.local_playeringame
+	player_class = s.player_class
+	frameon = s.frameon
+	frameskip = s.frameskip
+	oldnettics = s.oldnettics
+	oldentertics = s.oldentertics
+	viewactivestate = s.viewactivestate
+	menuactivestate = s.menuactivestate
+	inhelpscreensstate = s.inhelpscreensstate
+	fullscreen = s.fullscreen
+	oldgamestate1 = s.oldgamestate1
+	borderdrawcount = s.borderdrawcount
+	banners = s.banners
+	packs = s.packs
+	copyright_banners = s.copyright_banners
+	gameversions = s.gameversions
+	exitmsg = s.exitmsg
+	doom_loop_interface = s.doom_loop_interface
+	textscreens = s.textscreens
+	laststage = s.laststage
+	wipe_running = s.wipe_running
+	wipe_scr_start = s.wipe_scr_start
+	wipe_scr_end = s.wipe_scr_end
+	wipe_scr = s.wipe_scr
+	y_screen = s.y_screen
+	wipes = s.wipes
+	weapon_keys = s.weapon_keys
+	next_weapon = s.next_weapon
+	weapon_order_table = s.weapon_order_table
+	gamekeydown = s.gamekeydown
+	turnheld = s.turnheld
+	mousearray = s.mousearray
+	dclicktime = s.dclicktime
+	dclickstate = s.dclickstate
+	dclicks = s.dclicks
+	dclicktime2 = s.dclicktime2
+	dclickstate2 = s.dclickstate2
+	dclicks2 = s.dclicks2
+	joyxmove = s.joyxmove
+	joyymove = s.joyymove
+	joystrafemove = s.joystrafemove
+	joyarray = s.joyarray
+	savegameslot = s.savegameslot
+	savedescription = s.savedescription
+	carry = s.carry
+	plr1 = s.plr1
+	w_title = s.w_title
+	w_chat = s.w_chat
+	always_off = s.always_off
+	chat_dest = s.chat_dest
+	w_inputbuffer = s.w_inputbuffer
+	message_on = s.message_on
+	message_nottobefuckedwith = s.message_nottobefuckedwith
+	w_message = s.w_message
+	message_counter = s.message_counter
+	headsupactive = s.headsupactive
+	chatchars = s.chatchars
+	head = s.head
+	tail = s.tail
+	lastmessage = s.lastmessage
+	altdown = s.altdown
+	num_nobrainers = s.num_nobrainers
+	usejoystick = s.usejoystick
+	joystick_index = s.joystick_index
+	joystick_x_axis = s.joystick_x_axis
+	joystick_x_invert = s.joystick_x_invert
+	joystick_y_axis = s.joystick_y_axis
+	joystick_y_invert = s.joystick_y_invert
+	joystick_strafe_axis = s.joystick_strafe_axis
+	joystick_strafe_invert = s.joystick_strafe_invert
+	joystick_physical_buttons = s.joystick_physical_buttons
+	sound_module = s.sound_module
+	music_module = s.music_module
+	snd_sbport = s.snd_sbport
+	snd_sbirq = s.snd_sbirq
+	snd_sbdma = s.snd_sbdma
+	snd_mport = s.snd_mport
+	sound_modules = s.sound_modules
+	music_modules = s.music_modules
+	exit_funcs = s.exit_funcs
+	already_quitting = s.already_quitting
+	mem_dump_dos622 = s.mem_dump_dos622
+	mem_dump_win98 = s.mem_dump_win98
+	mem_dump_dosbox = s.mem_dump_dosbox
+	mem_dump_custom = s.mem_dump_custom
+	dos_mem_dump = s.dos_mem_dump
+	firsttime = s.firsttime
+	basetime = s.basetime
+	last_tick = s.last_tick
+	default_main_config = s.default_main_config
+	default_extra_config = s.default_extra_config
+	doom_defaults_list = s.doom_defaults_list
+	doom_defaults = s.doom_defaults
+	extra_defaults_list = s.extra_defaults_list
+	extra_defaults = s.extra_defaults
+	detailNames = s.detailNames
+	msgNames = s.msgNames
+	M_QuitResponse = s.M_QuitResponse
+	joywait = s.joywait
+	mousewait = s.mousewait
+	mousey1 = s.mousey1
+	lasty = s.lasty
+	mousex1 = s.mousex1
+	lastx = s.lastx
+	x = s.x
+	y2 = s.y2
+	rndtable = s.rndtable
+	easy = s.easy
+	baseaddr = s.baseaddr
+	intercepts_overrun = s.intercepts_overrun
+	dummy_mobj = s.dummy_mobj
+	totallines = s.totallines
+	null_sector_is_initialized = s.null_sector_is_initialized
+	null_sector = s.null_sector
+	first = s.first
+	tmp_s3_floorheight = s.tmp_s3_floorheight
+	tmp_s3_floorpic = s.tmp_s3_floorpic
+	background_buffer = s.background_buffer
+	clipbot = s.clipbot
+	cliptop = s.cliptop
+	captured_stats = s.captured_stats
+	num_captured_stats = s.num_captured_stats
+	plyr = s.plyr
+	st_firsttime = s.st_firsttime
+	lu_palette = s.lu_palette
+	st_statusbaron = s.st_statusbaron
+	st_notdeathmatch = s.st_notdeathmatch
+	st_armson = s.st_armson
+	st_fragson = s.st_fragson
+	sbar = s.sbar
+	tallnum = s.tallnum
+	tallpercent = s.tallpercent
+	shortnum = s.shortnum
+	keys = s.keys
+	faces = s.faces
+	faceback = s.faceback
+	armsbg = s.armsbg
+	arms = s.arms
+	w_ready = s.w_ready
+	w_frags = s.w_frags
+	w_health = s.w_health
+	w_armsbg = s.w_armsbg
+	w_arms = s.w_arms
+	w_faces = s.w_faces
+	w_keyboxes = s.w_keyboxes
+	w_armor = s.w_armor
+	w_ammo = s.w_ammo
+	w_maxammo = s.w_maxammo
+	st_fragscount = s.st_fragscount
+	st_oldhealth = s.st_oldhealth
+	oldweaponsowned = s.oldweaponsowned
+	st_facecount = s.st_facecount
+	st_faceindex = s.st_faceindex
+	keyboxes = s.keyboxes
+	st_randomnumber = s.st_randomnumber
+	lastcalc = s.lastcalc
+	oldhealth = s.oldhealth
+	lastattackdown = s.lastattackdown
+	priority = s.priority
+	largeammo = s.largeammo
+	st_palette = s.st_palette
+	st_stopped = s.st_stopped
+	channels = s.channels
+	snd_SfxVolume = s.snd_SfxVolume
+	mus_paused = s.mus_paused
+	mus_playing = s.mus_playing
+	dest_screen = s.dest_screen
+	lnodes = s.lnodes
+	epsd0animinfo = s.epsd0animinfo
+	epsd1animinfo = s.epsd1animinfo
+	epsd2animinfo = s.epsd2animinfo
+	NUMANIMS = s.NUMANIMS
+	anims1 = s.anims1
+	acceleratestage = s.acceleratestage
+	me = s.me
+	state = s.state
+	wbs = s.wbs
+	plrs = s.plrs
+	cnt = s.cnt
+	bcnt = s.bcnt
+	cnt_kills = s.cnt_kills
+	cnt_items = s.cnt_items
+	cnt_secret = s.cnt_secret
+	cnt_time = s.cnt_time
+	cnt_par = s.cnt_par
+	cnt_pause = s.cnt_pause
+	NUMCMAPS = s.NUMCMAPS
+	yah = s.yah
+	splat = s.splat
+	percent = s.percent
+	colon = s.colon
+	num = s.num
+	wiminus = s.wiminus
+	finished = s.finished
+	entering = s.entering
+	sp_secret = s.sp_secret
+	kills = s.kills
+	secret = s.secret
+	items = s.items
+	frags = s.frags
+	timepatch = s.timepatch
+	par = s.par
+	sucks = s.sucks
+	killers = s.killers
+	victims = s.victims
+	total = s.total
+	star = s.star
+	bstar = s.bstar
+	p = s.p
+	bp = s.bp
+	lnames = s.lnames
+	background = s.background
+	snl_pointeron = s.snl_pointeron
+	dm_state = s.dm_state
+	dm_frags = s.dm_frags
+	dm_totals = s.dm_totals
+	cnt_frags = s.cnt_frags
+	dofrags = s.dofrags
+	ng_state = s.ng_state
+	sp_state = s.sp_state
+	open_wadfiles = s.open_wadfiles
+	lumphash = s.lumphash
+	unique_lumps = s.unique_lumps
+	shiftdown = s.shiftdown
+	shiftxform = s.shiftxform
+	lastMouse = s.lastMouse
+	colors = s.colors
+	DG_ScreenBuffer = s.DG_ScreenBuffer
+	EpiDef = s.EpiDef
+	EpisodeMenu = s.EpisodeMenu
+	I_VideoBuffer = s.I_VideoBuffer
+	LoadDef = s.LoadDef
+	LoadMenu = s.LoadMenu
+	MainDef = s.MainDef
+	MainMenu = s.MainMenu
+	NewDef = s.NewDef
+	NewGameMenu = s.NewGameMenu
+	OptionsDef = s.OptionsDef
+	OptionsMenu = s.OptionsMenu
+	ReadDef1 = s.ReadDef1
+	ReadDef2 = s.ReadDef2
+	ReadMenu1 = s.ReadMenu1
+	ReadMenu2 = s.ReadMenu2
+	S_music = s.S_music
+	S_sfx = s.S_sfx
+	SaveDef = s.SaveDef
+	SaveMenu = s.SaveMenu
+	SoundDef = s.SoundDef
+	SoundMenu = s.SoundMenu
+	TRACEANGLE = s.TRACEANGLE
+	activeceilings = s.activeceilings
+	activeplats = s.activeplats
+	dont_run_demo = s.dont_run_demo
+	advancedemo = s.advancedemo
+	aimslope = s.aimslope
+	alphSwitchList = s.alphSwitchList
+	angleturn = s.angleturn
+	animdefs = s.animdefs
+	anims = s.anims
+	attackrange = s.attackrange
+	automapactive = s.automapactive
+	autostart = s.autostart
+	backsector = s.backsector
+	basecolfunc = s.basecolfunc
+	basexscale = s.basexscale
+	baseyscale = s.baseyscale
+	bestslidefrac = s.bestslidefrac
+	bestslideline = s.bestslideline
+	bfgedition = s.bfgedition
+	blocklinks = s.blocklinks
+	blockmap = s.blockmap
+	blockmaplump = s.blockmaplump
+	bmapheight = s.bmapheight
+	bmaporgx = s.bmaporgx
+	bmaporgy = s.bmaporgy
+	bmapwidth = s.bmapwidth
+	bodyque = s.bodyque
+	bodyqueslot = s.bodyqueslot
+	bombdamage = s.bombdamage
+	bombsource = s.bombsource
+	bombspot = s.bombspot
+	bottomfrac = s.bottomfrac
+	bottomslope = s.bottomslope
+	bottomstep = s.bottomstep
+	bottomtexture = s.bottomtexture
+	braintargeton = s.braintargeton
+	braintargets = s.braintargets
+	bulletslope = s.bulletslope
+	buttonlist = s.buttonlist
+	cacheddistance = s.cacheddistance
+	cachedheight = s.cachedheight
+	cachedxstep = s.cachedxstep
+	cachedystep = s.cachedystep
+	castattacking = s.castattacking
+	castdeath = s.castdeath
+	castframes = s.castframes
+	castnum = s.castnum
+	castonmelee = s.castonmelee
+	castorder = s.castorder
+	caststate = s.caststate
+	casttics = s.casttics
+	ceilingclip = s.ceilingclip
+	ceilingline = s.ceilingline
+	ceilingplane = s.ceilingplane
+	centerx = s.centerx
+	centerxfrac = s.centerxfrac
+	centery = s.centery
+	centeryfrac = s.centeryfrac
+	chat_macros = s.chat_macros
+	chat_on = s.chat_on
+	cheat_amap = s.cheat_amap
+	cheat_ammo = s.cheat_ammo
+	cheat_ammonokey = s.cheat_ammonokey
+	cheat_choppers = s.cheat_choppers
+	cheat_clev = s.cheat_clev
+	cheat_commercial_noclip = s.cheat_commercial_noclip
+	cheat_god = s.cheat_god
+	cheat_mus = s.cheat_mus
+	cheat_mypos = s.cheat_mypos
+	cheat_noclip = s.cheat_noclip
+	cheat_player_arrow = s.cheat_player_arrow
+	cheat_powerup = s.cheat_powerup
+	checkcoord = s.checkcoord
+	clipammo = s.clipammo
+	clipangle = s.clipangle
+	colfunc = s.colfunc
+	colormaps = s.colormaps
+	columnofs = s.columnofs
+	configdir = s.configdir
+	consistancy = s.consistancy
+	consoleplayer = s.consoleplayer
+	corpsehit = s.corpsehit
+	cpars = s.cpars
+	crushchange = s.crushchange
+	curline = s.curline
+	currentMenu = s.currentMenu
+	d_episode = s.d_episode
+	d_map = s.d_map
+	d_skill = s.d_skill
+	dc_colormap = s.dc_colormap
+	dc_iscale = s.dc_iscale
+	dc_source = s.dc_source
+	dc_texturemid = s.dc_texturemid
+	dc_translation = s.dc_translation
+	dc_x = s.dc_x
+	dc_yh = s.dc_yh
+	dc_yl = s.dc_yl
+	dclick_use = s.dclick_use
+	deathmatch = s.deathmatch
+	deathmatch_pos = s.deathmatch_pos
+	deathmatchstarts = s.deathmatchstarts
+	defdemoname = s.defdemoname
+	demo_pos = s.demo_pos
+	demobuffer = s.demobuffer
+	demoname = s.demoname
+	demoplayback = s.demoplayback
+	demorecording = s.demorecording
+	demosequence = s.demosequence
+	detailLevel = s.detailLevel
+	detailshift = s.detailshift
+	devparm = s.devparm
+	diags = s.diags
+	dirtybox = s.dirtybox
+	displayplayer = s.displayplayer
+	distscale = s.distscale
+	doom1_endmsg = s.doom1_endmsg
+	doom2_endmsg = s.doom2_endmsg
+	drawsegs = s.drawsegs
+	drone = s.drone
+	ds_colormap = s.ds_colormap
+	ds_index = s.ds_index
+	ds_source = s.ds_source
+	ds_x1 = s.ds_x1
+	ds_x2 = s.ds_x2
+	ds_xfrac = s.ds_xfrac
+	ds_xstep = s.ds_xstep
+	ds_y = s.ds_y
+	ds_yfrac = s.ds_yfrac
+	ds_ystep = s.ds_ystep
+	earlyout = s.earlyout
+	endstring = s.endstring
+	epi = s.epi
+	extralight = s.extralight
+	fastparm = s.fastparm
+	finalecount = s.finalecount
+	finaleflat = s.finaleflat
+	finalestage = s.finalestage
+	finaletext = s.finaletext
+	finecosine = s.finecosine
+	finesine = s.finesine
+	finetangent = s.finetangent
+	firstflat = s.firstflat
+	firstspritelump = s.firstspritelump
+	fixedcolormap = s.fixedcolormap
+	flattranslation = s.flattranslation
+	floatok = s.floatok
+	floorclip = s.floorclip
+	floorplane = s.floorplane
+	forwardmove = s.forwardmove
+	frontsector = s.frontsector
+	fuzzcolfunc = s.fuzzcolfunc
+	fuzzoffset = s.fuzzoffset
+	fuzzpos = s.fuzzpos
+	gameaction = s.gameaction
+	gamedescription = s.gamedescription
+	gameepisode = s.gameepisode
+	gamemap = s.gamemap
+	gamemission = s.gamemission
+	gamemode = s.gamemode
+	gameskill = s.gameskill
+	gamestate = s.gamestate
+	gametic = s.gametic
+	gameversion = s.gameversion
+	gammamsg = s.gammamsg
+	hu_font = s.hu_font
+	inhelpscreens = s.inhelpscreens
+	intercept_pos = s.intercept_pos
+	intercepts = s.intercepts
+	iquehead = s.iquehead
+	iquetail = s.iquetail
+	itemOn = s.itemOn
+	itemrespawnque = s.itemrespawnque
+	itemrespawntime = s.itemrespawntime
+	iwadfile = s.iwadfile
+	joybfire = s.joybfire
+	joybjump = s.joybjump
+	joybmenu = s.joybmenu
+	joybnextweapon = s.joybnextweapon
+	joybprevweapon = s.joybprevweapon
+	joybspeed = s.joybspeed
+	joybstrafe = s.joybstrafe
+	joybstrafeleft = s.joybstrafeleft
+	joybstraferight = s.joybstraferight
+	joybuse = s.joybuse
+	key_arti_all = s.key_arti_all
+	key_arti_blastradius = s.key_arti_blastradius
+	key_arti_egg = s.key_arti_egg
+	key_arti_health = s.key_arti_health
+	key_arti_invulnerability = s.key_arti_invulnerability
+	key_arti_poisonbag = s.key_arti_poisonbag
+	key_arti_teleport = s.key_arti_teleport
+	key_arti_teleportother = s.key_arti_teleportother
+	key_demo_quit = s.key_demo_quit
+	key_down = s.key_down
+	key_fire = s.key_fire
+	key_flycenter = s.key_flycenter
+	key_flydown = s.key_flydown
+	key_flyup = s.key_flyup
+	key_invdrop = s.key_invdrop
+	key_invend = s.key_invend
+	key_invhome = s.key_invhome
+	key_invkey = s.key_invkey
+	key_invleft = s.key_invleft
+	key_invpop = s.key_invpop
+	key_invquery = s.key_invquery
+	key_invright = s.key_invright
+	key_invuse = s.key_invuse
+	key_jump = s.key_jump
+	key_left = s.key_left
+	key_lookcenter = s.key_lookcenter
+	key_lookdown = s.key_lookdown
+	key_lookup = s.key_lookup
+	key_map_clearmark = s.key_map_clearmark
+	key_map_east = s.key_map_east
+	key_map_follow = s.key_map_follow
+	key_map_grid = s.key_map_grid
+	key_map_mark = s.key_map_mark
+	key_map_maxzoom = s.key_map_maxzoom
+	key_map_north = s.key_map_north
+	key_map_south = s.key_map_south
+	key_map_toggle = s.key_map_toggle
+	key_map_west = s.key_map_west
+	key_map_zoomin = s.key_map_zoomin
+	key_map_zoomout = s.key_map_zoomout
+	key_menu_abort = s.key_menu_abort
+	key_menu_activate = s.key_menu_activate
+	key_menu_back = s.key_menu_back
+	key_menu_confirm = s.key_menu_confirm
+	key_menu_decscreen = s.key_menu_decscreen
+	key_menu_detail = s.key_menu_detail
+	key_menu_down = s.key_menu_down
+	key_menu_endgame = s.key_menu_endgame
+	key_menu_forward = s.key_menu_forward
+	key_menu_gamma = s.key_menu_gamma
+	key_menu_help = s.key_menu_help
+	key_menu_incscreen = s.key_menu_incscreen
+	key_menu_left = s.key_menu_left
+	key_menu_load = s.key_menu_load
+	key_menu_messages = s.key_menu_messages
+	key_menu_qload = s.key_menu_qload
+	key_menu_qsave = s.key_menu_qsave
+	key_menu_quit = s.key_menu_quit
+	key_menu_right = s.key_menu_right
+	key_menu_save = s.key_menu_save
+	key_menu_screenshot = s.key_menu_screenshot
+	key_menu_up = s.key_menu_up
+	key_menu_volume = s.key_menu_volume
+	key_message_refresh = s.key_message_refresh
+	key_mission = s.key_mission
+	key_multi_msg = s.key_multi_msg
+	key_multi_msgplayer = s.key_multi_msgplayer
+	key_nextweapon = s.key_nextweapon
+	key_pause = s.key_pause
+	key_prevweapon = s.key_prevweapon
+	key_right = s.key_right
+	key_speed = s.key_speed
+	key_spy = s.key_spy
+	key_strafe = s.key_strafe
+	key_strafeleft = s.key_strafeleft
+	key_straferight = s.key_straferight
+	key_up = s.key_up
+	key_use = s.key_use
+	key_useartifact = s.key_useartifact
+	key_usehealth = s.key_usehealth
+	key_weapon1 = s.key_weapon1
+	key_weapon2 = s.key_weapon2
+	key_weapon3 = s.key_weapon3
+	key_weapon4 = s.key_weapon4
+	key_weapon5 = s.key_weapon5
+	key_weapon6 = s.key_weapon6
+	key_weapon7 = s.key_weapon7
+	key_weapon8 = s.key_weapon8
+	la_damage = s.la_damage
+	lastanim = s.lastanim
+	lastflat = s.lastflat
+	lastopening = s.lastopening
+	lastspritelump = s.lastspritelump
+	lasttime = s.lasttime
+	lastvisplane_index = s.lastvisplane_index
+	levelTimeCount = s.levelTimeCount
+	levelTimer = s.levelTimer
+	leveltime = s.leveltime
+	linedef = s.linedef
+	lines = s.lines
+	linespeciallist = s.linespeciallist
+	linetarget = s.linetarget
+	longtics = s.longtics
+	lowfloor = s.lowfloor
+	lowres_turn = s.lowres_turn
+	lumpinfo = s.lumpinfo
+	main_loop_started = s.main_loop_started
+	mapnames = s.mapnames
+	mapnames_commercial = s.mapnames_commercial
+	markceiling = s.markceiling
+	markfloor = s.markfloor
+	maskedtexture = s.maskedtexture
+	maskedtexturecol = s.maskedtexturecol
+	maxammo = s.maxammo
+	maxframe = s.maxframe
+	mceilingclip = s.mceilingclip
+	menuactive = s.menuactive
+	messageLastMenuActive = s.messageLastMenuActive
+	messageNeedsInput = s.messageNeedsInput
+	messageRoutine = s.messageRoutine
+	messageString = s.messageString
+	messageToPrint = s.messageToPrint
+	message_dontfuckwithme = s.message_dontfuckwithme
+	mfloorclip = s.mfloorclip
+	midtexture = s.midtexture
+	mobjinfo = s.mobjinfo
+	modifiedgame = s.modifiedgame
+	mouseSensitivity = s.mouseSensitivity
+	mouse_acceleration = s.mouse_acceleration
+	mouse_threshold = s.mouse_threshold
+	mousebbackward = s.mousebbackward
+	mousebfire = s.mousebfire
+	mousebforward = s.mousebforward
+	mousebjump = s.mousebjump
+	mousebnextweapon = s.mousebnextweapon
+	mousebprevweapon = s.mousebprevweapon
+	mousebstrafe = s.mousebstrafe
+	mousebstrafeleft = s.mousebstrafeleft
+	mousebstraferight = s.mousebstraferight
+	mousebuse = s.mousebuse
+	mousex = s.mousex
+	mousey = s.mousey
+	musicVolume = s.musicVolume
+	myargs = s.myargs
+	negonearray = s.negonearray
+	net_client_connected = s.net_client_connected
+	netcmds = s.netcmds
+	netdemo = s.netdemo
+	netgame = s.netgame
+	newend = s.newend
+	nodes = s.nodes
+	nodrawers = s.nodrawers
+	nofit = s.nofit
+	nomonsters = s.nomonsters
+	numbraintargets = s.numbraintargets
+	numflats = s.numflats
+	numlines = s.numlines
+	numlinespecials = s.numlinespecials
+	numlumps = s.numlumps
+	numnodes = s.numnodes
+	numsectors = s.numsectors
+	numsegs = s.numsegs
+	numsides = s.numsides
+	numspechit = s.numspechit
+	numspritelumps = s.numspritelumps
+	numsprites = s.numsprites
+	numsubsectors = s.numsubsectors
+	numswitches = s.numswitches
+	numtextures = s.numtextures
+	numvertexes = s.numvertexes
+	offsetms = s.offsetms
+	oldgamestate = s.oldgamestate
+	onground = s.onground
+	openbottom = s.openbottom
+	openings = s.openings
+	openrange = s.openrange
+	opentop = s.opentop
+	opposite = s.opposite
+	overflowsprite = s.overflowsprite
+	pagename = s.pagename
+	pagetic = s.pagetic
+	pars = s.pars
+	paused = s.paused
+	pixhigh = s.pixhigh
+	pixhighstep = s.pixhighstep
+	pixlow = s.pixlow
+	pixlowstep = s.pixlowstep
+	planeheight = s.planeheight
+	planezlight = s.planezlight
+	player_arrow = s.player_arrow
+	player_names = s.player_names
+	playeringame = s.playeringame
+	players = s.players
+	playerstarts = s.playerstarts
+	precache = s.precache
+	prndindex = s.prndindex
+	projection = s.projection
+	pspriteiscale = s.pspriteiscale
+	pspritescale = s.pspritescale
+	quickSaveSlot = s.quickSaveSlot
+	quitsounds = s.quitsounds
+	quitsounds2 = s.quitsounds2
+	rejectmatrix = s.rejectmatrix
+	respawnmonsters = s.respawnmonsters
+	respawnparm = s.respawnparm
+	rndindex = s.rndindex
+	rw_angle1 = s.rw_angle1
+	rw_bottomtexturemid = s.rw_bottomtexturemid
+	rw_centerangle = s.rw_centerangle
+	rw_distance = s.rw_distance
+	rw_midtexturemid = s.rw_midtexturemid
+	rw_normalangle = s.rw_normalangle
+	rw_offset = s.rw_offset
+	rw_scale = s.rw_scale
+	rw_scalestep = s.rw_scalestep
+	rw_stopx = s.rw_stopx
+	rw_toptexturemid = s.rw_toptexturemid
+	rw_x = s.rw_x
+	saveCharIndex = s.saveCharIndex
+	saveOldString = s.saveOldString
+	saveSlot = s.saveSlot
+	saveStringEnter = s.saveStringEnter
+	save_stream = s.save_stream
+	savegame_error = s.savegame_error
+	savegamedir = s.savegamedir
+	savegamestrings = s.savegamestrings
+	loadgameslot = s.loadgameslot
+	scaledviewwidth = s.scaledviewwidth
+	scalelight = s.scalelight
+	scalelightfixed = s.scalelightfixed
+	screenSize = s.screenSize
+	screenblocks = s.screenblocks
+	screenheightarray = s.screenheightarray
+	screensaver_mode = s.screensaver_mode
+	secretexit = s.secretexit
+	sectors = s.sectors
+	segs = s.segs
+	segtextured = s.segtextured
+	sendpause = s.sendpause
+	sendsave = s.sendsave
+	setblocks = s.setblocks
+	setdetail = s.setdetail
+	setsizeneeded = s.setsizeneeded
+	sfxVolume = s.sfxVolume
+	shootthing = s.shootthing
+	shootz = s.shootz
+	showMessages = s.showMessages
+	show_endoom = s.show_endoom
+	sidedef = s.sidedef
+	sidemove = s.sidemove
+	sides = s.sides
+	sightcounts = s.sightcounts
+	sightzstart = s.sightzstart
+	singledemo = s.singledemo
+	singletics = s.singletics
+	skullAnimCounter = s.skullAnimCounter
+	skullName = s.skullName
+	skyflatnum = s.skyflatnum
+	skytexture = s.skytexture
+	skytexturemid = s.skytexturemid
+	slidemo = s.slidemo
+	snd_cachesize = s.snd_cachesize
+	snd_channels = s.snd_channels
+	snd_maxslicetime_ms = s.snd_maxslicetime_ms
+	snd_musiccmd = s.snd_musiccmd
+	snd_musicdevice = s.snd_musicdevice
+	snd_samplerate = s.snd_samplerate
+	snd_sfxdevice = s.snd_sfxdevice
+	solidsegs = s.solidsegs
+	soundtarget = s.soundtarget
+	spanfunc = s.spanfunc
+	spanstart = s.spanstart
+	spechit = s.spechit
+	spritelights = s.spritelights
+	spriteoffset = s.spriteoffset
+	sprites = s.sprites
+	spritetopoffset = s.spritetopoffset
+	spritewidth = s.spritewidth
+	sprnames = s.sprnames
+	sprtemp = s.sprtemp
+	sprtopscreen = s.sprtopscreen
+	spryscale = s.spryscale
+	st_backing_screen = s.st_backing_screen
+	startepisode = s.startepisode
+	startloadgame = s.startloadgame
+	startmap = s.startmap
+	startskill = s.startskill
+	starttime = s.starttime
+	states = s.states
+	storedemo = s.storedemo
+	strace = s.strace
+	sttminus = s.sttminus
+	subsectors = s.subsectors
+	switchlist = s.switchlist
+	t2x = s.t2x
+	t2y = s.t2y
+	tantoangle = s.tantoangle
+	testcontrols = s.testcontrols
+	testcontrols_mousespeed = s.testcontrols_mousespeed
+	texturecolumnlump = s.texturecolumnlump
+	texturecolumnofs = s.texturecolumnofs
+	texturecomposite = s.texturecomposite
+	texturecompositesize = s.texturecompositesize
+	textureheight = s.textureheight
+	textures = s.textures
+	textures_hashtable = s.textures_hashtable
+	texturetranslation = s.texturetranslation
+	texturewidthmask = s.texturewidthmask
+	thinkercap = s.thinkercap
+	thintriangle_guy = s.thintriangle_guy
+	ticdup = s.ticdup
+	timelimit = s.timelimit
+	timingdemo = s.timingdemo
+	tmbbox = s.tmbbox
+	tmceilingz = s.tmceilingz
+	tmdropoffz = s.tmdropoffz
+	tmflags = s.tmflags
+	tmfloorz = s.tmfloorz
+	tmthing = s.tmthing
+	tmx = s.tmx
+	tmxmove = s.tmxmove
+	tmy = s.tmy
+	tmymove = s.tmymove
+	topfrac = s.topfrac
+	topslope = s.topslope
+	topstep = s.topstep
+	toptexture = s.toptexture
+	totalitems = s.totalitems
+	totalkills = s.totalkills
+	totalsecret = s.totalsecret
+	trace = s.trace
+	transcolfunc = s.transcolfunc
+	translationtables = s.translationtables
+	turbodetected = s.turbodetected
+	usegamma = s.usegamma
+	usemouse = s.usemouse
+	usergame = s.usergame
+	usething = s.usething
+	validcount = s.validcount
+	vanilla_demo_limit = s.vanilla_demo_limit
+	vanilla_keyboard_mapping = s.vanilla_keyboard_mapping
+	vanilla_savegame_limit = s.vanilla_savegame_limit
+	vertexes = s.vertexes
+	viewactive = s.viewactive
+	viewangle = s.viewangle
+	viewangleoffset = s.viewangleoffset
+	viewangletox = s.viewangletox
+	viewcos = s.viewcos
+	viewheight = s.viewheight
+	viewplayer = s.viewplayer
+	viewsin = s.viewsin
+	viewwidth = s.viewwidth
+	viewwindowx = s.viewwindowx
+	viewwindowy = s.viewwindowy
+	viewx = s.viewx
+	viewy = s.viewy
+	viewz = s.viewz
+	viletryx = s.viletryx
+	viletryy = s.viletryy
+	visplanes = s.visplanes
+	vissprite_n = s.vissprite_n
+	vissprites = s.vissprites
+	vsprsortedhead = s.vsprsortedhead
+	walllights = s.walllights
+	weaponinfo = s.weaponinfo
+	whichSkull = s.whichSkull
+	wipegamestate = s.wipegamestate
+	wminfo = s.wminfo
+	worldbottom = s.worldbottom
+	worldhigh = s.worldhigh
+	worldlow = s.worldlow
+	worldtop = s.worldtop
+	xspeed = s.xspeed
+	xtoviewangle = s.xtoviewangle
+	ylookup = s.ylookup
+	yslope = s.yslope
+	yspeed = s.yspeed
+	zlight = s.zlight
+	pwad_files = s.pwad_files
+	tic_override = s.tic_override
+	tic_override_set = s.tic_override_set
+	interp_enabled = s.interp_enabled
+	interp_tic = s.interp_tic
+	interp_active = s.interp_active
+	interp_savedmobjs = s.interp_savedmobjs
+	interp_savedsectors = s.interp_savedsectors
+	level_maplump = s.level_maplump
+	mobj_next_id = s.mobj_next_id
+	mobjTypeNames = s.mobjTypeNames
+	music_frontend = s.music_frontend
+	music_songs = s.music_songs
+	music_next_handle = s.music_next_handle
+	music_volume = s.music_volume
+	music_paused = s.music_paused
+	music_playing = s.music_playing
+	midi_music_module = s.midi_music_module
+	mus_controller_map = s.mus_controller_map
+	opl_logsin = s.opl_logsin
+	opl_exp = s.opl_exp
+	opl_mult = s.opl_mult
+	opl_kslrom = s.opl_kslrom
+	opl_kslshift = s.opl_kslshift
+	opl_eg_incstep = s.opl_eg_incstep
+	opl_voice_operators = s.opl_voice_operators
+	opl_volume_mapping_table = s.opl_volume_mapping_table
+	opl_chip = s.opl_chip
+	opl_main_instrs = s.opl_main_instrs
+	opl_percussion_instrs = s.opl_percussion_instrs
+	opl_voices = s.opl_voices
+	opl_voice_free = s.opl_voice_free
+	opl_voice_alloced = s.opl_voice_alloced
+	opl_channels = s.opl_channels
+	opl_songs = s.opl_songs
+	opl_next_handle = s.opl_next_handle
+	opl_song = s.opl_song
+	opl_song_pos = s.opl_song_pos
+	opl_song_tick = s.opl_song_tick
+	opl_song_acc = s.opl_song_acc
+	opl_tempo = s.opl_tempo
+	opl_looping = s.opl_looping
+	opl_paused = s.opl_paused
+	opl_music_volume = s.opl_music_volume
+	opl_resample_pos = s.opl_resample_pos
+	opl_prev = s.opl_prev
+	opl_next = s.opl_next
+	opl_buffer = s.opl_buffer
+	opl_music_module = s.opl_music_module
+	pal_enabled = s.pal_enabled
+	pal_frontend = s.pal_frontend
+	pal_screen = s.pal_screen
+	pal_hooks = s.pal_hooks
+	save_store = s.save_store
+	mixer_frontend = s.mixer_frontend
+	mixer_use_prefix = s.mixer_use_prefix
+	mixer_channels = s.mixer_channels
+	mixer_sounds = s.mixer_sounds
+	mixer_frames = s.mixer_frames
+	mixer_start_tic = s.mixer_start_tic
+	mixer_buffer = s.mixer_buffer
+	mixer_module = s.mixer_module
+	screenwidth = s.screenwidth
+	screenheight = s.screenheight
+	screenaspect = s.screenaspect
+	fovwidth = s.fovwidth
+}
@@ -0,0 +1,297 @@
+// Package env wraps a gore game as a reinforcement learning environment, in
+// the style of OpenAI Gym. Each Step applies one of a fixed set of discrete
+// actions for a number of tics, and returns the rendered frame, the game
+// state, and a reward computed from what happened.
+//
+//	e, err := env.New(env.Config{Args: []string{"-iwad", "doom1.wad"}})
+//	...
+//	obs, err := e.Reset("E1M1", 2)
+//	for {
+//		obs, reward, done, err := e.Step(agent.Act(obs))
+//		...
+//	}
+package env
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/AndreRenaud/gore"
+)
+
+// Command is the movement and buttons to apply for each tic of an action,
+// as passed to gore.Game.SetTicCommand
+type Command struct {
+	Forward int     // From -50 to 50, positive is forward
+	Side    int     // From -50 to 50, positive is to the right
+	Turn    float64 // Degrees per tic, positive is anticlockwise (left)
+	Buttons gore.TicButtons
+}
+
+// Indexes of the actions in DefaultActions
+const (
+	ActionNoop = iota
+	ActionForward
+	ActionBackward
+	ActionTurnLeft
+	ActionTurnRight
+	ActionStrafeLeft
+	ActionStrafeRight
+	ActionAttack
+	ActionUse
+	ActionForwardAttack
+	ActionTurnLeftAttack
+	ActionTurnRightAttack
+)
+
+// DefaultActions is the action set used when Config.Actions is empty. The
+// speeds are the same as running with the keyboard.
+var DefaultActions = []Command{
+	ActionNoop:            {},
+	ActionForward:         {Forward: 50},
+	ActionBackward:        {Forward: -50},
+	ActionTurnLeft:        {Turn: 5.625},
+	ActionTurnRight:       {Turn: -5.625},
+	ActionStrafeLeft:      {Side: -40},
+	ActionStrafeRight:     {Side: 40},
+	ActionAttack:          {Buttons: gore.ButtonAttack},
+	ActionUse:             {Buttons: gore.ButtonUse},
+	ActionForwardAttack:   {Forward: 50, Buttons: gore.ButtonAttack},
+	ActionTurnLeftAttack:  {Turn: 5.625, Buttons: gore.ButtonAttack},
+	ActionTurnRightAttack: {Turn: -5.625, Buttons: gore.ButtonAttack},
+}
+
+// Rewards weights the events which make up the reward for each step. The
+// weights are multiplied by the number of times the event happened, so
+// penalties should be negative.
+type Rewards struct {
+	Kill    float64 // Per monster killed
+	Item    float64 // Per item picked up which counts towards the item total
+	Secret  float64 // Per secret found
+	Damage  float64 // Per point of health lost
+	Exit    float64 // For finishing the level
+	Death   float64 // For the player dying
+	PerStep float64 // Every step, ie: a small negative value to encourage speed
+
+	// Custom, if set, is called after every tic with the state before and
+	// after it, and its result is added to the reward
+	Custom func(before, after gore.State) float64
+}
+
+// DefaultRewards is used when Config.Rewards is nil
+var DefaultRewards = Rewards{
+	Kill:   1,
+	Item:   0.1,
+	Secret: 0.5,
+	Damage: -0.01,
+	Exit:   10,
+	Death:  -5,
+}
+
+// Config controls how an Env is created
+type Config struct {
+	// Args are passed to gore.New, and must select the WAD (ie: -iwad
+	// doom1.wad). Other arguments such as -nomonsters or -fast also apply.
+	Args []string
+	// FS is the file system the WAD files are read from, as for
+	// gore.Options.FS
+	FS fs.FS
+	// FrameSkip is the number of tics each action is repeated for. Only the
+	// frame from the last one is returned. Defaults to 4.
+	FrameSkip int
+	// Actions is the discrete action set, indexed by the action passed to
+	// Step. Defaults to DefaultActions.
+	Actions []Command
+	// Rewards weights the reward for each step. Defaults to DefaultRewards.
+	// It is copied by New, so changing it afterwards has no effect.
+	Rewards *Rewards
+	// Buffers adds the depth, label and automap buffers to each observation
+	Buffers bool
+}
+
+// Observation is what the agent sees after each step
+type Observation struct {
+	// Frame is a copy of the rendered screen
+	Frame *image.RGBA
+	// Buffers is a copy of the auxiliary buffers, if Config.Buffers is set
+	Buffers *gore.FrameBuffers
+	State   gore.State
+}
+
+// Env is a reinforcement learning environment running a single game
+type Env struct {
+	game  *gore.Game
+	cfg   Config
+	state gore.State
+	done  bool
+}
+
+// ErrNotReset is returned by Step before Reset has been called, or once the
+// episode has finished
+var ErrNotReset = errors.New("env: Reset must be called before Step")
+
+// New creates an environment. The game is loaded, but Reset must be called
+// to start an episode.
+func New(cfg Config) (*Env, error) {
+	if cfg.FrameSkip <= 0 {
+		cfg.FrameSkip = 4
+	}
+	if len(cfg.Actions) == 0 {
+		cfg.Actions = DefaultActions
+	}
+	// Take a copy, so that changing the Rewards afterwards (or
+	// DefaultRewards) doesn't affect this Env
+	rewards := DefaultRewards
+	if cfg.Rewards != nil {
+		rewards = *cfg.Rewards
+	}
+	cfg.Rewards = &rewards
+	game, err := gore.New(headless{}, gore.Options{Args: cfg.Args, FS: cfg.FS, FullSpeed: true, Buffers: cfg.Buffers})
+	if err != nil {
+		return nil, err
+	}
+	return &Env{game: game, cfg: cfg, done: true}, nil
+}
+
+// NumActions returns the number of discrete actions, which are numbered from
+// 0 to NumActions()-1
+func (e *Env) NumActions() int {
+	return len(e.cfg.Actions)
+}
+
+// Game returns the underlying game, ie: for Game.Level or Game.Mobjs
+func (e *Env) Game() *gore.Game {
+	return e.game
+}
+
+// Reset starts a new episode on the given map (ie: "E1M1" or "MAP01") and
+// skill, from 0 (I'm too young to die) to 4 (Nightmare!)
+func (e *Env) Reset(mapName string, skill int) (Observation, error) {
+	episode, mapNum, err := parseMapName(mapName)
+	if err != nil {
+		return Observation{}, err
+	}
+	if err := e.game.NewGame(episode, mapNum, skill); err != nil {
+		return Observation{}, err
+	}
+	e.done = true
+	frame, err := e.game.Step()
+	if err != nil {
+		return Observation{}, err
+	}
+	if e.state, err = e.game.State(); err != nil {
+		return Observation{}, err
+	}
+	if !e.state.InLevel {
+		return Observation{}, fmt.Errorf("env: %s didn't start", mapName)
+	}
+	e.done = false
+	return observe(frame, e.state), nil
+}
+
+// Step applies an action for FrameSkip tics, and returns the resulting
+// observation, the total reward over those tics, and whether the episode has
+// finished because the player died or left the level. Once done, Reset must
+// be called before stepping again.
+func (e *Env) Step(action int) (obs Observation, reward float64, done bool, err error) {
+	if e.done {
+		return Observation{}, 0, true, ErrNotReset
+	}
+	if action < 0 || action >= len(e.cfg.Actions) {
+		return Observation{}, 0, false, fmt.Errorf("env: invalid action %d", action)
+	}
+	cmd := e.cfg.Actions[action]
+	reward = e.cfg.Rewards.PerStep
+	var frame gore.Frame
+	for range e.cfg.FrameSkip {
+		if err := e.game.SetTicCommand(cmd.Forward, cmd.Side, cmd.Turn, cmd.Buttons); err != nil {
+			return Observation{}, 0, false, err
+		}
+		if frame, err = e.game.Step(); err != nil {
+			return Observation{}, 0, false, err
+		}
+		before := e.state
+		if e.state, err = e.game.State(); err != nil {
+			return Observation{}, 0, false, err
+		}
+		reward += e.reward(before, e.state)
+		if !e.state.InLevel || !e.state.Player.Alive {
+			e.done = true
+			break
+		}
+	}
+	return observe(frame, e.state), reward, e.done, nil
+}
+
+// Close shuts down the game
+func (e *Env) Close() error {
+	return e.game.Close()
+}
+
+// reward calculates the reward for a single tic
+func (e *Env) reward(before, after gore.State) float64 {
+	r := e.cfg.Rewards
+	b, a := before.Player, after.Player
+	reward := r.Kill*float64(a.Kills-b.Kills) +
+		r.Item*float64(a.Items-b.Items) +
+		r.Secret*float64(a.Secrets-b.Secrets)
+	if lost := b.Health - a.Health; lost > 0 {
+		reward += r.Damage * float64(lost)
+	}
+	if before.InLevel && !after.InLevel {
+		reward += r.Exit
+	}
+	if b.Alive && !a.Alive {
+		reward += r.Death
+	}
+	if r.Custom != nil {
+		reward += r.Custom(before, after)
+	}
+	return reward
+}
+
+func observe(frame gore.Frame, state gore.State) Observation {
+	img := image.NewRGBA(frame.Image.Rect)
+	copy(img.Pix, frame.Image.Pix)
+	obs := Observation{Frame: img, State: state}
+	if b := frame.Buffers; b != nil {
+		obs.Buffers = &gore.FrameBuffers{
+			Depth:   image.NewGray16(b.Depth.Rect),
+			Labels:  &gore.LabelBuffer{Pix: slices.Clone(b.Labels.Pix), Width: b.Labels.Width, Height: b.Labels.Height},
+			Automap: image.NewRGBA(b.Automap.Rect),
+		}
+		copy(obs.Buffers.Depth.Pix, b.Depth.Pix)
+		copy(obs.Buffers.Automap.Pix, b.Automap.Pix)
+	}
+	return obs
+}
+
+// parseMapName splits a map name into its episode and map numbers
+func parseMapName(name string) (episode, mapNum int, err error) {
+	upper := strings.ToUpper(name)
+	if num, ok := strings.CutPrefix(upper, "MAP"); ok {
+		mapNum, err = strconv.Atoi(num)
+		return 1, mapNum, err
+	}
+	if len(upper) == 4 && upper[0] == 'E' && upper[2] == 'M' {
+		episode, err = strconv.Atoi(upper[1:2])
+		if err == nil {
+			mapNum, err = strconv.Atoi(upper[3:])
+		}
+		return episode, mapNum, err
+	}
+	return 0, 0, fmt.Errorf("env: invalid map name %q", name)
+}
+
+// headless is a frontend which discards the output, as the frames are taken
+// from gore.Game.Step instead
+type headless struct{}
+
+func (headless) DrawFrame(*image.RGBA)         {}
+func (headless) SetTitle(string)               {}
+func (headless) GetEvent(*gore.DoomEvent) bool { return false }
@@ -0,0 +1,76 @@
+package env
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEnv(t *testing.T) {
+	t.Parallel()
+	e, err := New(Config{Args: []string{"-iwad", "doom1.wad"}, FS: os.DirFS("..")})
+	if err != nil {
+		t.Fatalf("Error creating env: %v", err)
+	}
+	defer e.Close()
+	if e.cfg.Rewards == &DefaultRewards || e.cfg.Rewards.Kill != DefaultRewards.Kill {
+		t.Errorf("Rewards are %p %+v, expected a copy of DefaultRewards", e.cfg.Rewards, *e.cfg.Rewards)
+	}
+	if _, _, _, err := e.Step(ActionNoop); err != ErrNotReset {
+		t.Errorf("Step before Reset returned %v", err)
+	}
+	obs, err := e.Reset("E1M1", 2)
+	if err != nil {
+		t.Fatalf("Error resetting env: %v", err)
+	}
+	if !obs.State.InLevel || obs.State.Map != 1 || obs.State.Skill != 2 || obs.Frame == nil {
+		t.Fatalf("Wrong observation after reset: %+v", obs.State)
+	}
+	start := obs.State
+	for range 5 {
+		obs, _, done, err := e.Step(ActionForward)
+		if err != nil {
+			t.Fatalf("Error stepping env: %v", err)
+		}
+		if done {
+			t.Fatalf("Episode finished early: %+v", obs.State)
+		}
+	}
+	obs, _, _, err = e.Step(ActionNoop)
+	if err != nil {
+		t.Fatalf("Error stepping env: %v", err)
+	}
+	if obs.State.Player.Position.Y <= start.Player.Position.Y {
+		t.Errorf("Player didn't move north: %v -> %v", start.Player.Position, obs.State.Player.Position)
+	}
+	if tics := obs.State.LevelTime - start.LevelTime; tics != 6*4 {
+		t.Errorf("%d tics passed in 6 steps, expected a frame skip of 4", tics)
+	}
+
+	if _, err := e.Reset("E9M9", 2); err == nil {
+		t.Errorf("Reset to a missing map succeeded")
+	}
+	if _, _, _, err := e.Step(99); err == nil {
+		t.Errorf("Invalid action succeeded")
+	}
+}
+
+func TestParseMapName(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name            string
+		episode, mapNum int
+		wantErr         bool
+	}{
+		{"E1M1", 1, 1, false},
+		{"e2m9", 2, 9, false},
+		{"MAP01", 1, 1, false},
+		{"map32", 1, 32, false},
+		{"E1", 0, 0, true},
+		{"MAPXX", 0, 0, true},
+	} {
+		episode, mapNum, err := parseMapName(tc.name)
+		if (err != nil) != tc.wantErr || !tc.wantErr && (episode != tc.episode || mapNum != tc.mapNum) {
+			t.Errorf("parseMapName(%q) = %d, %d, %v", tc.name, episode, mapNum, err)
+		}
+	}
+}
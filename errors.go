@@ -0,0 +1,116 @@
+package gore
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// ErrSaveVersion is returned when restoring a savegame or snapshot which
+// isn't from this version of the game, or isn't one at all
+var ErrSaveVersion = errors.New("gore: not a savegame from this version")
+
+// ErrDemoRecording is returned when trying to start a demo while one is
+// already being recorded
+var ErrDemoRecording = errors.New("gore: already recording a demo")
+
+// EngineError is returned when the engine hits a fatal error, such as a
+// missing lump or an invalid WAD file. The game which raised it can no longer
+// be run, but other games are unaffected.
+type EngineError struct {
+	// Subsystem is the part of the engine which raised the error
+	// (ie: "wad", "render", "play")
+	Subsystem string
+	// Func is the name of the engine function which raised the error
+	Func string
+	// Message is the formatted error message
+	Message string
+	// Stack is the stack trace when the engine panicked, ie: with an index
+	// out of range from a corrupt WAD. It is empty for errors the engine
+	// raised itself.
+	Stack string
+}
+
+func (e *EngineError) Error() string {
+	if e.Subsystem == "" {
+		return "gore: " + e.Message
+	}
+	return "gore: " + e.Subsystem + ": " + e.Message
+}
+
+// Engine function prefixes, and the subsystem they belong to
+var engineSubsystems = map[string]string{
+	"am":  "automap",
+	"d":   "main",
+	"f":   "finale",
+	"g":   "game",
+	"hu":  "hud",
+	"i":   "system",
+	"m":   "misc",
+	"p":   "play",
+	"ptr": "play",
+	"r":   "render",
+	"s":   "sound",
+	"st":  "status",
+	"v":   "video",
+	"w":   "wad",
+	"wi":  "intermission",
+	"z":   "zone",
+}
+
+// newEngineError creates an EngineError for the function which called
+// i_Error
+func newEngineError(message string) *EngineError {
+	err := &EngineError{
+		Message: strings.TrimSpace(message),
+	}
+	if pc, _, _, ok := runtime.Caller(2); ok {
+		if f := runtime.FuncForPC(pc); f != nil {
+			err.setFunc(f.Name())
+		}
+	}
+	return err
+}
+
+// setFunc sets Func and Subsystem from the full name of an engine function
+func (e *EngineError) setFunc(name string) {
+	name = name[strings.LastIndexByte(name, '/')+1:]
+	name = name[strings.IndexByte(name, '.')+1:]
+	e.Func = name
+	if prefix, _, ok := strings.Cut(name, "_"); ok {
+		e.Subsystem = engineSubsystems[prefix]
+	}
+}
+
+// enginePackage is the prefix of the engine's function names
+var enginePackage = reflect.TypeFor[Game]().PkgPath() + "."
+
+// newRuntimeEngineError creates an EngineError for a runtime panic, such as
+// an index out of range, which is being recovered from. It returns nil if the
+// panic didn't come from the engine itself, ie: it was in a frontend
+// callback. It must be called from the deferred function doing the recover.
+func newRuntimeEngineError(r runtime.Error) *EngineError {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(0, pcs)])
+	panicking := false
+	for {
+		frame, more := frames.Next()
+		if panicking && !strings.HasPrefix(frame.Function, "runtime.") {
+			// The function which panicked
+			if !strings.HasPrefix(frame.Function, enginePackage) {
+				return nil
+			}
+			err := &EngineError{Message: r.Error(), Stack: string(debug.Stack())}
+			err.setFunc(frame.Function)
+			return err
+		}
+		if frame.Function == "runtime.gopanic" {
+			panicking = true
+		}
+		if !more {
+			return nil
+		}
+	}
+}
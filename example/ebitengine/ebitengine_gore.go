@@ -1,13 +1,20 @@
 package main
 
 import (
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"image"
+	"image/png"
+	"io/fs"
 	"log"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/AndreRenaud/gore"
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
@@ -22,42 +29,80 @@ type DoomGame struct {
 	events      []gore.DoomEvent
 	lock        sync.Mutex
 	terminating bool
+
+	audio doomAudio
+}
+
+// doomAudio buffers up the audio from Doom, ready for Ebitengine to play
+type doomAudio struct {
+	lock sync.Mutex
+	buf  []byte
+}
+
+// Most audio we'll buffer before dropping the oldest, to keep latency down.
+// This is 1/4 of a second of 16-bit stereo.
+const maxAudioBuffer = gore.AudioSampleRate * 4 / 4
+
+func (a *doomAudio) Read(p []byte) (int, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	p = p[:len(p)&^3] // Only ever return whole stereo frames
+	if len(a.buf) == 0 {
+		// Play silence rather than stalling the player
+		clear(p)
+		return len(p), nil
+	}
+	n := copy(p, a.buf)
+	a.buf = a.buf[n:]
+	return n, nil
+}
+
+func (g *DoomGame) PlayAudio(samples []int16) {
+	g.audio.lock.Lock()
+	defer g.audio.lock.Unlock()
+	for _, s := range samples {
+		g.audio.buf = binary.LittleEndian.AppendUint16(g.audio.buf, uint16(s))
+	}
+	if len(g.audio.buf) > maxAudioBuffer {
+		g.audio.buf = g.audio.buf[len(g.audio.buf)-maxAudioBuffer:]
+	}
 }
 
 func (g *DoomGame) Update() error {
 	keys := map[ebiten.Key]uint8{
-		ebiten.KeySpace:     gore.KEY_USE1,
-		ebiten.KeyEscape:    gore.KEY_ESCAPE,
-		ebiten.KeyUp:        gore.KEY_UPARROW1,
-		ebiten.KeyDown:      gore.KEY_DOWNARROW1,
-		ebiten.KeyLeft:      gore.KEY_LEFTARROW1,
-		ebiten.KeyRight:     gore.KEY_RIGHTARROW1,
-		ebiten.KeyEnter:     gore.KEY_ENTER,
-		ebiten.KeyControl:   gore.KEY_FIRE1,
-		ebiten.KeyShift:     0x80 + 0x36,
-		ebiten.KeyBackspace: gore.KEY_BACKSPACE3,
-		ebiten.KeyY:         'y',
-		ebiten.KeyN:         'n',
-		ebiten.KeyI:         'i',
-		ebiten.KeyD:         'd',
-		ebiten.KeyF:         'f',
-		ebiten.KeyA:         'a',
-		ebiten.KeyE:         'e',
-		ebiten.KeyR:         'r',
-		ebiten.KeyV:         'v',
-		ebiten.KeyC:         'c',
-		ebiten.KeyL:         'l',
-		ebiten.KeyQ:         'q',
-		ebiten.Key1:         '1',
-		ebiten.Key2:         '2',
-		ebiten.Key3:         '3',
-		ebiten.Key4:         '4',
-		ebiten.Key5:         '5',
-		ebiten.Key6:         '6',
-		ebiten.Key7:         '7',
-		ebiten.Key8:         '8',
-		ebiten.Key9:         '9',
-		ebiten.Key0:         '0',
+		ebiten.KeySpace:       gore.KEY_USE1,
+		ebiten.KeyEscape:      gore.KEY_ESCAPE,
+		ebiten.KeyUp:          gore.KEY_UPARROW1,
+		ebiten.KeyDown:        gore.KEY_DOWNARROW1,
+		ebiten.KeyLeft:        gore.KEY_LEFTARROW1,
+		ebiten.KeyRight:       gore.KEY_RIGHTARROW1,
+		ebiten.KeyEnter:       gore.KEY_ENTER,
+		ebiten.KeyControl:     gore.KEY_FIRE1,
+		ebiten.KeyShift:       0x80 + 0x36,
+		ebiten.KeyBackspace:   gore.KEY_BACKSPACE3,
+		ebiten.KeyPrintScreen: gore.KEY_PRTSCR,
+		ebiten.KeyY:           'y',
+		ebiten.KeyN:           'n',
+		ebiten.KeyI:           'i',
+		ebiten.KeyD:           'd',
+		ebiten.KeyF:           'f',
+		ebiten.KeyA:           'a',
+		ebiten.KeyE:           'e',
+		ebiten.KeyR:           'r',
+		ebiten.KeyV:           'v',
+		ebiten.KeyC:           'c',
+		ebiten.KeyL:           'l',
+		ebiten.KeyQ:           'q',
+		ebiten.Key1:           '1',
+		ebiten.Key2:           '2',
+		ebiten.Key3:           '3',
+		ebiten.Key4:           '4',
+		ebiten.Key5:           '5',
+		ebiten.Key6:           '6',
+		ebiten.Key7:           '7',
+		ebiten.Key8:           '8',
+		ebiten.Key9:           '9',
+		ebiten.Key0:           '0',
 	}
 	g.lock.Lock()
 	defer g.lock.Unlock()
@@ -140,6 +185,29 @@ func (g *DoomGame) DrawFrame(frame *image.RGBA) {
 	g.lastFrame.WritePixels(frame.Pix)
 }
 
+// SaveScreenshot saves the screen as the first unused DOOMnn.png
+func (g *DoomGame) SaveScreenshot(img *image.Paletted) error {
+	for i := range 100 {
+		name := fmt.Sprintf("DOOM%02d.png", i)
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = png.Encode(f, img)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(name)
+		}
+		return err
+	}
+	return errors.New("too many screenshots")
+}
+
 func (g *DoomGame) SetTitle(title string) {
 	ebiten.SetWindowTitle(title)
 }
@@ -149,8 +217,16 @@ func main() {
 	ebiten.SetWindowSize(screenWidth, screenHeight)
 	ebiten.SetWindowTitle("Gamepad (Ebitengine Demo)")
 	ebiten.SetFullscreen(true)
+	player, err := audio.NewContext(gore.AudioSampleRate).NewPlayer(&game.audio)
+	if err != nil {
+		log.Fatal(err)
+	}
+	player.SetBufferSize(50 * time.Millisecond)
+	player.Play()
 	go func() {
-		gore.Run(game, os.Args[1:])
+		if err := gore.Run(game, os.Args[1:]); err != nil {
+			log.Printf("Doom exited with an error: %v", err)
+		}
 		game.terminating = true
 	}()
 	if err := ebiten.RunGame(game); err != nil {
@@ -142,5 +142,8 @@ func main() {
 		outstandingKeys: make(map[uint8]time.Time),
 	}
 
-	gore.Run(termGame, os.Args[1:])
+	if err := gore.Run(termGame, os.Args[1:]); err != nil {
+		term.Restore(int(os.Stdin.Fd()), oldState)
+		log.Fatal(err)
+	}
 }
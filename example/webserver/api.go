@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image/png"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/AndreRenaud/gore"
+	"github.com/AndreRenaud/gore/recorder"
+)
+
+// apiFrontend wraps the frontend to keep the labels of the last frame drawn,
+// for /api/label
+type apiFrontend struct {
+	gore.DoomFrontend
+	lock   sync.Mutex
+	labels gore.LabelBuffer
+}
+
+func (f *apiFrontend) DrawBuffers(buffers *gore.FrameBuffers) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.labels.Pix = append(f.labels.Pix[:0], buffers.Labels.Pix...)
+	f.labels.Width, f.labels.Height = buffers.Labels.Width, buffers.Labels.Height
+}
+
+// label returns the label of a pixel of the last frame drawn, if it's on
+// the screen
+func (f *apiFrontend) label(x, y int) (gore.Label, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if x < 0 || y < 0 || x >= f.labels.Width || y >= f.labels.Height {
+		return 0, false
+	}
+	return f.labels.At(x, y), true
+}
+
+// apiDemo keeps the demo recorded through /api/demo/record, which the game
+// writes once it finishes
+type apiDemo struct {
+	lock sync.Mutex
+	data []byte
+}
+
+func (d *apiDemo) Write(p []byte) (int, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.data = append(d.data, p...)
+	return len(p), nil
+}
+
+// take returns the demo written so far, and clears it
+func (d *apiDemo) take() []byte {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	data := d.data
+	d.data = nil
+	return data
+}
+
+// serveAPI adds handlers to mux for inspecting and controlling the game
+// under /api/
+func serveAPI(mux *http.ServeMux, game *gore.Game, frontend *apiFrontend, saves gore.SaveStore) {
+	mux.HandleFunc("POST /api/stop", func(w http.ResponseWriter, r *http.Request) {
+		game.Stop()
+	})
+	mux.HandleFunc("GET /api/state", func(w http.ResponseWriter, r *http.Request) {
+		state, err := game.State()
+		writeJSON(w, state, err)
+	})
+	mux.HandleFunc("GET /api/mobjs", func(w http.ResponseWriter, r *http.Request) {
+		mobjs := []gore.Mobj{}
+		for mo, err := range game.Mobjs() {
+			if err != nil {
+				apiError(w, err)
+				return
+			}
+			mobjs = append(mobjs, mo)
+		}
+		writeJSON(w, mobjs, nil)
+	})
+	mux.HandleFunc("GET /api/mobjtypes", func(w http.ResponseWriter, r *http.Request) {
+		var names []string
+		for t := 0; gore.MobjTypeName(t) != ""; t++ {
+			names = append(names, gore.MobjTypeName(t))
+		}
+		writeJSON(w, names, nil)
+	})
+	mux.HandleFunc("GET /api/level", func(w http.ResponseWriter, r *http.Request) {
+		level, err := game.Level()
+		writeJSON(w, level, err)
+	})
+	mux.HandleFunc("POST /api/key/{key}/{state}", func(w http.ResponseWriter, r *http.Request) {
+		key, keyErr := strconv.ParseUint(r.PathValue("key"), 10, 8)
+		down, stateErr := strconv.ParseBool(r.PathValue("state"))
+		if err := errors.Join(keyErr, stateErr); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var err error
+		if down {
+			err = game.PressKey(uint8(key))
+		} else {
+			err = game.ReleaseKey(uint8(key))
+		}
+		if err != nil {
+			apiError(w, err)
+		}
+	})
+	mux.HandleFunc("POST /api/mouse/{dx}/{dy}", func(w http.ResponseWriter, r *http.Request) {
+		dx, dxErr := strconv.Atoi(r.PathValue("dx"))
+		dy, dyErr := strconv.Atoi(r.PathValue("dy"))
+		if err := errors.Join(dxErr, dyErr); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := game.MoveMouse(dx, dy); err != nil {
+			apiError(w, err)
+		}
+	})
+	mux.HandleFunc("POST /api/tic", func(w http.ResponseWriter, r *http.Request) {
+		forward, forwardErr := formNumber(r, "forward")
+		side, sideErr := formNumber(r, "side")
+		turn, turnErr := formNumber(r, "turn")
+		if err := errors.Join(forwardErr, sideErr, turnErr); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var buttons gore.TicButtons
+		if r.FormValue("attack") != "" {
+			buttons |= gore.ButtonAttack
+		}
+		if r.FormValue("use") != "" {
+			buttons |= gore.ButtonUse
+		}
+		if v := r.FormValue("weapon"); v != "" {
+			weapon, err := strconv.Atoi(v)
+			if err != nil || weapon < 0 || weapon >= int(gore.NumWeapons) {
+				http.Error(w, "Invalid weapon", http.StatusBadRequest)
+				return
+			}
+			buttons |= gore.ButtonChangeWeapon(gore.Weapon(weapon))
+		}
+		if err := game.SetTicCommand(int(forward), int(side), turn, buttons); err != nil {
+			apiError(w, err)
+		}
+	})
+	mux.HandleFunc("POST /api/newgame/{episode}/{map}/{skill}", func(w http.ResponseWriter, r *http.Request) {
+		var args [3]int
+		for i, name := range []string{"episode", "map", "skill"} {
+			v, err := strconv.Atoi(r.PathValue(name))
+			if err != nil {
+				http.Error(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+			args[i] = v
+		}
+		if err := game.NewGame(args[0], args[1], args[2]); err != nil {
+			apiError(w, err)
+		}
+	})
+	mux.HandleFunc("GET /api/label/{x}/{y}", func(w http.ResponseWriter, r *http.Request) {
+		x, xErr := strconv.Atoi(r.PathValue("x"))
+		y, yErr := strconv.Atoi(r.PathValue("y"))
+		if err := errors.Join(xErr, yErr); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		label, ok := frontend.label(x, y)
+		if !ok {
+			http.Error(w, "Pixel isn't on the screen", http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, struct {
+			Kind  gore.LabelKind
+			Index int
+		}{label.Kind(), label.Index()}, nil)
+	})
+	mux.HandleFunc("GET /api/record.gif", func(w http.ResponseWriter, r *http.Request) {
+		seconds, err := formNumber(r, "seconds")
+		if err != nil || seconds < 0 || seconds > 60 {
+			http.Error(w, "Invalid number of seconds", http.StatusBadRequest)
+			return
+		}
+		if seconds == 0 {
+			seconds = 5
+		}
+		var buf bytes.Buffer
+		rec, err := recorder.New(&buf, recorder.GIF)
+		if err != nil {
+			apiError(w, err)
+			return
+		}
+		if err := rec.Attach(game); err != nil {
+			rec.Close()
+			apiError(w, err)
+			return
+		}
+		select {
+		case <-time.After(time.Duration(seconds * float64(time.Second))):
+		case <-r.Context().Done():
+		}
+		if err := rec.Close(); err != nil {
+			apiError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "image/gif")
+		w.Write(buf.Bytes())
+	})
+	mux.HandleFunc("GET /api/screenshot.png", func(w http.ResponseWriter, r *http.Request) {
+		img, err := game.Screenshot()
+		if err != nil {
+			apiError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		if err := png.Encode(w, img); err != nil {
+			log.Printf("Error writing screenshot: %v\n", err)
+		}
+	})
+	var demo apiDemo
+	mux.HandleFunc("POST /api/demo/record", func(w http.ResponseWriter, r *http.Request) {
+		// Drop any demo which finished without being stopped
+		demo.take()
+		if err := game.RecordDemo(&demo); err != nil {
+			apiError(w, err)
+		}
+	})
+	mux.HandleFunc("POST /api/demo/stop", func(w http.ResponseWriter, r *http.Request) {
+		if err := game.StopDemo(); err != nil {
+			apiError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(demo.take())
+	})
+	mux.HandleFunc("POST /api/demo/play", func(w http.ResponseWriter, r *http.Request) {
+		if err := game.PlayDemo(r.Body); err != nil {
+			apiError(w, err)
+		}
+	})
+	mux.HandleFunc("GET /api/saves", func(w http.ResponseWriter, r *http.Request) {
+		slots, err := saves.List()
+		writeJSON(w, slots, err)
+	})
+	mux.HandleFunc("/api/saves/{slot}", func(w http.ResponseWriter, r *http.Request) {
+		slot, err := strconv.Atoi(r.PathValue("slot"))
+		if err != nil || slot < 0 {
+			http.Error(w, "Invalid save slot", http.StatusBadRequest)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			var data []byte
+			if data, err = saves.Read(slot); err == nil {
+				w.Header().Set("Content-Type", "application/octet-stream")
+				w.Write(data)
+			}
+		case http.MethodPut:
+			var data []byte
+			if data, err = io.ReadAll(r.Body); err == nil {
+				err = saves.Write(slot, data)
+			}
+		case http.MethodDelete:
+			err = saves.Delete(slot)
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err != nil {
+			apiError(w, err)
+		}
+	})
+	mux.HandleFunc("POST /api/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		data, err := game.Snapshot()
+		if err != nil {
+			apiError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(data)
+	})
+	mux.HandleFunc("POST /api/restore", func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err == nil {
+			err = game.Restore(data)
+		}
+		if err != nil {
+			apiError(w, err)
+		}
+	})
+}
+
+// formNumber returns a form value as a number, or 0 if it isn't set
+func formNumber(r *http.Request, name string) (float64, error) {
+	v := r.FormValue(name)
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+// apiError responds with err. The game can be busy calling the frontend,
+// in which case the client can try again.
+func apiError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, gore.ErrReentrant):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, fs.ErrNotExist):
+		status = http.StatusNotFound
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// writeJSON responds with v as JSON, or with err if it isn't nil
+func writeJSON(w http.ResponseWriter, v any, err error) {
+	if err != nil {
+		apiError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error writing JSON response: %v\n", err)
+	}
+}
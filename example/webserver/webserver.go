@@ -5,8 +5,10 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/AndreRenaud/gore"
 )
@@ -100,6 +102,45 @@ func (w *webDoomFrontend) SetTitle(title string) {
 	// This is a stub; actual window title setting would depend on the platform and windowing system.
 }
 
+// runGame creates the game itself, rather than leaving it to gore.Run, so
+// that with -api the server can use it while it runs. With -step, the server
+// advances it with Step at 35Hz rather than the game following the wall
+// clock itself.
+func runGame(mux *http.ServeMux, frontend gore.DoomFrontend, args []string) error {
+	// The API keeps saved games in memory, to be fetched and replaced
+	// through it
+	opts := gore.Options{Args: args}
+	var api *apiFrontend
+	if slices.Contains(args, "-api") {
+		api = &apiFrontend{DoomFrontend: frontend}
+		frontend = api
+		opts.SaveStore = gore.NewMemorySaveStore()
+	}
+	game, err := gore.New(frontend, opts)
+	if err != nil {
+		return err
+	}
+	defer game.Close()
+	if api != nil {
+		serveAPI(mux, game, api, opts.SaveStore)
+	}
+	if !slices.Contains(args, "-step") {
+		return game.Run()
+	}
+	ticker := time.NewTicker(time.Second / gore.TICRATE)
+	defer ticker.Stop()
+	for range ticker.C {
+		frame, err := game.Step()
+		if err != nil {
+			return err
+		}
+		if frame.Exited {
+			break
+		}
+	}
+	return nil
+}
+
 func main() {
 	frontend := &webDoomFrontend{}
 
@@ -130,7 +171,19 @@ func main() {
 	// Called explicitly here to ensure `deadcode` check doesn't impact us
 	gore.SetVirtualFileSystem(os.DirFS("."))
 
+	// -api serves the game's API, and -step has the server advance the
+	// game; Doom itself ignores both
+	args := os.Args[1:]
+	if slices.Contains(args, "-api") || slices.Contains(args, "-step") {
+		if err := runGame(mux, frontend, args); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	defer gore.Stop()
 
-	gore.Run(frontend, os.Args[1:])
+	if err := gore.Run(frontend, args); err != nil {
+		log.Fatal(err)
+	}
 }
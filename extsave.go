@@ -0,0 +1,138 @@
+package gore
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// The extended savegame format, for games which Vanilla Doom couldn't load
+// anyway: ones played with PWADs, or too big for its savegame buffer. It is
+// the Vanilla format with its own version string, and the PWADs which were
+// loaded (with their SHA-1 checksums) after the header, so that a game is
+// only ever loaded with the WADs it was saved with. There is no size limit.
+
+// SAVEGAME_EXTVERSION is the version of the extended format, which is
+// increased whenever it changes
+const SAVEGAME_EXTVERSION = 1
+
+// WADFile is a PWAD recorded in a savegame
+type WADFile struct {
+	Name string // Base name of the file, ie: "sigil.wad"
+	SHA1 [sha1.Size]byte
+}
+
+func (w WADFile) String() string {
+	return fmt.Sprintf("%s (%s)", w.Name, hex.EncodeToString(w.SHA1[:4]))
+}
+
+// WADMismatchError is returned when loading a savegame which was saved with
+// different PWADs to the ones loaded, as the game would desync
+type WADMismatchError struct {
+	Saved  []WADFile // The PWADs the game was saved with
+	Loaded []WADFile // The PWADs which are loaded
+}
+
+func (e *WADMismatchError) Error() string {
+	list := func(wads []WADFile) string {
+		if len(wads) == 0 {
+			return "none"
+		}
+		names := make([]string, len(wads))
+		for i, wad := range wads {
+			names[i] = wad.String()
+		}
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("gore: savegame needs PWADs %s, but %s loaded", list(e.Saved), list(e.Loaded))
+}
+
+// pwad_files are the PWADs loaded with -file, in order
+var pwad_files []WADFile
+
+// w_AddPWAD adds a PWAD given on the command line, recording its checksum
+// for savegames
+func w_AddPWAD(filename string) {
+	handle := w_AddFile(filename)
+	if handle == nil {
+		return
+	}
+	wad := WADFile{Name: filepath.Base(filename)}
+	sha := sha1.New()
+	stat, err := handle.Stat()
+	if err == nil {
+		_, err = io.Copy(sha, io.NewSectionReader(handle.(io.ReaderAt), 0, stat.Size()))
+	}
+	if err != nil {
+		i_Error("w_AddPWAD: error reading %s: %v", filename, err)
+	}
+	copy(wad.SHA1[:], sha.Sum(nil))
+	pwad_files = append(pwad_files, wad)
+}
+
+// p_ExtendedVersion returns the version string of extended savegames
+func p_ExtendedVersion() string {
+	return fmt.Sprintf("gore save %d", SAVEGAME_EXTVERSION)
+}
+
+// p_WriteSaveGamePWADs writes the list of PWADs after an extended header
+func p_WriteSaveGamePWADs() {
+	saveg_write8(uint8(len(pwad_files)))
+	for _, wad := range pwad_files {
+		name := wad.Name[:min(len(wad.Name), 255)]
+		saveg_write8(uint8(len(name)))
+		for i := range len(name) {
+			saveg_write8(name[i])
+		}
+		for _, b := range wad.SHA1 {
+			saveg_write8(b)
+		}
+	}
+}
+
+// p_ReadSaveGamePWADs reads the list of PWADs from an extended header, and
+// checks they're the ones loaded
+func p_ReadSaveGamePWADs() error {
+	saved := make([]WADFile, saveg_read8())
+	for i := range saved {
+		name := make([]byte, saveg_read8())
+		for j := range name {
+			name[j] = saveg_read8()
+		}
+		saved[i].Name = string(name)
+		for j := range saved[i].SHA1 {
+			saved[i].SHA1[j] = saveg_read8()
+		}
+	}
+	// Only the contents matter, not what the files are called
+	match := len(saved) == len(pwad_files)
+	for i := 0; match && i < len(saved); i++ {
+		match = saved[i].SHA1 == pwad_files[i].SHA1
+	}
+	if !match {
+		return &WADMismatchError{Saved: saved, Loaded: slices.Clone(pwad_files)}
+	}
+	return nil
+}
+
+// g_WriteSaveGame returns the current level as a savegame. It is in the
+// Vanilla format if Vanilla Doom could load it, and the extended format if
+// there are PWADs loaded, or it is over the Vanilla size limit (unless
+// vanilla_savegame_limit is turned off), in which case oversize is set.
+func g_WriteSaveGame(description string) (data []byte, oversize bool, err error) {
+	if len(pwad_files) > 255 {
+		return nil, false, fmt.Errorf("gore: can't save with %d PWADs loaded, the most is 255", len(pwad_files))
+	}
+	extended := len(pwad_files) > 0
+	data = g_WriteSaveGameFormat(description, extended)
+	if !extended && vanilla_savegame_limit != 0 && len(data) > SAVEGAMESIZE {
+		log.Printf("g_WriteSaveGame: savegame is %d bytes, over the Vanilla limit, so using the extended format", len(data))
+		return g_WriteSaveGameFormat(description, true), true, nil
+	}
+	return data, false, nil
+}
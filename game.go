@@ -0,0 +1,401 @@
+package gore
+
+//go:generate go run gen_engine_state.go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The engine itself still works on package level variables. Each Game keeps
+// its own copy of them in an engineState, and swaps it in whenever it needs to
+// run. engineLock serialises this, so multiple games can run concurrently from
+// different goroutines, but only one of them is ever executing engine code at
+// a time.
+var (
+	engineLock      sync.Mutex
+	engineCallback  atomic.Bool  // Set while the engine is in a callback
+	engineCurrent   *Game        // Game whose state is currently loaded
+	enginePristine  engineState  // State prior to any game being started
+	enginePristined sync.Once    // Guards the capture of enginePristine
+	defaultVFS      fs.FS        = os.DirFS(".")
+	runGame         atomic.Value // *Game started by the package level Run
+)
+
+// ErrClosed is returned when trying to run a game which has been closed
+var ErrClosed = errors.New("gore: game closed")
+
+// ErrReentrant is returned by a Game's methods when they are called while a
+// game is calling one of the frontend's methods, a SaveStore or a frame hook,
+// as calling them from there would otherwise deadlock. A call from another
+// goroutine which happens to coincide with one gets it too, and can be
+// retried. Stop is the only method which can be called from a callback.
+var ErrReentrant = errors.New("gore: game method called from within a running game")
+
+// ErrNoLevel is returned when asking for the current level while one isn't
+// being played, ie: before one has been loaded
+var ErrNoLevel = errors.New("gore: no level loaded")
+
+// Options controls how a Game is created
+type Options struct {
+	// Args are the command line arguments, as they would be given to the
+	// original doom binary (ie: -iwad doom1.wad)
+	Args []string
+	// FS is the file system WAD, config & save files are read from. If nil,
+	// the one set by SetVirtualFileSystem is used.
+	FS fs.FS
+	// SaveStore is where games are saved to and loaded from. If nil, they
+	// are kept as files in the .savegame directory.
+	SaveStore SaveStore
+	// FullSpeed runs the game as fast as possible, advancing the clock by one
+	// tick per frame rather than following the wall clock.
+	FullSpeed bool
+	// Buffers enables rendering the auxiliary FrameBuffers, which are
+	// returned by Step. They are also enabled if the frontend implements
+	// DoomBuffersFrontend.
+	Buffers bool
+	// Paletted skips converting each frame to RGBA, so Step only returns
+	// Frame.Paletted and DrawFrame isn't called. It is also enabled if the
+	// frontend implements DoomPalettedFrontend.
+	Paletted bool
+	// Width and Height are the size of the screen to render, from 320x200
+	// up to 3840x2400. The 3D view is drawn at the full resolution, whilst
+	// the status bar, menus and other graphics are scaled up from their
+	// original 320x200 layout. Zero uses the original 320x200, widened in
+	// widescreen. The -width and -height command line parameters override
+	// these.
+	Width, Height int
+	// Aspect is the aspect ratio the screen is displayed at. The original is
+	// 4:3, stretching the pixels vertically. In widescreen the status bar,
+	// menus and other graphics are centered, and the 3D view's field of view
+	// is widened. The -aspect command line parameter overrides this.
+	Aspect Aspect
+	// Uncapped draws a frame every time the frontend is ready for one,
+	// rather than only after each 35Hz tic, with everything moved part way
+	// between the last tic and the next. The game itself still runs at 35Hz,
+	// so it makes no difference to demos. DrawFrame is called as fast as
+	// possible, so should wait for the display to be ready. It has no effect
+	// on Step or with FullSpeed. The -uncapped command line parameter also
+	// enables it.
+	Uncapped bool
+
+	// Used in the test suite to stop the demo running in the background
+	dontRunDemo bool
+}
+
+// Game is a single instance of the Doom engine
+type Game struct {
+	state    engineState
+	frontend DoomFrontend
+	stopped  atomic.Bool
+	steps    uint64 // Number of calls to Step
+	err      error  // Fatal error raised by the engine
+	closed   bool
+}
+
+// Frame is the result of advancing a game with Step
+type Frame struct {
+	// Image is the rendered screen, as also passed to DrawFrame. It is
+	// overwritten by the next call to Step. It is nil if Options.Paletted is
+	// set, or the frontend implements DoomPalettedFrontend.
+	Image *image.RGBA
+	// Paletted is the rendered screen before it is converted to RGBA, with
+	// the current palette. It is the screen the engine draws to, so is also
+	// overwritten by the next call to Step.
+	Paletted *image.Paletted
+	// Tic is the number of game tics which have run so far
+	Tic int
+	// Buffers are the auxiliary buffers for the frame, if they are enabled.
+	// They are also overwritten by the next call to Step.
+	Buffers *FrameBuffers
+	// Exited is set once the player has quit the game
+	Exited bool
+}
+
+// New creates a game which renders to, and reads input from, fg. The WAD
+// files are loaded and the engine is initialised, but no tics are run until
+// Run is called.
+func New(fg DoomFrontend, opts Options) (*Game, error) {
+	if fg == nil {
+		return nil, errors.New("gore: nil frontend")
+	}
+	g := &Game{frontend: fg}
+
+	enginePristined.Do(func() {
+		engineLock.Lock()
+		defer engineLock.Unlock()
+		enginePristine.save()
+	})
+	g.state = enginePristine
+	if err := g.start(opts); err != nil {
+		g.Close()
+		return nil, err
+	}
+	return g, nil
+}
+
+// start initialises the engine, ready to run the first tic
+func (g *Game) start(opts Options) (err error) {
+	if err := g.lock(); err != nil {
+		return err
+	}
+	defer g.unlock()
+	defer g.catch(&err)
+	vfs = opts.FS
+	if vfs == nil {
+		vfs = defaultVFS
+	}
+	save_store = opts.SaveStore
+	dg_frontend = g.frontend
+	dg_run_full_speed = opts.FullSpeed
+	aux_enabled = opts.Buffers
+	pal_enabled = opts.Paletted
+	interp_enabled = opts.Uncapped
+	if err := i_SetResolution(opts.Width, opts.Height, opts.Aspect); err != nil {
+		return err
+	}
+	dont_run_demo = opts.dontRunDemo
+	start_time = time.Now()
+
+	args := append([]string{"doom"}, opts.Args...) // prepend "doom" as argv[0]
+	doomgeneric_Create(args)
+	return nil
+}
+
+// catch recovers from an EngineError raised by i_Error, or a runtime panic
+// in the engine, and stores it in err. Once this has happened the game can't
+// be run again. Other panics, such as from the frontend, are passed on.
+func (g *Game) catch(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	var engineErr *EngineError
+	switch r := r.(type) {
+	case *EngineError:
+		engineErr = r
+	case runtime.Error:
+		engineErr = newRuntimeEngineError(r)
+	}
+	if engineErr == nil {
+		panic(r)
+	}
+	g.err = engineErr
+	*err = engineErr
+}
+
+// lock waits until no other game is running, and then loads the engine state
+// for g. It returns ErrReentrant rather than waiting forever if the engine is
+// in a callback, which may be what is calling.
+func (g *Game) lock() error {
+	if !engineLock.TryLock() {
+		if engineCallback.Load() {
+			return ErrReentrant
+		}
+		engineLock.Lock()
+	}
+	if engineCurrent == g {
+		return nil
+	}
+	if engineCurrent != nil {
+		engineCurrent.state.save()
+	}
+	g.state.load()
+	engineCurrent = g
+	return nil
+}
+
+func (g *Game) unlock() {
+	engineLock.Unlock()
+}
+
+// i_Callback calls f, which calls out of the engine to the frontend, a
+// SaveStore, a frame hook or a demo's writer, flagging that the engine is
+// in a callback for lock
+func i_Callback(f func()) {
+	engineCallback.Store(true)
+	defer engineCallback.Store(false)
+	f()
+}
+
+// Run runs the game until either Stop is called, or the player quits. If the
+// engine hits a fatal error, it is returned as an *EngineError.
+func (g *Game) Run() error {
+	return g.RunContext(context.Background())
+}
+
+// RunContext runs the game until either ctx is cancelled, Stop is called, or
+// the player quits. The game is closed before it returns. If ctx was
+// cancelled, its error is returned.
+func (g *Game) RunContext(ctx context.Context) (err error) {
+	defer func() {
+		if closeErr := g.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	for !g.stopped.Load() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		exiting, err := g.tick()
+		if err != nil {
+			return err
+		}
+		if exiting {
+			break
+		}
+	}
+	return nil
+}
+
+// Close shuts the game down, and releases the WAD files it has open. If the
+// player hasn't already quit, the engine's exit handlers are run first (ie:
+// to save the config file). Run and RunContext close the game before they
+// return, so this is only needed for games driven by Step.
+func (g *Game) Close() (err error) {
+	if err := g.lock(); err != nil {
+		return err
+	}
+	defer g.unlock()
+	if g.closed {
+		return nil
+	}
+	g.closed = true
+	defer func() {
+		w_CloseFiles()
+		// Nothing from here on needs the state, so let it be collected
+		g.state = engineState{}
+		engineCurrent = nil
+	}()
+	if g.err == nil && !dg_exiting {
+		defer g.catch(&err)
+		i_Quit()
+	}
+	// Any demo being recorded by RecordDemo was written out by i_Quit
+	return demo_err
+}
+
+// tick runs a single iteration of the main loop, returning whether the engine
+// has exited
+func (g *Game) tick() (exiting bool, err error) {
+	if err := g.lock(); err != nil {
+		return true, err
+	}
+	defer g.unlock()
+	if g.closed {
+		return true, ErrClosed
+	}
+	if g.err != nil {
+		return true, g.err
+	}
+	defer g.catch(&err)
+	doomgeneric_Tick()
+	return dg_exiting, nil
+}
+
+// Step advances the game by exactly one tic and renders the result. The
+// events are processed before the tic runs, in addition to any reported by
+// the frontend's GetEvent.
+//
+// Once Step has been called the game no longer follows the wall clock; time
+// only advances by one tic per call, so the same sequence of events always
+// produces the same result.
+func (g *Game) Step(events ...DoomEvent) (frame Frame, err error) {
+	if err := g.lock(); err != nil {
+		return Frame{}, err
+	}
+	defer g.unlock()
+	if g.closed {
+		return Frame{Exited: true}, ErrClosed
+	}
+	if g.err != nil {
+		return Frame{Exited: true}, g.err
+	}
+	defer g.catch(&err)
+	singletics = 1
+	dg_run_full_speed = true
+	g.steps++
+	// Keep the clock in step with the tics, for the parts of the engine
+	// (ie: menus, screen wipes) which rely on it
+	if now := g.steps * 1000 / TICRATE; dg_fake_tics < now {
+		dg_fake_tics = now
+	}
+	for i := range events {
+		i_PostDoomEvent(&events[i])
+	}
+	doomgeneric_Tick()
+	img := DG_ScreenBuffer
+	if pal_enabled {
+		img = nil
+	}
+	return Frame{
+		Image:    img,
+		Paletted: pal_screen,
+		Tic:      int(gametic),
+		Buffers:  aux_buffers,
+		Exited:   dg_exiting,
+	}, nil
+}
+
+// NewGame starts a new game on the given episode (always 1 for Doom II), map
+// and skill (from 0 to 4), as if it had been chosen from the menu. Any demo
+// being played is stopped. The level is loaded by the next tic.
+func (g *Game) NewGame(episode, mapNum, skill int) error {
+	if err := g.lock(); err != nil {
+		return err
+	}
+	defer g.unlock()
+	if g.closed {
+		return ErrClosed
+	}
+	if g.err != nil {
+		return g.err
+	}
+	if skill < int(sk_baby) || skill > int(sk_nightmare) {
+		return fmt.Errorf("gore: invalid skill %d", skill)
+	}
+	if name := mapLumpName(int32(episode), int32(mapNum)); w_CheckNumForName(name) < 0 {
+		return fmt.Errorf("gore: map %s not found", name)
+	}
+	g_DeferedInitNew(skill_t(skill), int32(episode), int32(mapNum))
+	return nil
+}
+
+// Stop causes Run to return once the current tic has completed. It is safe to
+// call from any goroutine, including from within the frontend callbacks.
+func (g *Game) Stop() {
+	g.stopped.Store(true)
+}
+
+// Run creates a new game with the default options and runs it until either
+// Stop is called, or the player quits
+func Run(fg DoomFrontend, args []string) error {
+	return RunContext(context.Background(), fg, args)
+}
+
+// RunContext creates a new game with the default options and runs it until
+// either ctx is cancelled, Stop is called, or the player quits. Once it has
+// returned, it can be called again to start a fresh game.
+func RunContext(ctx context.Context, fg DoomFrontend, args []string) error {
+	g, err := New(fg, Options{Args: args})
+	if err != nil {
+		return err
+	}
+	runGame.Store(g)
+	return g.RunContext(ctx)
+}
+
+// Stop stops the game most recently started by Run
+func Stop() {
+	if g, ok := runGame.Load().(*Game); ok {
+		g.Stop()
+	}
+}
@@ -0,0 +1,117 @@
+//go:build ignore
+
+// gen_engine_state generates engine_state.go, which contains a copy of every
+// package level engine variable, along with the functions to save and restore
+// them. This is what allows multiple Game instances to share the package level
+// engine state.
+//
+// Run via `go generate` after adding or removing package level variables.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const outputFile = "engine_state.go"
+
+// Files whose variables are shared between all games, rather than being part
+// of the engine state
+var sharedFiles = map[string]bool{
+	"errors.go": true,
+	"game.go":   true,
+}
+
+func main() {
+	fset := token.NewFileSet()
+	matches, err := filepath.Glob("*.go")
+	if err != nil {
+		log.Fatal(err)
+	}
+	var files []*ast.File
+	for _, name := range matches {
+		if name == outputFile || strings.HasSuffix(name, "_test.go") || strings.HasPrefix(name, "gen_") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			log.Fatal(err)
+		}
+		files = append(files, f)
+	}
+
+	// The generated file is excluded, so references to engineState will fail
+	// to resolve. We only care about the variable types, so ignore errors.
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+	pkg, _ := conf.Check("gore", fset, files, nil)
+
+	var vars []*types.Var
+	for _, name := range pkg.Scope().Names() {
+		v, ok := pkg.Scope().Lookup(name).(*types.Var)
+		if !ok || sharedFiles[filepath.Base(fset.Position(v.Pos()).Filename)] {
+			continue
+		}
+		vars = append(vars, v)
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Pos() < vars[j].Pos() })
+
+	imports := map[string]bool{}
+	qualifier := func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		imports[p.Path()] = true
+		return p.Name()
+	}
+
+	var fields, save, load bytes.Buffer
+	for _, v := range vars {
+		fmt.Fprintf(&fields, "\t%s %s\n", v.Name(), types.TypeString(v.Type(), qualifier))
+		fmt.Fprintf(&save, "\ts.%s = %s\n", v.Name(), v.Name())
+		fmt.Fprintf(&load, "\t%s = s.%s\n", v.Name(), v.Name())
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by gen_engine_state.go; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package gore\n\n")
+	if len(imports) > 0 {
+		var paths []string
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		fmt.Fprintf(&out, "import (\n")
+		for _, path := range paths {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+		fmt.Fprintf(&out, ")\n\n")
+	}
+	fmt.Fprintf(&out, "// engineState is a copy of all of the package level engine variables\n")
+	fmt.Fprintf(&out, "type engineState struct {\n%s}\n\n", fields.Bytes())
+	fmt.Fprintf(&out, "// save copies the current package level engine variables into s\n")
+	fmt.Fprintf(&out, "func (s *engineState) save() {\n%s}\n\n", save.Bytes())
+	fmt.Fprintf(&out, "// load replaces the package level engine variables with the contents of s\n")
+	fmt.Fprintf(&out, "func (s *engineState) load() {\n%s}\n", load.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Fatalf("formatting generated code: %v", err)
+	}
+	if err := os.WriteFile(outputFile, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
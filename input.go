@@ -0,0 +1,124 @@
+package gore
+
+import "math"
+
+// Injecting input directly into a game, for bots and scripted tests, rather
+// than going through the frontend's GetEvent.
+
+// TicButtons are the buttons held down during a tic, for SetTicCommand
+type TicButtons uint8
+
+const (
+	ButtonAttack TicButtons = bt_ATTACK // Fire the current weapon
+	ButtonUse    TicButtons = bt_USE    // Open doors and press switches
+)
+
+// ButtonChangeWeapon returns the buttons to switch to the given weapon. As
+// with the number keys, the super shotgun shares the shotgun's slot, so
+// switches between the two.
+func ButtonChangeWeapon(w Weapon) TicButtons {
+	if w == WeaponSuperShotgun {
+		w = WeaponShotgun
+	}
+	return TicButtons(bt_CHANGE | int(w)<<bt_WEAPONSHIFT&bt_WEAPONMASK)
+}
+
+// tic_override replaces the movement and buttons of the next ticcmd built,
+// if tic_override_set is set
+var tic_override ticcmd_t
+var tic_override_set bool
+
+// PressKey queues a key down event, using the same doom key codes as
+// DoomEvent.Key. The key stays down until ReleaseKey is called.
+func (g *Game) PressKey(key uint8) error {
+	return g.postEvent(&DoomEvent{Type: Ev_keydown, Key: key})
+}
+
+// ReleaseKey queues a key up event for a key previously pressed
+func (g *Game) ReleaseKey(key uint8) error {
+	return g.postEvent(&DoomEvent{Type: Ev_keyup, Key: key})
+}
+
+// MoveMouse queues a relative mouse movement, in the same units as the
+// original engine. Positive dx turns right, and positive dy moves forward.
+// The mouse sensitivity setting is applied as usual.
+func (g *Game) MoveMouse(dx, dy int) error {
+	if err := g.lock(); err != nil {
+		return err
+	}
+	defer g.unlock()
+	if err := g.inputError(); err != nil {
+		return err
+	}
+	var buttons int32
+	for i := range int32(MAX_MOUSE_BUTTONS) {
+		if mouseButton(i) {
+			buttons |= 1 << i
+		}
+	}
+	d_PostEvent(&event_t{
+		Ftype1: Ev_mouse,
+		Fdata1: buttons,
+		Fdata2: int32(dx),
+		Fdata3: int32(dy),
+	})
+	return nil
+}
+
+// SetTicCommand sets the movement and buttons for the next tic the player
+// runs, replacing whatever the keyboard and mouse would have produced.
+// forward and side are the distance to move, from -50 to 50 (running speed)
+// with positive values moving forward and to the right; walking speed is 25
+// forward and 24 sideways. turn is in degrees, anticlockwise.
+//
+// It only applies to a single tic, so needs calling before each Step.
+func (g *Game) SetTicCommand(forward, side int, turn float64, buttons TicButtons) error {
+	if err := g.lock(); err != nil {
+		return err
+	}
+	defer g.unlock()
+	if err := g.inputError(); err != nil {
+		return err
+	}
+	maxMove := int(forwardmove[1])
+	tic_override = ticcmd_t{
+		Fforwardmove: int8(max(-maxMove, min(maxMove, forward))),
+		Fsidemove:    int8(max(-maxMove, min(maxMove, side))),
+		Fangleturn:   int16(max(math.MinInt16, min(math.MaxInt16, math.Round(turn*(1<<16)/360)))),
+		Fbuttons:     uint8(buttons),
+	}
+	tic_override_set = true
+	return nil
+}
+
+func (g *Game) postEvent(event *DoomEvent) error {
+	if err := g.lock(); err != nil {
+		return err
+	}
+	defer g.unlock()
+	if err := g.inputError(); err != nil {
+		return err
+	}
+	i_PostDoomEvent(event)
+	return nil
+}
+
+func (g *Game) inputError() error {
+	if g.closed {
+		return ErrClosed
+	}
+	return g.err
+}
+
+// g_ApplyTicOverride replaces the movement and buttons of cmd with those
+// from SetTicCommand, if there are any
+func g_ApplyTicOverride(cmd *ticcmd_t) {
+	if !tic_override_set {
+		return
+	}
+	tic_override_set = false
+	cmd.Fforwardmove = tic_override.Fforwardmove
+	cmd.Fsidemove = tic_override.Fsidemove
+	cmd.Fangleturn = tic_override.Fangleturn
+	cmd.Fbuttons = tic_override.Fbuttons
+}
@@ -0,0 +1,132 @@
+package gore
+
+// Rendering frames in between tics, for displays faster than the 35Hz the
+// game runs at. The position of everything at the start of each tic is
+// recorded, and each frame is drawn with objects, the view and sector
+// heights part way between there and where they are now. The interpolated
+// values are only swapped in while drawing, so the game itself runs exactly
+// as before.
+
+// interp_enabled renders a frame every time around the main loop, rather
+// than only after a tic has run
+var interp_enabled bool
+
+// interp_tic is the tic the starting positions were recorded for
+var interp_tic int32 = -1
+
+// interp_active is set while the interpolated values are swapped in, with
+// the real ones saved in interp_savedmobjs etc
+var interp_active bool
+
+type interp_savedmobj_t struct {
+	Fmo      *mobj_t
+	Fx, Fy   fixed_t
+	Fz       fixed_t
+	Fangle   angle_t
+	Fviewz   fixed_t
+	Fplayerz bool // Fviewz is set
+}
+
+var interp_savedmobjs []interp_savedmobj_t
+var interp_savedsectors []fixed_t // Floor and ceiling of each sector
+
+// i_CheckUncappedParm applies the -uncapped command line parameter
+func i_CheckUncappedParm() {
+	//!
+	// Render frames in between tics, rather than 35 times a second.
+	//
+	if m_CheckParm("-uncapped") != 0 {
+		interp_enabled = true
+	}
+}
+
+// p_RecordInterpolation records the positions at the start of a tic, for
+// drawing frames between it and the next
+func p_RecordInterpolation() {
+	if !interp_enabled {
+		return
+	}
+	interp_tic = gametic
+	for th := thinkercap.Fnext; th != nil && th != &thinkercap; th = th.Fnext {
+		if mo, ok := th.Ffunction.(*mobj_t); ok {
+			mo.Foldx = mo.Fx
+			mo.Foldy = mo.Fy
+			mo.Foldz = mo.Fz
+			mo.Foldangle = mo.Fangle
+			mo.Finterpolate = 1
+		}
+	}
+	for i := range sectors {
+		sec := &sectors[i]
+		sec.Foldfloorheight = sec.Ffloorheight
+		sec.Foldceilingheight = sec.Fceilingheight
+	}
+	for i := range players {
+		players[i].Foldviewz = players[i].Fviewz
+	}
+}
+
+// r_InterpolationFrac returns how far through the current tic the clock is
+func r_InterpolationFrac() fixed_t {
+	// Only the fraction of a second matters, and multiplying the whole time
+	// by TICRATE overflows after 17 hours
+	ms := uint32(I_GetTimeMS()) % 1000
+	return fixed_t(ms * TICRATE % 1000 * (1 << FRACBITS) / 1000)
+}
+
+// r_BeginInterpolation swaps in the positions frac of the way from the start
+// of the last tic to now, if there is a frame to draw in between
+func r_BeginInterpolation(frac fixed_t) {
+	if !interp_enabled || gamestate != gs_LEVEL || interp_tic != gametic-1 {
+		return
+	}
+	lerp := func(old, cur fixed_t) fixed_t {
+		return old + fixedMul(cur-old, frac)
+	}
+	interp_active = true
+	interp_savedmobjs = interp_savedmobjs[:0]
+	for th := thinkercap.Fnext; th != nil && th != &thinkercap; th = th.Fnext {
+		mo, ok := th.Ffunction.(*mobj_t)
+		if !ok || mo.Finterpolate == 0 {
+			continue
+		}
+		saved := interp_savedmobj_t{Fmo: mo, Fx: mo.Fx, Fy: mo.Fy, Fz: mo.Fz, Fangle: mo.Fangle}
+		if mo.Fplayer != nil {
+			saved.Fviewz = mo.Fplayer.Fviewz
+			saved.Fplayerz = true
+			mo.Fplayer.Fviewz = lerp(mo.Fplayer.Foldviewz, mo.Fplayer.Fviewz)
+		}
+		interp_savedmobjs = append(interp_savedmobjs, saved)
+		mo.Fx = lerp(mo.Foldx, mo.Fx)
+		mo.Fy = lerp(mo.Foldy, mo.Fy)
+		mo.Fz = lerp(mo.Foldz, mo.Fz)
+		// Turn the shortest way round
+		mo.Fangle = mo.Foldangle + angle_t(fixedMul(int32(mo.Fangle-mo.Foldangle), frac))
+	}
+	interp_savedsectors = interp_savedsectors[:0]
+	for i := range sectors {
+		sec := &sectors[i]
+		interp_savedsectors = append(interp_savedsectors, sec.Ffloorheight, sec.Fceilingheight)
+		sec.Ffloorheight = lerp(sec.Foldfloorheight, sec.Ffloorheight)
+		sec.Fceilingheight = lerp(sec.Foldceilingheight, sec.Fceilingheight)
+	}
+}
+
+// r_EndInterpolation puts back the real positions after drawing
+func r_EndInterpolation() {
+	if !interp_active {
+		return
+	}
+	interp_active = false
+	for _, saved := range interp_savedmobjs {
+		mo := saved.Fmo
+		mo.Fx, mo.Fy, mo.Fz, mo.Fangle = saved.Fx, saved.Fy, saved.Fz, saved.Fangle
+		if saved.Fplayerz {
+			mo.Fplayer.Fviewz = saved.Fviewz
+		}
+	}
+	for i := range sectors {
+		sectors[i].Ffloorheight = interp_savedsectors[i*2]
+		sectors[i].Fceilingheight = interp_savedsectors[i*2+1]
+	}
+}
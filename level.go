@@ -0,0 +1,310 @@
+package gore
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Snapshots of the geometry of the current level, for mini-maps,
+// pathfinding and level analysis tools.
+
+// LineFlags are the linedef flags, as stored in the map
+type LineFlags uint16
+
+const (
+	LineBlocking      LineFlags = ml_BLOCKING      // Blocks players and monsters
+	LineBlockMonsters LineFlags = ml_BLOCKMONSTERS // Blocks monsters only
+	LineTwoSided      LineFlags = ml_TWOSIDED      // Has a back side
+	LineDontPegTop    LineFlags = ml_DONTPEGTOP    // Upper texture is unpegged
+	LineDontPegBottom LineFlags = ml_DONTPEGBOTTOM // Lower texture is unpegged
+	LineSecret        LineFlags = ml_SECRET        // Drawn as one sided on the automap
+	LineSoundBlock    LineFlags = ml_SOUNDBLOCK    // Blocks sound
+	LineDontDraw      LineFlags = ml_DONTDRAW      // Never drawn on the automap
+	LineMapped        LineFlags = ml_MAPPED        // Has been seen, so is drawn on the automap
+)
+
+// NodeSubsector is set in a Node's child when it is a subsector rather than
+// another node
+const NodeSubsector = NF_SUBSECTOR1
+
+// Vertex is a point in the map, in map units
+type Vertex struct {
+	X, Y float64
+}
+
+// Line is a linedef, a wall or boundary between two sectors
+type Line struct {
+	V1, V2  int // Indexes into Level.Vertices
+	Flags   LineFlags
+	Special int // Action triggered by the line, or 0
+	Tag     int // Sectors with the same tag are affected by the special
+	// Front and Back are indexes into Level.Sides, or -1 if there isn't one
+	Front, Back int
+}
+
+// Side is a sidedef, the textures of one side of a linedef
+type Side struct {
+	XOffset, YOffset float64
+	// Texture names, or "" if there isn't one
+	Top, Bottom, Middle string
+	Sector              int // Index into Level.Sectors of the sector it faces
+}
+
+// Sector is an area of the map with a single floor and ceiling height.
+// Heights reflect any doors, lifts or crushers which are currently moving.
+type Sector struct {
+	Floor, Ceiling         float64 // Heights, in map units
+	FloorFlat, CeilingFlat string  // Flat names, ie: "FLOOR4_8" or "F_SKY1"
+	Light                  int     // Light level, from 0 to 255
+	Special                int     // Damage, light effect or secret, or 0
+	Tag                    int
+	Lines                  []int // Indexes into Level.Lines of the lines bordering the sector
+}
+
+// Thing is an object placed in the map by its author. Things which weren't
+// spawned, such as those for other skill levels, are included.
+type Thing struct {
+	X, Y  float64
+	Angle int // Direction faced, in degrees anticlockwise from east
+	Type  int // The editor number, ie: 1 for the player 1 start
+	Flags int // Skill levels, deaf and multiplayer only flags
+}
+
+// Seg is the part of a linedef which lies within a subsector
+type Seg struct {
+	V1, V2 int // Indexes into Level.Vertices
+	Line   int // Index into Level.Lines
+	Side   int // 0 for the front of the line, 1 for the back
+	Offset float64
+	Angle  float64 // In degrees anticlockwise from east
+}
+
+// Subsector is a convex area of a sector, as split up by the BSP tree
+type Subsector struct {
+	Sector   int // Index into Level.Sectors
+	FirstSeg int // Index into Level.Segs
+	NumSegs  int
+}
+
+// Box is an axis aligned bounding box, in map units
+type Box struct {
+	Top, Bottom, Left, Right float64
+}
+
+// Node is a node of the BSP tree, which splits the map in two along a
+// partition line. The root of the tree is the last node.
+type Node struct {
+	X, Y, DX, DY float64 // The partition line
+	// The bounding boxes and children on the right (front) and left (back)
+	// of the partition line. Each child is an index into Level.Nodes, or if
+	// it has NodeSubsector set, into Level.Subsectors.
+	BBox     [2]Box
+	Children [2]int
+}
+
+// Blockmap divides the map into 128x128 unit blocks, each listing the lines
+// which cross it, for fast collision detection
+type Blockmap struct {
+	OriginX, OriginY float64 // Bottom left corner of the blockmap
+	Width, Height    int     // In blocks
+	// Lines holds the indexes into Level.Lines for each block, indexed by
+	// y*Width+x, as checked by the engine
+	Lines [][]int
+}
+
+// Level is a snapshot of the geometry of the current level
+type Level struct {
+	Name       string // Map lump name, ie: "E1M1" or "MAP01"
+	Vertices   []Vertex
+	Lines      []Line
+	Sides      []Side
+	Sectors    []Sector
+	Things     []Thing
+	Segs       []Seg
+	Subsectors []Subsector
+	Nodes      []Node
+	Blockmap   Blockmap
+}
+
+// level_maplump is the lump number of the current map's marker
+var level_maplump int32
+
+// Level returns a snapshot of the geometry of the current level. It returns
+// ErrNoLevel if a level isn't being played, ie: during the intermission.
+func (g *Game) Level() (Level, error) {
+	if err := g.lock(); err != nil {
+		return Level{}, err
+	}
+	defer g.unlock()
+	if g.closed {
+		return Level{}, ErrClosed
+	}
+	if g.err != nil {
+		return Level{}, g.err
+	}
+	if gamestate != gs_LEVEL {
+		return Level{}, ErrNoLevel
+	}
+	return levelSnapshot(), nil
+}
+
+// mapLumpName returns the name of the marker lump for a map, as used by
+// p_SetupLevel
+func mapLumpName(episode, mapNum int32) string {
+	if gamemode == commercial {
+		return fmt.Sprintf("MAP%02d", mapNum)
+	}
+	return fmt.Sprintf("E%dM%d", episode, mapNum)
+}
+
+func levelSnapshot() Level {
+	l := Level{
+		Name:       gostring_bytes(lumpinfo[level_maplump].Fname[:]),
+		Vertices:   make([]Vertex, len(vertexes)),
+		Lines:      make([]Line, len(lines)),
+		Sides:      make([]Side, len(sides)),
+		Sectors:    make([]Sector, len(sectors)),
+		Things:     levelThings(),
+		Segs:       make([]Seg, len(segs)),
+		Subsectors: make([]Subsector, len(subsectors)),
+		Nodes:      make([]Node, len(nodes)),
+	}
+	vertexIndex := make(map[*vertex_t]int, len(vertexes))
+	for i := range vertexes {
+		v := &vertexes[i]
+		vertexIndex[v] = i
+		l.Vertices[i] = Vertex{fixedToFloat(v.Fx), fixedToFloat(v.Fy)}
+	}
+	sectorIndex := make(map[*sector_t]int, len(sectors))
+	for i := range sectors {
+		sectorIndex[&sectors[i]] = i
+	}
+	lineIndex := make(map[*line_t]int, len(lines))
+	for i := range lines {
+		ld := &lines[i]
+		lineIndex[ld] = i
+		l.Lines[i] = Line{
+			V1:      vertexIndex[ld.Fv1],
+			V2:      vertexIndex[ld.Fv2],
+			Flags:   LineFlags(ld.Fflags),
+			Special: int(ld.Fspecial),
+			Tag:     int(ld.Ftag),
+			Front:   int(ld.Fsidenum[0]),
+			Back:    int(ld.Fsidenum[1]),
+		}
+	}
+	for i := range sides {
+		sd := &sides[i]
+		l.Sides[i] = Side{
+			XOffset: fixedToFloat(sd.Ftextureoffset),
+			YOffset: fixedToFloat(sd.Frowoffset),
+			Top:     textureName(sd.Ftoptexture),
+			Bottom:  textureName(sd.Fbottomtexture),
+			Middle:  textureName(sd.Fmidtexture),
+			Sector:  sectorIndex[sd.Fsector],
+		}
+	}
+	for i := range sectors {
+		sec := &sectors[i]
+		s := Sector{
+			Floor:       fixedToFloat(sec.Ffloorheight),
+			Ceiling:     fixedToFloat(sec.Fceilingheight),
+			FloorFlat:   flatName(sec.Ffloorpic),
+			CeilingFlat: flatName(sec.Fceilingpic),
+			Light:       int(sec.Flightlevel),
+			Special:     int(sec.Fspecial),
+			Tag:         int(sec.Ftag),
+			Lines:       make([]int, len(sec.Flines)),
+		}
+		for j, ld := range sec.Flines {
+			s.Lines[j] = lineIndex[ld]
+		}
+		l.Sectors[i] = s
+	}
+	for i := range segs {
+		seg := &segs[i]
+		s := Seg{
+			V1:     vertexIndex[seg.Fv1],
+			V2:     vertexIndex[seg.Fv2],
+			Line:   lineIndex[seg.Flinedef],
+			Offset: fixedToFloat(seg.Foffset),
+			Angle:  angleToDegrees(seg.Fangle),
+		}
+		if seg.Flinedef != nil && seg.Flinedef.Fsidenum[1] >= 0 && seg.Fsidedef == &sides[seg.Flinedef.Fsidenum[1]] {
+			s.Side = 1
+		}
+		l.Segs[i] = s
+	}
+	for i := range subsectors {
+		ss := &subsectors[i]
+		l.Subsectors[i] = Subsector{
+			Sector:   sectorIndex[ss.Fsector],
+			FirstSeg: int(ss.Ffirstline),
+			NumSegs:  int(ss.Fnumlines),
+		}
+	}
+	for i := range nodes {
+		n := &nodes[i]
+		l.Nodes[i] = Node{
+			X:        fixedToFloat(n.Fx),
+			Y:        fixedToFloat(n.Fy),
+			DX:       fixedToFloat(n.Fdx),
+			DY:       fixedToFloat(n.Fdy),
+			BBox:     [2]Box{boxToFloat(n.Fbbox[0]), boxToFloat(n.Fbbox[1])},
+			Children: [2]int{int(n.Fchildren[0]), int(n.Fchildren[1])},
+		}
+	}
+	l.Blockmap = Blockmap{
+		OriginX: fixedToFloat(bmaporgx),
+		OriginY: fixedToFloat(bmaporgy),
+		Width:   int(bmapwidth),
+		Height:  int(bmapheight),
+		Lines:   make([][]int, bmapwidth*bmapheight),
+	}
+	for i := range l.Blockmap.Lines {
+		for pos := int32(blockmap[i]); blockmaplump[pos] != -1; pos++ {
+			l.Blockmap.Lines[i] = append(l.Blockmap.Lines[i], int(blockmaplump[pos]))
+		}
+	}
+	return l
+}
+
+// levelThings reads the things from the map, as they aren't kept once
+// they've been spawned
+func levelThings() []Thing {
+	const thingSize = 10
+	data := w_CacheLumpNumBytes(level_maplump + ml_THINGS)
+	things := make([]Thing, len(data)/thingSize)
+	for i := range things {
+		mt := data[i*thingSize:]
+		things[i] = Thing{
+			X:     float64(int16(binary.LittleEndian.Uint16(mt[0:]))),
+			Y:     float64(int16(binary.LittleEndian.Uint16(mt[2:]))),
+			Angle: int(int16(binary.LittleEndian.Uint16(mt[4:]))),
+			Type:  int(int16(binary.LittleEndian.Uint16(mt[6:]))),
+			Flags: int(int16(binary.LittleEndian.Uint16(mt[8:]))),
+		}
+	}
+	return things
+}
+
+// textureName returns the name of a wall texture, or "" for none
+func textureName(tex int16) string {
+	if tex <= 0 || int(tex) >= len(textures) {
+		return ""
+	}
+	return gostring_bytes(textures[tex].Fname[:])
+}
+
+func flatName(flat int16) string {
+	return gostring_bytes(lumpinfo[firstflat+int32(flat)].Fname[:])
+}
+
+func boxToFloat(b box_t) Box {
+	return Box{
+		Top:    fixedToFloat(b[BOXTOP]),
+		Bottom: fixedToFloat(b[BOXBOTTOM]),
+		Left:   fixedToFloat(b[BOXLEFT]),
+		Right:  fixedToFloat(b[BOXRIGHT]),
+	}
+}
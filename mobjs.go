@@ -0,0 +1,185 @@
+package gore
+
+import "iter"
+
+// Snapshots of the map objects (monsters, items, projectiles, decorations
+// and players) in the level.
+
+// MobjFlags are the mobj_t flags, describing an object's behaviour
+type MobjFlags uint32
+
+const (
+	MobjSpecial       MobjFlags = mf_SPECIAL      // Can be picked up
+	MobjSolid         MobjFlags = mf_SOLID        // Blocks movement
+	MobjShootable     MobjFlags = mf_SHOOTABLE    // Can be hit
+	MobjNoSector      MobjFlags = mf_NOSECTOR     // Not drawn, but can be touched
+	MobjNoBlockmap    MobjFlags = mf_NOBLOCKMAP   // Can't be touched
+	MobjAmbush        MobjFlags = mf_AMBUSH       // A deaf monster
+	MobjJustHit       MobjFlags = mf_JUSTHIT      // Will try to attack right back
+	MobjJustAttacked  MobjFlags = mf_JUSTATTACKED // Will take at least one step before attacking
+	MobjSpawnCeiling  MobjFlags = mf_SPAWNCEILING // Hangs from the ceiling
+	MobjNoGravity     MobjFlags = mf_NOGRAVITY    // Doesn't fall
+	MobjDropOff       MobjFlags = mf_DROPOFF      // Can step off ledges
+	MobjPickup        MobjFlags = mf_PICKUP       // Picks up items (ie: a player)
+	MobjNoClip        MobjFlags = mf_NOCLIP       // Passes through walls
+	MobjFloat         MobjFlags = mf_FLOAT        // Flies
+	MobjTeleport      MobjFlags = mf_TELEPORT     // Has just teleported
+	MobjMissile       MobjFlags = mf_MISSILE      // A projectile
+	MobjDropped       MobjFlags = mf_DROPPED      // Dropped by a monster, rather than placed in the map
+	MobjShadow        MobjFlags = mf_SHADOW       // Partially invisible
+	MobjNoBlood       MobjFlags = mf_NOBLOOD      // Puffs rather than bleeds when shot
+	MobjCorpse        MobjFlags = mf_CORPSE       // A dead body
+	MobjInFloat       MobjFlags = mf_INFLOAT      // Floating to the target's height
+	MobjCountKill     MobjFlags = mf_COUNTKILL    // Counts towards the kill total
+	MobjCountItem     MobjFlags = mf_COUNTITEM    // Counts towards the item total
+	MobjSkullFly      MobjFlags = mf_SKULLFLY     // A lost soul in flight
+	MobjNotDeathmatch MobjFlags = mf_NOTDMATCH    // Not spawned in deathmatch
+)
+
+// Mobj is a snapshot of a map object
+type Mobj struct {
+	// ID is unique within the game, and stays the same for as long as the
+	// object exists. Loading a saved game assigns new IDs, but restoring a
+	// snapshot doesn't.
+	ID     uint32
+	Type   string // Type name, as in the original source, ie: "MT_TROOP"
+	Sprite string // Current sprite, ie: "TROO"
+	Frame  int    // Current frame of the sprite, from 0 for 'A'
+	State  int    // Index of the current state
+	Flags  MobjFlags
+
+	Position Vec3    // Position of the bottom centre of the object
+	Momentum Vec3    // Momentum, in map units per tic
+	Angle    float64 // Direction the object is facing, in degrees anticlockwise from east
+	Radius   float64
+	Height   float64
+	Health   int
+
+	// Target is the ID of the object being attacked or chased, or which
+	// fired a projectile. 0 if there isn't one.
+	Target uint32
+	// Player is the index of the player controlling the object, or -1
+	Player int
+}
+
+var mobj_next_id uint32
+
+// p_NewMobjID returns the ID for a newly created mobj
+func p_NewMobjID() uint32 {
+	mobj_next_id++
+	return mobj_next_id
+}
+
+var mobjTypeNames = [len(mobjinfo)]string{
+	"MT_PLAYER", "MT_POSSESSED", "MT_SHOTGUY", "MT_VILE", "MT_FIRE",
+	"MT_UNDEAD", "MT_TRACER", "MT_SMOKE", "MT_FATSO", "MT_FATSHOT",
+	"MT_CHAINGUY", "MT_TROOP", "MT_SERGEANT", "MT_SHADOWS", "MT_HEAD",
+	"MT_BRUISER", "MT_BRUISERSHOT", "MT_KNIGHT", "MT_SKULL", "MT_SPIDER",
+	"MT_BABY", "MT_CYBORG", "MT_PAIN", "MT_WOLFSS", "MT_KEEN",
+	"MT_BOSSBRAIN", "MT_BOSSSPIT", "MT_BOSSTARGET", "MT_SPAWNSHOT", "MT_SPAWNFIRE",
+	"MT_BARREL", "MT_TROOPSHOT", "MT_HEADSHOT", "MT_ROCKET", "MT_PLASMA",
+	"MT_BFG", "MT_ARACHPLAZ", "MT_PUFF", "MT_BLOOD", "MT_TFOG",
+	"MT_IFOG", "MT_TELEPORTMAN", "MT_EXTRABFG", "MT_MISC0", "MT_MISC1",
+	"MT_MISC2", "MT_MISC3", "MT_MISC4", "MT_MISC5", "MT_MISC6",
+	"MT_MISC7", "MT_MISC8", "MT_MISC9", "MT_MISC10", "MT_MISC11",
+	"MT_MISC12", "MT_INV", "MT_MISC13", "MT_INS", "MT_MISC14",
+	"MT_MISC15", "MT_MISC16", "MT_MEGA", "MT_CLIP", "MT_MISC17",
+	"MT_MISC18", "MT_MISC19", "MT_MISC20", "MT_MISC21", "MT_MISC22",
+	"MT_MISC23", "MT_MISC24", "MT_MISC25", "MT_CHAINGUN", "MT_MISC26",
+	"MT_MISC27", "MT_MISC28", "MT_SHOTGUN", "MT_SUPERSHOTGUN", "MT_MISC29",
+	"MT_MISC30", "MT_MISC31", "MT_MISC32", "MT_MISC33", "MT_MISC34",
+	"MT_MISC35", "MT_MISC36", "MT_MISC37", "MT_MISC38", "MT_MISC39",
+	"MT_MISC40", "MT_MISC41", "MT_MISC42", "MT_MISC43", "MT_MISC44",
+	"MT_MISC45", "MT_MISC46", "MT_MISC47", "MT_MISC48", "MT_MISC49",
+	"MT_MISC50", "MT_MISC51", "MT_MISC52", "MT_MISC53", "MT_MISC54",
+	"MT_MISC55", "MT_MISC56", "MT_MISC57", "MT_MISC58", "MT_MISC59",
+	"MT_MISC60", "MT_MISC61", "MT_MISC62", "MT_MISC63", "MT_MISC64",
+	"MT_MISC65", "MT_MISC66", "MT_MISC67", "MT_MISC68", "MT_MISC69",
+	"MT_MISC70", "MT_MISC71", "MT_MISC72", "MT_MISC73", "MT_MISC74",
+	"MT_MISC75", "MT_MISC76", "MT_MISC77", "MT_MISC78", "MT_MISC79",
+	"MT_MISC80", "MT_MISC81", "MT_MISC82", "MT_MISC83", "MT_MISC84",
+	"MT_MISC85", "MT_MISC86",
+}
+
+// MobjTypeName returns the name of a mobj type number, as stored in saved
+// games, ie: "MT_TROOP". It returns "" for an unknown type.
+func MobjTypeName(t int) string {
+	if t < 0 || t >= len(mobjTypeNames) {
+		return ""
+	}
+	return mobjTypeNames[t]
+}
+
+// Mobjs iterates over a snapshot of all of the objects in the current
+// level, in the order they think. The snapshot is taken when the iteration
+// starts, so the game can safely be used from within the loop. If it can't
+// be taken, ie: because the game is closed, the error is all that is
+// returned.
+func (g *Game) Mobjs() iter.Seq2[Mobj, error] {
+	return func(yield func(Mobj, error) bool) {
+		mobjs, err := g.mobjs()
+		if err != nil {
+			yield(Mobj{}, err)
+			return
+		}
+		for _, mo := range mobjs {
+			if !yield(mo, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (g *Game) mobjs() ([]Mobj, error) {
+	if err := g.lock(); err != nil {
+		return nil, err
+	}
+	defer g.unlock()
+	if g.closed {
+		return nil, ErrClosed
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+	if thinkercap.Fnext == nil {
+		return nil, nil
+	}
+	var mobjs []Mobj
+	for th := thinkercap.Fnext; th != &thinkercap; th = th.Fnext {
+		if mo, ok := th.Ffunction.(*mobj_t); ok {
+			mobjs = append(mobjs, mobjSnapshot(mo))
+		}
+	}
+	return mobjs, nil
+}
+
+func mobjSnapshot(mo *mobj_t) Mobj {
+	m := Mobj{
+		ID:       mo.Fid,
+		Type:     mobjTypeNames[mo.Ftype1],
+		Sprite:   sprnames[mo.Fsprite],
+		Frame:    int(mo.Fframe & FF_FRAMEMASK1),
+		Flags:    MobjFlags(mo.Fflags),
+		Position: Vec3{fixedToFloat(mo.Fx), fixedToFloat(mo.Fy), fixedToFloat(mo.Fz)},
+		Momentum: Vec3{fixedToFloat(mo.Fmomx), fixedToFloat(mo.Fmomy), fixedToFloat(mo.Fmomz)},
+		Angle:    angleToDegrees(mo.Fangle),
+		Radius:   fixedToFloat(mo.Fradius),
+		Height:   fixedToFloat(mo.Fheight),
+		Health:   int(mo.Fhealth),
+		Player:   -1,
+	}
+	if mo.Fstate != nil {
+		m.State = int(stateIndex(mo.Fstate))
+	}
+	if mo.Ftarget != nil {
+		m.Target = mo.Ftarget.Fid
+	}
+	if mo.Fplayer != nil {
+		for i := range players {
+			if mo.Fplayer == &players[i] {
+				m.Player = i
+			}
+		}
+	}
+	return m
+}
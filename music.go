@@ -0,0 +1,346 @@
+package gore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+)
+
+// Music support. Doom stores its music in the MUS format, which is converted
+// to a standard MIDI file (as in mus2mid.c from Chocolate Doom), and passed
+// to the frontend to play.
+
+// DoomMusicFrontend can optionally be implemented by a DoomFrontend to play
+// the game's music. If the frontend doesn't implement it, there is no music.
+//
+// These are called from within the game loop, so they should return quickly.
+type DoomMusicFrontend interface {
+	// PlayMusic starts playing a song, given as a type 0 standard MIDI
+	// file. If looping is set, the song should restart when it finishes.
+	// Any previously playing song should be replaced.
+	PlayMusic(midi []byte, looping bool)
+	// StopMusic stops the current song
+	StopMusic()
+	// SetMusicVolume sets the music volume, from 0 (silent) to 127. While
+	// the game is paused the volume is set to 0.
+	SetMusicVolume(volume int)
+}
+
+var music_frontend DoomMusicFrontend
+var music_songs map[uintptr][]byte // MIDI data for the registered songs, by handle
+var music_next_handle uintptr
+var music_volume int32
+var music_paused boolean
+var music_playing boolean
+
+var midi_music_module = music_module_t{
+	Fsound_devices:     []snddevice_t{SNDDEVICE_ADLIB, SNDDEVICE_SB, SNDDEVICE_GUS, SNDDEVICE_GENMIDI},
+	Fnum_sound_devices: 4,
+	FInit:              midiMusicInit,
+	FShutdown:          midiMusicShutdown,
+	FSetMusicVolume:    midiMusicSetVolume,
+	FPauseMusic:        midiMusicPause,
+	FResumeMusic:       midiMusicResume,
+	FRegisterSong:      midiMusicRegisterSong,
+	FUnRegisterSong:    midiMusicUnRegisterSong,
+	FPlaySong:          midiMusicPlaySong,
+	FStopSong:          midiMusicStopSong,
+	FMusicIsPlaying:    midiMusicIsPlaying,
+}
+
+func midiMusicInit() boolean {
+	frontend, ok := dg_frontend.(DoomMusicFrontend)
+	if !ok {
+		return 0
+	}
+	music_frontend = frontend
+	music_songs = map[uintptr][]byte{}
+	music_next_handle = 0
+	music_volume = 127
+	music_paused = 0
+	music_playing = 0
+	return 1
+}
+
+func midiMusicShutdown() {
+	midiMusicStopSong()
+	music_frontend = nil
+	music_songs = nil
+}
+
+// midiMusicUpdateVolume passes the current volume to the frontend. While
+// paused, the music is silenced rather than stopped, as in Chocolate Doom.
+func midiMusicUpdateVolume() {
+	volume := music_volume
+	if music_paused != 0 {
+		volume = 0
+	}
+	i_Callback(func() { music_frontend.SetMusicVolume(int(volume)) })
+}
+
+func midiMusicSetVolume(volume int32) {
+	music_volume = volume
+	midiMusicUpdateVolume()
+}
+
+func midiMusicPause() {
+	music_paused = 1
+	midiMusicUpdateVolume()
+}
+
+func midiMusicResume() {
+	music_paused = 0
+	midiMusicUpdateVolume()
+}
+
+func midiMusicRegisterSong(data []byte) uintptr {
+	midi := musicToMIDI(data)
+	if midi == nil {
+		return 0
+	}
+	music_next_handle++
+	music_songs[music_next_handle] = midi
+	return music_next_handle
+}
+
+func midiMusicUnRegisterSong(handle uintptr) {
+	delete(music_songs, handle)
+}
+
+func midiMusicPlaySong(handle uintptr, looping boolean) boolean {
+	midi, ok := music_songs[handle]
+	if !ok {
+		return 0
+	}
+	i_Callback(func() { music_frontend.PlayMusic(midi, looping != 0) })
+	music_playing = 1
+	return 1
+}
+
+func midiMusicStopSong() {
+	if music_playing != 0 {
+		i_Callback(func() { music_frontend.StopMusic() })
+		music_playing = 0
+	}
+}
+
+func midiMusicIsPlaying() boolean {
+	return music_playing
+}
+
+// musicToMIDI converts a music lump to a MIDI file, returning nil if it
+// isn't valid
+func musicToMIDI(data []byte) []byte {
+	if bytes.HasPrefix(data, []byte("MThd")) {
+		// PWADs may contain MIDI music, which doesn't need converting
+		return data
+	}
+	midi := mus2mid(data)
+	if midi == nil {
+		fprintf_ccgo(os.Stderr, "musicToMIDI: Failed to convert MUS to MIDI\n")
+	}
+	return midi
+}
+
+// MUS event types, from the top nibble of each event
+const (
+	mus_releasekey       = 0x00
+	mus_presskey         = 0x10
+	mus_pitchwheel       = 0x20
+	mus_systemevent      = 0x30
+	mus_changecontroller = 0x40
+	mus_scoreend         = 0x60
+)
+
+// MIDI event types
+const (
+	midi_releasekey       = 0x80
+	midi_presskey         = 0x90
+	midi_changecontroller = 0xb0
+	midi_changepatch      = 0xc0
+	midi_pitchwheel       = 0xe0
+)
+
+const mus_NUMCHANNELS = 16
+const mus_PERCUSSION_CHAN = 15
+const midi_PERCUSSION_CHAN = 9
+
+// MUS ticks run at 140Hz. With the default MIDI tempo of 120 beats per
+// minute, 70 ticks per quarter note gives the same rate.
+const midi_TICKS_PER_BEAT = 70
+
+// Maps MUS controller numbers (and system events, from 10 onwards) to MIDI
+// controller numbers. 0 is the instrument, which is a MIDI program change.
+var mus_controller_map = [15]byte{
+	0x00, 0x20, 0x01, 0x07, 0x0a, 0x0b, 0x5b, 0x5d,
+	0x40, 0x43, 0x78, 0x7b, 0x7e, 0x7f, 0x79,
+}
+
+// mus2mid converts a MUS lump to a type 0 standard MIDI file. Invalid lumps
+// return nil.
+func mus2mid(mus []byte) []byte {
+	// Header: ID, score length, score start, primary channels,
+	// secondary channels, instrument count
+	if len(mus) < 16 || string(mus[:4]) != "MUS\x1a" {
+		return nil
+	}
+	scorelen := int(binary.LittleEndian.Uint16(mus[4:]))
+	scorestart := int(binary.LittleEndian.Uint16(mus[6:]))
+	if scorestart > len(mus) {
+		return nil
+	}
+	score := mus[scorestart:]
+	if scorelen < len(score) {
+		score = score[:scorelen]
+	}
+
+	var track []byte
+	var queuedtime uint32
+	var channel_map [mus_NUMCHANNELS]int32
+	var channel_velocities [mus_NUMCHANNELS]byte
+	for i := range channel_map {
+		channel_map[i] = -1
+		channel_velocities[i] = 127
+	}
+	nextchannel := int32(0)
+
+	writeEvent := func(data ...byte) {
+		track = appendVarLen(track, queuedtime)
+		queuedtime = 0
+		track = append(track, data...)
+	}
+
+	// Get the MIDI channel for a MUS channel, allocating it the first time
+	// the MUS channel is used
+	midiChannel := func(channel byte) byte {
+		if channel == mus_PERCUSSION_CHAN {
+			return midi_PERCUSSION_CHAN
+		}
+		if channel_map[channel] == -1 {
+			if nextchannel == midi_PERCUSSION_CHAN {
+				nextchannel++
+			}
+			channel_map[channel] = nextchannel
+			nextchannel++
+			// Turn off any notes left over on the channel
+			writeEvent(midi_changecontroller|byte(channel_map[channel]), 0x7b, 0)
+		}
+		return byte(channel_map[channel])
+	}
+
+	pos := 0
+	next := func() (byte, bool) {
+		if pos >= len(score) {
+			return 0, false
+		}
+		pos++
+		return score[pos-1], true
+	}
+
+	for {
+		descriptor, ok := next()
+		if !ok {
+			return nil
+		}
+		channel := midiChannel(descriptor & 0x0f)
+		var key, value byte
+		switch descriptor & 0x70 {
+		case mus_releasekey:
+			if key, ok = next(); !ok {
+				return nil
+			}
+			writeEvent(midi_releasekey|channel, key&0x7f, 0)
+		case mus_presskey:
+			if key, ok = next(); !ok {
+				return nil
+			}
+			if key&0x80 != 0 {
+				if value, ok = next(); !ok {
+					return nil
+				}
+				channel_velocities[descriptor&0x0f] = value & 0x7f
+			}
+			writeEvent(midi_presskey|channel, key&0x7f, channel_velocities[descriptor&0x0f])
+		case mus_pitchwheel:
+			if value, ok = next(); !ok {
+				return nil
+			}
+			// Scale the 8-bit MUS value up to the 14-bit MIDI one
+			wheel := uint16(value) * 64
+			writeEvent(midi_pitchwheel|channel, byte(wheel&0x7f), byte(wheel>>7&0x7f))
+		case mus_systemevent:
+			if value, ok = next(); !ok {
+				return nil
+			}
+			if value < 10 || value > 14 {
+				return nil
+			}
+			writeEvent(midi_changecontroller|channel, mus_controller_map[value], 0)
+		case mus_changecontroller:
+			if key, ok = next(); !ok {
+				return nil
+			}
+			if value, ok = next(); !ok {
+				return nil
+			}
+			// Some wads contain out of range values
+			value = min(value, 0x7f)
+			if key == 0 {
+				writeEvent(midi_changepatch|channel, value)
+			} else {
+				if key > 9 {
+					return nil
+				}
+				writeEvent(midi_changecontroller|channel, mus_controller_map[key], value)
+			}
+		case mus_scoreend:
+			// End of track meta event
+			writeEvent(0xff, 0x2f, 0x00)
+			return midiFile(track)
+		default:
+			return nil
+		}
+
+		// The last event in a group is followed by the delay until the
+		// next one
+		if descriptor&0x80 != 0 {
+			var delay uint32
+			for {
+				b, ok := next()
+				if !ok {
+					return nil
+				}
+				delay = delay<<7 | uint32(b&0x7f)
+				if b&0x80 == 0 {
+					break
+				}
+			}
+			queuedtime += delay
+		}
+	}
+}
+
+// appendVarLen appends a MIDI variable length quantity
+func appendVarLen(buf []byte, value uint32) []byte {
+	var tmp [5]byte
+	i := len(tmp) - 1
+	tmp[i] = byte(value & 0x7f)
+	for value >>= 7; value != 0; value >>= 7 {
+		i--
+		tmp[i] = byte(value&0x7f) | 0x80
+	}
+	return append(buf, tmp[i:]...)
+}
+
+// midiFile wraps a single track in a type 0 MIDI file
+func midiFile(track []byte) []byte {
+	out := make([]byte, 0, 22+len(track))
+	out = append(out, "MThd"...)
+	out = binary.BigEndian.AppendUint32(out, 6)
+	out = binary.BigEndian.AppendUint16(out, 0) // Format 0
+	out = binary.BigEndian.AppendUint16(out, 1) // One track
+	out = binary.BigEndian.AppendUint16(out, midi_TICKS_PER_BEAT)
+	out = append(out, "MTrk"...)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(track)))
+	return append(out, track...)
+}
@@ -0,0 +1,491 @@
+package gore
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Yamaha YMF262 (OPL3) FM synthesis emulator, used to render music the way
+// it sounded on AdLib and Sound Blaster cards. An OPL3 is a superset of the
+// OPL2, so this covers both. The operator, envelope and phase generators
+// follow the real chip (as documented by Nuked OPL3), but the parts Doom
+// doesn't use are left out: the timers, rhythm mode and 4-operator channels.
+
+// opl_RATE is the native sample rate of the chip, in Hz
+const opl_RATE = 49716
+
+const opl_NUMCHANNELS = 18
+
+// Envelope generator states
+const (
+	opl_eg_attack = iota
+	opl_eg_decay
+	opl_eg_sustain
+	opl_eg_release
+)
+
+type opl_slot_t struct {
+	// Register values
+	Fam   boolean // Tremolo
+	Fvib  boolean // Vibrato
+	Ftype byte    // Sustain the note until it's released
+	Fksr  byte    // Key scale rate
+	Fmult byte
+	Fksl  byte // Key scale level
+	Ftl   byte // Total level
+	Far   byte
+	Fdr   byte
+	Fsl   byte
+	Frr   byte
+	Fwf   byte // Waveform
+
+	Fout     int32
+	Fprout   int32 // Previous output, for feedback
+	Ffbmod   int32 // Feedback modulation
+	Feg_rout int32 // Envelope attenuation, 0 (loudest) to 0x1ff
+	Feg_out  int32 // Envelope attenuation including level and tremolo
+	Feg_gen  byte
+	Feg_key  boolean
+	Feg_ksl  int32
+	Fpg_rst  boolean
+	Fpg_pos  uint32 // Phase, as 10.9 fixed point
+	Fpg_out  uint32
+}
+
+type opl_channel_t struct {
+	Fslots [2]opl_slot_t // Modulator and carrier
+	Ffnum  uint32
+	Fblock uint32
+	Fksv   byte
+	Ffb    byte    // Feedback
+	Fcon   byte    // Connection: 0 for FM, 1 for additive
+	Fleft  boolean // Output on the left channel
+	Fright boolean // Output on the right channel
+}
+
+type opl_chip_t struct {
+	Fchannels [opl_NUMCHANNELS]opl_channel_t
+	Fnew      boolean // OPL3 mode
+	Fnts      byte    // Note select
+	Fdam      byte    // Tremolo depth
+	Fdvb      byte    // Vibrato depth
+
+	Ftimer       uint32
+	Feg_timer    uint64
+	Feg_timerrem boolean
+	Feg_state    boolean
+	Feg_add      byte
+	Feg_timer_lo byte
+	Fvibpos      byte
+	Ftremolopos  byte
+	Ftremolo     int32
+}
+
+// opl_logsin is the log of the first quarter of a sine wave, and opl_exp is
+// the matching exponent table
+var opl_logsin, opl_exp = func() (logsin, exp [256]int32) {
+	for i := range 256 {
+		logsin[i] = int32(math.Round(-math.Log2(math.Sin((float64(i)+0.5)*math.Pi/512)) * 256))
+		exp[i] = int32(math.Round(math.Exp2(float64(255-i)/256) * 1024))
+	}
+	return
+}()
+
+var opl_mult = [16]uint32{1, 2, 4, 6, 8, 10, 12, 14, 16, 18, 20, 20, 24, 24, 30, 30}
+var opl_kslrom = [16]int32{0, 32, 40, 45, 48, 51, 53, 55, 56, 58, 59, 60, 61, 62, 63, 64}
+var opl_kslshift = [4]byte{8, 1, 2, 0}
+var opl_eg_incstep = [4][4]byte{
+	{0, 0, 0, 0},
+	{1, 0, 0, 0},
+	{1, 0, 1, 0},
+	{1, 1, 1, 0},
+}
+
+func newOPLChip() *opl_chip_t {
+	chip := &opl_chip_t{}
+	for i := range chip.Fchannels {
+		c := &chip.Fchannels[i]
+		c.Fleft, c.Fright = 1, 1
+		for j := range c.Fslots {
+			c.Fslots[j].Feg_rout = 0x1ff
+			c.Fslots[j].Feg_out = 0x1ff
+			c.Fslots[j].Feg_gen = opl_eg_release
+		}
+	}
+	return chip
+}
+
+// oplCalcExp converts a log attenuation to a linear level
+func oplCalcExp(level int32) int32 {
+	if level > 0x1fff {
+		level = 0x1fff
+	}
+	return opl_exp[level&0xff] << 1 >> (level >> 8)
+}
+
+// oplCalcWave returns the output of a waveform at the given 10 bit phase,
+// attenuated by envelope
+func oplCalcWave(wf byte, phase uint32, envelope int32) int32 {
+	phase &= 0x3ff
+	env := envelope << 3
+	var out int32
+	neg := false
+	switch wf {
+	case 0: // Sine
+		neg = phase&0x200 != 0
+		out = oplLogSin(phase)
+	case 1: // Half sine
+		if phase&0x200 != 0 {
+			out = 0x1000
+		} else {
+			out = oplLogSin(phase)
+		}
+	case 2: // Absolute sine
+		out = oplLogSin(phase)
+	case 3: // Pulse sine
+		if phase&0x100 != 0 {
+			out = 0x1000
+		} else {
+			out = opl_logsin[phase&0xff]
+		}
+	case 4: // Alternating sine
+		neg = phase&0x300 == 0x100
+		if phase&0x200 != 0 {
+			out = 0x1000
+		} else if phase&0x80 != 0 {
+			out = opl_logsin[(phase^0xff)<<1&0xff]
+		} else {
+			out = opl_logsin[phase<<1&0xff]
+		}
+	case 5: // Camel sine
+		if phase&0x200 != 0 {
+			out = 0x1000
+		} else if phase&0x80 != 0 {
+			out = opl_logsin[(phase^0xff)<<1&0xff]
+		} else {
+			out = opl_logsin[phase<<1&0xff]
+		}
+	case 6: // Square
+		neg = phase&0x200 != 0
+	case 7: // Logarithmic sawtooth
+		if phase&0x200 != 0 {
+			neg = true
+			phase = phase&0x1ff ^ 0x1ff
+		}
+		out = int32(phase) << 3
+	}
+	level := oplCalcExp(out + env)
+	if neg {
+		return ^level
+	}
+	return level
+}
+
+// oplLogSin looks up the log sine of a half wave, mirroring the quarter wave
+// table
+func oplLogSin(phase uint32) int32 {
+	if phase&0x100 != 0 {
+		return opl_logsin[phase&0xff^0xff]
+	}
+	return opl_logsin[phase&0xff]
+}
+
+func (c *opl_channel_t) updateFrequency(chip *opl_chip_t) {
+	c.Fksv = byte(c.Fblock<<1) | byte(c.Ffnum>>(9-chip.Fnts)&1)
+	ksl := opl_kslrom[c.Ffnum>>6]<<2 - int32(8-c.Fblock)<<5
+	for i := range c.Fslots {
+		c.Fslots[i].Feg_ksl = max(ksl, 0)
+	}
+}
+
+func (s *opl_slot_t) envelopeCalc(chip *opl_chip_t, c *opl_channel_t) {
+	s.Feg_out = s.Feg_rout + int32(s.Ftl)<<2 + s.Feg_ksl>>opl_kslshift[s.Fksl]
+	if s.Fam != 0 {
+		s.Feg_out += chip.Ftremolo
+	}
+	s.Feg_out = min(s.Feg_out, 0x1ff)
+
+	var reg_rate byte
+	reset := false
+	if s.Feg_key != 0 && s.Feg_gen == opl_eg_release {
+		reset = true
+		reg_rate = s.Far
+	} else {
+		switch s.Feg_gen {
+		case opl_eg_attack:
+			reg_rate = s.Far
+		case opl_eg_decay:
+			reg_rate = s.Fdr
+		case opl_eg_sustain:
+			if s.Ftype == 0 {
+				reg_rate = s.Frr
+			}
+		case opl_eg_release:
+			reg_rate = s.Frr
+		}
+	}
+	s.Fpg_rst = booluint32(reset)
+	ks := c.Fksv >> ((s.Fksr ^ 1) << 1)
+	rate := ks + reg_rate<<2
+	rate_hi := rate >> 2
+	rate_lo := rate & 3
+	if rate_hi&0x10 != 0 {
+		rate_hi = 0x0f
+	}
+	eg_shift := rate_hi + chip.Feg_add
+	var shift byte
+	if reg_rate != 0 {
+		if rate_hi < 12 {
+			if chip.Feg_state != 0 {
+				switch eg_shift {
+				case 12:
+					shift = 1
+				case 13:
+					shift = rate_lo >> 1 & 1
+				case 14:
+					shift = rate_lo & 1
+				}
+			}
+		} else {
+			shift = rate_hi&3 + opl_eg_incstep[rate_lo][chip.Feg_timer_lo]
+			if shift&4 != 0 {
+				shift = 3
+			}
+			if shift == 0 {
+				shift = byte(chip.Feg_state)
+			}
+		}
+	}
+
+	eg_rout := s.Feg_rout
+	var eg_inc int32
+	// Instant attack
+	if reset && rate_hi == 0x0f {
+		eg_rout = 0
+	}
+	// Envelope off
+	eg_off := s.Feg_rout&0x1f8 == 0x1f8
+	if s.Feg_gen != opl_eg_attack && !reset && eg_off {
+		eg_rout = 0x1ff
+	}
+	switch s.Feg_gen {
+	case opl_eg_attack:
+		if s.Feg_rout == 0 {
+			s.Feg_gen = opl_eg_decay
+		} else if s.Feg_key != 0 && shift > 0 && rate_hi != 0x0f {
+			eg_inc = ^s.Feg_rout >> (4 - shift)
+		}
+	case opl_eg_decay:
+		if s.Feg_rout>>4 == int32(s.Fsl) {
+			s.Feg_gen = opl_eg_sustain
+		} else if !eg_off && !reset && shift > 0 {
+			eg_inc = 1 << (shift - 1)
+		}
+	case opl_eg_sustain, opl_eg_release:
+		if !eg_off && !reset && shift > 0 {
+			eg_inc = 1 << (shift - 1)
+		}
+	}
+	s.Feg_rout = (eg_rout + eg_inc) & 0x1ff
+	if reset {
+		s.Feg_gen = opl_eg_attack
+	}
+	if s.Feg_key == 0 {
+		s.Feg_gen = opl_eg_release
+	}
+}
+
+func (s *opl_slot_t) phaseGenerate(chip *opl_chip_t, c *opl_channel_t) {
+	fnum := c.Ffnum
+	if s.Fvib != 0 {
+		r := int32(fnum >> 7 & 7)
+		vibpos := chip.Fvibpos
+		if vibpos&3 == 0 {
+			r = 0
+		} else if vibpos&1 != 0 {
+			r >>= 1
+		}
+		r >>= 1 - chip.Fdvb
+		if vibpos&4 != 0 {
+			r = -r
+		}
+		fnum = uint32(int32(fnum)+r) & 0x3ff
+	}
+	basefreq := fnum << c.Fblock >> 1
+	phase := s.Fpg_pos >> 9
+	if s.Fpg_rst != 0 {
+		s.Fpg_pos = 0
+	}
+	s.Fpg_pos += basefreq * opl_mult[s.Fmult] >> 1
+	s.Fpg_out = phase
+}
+
+func (s *opl_slot_t) generate(mod int32) {
+	s.Fout = oplCalcWave(s.Fwf, uint32(int32(s.Fpg_out)+mod), s.Feg_out)
+}
+
+// silent reports whether the slot has finished its release, and so can't
+// be heard until it is keyed on again
+func (s *opl_slot_t) silent() bool {
+	return s.Feg_key == 0 && s.Feg_rout == 0x1ff
+}
+
+// writeReg writes to one of the chip's registers. Registers 0x100-0x1ff are
+// the second bank, which is only present on an OPL3.
+func (chip *opl_chip_t) writeReg(reg uint16, value byte) {
+	bank := int(reg>>8) & 1
+	low := byte(reg)
+	switch {
+	case reg == 0x105:
+		chip.Fnew = uint32(value & 1)
+	case bank == 0 && low == 0x08:
+		chip.Fnts = value >> 6 & 1
+	case bank == 0 && low == 0xbd:
+		chip.Fdam = value >> 7
+		chip.Fdvb = value >> 6 & 1
+	case low >= 0x20 && low < 0xa0 || low >= 0xe0:
+		s := chip.slot(bank, low&0x1f)
+		if s == nil {
+			return
+		}
+		switch low & 0xe0 {
+		case 0x20:
+			s.Fam = uint32(value >> 7)
+			s.Fvib = uint32(value >> 6 & 1)
+			s.Ftype = value >> 5 & 1
+			s.Fksr = value >> 4 & 1
+			s.Fmult = value & 0x0f
+		case 0x40:
+			s.Fksl = value >> 6
+			s.Ftl = value & 0x3f
+		case 0x60:
+			s.Far = value >> 4
+			s.Fdr = value & 0x0f
+		case 0x80:
+			s.Fsl = value >> 4
+			if s.Fsl == 0x0f {
+				s.Fsl = 0x1f
+			}
+			s.Frr = value & 0x0f
+		case 0xe0:
+			s.Fwf = value & 7
+			if chip.Fnew == 0 {
+				s.Fwf &= 3
+			}
+		}
+	case low&0x0f < 9 && (low&0xf0 == 0xa0 || low&0xf0 == 0xb0 || low&0xf0 == 0xc0):
+		c := &chip.Fchannels[bank*9+int(low&0x0f)]
+		switch low & 0xf0 {
+		case 0xa0:
+			c.Ffnum = c.Ffnum&0x300 | uint32(value)
+			c.updateFrequency(chip)
+		case 0xb0:
+			c.Ffnum = c.Ffnum&0xff | uint32(value&3)<<8
+			c.Fblock = uint32(value >> 2 & 7)
+			c.updateFrequency(chip)
+			key := uint32(value >> 5 & 1)
+			c.Fslots[0].Feg_key = key
+			c.Fslots[1].Feg_key = key
+		case 0xc0:
+			c.Ffb = value >> 1 & 7
+			c.Fcon = value & 1
+			if chip.Fnew != 0 {
+				c.Fleft = uint32(value >> 4 & 1)
+				c.Fright = uint32(value >> 5 & 1)
+			} else {
+				c.Fleft, c.Fright = 1, 1
+			}
+		}
+	}
+}
+
+// slot finds the operator for a register offset. Each bank's operators are
+// in groups of 6, covering 3 channels, with a gap of 2 between each group.
+func (chip *opl_chip_t) slot(bank int, offset byte) *opl_slot_t {
+	group, n := offset/8, offset%8
+	if group > 2 || n > 5 {
+		return nil
+	}
+	return &chip.Fchannels[bank*9+int(group*3+n%3)].Fslots[n/3]
+}
+
+// generate runs the chip for a single sample, returning the left and right
+// outputs
+func (chip *opl_chip_t) generate() (left, right int32) {
+	for i := range chip.Fchannels {
+		c := &chip.Fchannels[i]
+		mod, car := &c.Fslots[0], &c.Fslots[1]
+		if mod.silent() && car.silent() {
+			// Nothing to hear, so skip all of the work
+			mod.Fout, mod.Fprout, car.Fout, car.Fprout = 0, 0, 0, 0
+			continue
+		}
+
+		if c.Ffb != 0 {
+			mod.Ffbmod = (mod.Fprout + mod.Fout) >> (9 - c.Ffb)
+		} else {
+			mod.Ffbmod = 0
+		}
+		mod.Fprout = mod.Fout
+		mod.envelopeCalc(chip, c)
+		mod.phaseGenerate(chip, c)
+		mod.generate(mod.Ffbmod)
+
+		car.Fprout = car.Fout
+		car.envelopeCalc(chip, c)
+		car.phaseGenerate(chip, c)
+		var out int32
+		if c.Fcon == 0 {
+			car.generate(mod.Fout)
+			out = car.Fout
+		} else {
+			car.generate(0)
+			out = mod.Fout + car.Fout
+		}
+		if c.Fleft != 0 {
+			left += out
+		}
+		if c.Fright != 0 {
+			right += out
+		}
+	}
+
+	// Tremolo and vibrato run at fixed rates
+	if chip.Ftimer&0x3f == 0x3f {
+		chip.Ftremolopos = (chip.Ftremolopos + 1) % 210
+	}
+	shift := 4 - chip.Fdam<<1
+	if chip.Ftremolopos < 105 {
+		chip.Ftremolo = int32(chip.Ftremolopos >> shift)
+	} else {
+		chip.Ftremolo = int32((210 - chip.Ftremolopos) >> shift)
+	}
+	if chip.Ftimer&0x3ff == 0x3ff {
+		chip.Fvibpos = (chip.Fvibpos + 1) & 7
+	}
+	chip.Ftimer++
+
+	// The envelope generators step at a rate based on the lowest set bit of
+	// a counter, which advances every second sample
+	if chip.Feg_state != 0 {
+		shift := bits.TrailingZeros64(chip.Feg_timer)
+		if shift > 12 {
+			chip.Feg_add = 0
+		} else {
+			chip.Feg_add = byte(shift + 1)
+		}
+		chip.Feg_timer_lo = byte(chip.Feg_timer & 3)
+	}
+	if chip.Feg_timerrem != 0 || chip.Feg_state != 0 {
+		if chip.Feg_timer == 0xfffffffff {
+			chip.Feg_timer = 0
+			chip.Feg_timerrem = 1
+		} else {
+			chip.Feg_timer++
+			chip.Feg_timerrem = 0
+		}
+	}
+	chip.Feg_state ^= 1
+
+	return min(max(left, -32768), 32767), min(max(right, -32768), 32767)
+}
@@ -0,0 +1,828 @@
+package gore
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+)
+
+// OPL music playback. This follows i_oplmusic.c from Chocolate Doom: songs
+// are played as MIDI, using the instruments from the GENMIDI lump, on an
+// emulated OPL3. The result is mixed in with the sound effects, so this needs
+// a DoomSoundFrontend.
+
+const genmidi_HEADER = "#OPL_II#"
+const genmidi_NUM_INSTRS = 128
+const genmidi_NUM_PERCUSSION = 47
+const genmidi_FLAG_FIXED = 0x0001  // Fixed pitch
+const genmidi_FLAG_2VOICE = 0x0004 // Double voice (OPL3)
+
+type genmidi_op_t struct {
+	Ftremolo  byte
+	Fattack   byte
+	Fsustain  byte
+	Fwaveform byte
+	Fscale    byte
+	Flevel    byte
+}
+
+type genmidi_voice_t struct {
+	Fmodulator        genmidi_op_t
+	Ffeedback         byte
+	Fcarrier          genmidi_op_t
+	Fbase_note_offset int16
+}
+
+type genmidi_instr_t struct {
+	Fflags       uint16
+	Ffine_tuning byte
+	Ffixed_note  byte
+	Fvoices      [2]genmidi_voice_t
+}
+
+// Operator register bases, added to an operator's offset
+const (
+	opl_REGS_TREMOLO  = 0x20
+	opl_REGS_LEVEL    = 0x40
+	opl_REGS_ATTACK   = 0x60
+	opl_REGS_SUSTAIN  = 0x80
+	opl_REGS_WAVEFORM = 0xe0
+)
+
+// Channel register bases, added to a channel's index
+const (
+	opl_REGS_FREQ_1   = 0xa0
+	opl_REGS_FREQ_2   = 0xb0
+	opl_REGS_FEEDBACK = 0xc0
+)
+
+// Operator offsets for each of the 9 channels in a bank
+var opl_voice_operators = [2][9]uint16{
+	{0x00, 0x01, 0x02, 0x08, 0x09, 0x0a, 0x10, 0x11, 0x12},
+	{0x03, 0x04, 0x05, 0x0b, 0x0c, 0x0d, 0x13, 0x14, 0x15},
+}
+
+type opl_voice_t struct {
+	Findex       uint16 // Channel on the chip, within the bank
+	Farray       uint16 // 0x100 if the voice is in the second bank
+	Fop1, Fop2   uint16 // Operator offsets
+	Fchannel     int32  // MIDI channel playing on this voice, or -1
+	Finstr       *genmidi_instr_t
+	Finstr_voice int32
+	Fkey         int32 // The MIDI key that was pressed
+	Fnote        int32 // The note being played, which differs for fixed pitch instruments
+	Fnote_volume int32
+	Fcar_volume  uint32
+	Fmod_volume  uint32
+	Freg_pan     byte
+	Ffreq        uint32 // The frequency and key on register values
+}
+
+type opl_channel_data_t struct {
+	Finstr       *genmidi_instr_t
+	Fvolume      int32
+	Fvolume_base int32
+	Fpan         byte
+	Fbend        int32 // In 1/32 semitones
+}
+
+// A MIDI event, flattened out into a single track of absolute times
+type midi_event_t struct {
+	Ftick   uint32 // Time of the event, in MIDI ticks
+	Fstatus byte   // MIDI status, or 0xff for a tempo change
+	Fdata1  byte
+	Fdata2  byte
+	Ftempo  uint32 // New tempo, in microseconds per beat
+}
+
+type midi_song_t struct {
+	Fevents   []midi_event_t
+	Fdivision uint32 // MIDI ticks per beat
+}
+
+// Default MIDI tempo of 120 beats per minute
+const midi_DEFAULT_TEMPO = 500000
+
+// The DMX volume curve, mapping MIDI volumes to OPL levels
+var opl_volume_mapping_table = [128]uint32{
+	0, 1, 3, 5, 6, 8, 10, 11,
+	13, 14, 16, 17, 19, 20, 22, 23,
+	25, 26, 27, 29, 30, 32, 33, 34,
+	36, 37, 39, 41, 43, 45, 47, 49,
+	50, 52, 54, 55, 57, 59, 60, 61,
+	63, 64, 66, 67, 68, 69, 71, 72,
+	73, 74, 75, 76, 77, 79, 80, 81,
+	82, 83, 84, 84, 85, 86, 87, 88,
+	89, 90, 91, 92, 92, 93, 94, 95,
+	96, 96, 97, 98, 99, 99, 100, 101,
+	101, 102, 103, 103, 104, 105, 105, 106,
+	107, 107, 108, 109, 109, 110, 110, 111,
+	112, 112, 113, 113, 114, 114, 115, 115,
+	116, 117, 117, 118, 118, 119, 119, 120,
+	120, 121, 121, 122, 122, 123, 123, 123,
+	124, 124, 125, 125, 126, 126, 127, 127,
+}
+
+var opl_chip *opl_chip_t
+var opl_main_instrs []genmidi_instr_t
+var opl_percussion_instrs []genmidi_instr_t
+var opl_voices [opl_NUMCHANNELS]opl_voice_t
+var opl_voice_free []int32    // Free voices, in the order they were released
+var opl_voice_alloced []int32 // Voices in use, in the order they were allocated
+var opl_channels [16]opl_channel_data_t
+var opl_songs map[uintptr]*midi_song_t
+var opl_next_handle uintptr
+var opl_song *midi_song_t // The song being played, or nil
+var opl_song_pos int      // Index of the next event in opl_song
+var opl_song_tick uint32  // Current time in the song, in MIDI ticks
+var opl_song_acc uint64   // Fraction of a tick, in units of opl_RATE*tempo
+var opl_tempo uint32
+var opl_looping boolean
+var opl_paused boolean
+var opl_music_volume int32
+var opl_resample_pos uint32 // Position between opl_prev and opl_next, in 16.16 fixed point
+var opl_prev, opl_next [2]int32
+var opl_buffer []int16
+
+var opl_music_module = music_module_t{
+	Fsound_devices:     []snddevice_t{SNDDEVICE_ADLIB, SNDDEVICE_SB},
+	Fnum_sound_devices: 2,
+	FInit:              oplMusicInit,
+	FShutdown:          oplMusicShutdown,
+	FSetMusicVolume:    oplMusicSetVolume,
+	FPauseMusic:        oplMusicPause,
+	FResumeMusic:       oplMusicResume,
+	FRegisterSong:      oplMusicRegisterSong,
+	FUnRegisterSong:    oplMusicUnRegisterSong,
+	FPlaySong:          oplMusicPlaySong,
+	FStopSong:          oplMusicStopSong,
+	FMusicIsPlaying:    oplMusicIsPlaying,
+}
+
+func oplMusicInit() boolean {
+	// The music is played through the sound effect mixer
+	if mixer_frontend == nil {
+		return 0
+	}
+	lumpnum := w_CheckNumForName("GENMIDI")
+	if lumpnum < 0 || loadGenMIDI(w_CacheLumpNumBytes(lumpnum)) == 0 {
+		fprintf_ccgo(os.Stderr, "oplMusicInit: Failed to load GENMIDI lump\n")
+		return 0
+	}
+	opl_chip = newOPLChip()
+	oplInitRegisters()
+	opl_voice_free = opl_voice_free[:0]
+	opl_voice_alloced = opl_voice_alloced[:0]
+	for i := range opl_voices {
+		v := &opl_voices[i]
+		*v = opl_voice_t{
+			Findex:   uint16(i % 9),
+			Farray:   uint16(i/9) << 8,
+			Fop1:     opl_voice_operators[0][i%9],
+			Fop2:     opl_voice_operators[1][i%9],
+			Fchannel: -1,
+		}
+		opl_voice_free = append(opl_voice_free, int32(i))
+	}
+	opl_songs = map[uintptr]*midi_song_t{}
+	opl_next_handle = 0
+	opl_song = nil
+	opl_paused = 0
+	opl_music_volume = 127
+	opl_resample_pos = 0
+	opl_prev, opl_next = [2]int32{}, [2]int32{}
+	oplInitChannels()
+	return 1
+}
+
+func oplMusicShutdown() {
+	oplMusicStopSong()
+	opl_chip = nil
+	opl_songs = nil
+	opl_buffer = nil
+}
+
+func loadGenMIDI(data []byte) boolean {
+	const voice_size = 16
+	const instr_size = 4 + 2*voice_size
+	count := genmidi_NUM_INSTRS + genmidi_NUM_PERCUSSION
+	if len(data) < len(genmidi_HEADER)+count*instr_size || string(data[:len(genmidi_HEADER)]) != genmidi_HEADER {
+		return 0
+	}
+	readOp := func(b []byte) genmidi_op_t {
+		return genmidi_op_t{
+			Ftremolo:  b[0],
+			Fattack:   b[1],
+			Fsustain:  b[2],
+			Fwaveform: b[3],
+			Fscale:    b[4],
+			Flevel:    b[5],
+		}
+	}
+	instrs := make([]genmidi_instr_t, count)
+	for i := range instrs {
+		b := data[len(genmidi_HEADER)+i*instr_size:]
+		instr := &instrs[i]
+		instr.Fflags = binary.LittleEndian.Uint16(b)
+		instr.Ffine_tuning = b[2]
+		instr.Ffixed_note = b[3]
+		for j := range instr.Fvoices {
+			v := b[4+j*voice_size:]
+			instr.Fvoices[j] = genmidi_voice_t{
+				Fmodulator:        readOp(v[0:]),
+				Ffeedback:         v[6],
+				Fcarrier:          readOp(v[7:]),
+				Fbase_note_offset: int16(binary.LittleEndian.Uint16(v[14:])),
+			}
+		}
+	}
+	opl_main_instrs = instrs[:genmidi_NUM_INSTRS]
+	opl_percussion_instrs = instrs[genmidi_NUM_INSTRS:]
+	return 1
+}
+
+// oplInitRegisters puts the chip into OPL3 mode, with all operators silent
+func oplInitRegisters() {
+	opl_chip.writeReg(0x105, 0x01)
+	for _, array := range []uint16{0, 0x100} {
+		for _, ops := range opl_voice_operators {
+			for _, op := range ops {
+				opl_chip.writeReg(opl_REGS_LEVEL+op|array, 0x3f)
+				opl_chip.writeReg(opl_REGS_TREMOLO+op|array, 0)
+				opl_chip.writeReg(opl_REGS_ATTACK+op|array, 0)
+				opl_chip.writeReg(opl_REGS_SUSTAIN+op|array, 0)
+				opl_chip.writeReg(opl_REGS_WAVEFORM+op|array, 0)
+			}
+		}
+		for i := range uint16(9) {
+			opl_chip.writeReg(opl_REGS_FREQ_1+i|array, 0)
+			opl_chip.writeReg(opl_REGS_FREQ_2+i|array, 0)
+			opl_chip.writeReg(opl_REGS_FEEDBACK+i|array, 0x30)
+		}
+	}
+	// Enable the waveforms, and set the keyboard split point
+	opl_chip.writeReg(0x01, 0x20)
+	opl_chip.writeReg(0x08, 0x40)
+	opl_chip.writeReg(0xbd, 0x00)
+}
+
+func oplInitChannels() {
+	for i := range opl_channels {
+		c := &opl_channels[i]
+		c.Finstr = &opl_main_instrs[0]
+		c.Fpan = 0x30
+		c.Fbend = 0
+		oplSetChannelVolume(int32(i), 100)
+	}
+}
+
+func oplLoadOperatorData(op uint16, data *genmidi_op_t, max_level bool) uint32 {
+	// The scale and level fields must be combined for the level register.
+	// For the carrier we always set the maximum level, until the voice
+	// volume is set.
+	level := uint32(data.Fscale)
+	if max_level {
+		level |= 0x3f
+	} else {
+		level |= uint32(data.Flevel)
+	}
+	opl_chip.writeReg(opl_REGS_LEVEL+op, byte(level))
+	opl_chip.writeReg(opl_REGS_TREMOLO+op, data.Ftremolo)
+	opl_chip.writeReg(opl_REGS_ATTACK+op, data.Fattack)
+	opl_chip.writeReg(opl_REGS_SUSTAIN+op, data.Fsustain)
+	opl_chip.writeReg(opl_REGS_WAVEFORM+op, data.Fwaveform)
+	return level
+}
+
+func oplSetVoiceInstrument(v *opl_voice_t, instr *genmidi_instr_t, instr_voice int32) {
+	// Instrument already set for this channel?
+	if v.Finstr == instr && v.Finstr_voice == instr_voice {
+		return
+	}
+	v.Finstr = instr
+	v.Finstr_voice = instr_voice
+	data := &instr.Fvoices[instr_voice]
+	// In modulated (FM) mode, only the carrier's volume changes
+	modulating := data.Ffeedback&1 == 0
+	v.Fcar_volume = oplLoadOperatorData(v.Fop2|v.Farray, &data.Fcarrier, true)
+	v.Fmod_volume = oplLoadOperatorData(v.Fop1|v.Farray, &data.Fmodulator, !modulating)
+	opl_chip.writeReg(opl_REGS_FEEDBACK+v.Findex|v.Farray, data.Ffeedback|v.Freg_pan)
+}
+
+func oplSetVoiceVolume(v *opl_voice_t, volume int32) {
+	v.Fnote_volume = volume
+	data := &v.Finstr.Fvoices[v.Finstr_voice]
+	// Multiply the note volume and channel volume to get the actual volume
+	midi_volume := 2 * (opl_volume_mapping_table[opl_channels[v.Fchannel].Fvolume] + 1)
+	full_volume := opl_volume_mapping_table[v.Fnote_volume] * midi_volume >> 9
+	car_volume := 0x3f - full_volume
+	if car_volume == v.Fcar_volume&0x3f {
+		return
+	}
+	v.Fcar_volume = car_volume | v.Fcar_volume&0xc0
+	opl_chip.writeReg(opl_REGS_LEVEL+v.Fop2|v.Farray, byte(v.Fcar_volume))
+	// In non-modulated feedback mode, both operators are heard, so the
+	// modulator volume must be set too
+	if data.Ffeedback&1 != 0 && data.Fmodulator.Flevel != 0x3f {
+		mod_volume := max(uint32(data.Fmodulator.Flevel), car_volume)
+		mod_volume |= v.Fmod_volume & 0xc0
+		if mod_volume != v.Fmod_volume {
+			v.Fmod_volume = mod_volume
+			opl_chip.writeReg(opl_REGS_LEVEL+v.Fop1|v.Farray, byte(mod_volume|uint32(data.Fmodulator.Fscale)&0xc0))
+		}
+	}
+}
+
+// oplVoiceFrequency works out the frequency and block register values for
+// a voice. DMX uses a table in 1/32 semitone steps, starting two semitones
+// below C0, which is calculated here instead.
+func oplVoiceFrequency(v *opl_voice_t) uint32 {
+	note := v.Fnote
+	data := &v.Finstr.Fvoices[v.Finstr_voice]
+	if v.Finstr.Fflags&genmidi_FLAG_FIXED == 0 {
+		note += int32(data.Fbase_note_offset)
+	}
+	// Avoid possible overflow due to the base note offset
+	for note < 0 {
+		note += 12
+	}
+	for note > 95 {
+		note -= 12
+	}
+	freq_index := 64 + 32*note + opl_channels[v.Fchannel].Fbend
+	// The second voice of a double voice instrument can be fine tuned
+	if v.Finstr_voice != 0 {
+		freq_index += int32(v.Finstr.Ffine_tuning)/2 - 64
+	}
+	freq_index = max(freq_index, 0)
+	hz := 16.3516 * math.Exp2(float64(freq_index-64)/(12*32))
+	fnum := hz * (1 << 20) / opl_RATE
+	var block uint32
+	for fnum >= 1024 && block < 7 {
+		fnum /= 2
+		block++
+	}
+	return min(uint32(math.Round(fnum)), 1023) | block<<10
+}
+
+func oplUpdateVoiceFrequency(v *opl_voice_t) {
+	freq := oplVoiceFrequency(v)
+	if v.Ffreq != freq {
+		opl_chip.writeReg(opl_REGS_FREQ_1+v.Findex|v.Farray, byte(freq))
+		opl_chip.writeReg(opl_REGS_FREQ_2+v.Findex|v.Farray, byte(freq>>8)|0x20)
+		v.Ffreq = freq
+	}
+}
+
+func oplVoiceKeyOff(v *opl_voice_t) {
+	opl_chip.writeReg(opl_REGS_FREQ_2+v.Findex|v.Farray, byte(v.Ffreq>>8))
+}
+
+// oplReleaseVoice turns off the n'th allocated voice, and returns it to the
+// free list
+func oplReleaseVoice(n int) {
+	index := opl_voice_alloced[n]
+	v := &opl_voices[index]
+	oplVoiceKeyOff(v)
+	v.Fchannel = -1
+	v.Fnote = 0
+	opl_voice_alloced = append(opl_voice_alloced[:n], opl_voice_alloced[n+1:]...)
+	opl_voice_free = append(opl_voice_free, index)
+}
+
+// oplReplaceExistingVoice frees up a voice when they're all in use. Second
+// voices of double voice instruments are non-essential, and lower numbered
+// MIDI channels are higher priority.
+func oplReplaceExistingVoice() {
+	result := 0
+	for i, index := range opl_voice_alloced {
+		v := &opl_voices[index]
+		if v.Finstr_voice != 0 || v.Fchannel >= opl_voices[opl_voice_alloced[result]].Fchannel {
+			result = i
+		}
+	}
+	oplReleaseVoice(result)
+}
+
+func oplVoiceKeyOn(channel int32, instr *genmidi_instr_t, instr_voice int32, note int32, key int32, volume int32) {
+	if len(opl_voice_free) == 0 {
+		return
+	}
+	index := opl_voice_free[0]
+	opl_voice_free = opl_voice_free[1:]
+	opl_voice_alloced = append(opl_voice_alloced, index)
+	v := &opl_voices[index]
+	v.Fchannel = channel
+	v.Fkey = key
+	// The note is normally the same as the key, unless it's a fixed pitch
+	// instrument
+	if instr.Fflags&genmidi_FLAG_FIXED != 0 {
+		v.Fnote = int32(instr.Ffixed_note)
+	} else {
+		v.Fnote = note
+	}
+	if v.Freg_pan != opl_channels[channel].Fpan {
+		v.Freg_pan = opl_channels[channel].Fpan
+		// Force the feedback register, which holds the pan, to be rewritten
+		v.Finstr = nil
+	}
+	oplSetVoiceInstrument(v, instr, instr_voice)
+	oplSetVoiceVolume(v, volume)
+	// Writing the frequency turns the note on
+	v.Ffreq = 0
+	oplUpdateVoiceFrequency(v)
+}
+
+func oplKeyOffEvent(channel int32, key int32) {
+	// There may be more than one voice, for double voice instruments
+	for i := 0; i < len(opl_voice_alloced); {
+		v := &opl_voices[opl_voice_alloced[i]]
+		if v.Fchannel == channel && v.Fkey == key {
+			oplReleaseVoice(i)
+		} else {
+			i++
+		}
+	}
+}
+
+func oplKeyOnEvent(channel int32, key int32, volume int32) {
+	// A volume of zero means key off
+	if volume == 0 {
+		oplKeyOffEvent(channel, key)
+		return
+	}
+	var instr *genmidi_instr_t
+	if channel == midi_PERCUSSION_CHAN {
+		if key < 35 || key > 81 {
+			return
+		}
+		instr = &opl_percussion_instrs[key-35]
+	} else {
+		instr = opl_channels[channel].Finstr
+	}
+	double_voice := instr.Fflags&genmidi_FLAG_2VOICE != 0
+	needed := 1
+	if double_voice {
+		needed = 2
+	}
+	for len(opl_voice_free) < needed && len(opl_voice_alloced) > 0 {
+		oplReplaceExistingVoice()
+	}
+	oplVoiceKeyOn(channel, instr, 0, key, key, volume)
+	if double_voice {
+		oplVoiceKeyOn(channel, instr, 1, key, key, volume)
+	}
+}
+
+func oplSetChannelVolume(channel int32, volume int32) {
+	c := &opl_channels[channel]
+	c.Fvolume_base = volume
+	c.Fvolume = min(volume, opl_music_volume)
+	for _, index := range opl_voice_alloced {
+		v := &opl_voices[index]
+		if v.Fchannel == channel {
+			oplSetVoiceVolume(v, v.Fnote_volume)
+		}
+	}
+}
+
+func oplSetChannelPan(channel int32, pan byte) {
+	// DMX has the stereo channels backwards, which isn't preserved here
+	var reg_pan byte
+	if pan >= 96 {
+		reg_pan = 0x20
+	} else if pan <= 48 {
+		reg_pan = 0x10
+	} else {
+		reg_pan = 0x30
+	}
+	c := &opl_channels[channel]
+	if c.Fpan == reg_pan {
+		return
+	}
+	c.Fpan = reg_pan
+	for _, index := range opl_voice_alloced {
+		v := &opl_voices[index]
+		if v.Fchannel == channel {
+			v.Freg_pan = reg_pan
+			data := &v.Finstr.Fvoices[v.Finstr_voice]
+			opl_chip.writeReg(opl_REGS_FEEDBACK+v.Findex|v.Farray, data.Ffeedback|reg_pan)
+		}
+	}
+}
+
+func oplAllNotesOff(channel int32) {
+	for i := 0; i < len(opl_voice_alloced); {
+		if opl_voices[opl_voice_alloced[i]].Fchannel == channel {
+			oplReleaseVoice(i)
+		} else {
+			i++
+		}
+	}
+}
+
+func oplPitchBendEvent(channel int32, bend byte) {
+	opl_channels[channel].Fbend = int32(bend) - 64
+	for _, index := range opl_voice_alloced {
+		v := &opl_voices[index]
+		if v.Fchannel == channel {
+			oplUpdateVoiceFrequency(v)
+		}
+	}
+}
+
+func oplProcessEvent(ev *midi_event_t) {
+	if ev.Fstatus == 0xff {
+		opl_tempo = ev.Ftempo
+		return
+	}
+	channel := int32(ev.Fstatus & 0x0f)
+	switch ev.Fstatus & 0xf0 {
+	case midi_releasekey:
+		oplKeyOffEvent(channel, int32(ev.Fdata1))
+	case midi_presskey:
+		oplKeyOnEvent(channel, int32(ev.Fdata1), int32(ev.Fdata2))
+	case midi_changecontroller:
+		switch ev.Fdata1 {
+		case 0x07: // Main volume
+			oplSetChannelVolume(channel, int32(ev.Fdata2))
+		case 0x0a: // Pan
+			oplSetChannelPan(channel, ev.Fdata2)
+		case 0x78, 0x7b: // All sounds off, all notes off
+			oplAllNotesOff(channel)
+		}
+	case midi_changepatch:
+		opl_channels[channel].Finstr = &opl_main_instrs[ev.Fdata1]
+	case midi_pitchwheel:
+		oplPitchBendEvent(channel, ev.Fdata2)
+	}
+}
+
+// oplAdvanceSong moves the song along by one chip sample, processing any
+// events that are due
+func oplAdvanceSong() {
+	opl_song_acc += uint64(opl_song.Fdivision) * 1000000
+	limit := uint64(opl_tempo) * opl_RATE
+	for opl_song_acc >= limit {
+		opl_song_acc -= limit
+		opl_song_tick++
+	}
+	for opl_song_pos < len(opl_song.Fevents) && opl_song.Fevents[opl_song_pos].Ftick <= opl_song_tick {
+		oplProcessEvent(&opl_song.Fevents[opl_song_pos])
+		opl_song_pos++
+	}
+	if opl_song_pos == len(opl_song.Fevents) {
+		if opl_looping != 0 {
+			oplRestartSong()
+		} else {
+			oplMusicStopSong()
+		}
+	}
+}
+
+func oplRestartSong() {
+	opl_song_pos = 0
+	opl_song_tick = 0
+	opl_song_acc = 0
+	opl_tempo = midi_DEFAULT_TEMPO
+}
+
+// oplMusicRender renders the given number of sample frames of music, at
+// AudioSampleRate, or returns nil if there is nothing to play
+func oplMusicRender(frames int) []int16 {
+	if opl_chip == nil || opl_paused != 0 {
+		return nil
+	}
+	if cap(opl_buffer) < frames*2 {
+		opl_buffer = make([]int16, frames*2)
+	}
+	buffer := opl_buffer[:frames*2]
+	const step = opl_RATE << 16 / AudioSampleRate
+	for i := range frames {
+		for opl_resample_pos >= 1<<16 {
+			opl_resample_pos -= 1 << 16
+			if opl_song != nil {
+				oplAdvanceSong()
+			}
+			opl_prev = opl_next
+			opl_next[0], opl_next[1] = opl_chip.generate()
+		}
+		// Linear interpolation between the chip's samples
+		frac := int64(opl_resample_pos)
+		buffer[i*2] = int16(opl_prev[0] + int32(int64(opl_next[0]-opl_prev[0])*frac>>16))
+		buffer[i*2+1] = int16(opl_prev[1] + int32(int64(opl_next[1]-opl_prev[1])*frac>>16))
+		opl_resample_pos += step
+	}
+	return buffer
+}
+
+func oplMusicSetVolume(volume int32) {
+	opl_music_volume = volume
+	for i := range opl_channels {
+		oplSetChannelVolume(int32(i), opl_channels[i].Fvolume_base)
+	}
+}
+
+func oplMusicPause() {
+	// Freezing the chip holds the notes until the music is resumed
+	opl_paused = 1
+}
+
+func oplMusicResume() {
+	opl_paused = 0
+}
+
+func oplMusicRegisterSong(data []byte) uintptr {
+	midi := musicToMIDI(data)
+	if midi == nil {
+		return 0
+	}
+	song := parseMIDI(midi)
+	if song == nil {
+		fprintf_ccgo(os.Stderr, "oplMusicRegisterSong: Failed to parse MIDI\n")
+		return 0
+	}
+	opl_next_handle++
+	opl_songs[opl_next_handle] = song
+	return opl_next_handle
+}
+
+func oplMusicUnRegisterSong(handle uintptr) {
+	delete(opl_songs, handle)
+}
+
+func oplMusicPlaySong(handle uintptr, looping boolean) boolean {
+	song, ok := opl_songs[handle]
+	if !ok {
+		return 0
+	}
+	oplMusicStopSong()
+	opl_song = song
+	opl_looping = looping
+	oplRestartSong()
+	oplInitChannels()
+	return 1
+}
+
+func oplMusicStopSong() {
+	if opl_song == nil {
+		return
+	}
+	opl_song = nil
+	for len(opl_voice_alloced) > 0 {
+		oplReleaseVoice(0)
+	}
+}
+
+func oplMusicIsPlaying() boolean {
+	return booluint32(opl_song != nil)
+}
+
+// parseMIDI reads a standard MIDI file, merging all of its tracks into a
+// single list of events. Invalid files return nil.
+func parseMIDI(data []byte) *midi_song_t {
+	if len(data) < 14 || string(data[:4]) != "MThd" {
+		return nil
+	}
+	header_len := binary.BigEndian.Uint32(data[4:])
+	num_tracks := int(binary.BigEndian.Uint16(data[10:]))
+	division := uint32(binary.BigEndian.Uint16(data[12:]))
+	if header_len < 6 || uint64(header_len)+8 > uint64(len(data)) || division == 0 || division&0x8000 != 0 {
+		// SMPTE time divisions aren't supported
+		return nil
+	}
+	song := &midi_song_t{Fdivision: division}
+	data = data[8+header_len:]
+	for range num_tracks {
+		if len(data) < 8 {
+			return nil
+		}
+		length := binary.BigEndian.Uint32(data[4:])
+		if uint64(length)+8 > uint64(len(data)) {
+			return nil
+		}
+		if string(data[:4]) == "MTrk" {
+			events, ok := parseMIDITrack(data[8 : 8+length])
+			if !ok {
+				return nil
+			}
+			song.Fevents = mergeMIDIEvents(song.Fevents, events)
+		}
+		data = data[8+length:]
+	}
+	return song
+}
+
+func parseMIDITrack(track []byte) ([]midi_event_t, bool) {
+	var events []midi_event_t
+	var tick uint32
+	var running byte
+	pos := 0
+	readByte := func() (byte, bool) {
+		if pos >= len(track) {
+			return 0, false
+		}
+		pos++
+		return track[pos-1], true
+	}
+	readVarLen := func() (uint32, bool) {
+		var value uint32
+		for range 4 {
+			b, ok := readByte()
+			if !ok {
+				return 0, false
+			}
+			value = value<<7 | uint32(b&0x7f)
+			if b&0x80 == 0 {
+				return value, true
+			}
+		}
+		return 0, false
+	}
+	for pos < len(track) {
+		delta, ok := readVarLen()
+		if !ok {
+			return nil, false
+		}
+		tick += delta
+		status, ok := readByte()
+		if !ok {
+			return nil, false
+		}
+		switch {
+		case status == 0xff:
+			meta_type, ok := readByte()
+			if !ok {
+				return nil, false
+			}
+			length, ok := readVarLen()
+			if !ok || uint64(pos)+uint64(length) > uint64(len(track)) {
+				return nil, false
+			}
+			meta := track[pos : pos+int(length)]
+			pos += int(length)
+			if meta_type == 0x2f {
+				// End of track
+				return events, true
+			}
+			if meta_type == 0x51 && length == 3 {
+				events = append(events, midi_event_t{
+					Ftick:   tick,
+					Fstatus: 0xff,
+					Ftempo:  uint32(meta[0])<<16 | uint32(meta[1])<<8 | uint32(meta[2]),
+				})
+			}
+		case status == 0xf0 || status == 0xf7:
+			// System exclusive events are skipped
+			length, ok := readVarLen()
+			if !ok || uint64(pos)+uint64(length) > uint64(len(track)) {
+				return nil, false
+			}
+			pos += int(length)
+		default:
+			ev := midi_event_t{Ftick: tick}
+			if status&0x80 != 0 {
+				running = status
+				ev.Fstatus = status
+				if ev.Fdata1, ok = readByte(); !ok {
+					return nil, false
+				}
+			} else if running != 0 {
+				// Running status, so this is the first data byte
+				ev.Fstatus = running
+				ev.Fdata1 = status
+			} else {
+				return nil, false
+			}
+			// Program change and channel pressure have a single data byte
+			if ev.Fstatus&0xf0 != 0xc0 && ev.Fstatus&0xf0 != 0xd0 {
+				if ev.Fdata2, ok = readByte(); !ok {
+					return nil, false
+				}
+			}
+			ev.Fdata1 &= 0x7f
+			ev.Fdata2 &= 0x7f
+			events = append(events, ev)
+		}
+	}
+	return events, true
+}
+
+// mergeMIDIEvents merges two time ordered event lists
+func mergeMIDIEvents(a, b []midi_event_t) []midi_event_t {
+	if len(a) == 0 {
+		return b
+	}
+	merged := make([]midi_event_t, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if b[0].Ftick < a[0].Ftick {
+			merged = append(merged, b[0])
+			b = b[1:]
+		} else {
+			merged = append(merged, a[0])
+			a = a[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
@@ -0,0 +1,98 @@
+package gore
+
+import (
+	"image"
+	"image/color"
+	"slices"
+)
+
+// The screen is drawn as 8-bit palette indexes, which are normally converted
+// to RGBA for DrawFrame. Frontends which can use the palette directly (ie:
+// uploading it to a GPU texture, or encoding GIF or PNG) can skip that.
+
+// DoomPalettedFrontend can optionally be implemented by a DoomFrontend to
+// receive each frame as the raw screen and palette, rather than converted to
+// RGBA.
+type DoomPalettedFrontend interface {
+	// DrawPalettedFrame is called instead of DrawFrame. The image is the
+	// screen the engine draws to, and its palette is updated as the palette
+	// changes (ie: when the player is hurt), so both must be copied if they
+	// are to be retained.
+	DrawPalettedFrame(img *image.Paletted)
+}
+
+// pal_enabled skips converting the screen to RGBA
+var pal_enabled bool
+var pal_frontend DoomPalettedFrontend
+var pal_screen *image.Paletted
+
+type pal_hook_t struct {
+	Fhook func(img *image.Paletted, tic int)
+}
+
+var pal_hooks []*pal_hook_t
+
+// i_InitPalettedScreen wraps I_VideoBuffer in pal_screen
+func i_InitPalettedScreen() {
+	pal_frontend, _ = dg_frontend.(DoomPalettedFrontend)
+	if pal_frontend != nil {
+		pal_enabled = true
+	}
+	pal_screen = &image.Paletted{
+		Pix:     I_VideoBuffer,
+		Stride:  int(screenwidth),
+		Rect:    image.Rect(0, 0, int(screenwidth), int(screenheight)),
+		Palette: make(color.Palette, len(colors)),
+	}
+	i_UpdatePalettedScreen()
+}
+
+// i_UpdatePalettedScreen copies the palette set by i_SetPalette into
+// pal_screen. i_SetPalette only sets the RGB of colors, so they're made
+// opaque here.
+func i_UpdatePalettedScreen() {
+	if pal_screen == nil {
+		return
+	}
+	for i, c := range colors {
+		pal_screen.Palette[i] = color.RGBA{R: c.R, G: c.G, B: c.B, A: 0xff}
+	}
+}
+
+// i_CallFrameHooks passes the frame which has just been drawn to the hooks
+// added by AddFrameHook
+func i_CallFrameHooks() {
+	for _, h := range pal_hooks {
+		i_Callback(func() { h.Fhook(pal_screen, int(gametic)) })
+	}
+}
+
+// AddFrameHook calls hook with each frame as it is drawn, along with the
+// number of tics which have run, ie: to record the game. Usually there is one
+// frame per tic, but there may be several with Options.Uncapped, or tics may
+// be skipped if the computer can't keep up. The image is the screen the
+// engine draws to, so must be copied if it is to be retained. The hook is
+// called from whichever goroutine is running the game, with the game locked,
+// so calling any of the Game's methods from it (including removing the hook)
+// returns ErrReentrant. The returned function removes the hook.
+func (g *Game) AddFrameHook(hook func(img *image.Paletted, tic int)) (remove func() error, err error) {
+	if err := g.lock(); err != nil {
+		return nil, err
+	}
+	defer g.unlock()
+	if g.closed {
+		return nil, ErrClosed
+	}
+	h := &pal_hook_t{Fhook: hook}
+	pal_hooks = append(pal_hooks, h)
+	return func() error {
+		if err := g.lock(); err != nil {
+			return err
+		}
+		defer g.unlock()
+		if !g.closed {
+			pal_hooks = slices.DeleteFunc(pal_hooks, func(other *pal_hook_t) bool { return other == h })
+		}
+		return nil
+	}, nil
+}
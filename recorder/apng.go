@@ -0,0 +1,122 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"math"
+)
+
+// An animated PNG is an ordinary PNG of the first frame, with an acTL chunk
+// giving the number of frames, an fcTL chunk before each frame giving its
+// delay, and the image data of the others in fdAT chunks, which are IDAT
+// chunks with a sequence number. Each frame is encoded by image/png, and its
+// image data is pulled out of the result.
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+type apngFrame struct {
+	data []byte // Concatenated IDAT chunk data
+	tics int
+}
+
+type apngEncoder struct {
+	w      io.Writer
+	enc    png.Encoder
+	ihdr   []byte
+	frames []apngFrame
+	buf    bytes.Buffer
+}
+
+func newAPNGEncoder(w io.Writer) *apngEncoder {
+	return &apngEncoder{w: w, enc: png.Encoder{CompressionLevel: png.BestSpeed}}
+}
+
+func (e *apngEncoder) writeFrame(img image.Image, tics int) error {
+	// Always encode as RGB, as frames can't have their own palettes
+	e.buf.Reset()
+	if err := e.enc.Encode(&e.buf, toRGBA(img)); err != nil {
+		return err
+	}
+	chunks := e.buf.Bytes()[len(pngSignature):]
+	var frame apngFrame
+	frame.tics = tics
+	for len(chunks) >= 12 {
+		length := binary.BigEndian.Uint32(chunks)
+		if uint64(length)+12 > uint64(len(chunks)) {
+			break
+		}
+		kind, data := string(chunks[4:8]), chunks[8:8+length]
+		switch kind {
+		case "IHDR":
+			if e.ihdr == nil {
+				e.ihdr = bytes.Clone(data)
+			} else if !bytes.Equal(e.ihdr, data) {
+				return errors.New("recorder: frame size changed")
+			}
+		case "IDAT":
+			frame.data = append(frame.data, data...)
+		}
+		chunks = chunks[12+length:]
+	}
+	e.frames = append(e.frames, frame)
+	return nil
+}
+
+func (e *apngEncoder) close() error {
+	if len(e.frames) == 0 {
+		return nil
+	}
+	var out bytes.Buffer
+	out.Write(pngSignature)
+	writeChunk(&out, "IHDR", e.ihdr)
+	writeChunk(&out, "acTL", binary.BigEndian.AppendUint32(
+		binary.BigEndian.AppendUint32(nil, uint32(len(e.frames))),
+		0)) // Loop forever
+	width, height := e.ihdr[0:4], e.ihdr[4:8]
+	var seq uint32
+	for i, frame := range e.frames {
+		fctl := binary.BigEndian.AppendUint32(nil, seq)
+		seq++
+		fctl = append(fctl, width...)
+		fctl = append(fctl, height...)
+		fctl = binary.BigEndian.AppendUint32(fctl, 0) // x offset
+		fctl = binary.BigEndian.AppendUint32(fctl, 0) // y offset
+		fctl = binary.BigEndian.AppendUint16(fctl, uint16(min(frame.tics, math.MaxUint16)))
+		fctl = binary.BigEndian.AppendUint16(fctl, TicRate)
+		fctl = append(fctl, 0, 0) // Dispose and blend ops: none, replace
+		writeChunk(&out, "fcTL", fctl)
+		if i == 0 {
+			writeChunk(&out, "IDAT", frame.data)
+			continue
+		}
+		fdat := binary.BigEndian.AppendUint32(nil, seq)
+		seq++
+		writeChunk(&out, "fdAT", append(fdat, frame.data...))
+		// Write as we go, rather than keeping two copies of everything
+		if _, err := e.w.Write(out.Bytes()); err != nil {
+			return err
+		}
+		out.Reset()
+	}
+	writeChunk(&out, "IEND", nil)
+	_, err := e.w.Write(out.Bytes())
+	return err
+}
+
+// writeChunk writes a PNG chunk, with its length and CRC
+func writeChunk(w *bytes.Buffer, kind string, data []byte) {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], kind)
+	w.Write(header[:])
+	w.Write(data)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	binary.Write(w, binary.BigEndian, crc.Sum32())
+}
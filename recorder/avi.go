@@ -0,0 +1,180 @@
+package recorder
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+)
+
+// An uncompressed AVI is a RIFF file with a header list describing the one
+// video stream, a movi list holding each frame as a bottom-up 24-bit BGR
+// bitmap, and an index of where each frame is. The frame counts and sizes in
+// the header are filled in once everything has been written.
+
+// Offsets of the fields filled in by close, from the start of the file
+const (
+	aviRIFFSize        = 4
+	aviTotalFrames     = 48
+	aviStreamLength    = 140
+	aviMoviSize        = 216
+	aviMovi            = 220 // The movi list's fourcc, which index offsets are relative to
+	aviHeaderLength    = 224
+	aviFlagHasIndex    = 0x10
+	aviFlagKeyFrame    = 0x10
+	aviMaxFileSize     = 1<<32 - 1
+	aviIndexEntryBytes = 16
+)
+
+type aviEncoder struct {
+	w             io.WriteSeeker
+	start         int64 // Where the file starts in w
+	width, height int
+	stride        int
+	frame         []byte
+	index         []byte // idx1 entries
+	pos           int64  // Bytes written since start
+	frames        uint32
+}
+
+func newAVIEncoder(w io.WriteSeeker) (*aviEncoder, error) {
+	start, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	return &aviEncoder{w: w, start: start}, nil
+}
+
+func (e *aviEncoder) write(data []byte) error {
+	n, err := e.w.Write(data)
+	e.pos += int64(n)
+	return err
+}
+
+// writeHeader writes everything up to the first frame
+func (e *aviEncoder) writeHeader() error {
+	le := binary.LittleEndian
+	frameSize := uint32(e.stride * e.height)
+	h := make([]byte, 0, aviHeaderLength)
+	h = append(h, "RIFF"...)
+	h = le.AppendUint32(h, 0) // Filled in by close
+	h = append(h, "AVI LIST"...)
+	h = le.AppendUint32(h, 192)
+	h = append(h, "hdrlavih"...)
+	h = le.AppendUint32(h, 56)
+	h = le.AppendUint32(h, 1000000/TicRate) // Microseconds per frame
+	h = le.AppendUint32(h, frameSize*TicRate)
+	h = le.AppendUint32(h, 0) // Padding granularity
+	h = le.AppendUint32(h, aviFlagHasIndex)
+	h = le.AppendUint32(h, 0) // Total frames, filled in by close
+	h = le.AppendUint32(h, 0) // Initial frames
+	h = le.AppendUint32(h, 1) // Streams
+	h = le.AppendUint32(h, frameSize)
+	h = le.AppendUint32(h, uint32(e.width))
+	h = le.AppendUint32(h, uint32(e.height))
+	h = append(h, make([]byte, 16)...) // Reserved
+	h = append(h, "LIST"...)
+	h = le.AppendUint32(h, 116)
+	h = append(h, "strlstrh"...)
+	h = le.AppendUint32(h, 56)
+	h = append(h, "vidsDIB "...)
+	h = le.AppendUint32(h, 0) // Flags
+	h = le.AppendUint32(h, 0) // Priority and language
+	h = le.AppendUint32(h, 0) // Initial frames
+	h = le.AppendUint32(h, 1) // Scale
+	h = le.AppendUint32(h, TicRate)
+	h = le.AppendUint32(h, 0) // Start
+	h = le.AppendUint32(h, 0) // Length, filled in by close
+	h = le.AppendUint32(h, frameSize)
+	h = le.AppendUint32(h, 0xffffffff) // Default quality
+	h = le.AppendUint32(h, 0)          // Sample size
+	h = le.AppendUint16(h, 0)          // Frame rectangle
+	h = le.AppendUint16(h, 0)
+	h = le.AppendUint16(h, uint16(e.width))
+	h = le.AppendUint16(h, uint16(e.height))
+	h = append(h, "strf"...)
+	h = le.AppendUint32(h, 40)
+	h = le.AppendUint32(h, 40) // BITMAPINFOHEADER size
+	h = le.AppendUint32(h, uint32(e.width))
+	h = le.AppendUint32(h, uint32(e.height)) // Positive, so bottom-up
+	h = le.AppendUint16(h, 1)                // Planes
+	h = le.AppendUint16(h, 24)               // Bits per pixel
+	h = le.AppendUint32(h, 0)                // BI_RGB
+	h = le.AppendUint32(h, frameSize)
+	h = append(h, make([]byte, 16)...) // Resolution and palette sizes
+	h = append(h, "LIST"...)
+	h = le.AppendUint32(h, 0) // Filled in by close
+	h = append(h, "movi"...)
+	return e.write(h)
+}
+
+func (e *aviEncoder) writeFrame(img image.Image, tics int) error {
+	rgba := toRGBA(img)
+	if e.frame == nil {
+		e.width, e.height = rgba.Rect.Dx(), rgba.Rect.Dy()
+		e.stride = (e.width*3 + 3) &^ 3
+		e.frame = make([]byte, 8+e.stride*e.height)
+		copy(e.frame, "00db")
+		binary.LittleEndian.PutUint32(e.frame[4:], uint32(e.stride*e.height))
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	} else if rgba.Rect.Dx() != e.width || rgba.Rect.Dy() != e.height {
+		return errors.New("recorder: frame size changed")
+	}
+	pix := e.frame[8:]
+	for y := range e.height {
+		src := rgba.Pix[y*rgba.Stride:]
+		dst := pix[(e.height-1-y)*e.stride:]
+		for x := range e.width {
+			dst[x*3] = src[x*4+2]
+			dst[x*3+1] = src[x*4+1]
+			dst[x*3+2] = src[x*4]
+		}
+	}
+	for range tics {
+		if e.pos+int64(len(e.frame)+len(e.index)+aviIndexEntryBytes+8) > aviMaxFileSize {
+			return errors.New("recorder: AVI is too large")
+		}
+		offset := e.pos - aviMovi
+		if err := e.write(e.frame); err != nil {
+			return err
+		}
+		e.index = append(e.index, "00db"...)
+		e.index = binary.LittleEndian.AppendUint32(e.index, aviFlagKeyFrame)
+		e.index = binary.LittleEndian.AppendUint32(e.index, uint32(offset))
+		e.index = binary.LittleEndian.AppendUint32(e.index, uint32(len(e.frame)-8))
+		e.frames++
+	}
+	return nil
+}
+
+func (e *aviEncoder) close() error {
+	if e.frame == nil {
+		return nil
+	}
+	moviSize := e.pos - aviMovi
+	idx1 := binary.LittleEndian.AppendUint32([]byte("idx1"), uint32(len(e.index)))
+	if err := e.write(append(idx1, e.index...)); err != nil {
+		return err
+	}
+	fields := []struct {
+		offset int64
+		value  uint32
+	}{
+		{aviRIFFSize, uint32(e.pos - 8)},
+		{aviTotalFrames, e.frames},
+		{aviStreamLength, e.frames},
+		{aviMoviSize, uint32(moviSize)},
+	}
+	for _, field := range fields {
+		if _, err := e.w.Seek(e.start+field.offset, io.SeekStart); err != nil {
+			return err
+		}
+		if err := binary.Write(e.w, binary.LittleEndian, field.value); err != nil {
+			return err
+		}
+	}
+	_, err := e.w.Seek(e.start+e.pos, io.SeekStart)
+	return err
+}
@@ -0,0 +1,58 @@
+package recorder
+
+import (
+	"bytes"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"slices"
+)
+
+type gifEncoder struct {
+	w       io.Writer
+	anim    gif.GIF
+	elapsed int // Tics up to the end of the last frame
+}
+
+func newGIFEncoder(w io.Writer) *gifEncoder {
+	return &gifEncoder{w: w}
+}
+
+// gifTime converts tics to the hundredths of a second GIF delays are in
+func gifTime(tics int) int {
+	return (tics*100 + TicRate/2) / TicRate
+}
+
+func (e *gifEncoder) writeFrame(img image.Image, tics int) error {
+	frame, ok := img.(*image.Paletted)
+	if !ok {
+		frame = image.NewPaletted(img.Bounds(), palette.Plan9)
+		draw.Draw(frame, frame.Rect, img, img.Bounds().Min, draw.Src)
+	}
+	delay := gifTime(e.elapsed+tics) - gifTime(e.elapsed)
+	e.elapsed += tics
+	// Lengthen the previous frame rather than repeating it
+	if n := len(e.anim.Image); n > 0 {
+		prev := e.anim.Image[n-1]
+		if bytes.Equal(prev.Pix, frame.Pix) && slices.Equal(prev.Palette, frame.Palette) {
+			e.anim.Delay[n-1] += delay
+			return nil
+		}
+	}
+	e.anim.Image = append(e.anim.Image, frame)
+	e.anim.Delay = append(e.anim.Delay, delay)
+	return nil
+}
+
+func (e *gifEncoder) close() error {
+	if len(e.anim.Image) == 0 {
+		return nil
+	}
+	// Frames only need their own palette when it differs from the first
+	e.anim.Config.ColorModel = e.anim.Image[0].Palette
+	e.anim.Config.Width = e.anim.Image[0].Rect.Dx()
+	e.anim.Config.Height = e.anim.Image[0].Rect.Dy()
+	return gif.EncodeAll(e.w, &e.anim)
+}
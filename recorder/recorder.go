@@ -0,0 +1,273 @@
+// Package recorder records gore games as animated GIF or PNG, or as
+// uncompressed video, without needing any external tools. Frames are timed
+// by the game's tics, so the recording plays back at the game's 35 frames a
+// second, however fast or slow it was drawn.
+//
+//	rec, err := recorder.Create("e1m1.gif")
+//	...
+//	err = rec.Attach(game)
+//	game.Run()
+//	err = rec.Close()
+package recorder
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// TicRate is the number of tics the game runs per second, and so the frame
+// rate of the recordings
+const TicRate = 35
+
+// Format is the type of file to record
+type Format int
+
+const (
+	// GIF is an animated GIF, using Doom's own palette. The whole animation
+	// is kept in memory until the recorder is closed.
+	GIF Format = iota
+	// APNG is an animated PNG. Each frame is compressed as it is recorded,
+	// but they are kept in memory until the recorder is closed.
+	APNG
+	// AVI is uncompressed 24-bit AVI video. It is written as it is
+	// recorded, but the writer must be an io.WriteSeeker so the header can
+	// be filled in once the length is known.
+	AVI
+	// Y4M is a YUV4MPEG2 stream, as understood by ffmpeg and most video
+	// encoders, with 4:2:0 chroma. It is written as it is recorded.
+	Y4M
+)
+
+func (f Format) String() string {
+	switch f {
+	case GIF:
+		return "GIF"
+	case APNG:
+		return "APNG"
+	case AVI:
+		return "AVI"
+	case Y4M:
+		return "Y4M"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// FormatForFile picks a format from a filename's extension: .gif, .png or
+// .apng, .avi, or .y4m
+func FormatForFile(name string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gif":
+		return GIF, nil
+	case ".png", ".apng":
+		return APNG, nil
+	case ".avi":
+		return AVI, nil
+	case ".y4m":
+		return Y4M, nil
+	}
+	return 0, fmt.Errorf("recorder: unknown format for %q", name)
+}
+
+// FrameSource is something frames can be recorded from, ie: a *gore.Game
+type FrameSource interface {
+	AddFrameHook(hook func(img *image.Paletted, tic int)) (remove func() error, err error)
+}
+
+// encoder writes frames in a particular format
+type encoder interface {
+	// writeFrame adds img, to be shown for the given number of tics
+	writeFrame(img image.Image, tics int) error
+	// close finishes the file
+	close() error
+}
+
+// ErrClosed is returned when writing frames to a recorder which has been
+// closed
+var ErrClosed = errors.New("recorder: closed")
+
+// Recorder writes frames to a file. Each frame is shown until the tic of the
+// next one.
+type Recorder struct {
+	lock       sync.Mutex
+	enc        encoder
+	file       io.Closer // Opened by Create
+	pending    image.Image
+	pendingTic int
+	detach     func() error
+	err        error
+	closed     bool
+}
+
+// New creates a recorder which writes to w in the given format
+func New(w io.Writer, format Format) (*Recorder, error) {
+	var enc encoder
+	var err error
+	switch format {
+	case GIF:
+		enc = newGIFEncoder(w)
+	case APNG:
+		enc = newAPNGEncoder(w)
+	case AVI:
+		ws, ok := w.(io.WriteSeeker)
+		if !ok {
+			return nil, errors.New("recorder: AVI needs an io.WriteSeeker")
+		}
+		enc, err = newAVIEncoder(ws)
+	case Y4M:
+		enc = newY4MEncoder(w)
+	default:
+		err = fmt.Errorf("recorder: unknown format %v", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{enc: enc}, nil
+}
+
+// Create creates a recorder which writes to the named file, in the format
+// given by its extension, as chosen by FormatForFile
+func Create(name string) (*Recorder, error) {
+	format, err := FormatForFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	r, err := New(f, format)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.file = f
+	return r, nil
+}
+
+// Attach records every frame drawn by src, until the recorder is closed. Any
+// error writing them is returned by Close.
+func (r *Recorder) Attach(src FrameSource) error {
+	detach, err := src.AddFrameHook(func(img *image.Paletted, tic int) {
+		r.WriteFrame(img, tic)
+	})
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closed {
+		// Don't call the hook's remove function with our lock held, as it
+		// waits for the game
+		defer detach()
+		return ErrClosed
+	}
+	prev := r.detach
+	r.detach = func() error {
+		var err error
+		if prev != nil {
+			err = prev()
+		}
+		return errors.Join(err, detach())
+	}
+	return nil
+}
+
+// WriteFrame records img, which was drawn after the given number of tics.
+// The previous frame is shown until then; frames drawn during the same tic
+// as the previous one are skipped. img is copied, so may be reused.
+func (r *Recorder) WriteFrame(img image.Image, tic int) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closed {
+		return ErrClosed
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if r.pending != nil {
+		if tic == r.pendingTic {
+			return nil
+		}
+		tics := tic - r.pendingTic
+		if tics < 0 {
+			// The tic count has been reset
+			tics = 1
+		}
+		if r.err = r.enc.writeFrame(r.pending, tics); r.err != nil {
+			return r.err
+		}
+	}
+	r.pending = copyImage(img)
+	r.pendingTic = tic
+	return nil
+}
+
+// Close stops recording, and finishes the file. If the recorder was created
+// with Create, the file is closed.
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	detach := r.detach
+	r.detach = nil
+	r.lock.Unlock()
+	// Wait for the game to finish with the hook before closing
+	var detachErr error
+	if detach != nil {
+		detachErr = detach()
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closed {
+		return ErrClosed
+	}
+	r.closed = true
+	if r.pending != nil && r.err == nil {
+		r.err = r.enc.writeFrame(r.pending, 1)
+	}
+	r.pending = nil
+	if err := r.enc.close(); r.err == nil {
+		r.err = err
+	}
+	if r.file != nil {
+		if err := r.file.Close(); r.err == nil {
+			r.err = err
+		}
+	}
+	if r.err == nil {
+		r.err = detachErr
+	}
+	return r.err
+}
+
+// copyImage copies img, keeping it paletted if it is
+func copyImage(img image.Image) image.Image {
+	bounds := img.Bounds()
+	if p, ok := img.(*image.Paletted); ok {
+		dst := image.NewPaletted(image.Rect(0, 0, bounds.Dx(), bounds.Dy()), append(p.Palette[:0:0], p.Palette...))
+		for y := range bounds.Dy() {
+			copy(dst.Pix[y*dst.Stride:], p.Pix[p.PixOffset(bounds.Min.X, bounds.Min.Y+y):][:bounds.Dx()])
+		}
+		return dst
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Rect, img, bounds.Min, draw.Src)
+	return dst
+}
+
+// toRGBA converts img to RGBA, starting at 0,0
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	if rgba, ok := img.(*image.RGBA); ok && bounds.Min == (image.Point{}) {
+		return rgba
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Rect, img, bounds.Min, draw.Src)
+	return dst
+}
@@ -0,0 +1,239 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testFrame returns a paletted frame filled with a single colour
+func testFrame(c color.RGBA) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, 32, 20), color.Palette{color.RGBA{A: 0xff}, c})
+	for i := range img.Pix {
+		img.Pix[i] = 1
+	}
+	return img
+}
+
+var (
+	red   = color.RGBA{R: 0xff, A: 0xff}
+	green = color.RGBA{G: 0xff, A: 0xff}
+	blue  = color.RGBA{B: 0xff, A: 0xff}
+)
+
+// record writes red at tic 0, green at tic 1 (twice), and blue at tic 3
+func record(t *testing.T, r *Recorder) {
+	t.Helper()
+	frames := []struct {
+		img *image.Paletted
+		tic int
+	}{
+		{testFrame(red), 0},
+		{testFrame(green), 1},
+		{testFrame(blue), 1}, // Skipped, as it is in the same tic
+		{testFrame(blue), 3},
+	}
+	for _, frame := range frames {
+		if err := r.WriteFrame(frame.img, frame.tic); err != nil {
+			t.Fatalf("Error writing frame: %v", err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Error closing recorder: %v", err)
+	}
+}
+
+func TestGIF(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	r, err := New(&buf, GIF)
+	if err != nil {
+		t.Fatalf("Error creating recorder: %v", err)
+	}
+	record(t, r)
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatalf("Error decoding GIF: %v", err)
+	}
+	expected := []struct {
+		c     color.RGBA
+		delay int
+	}{
+		{red, 3},
+		{green, 6},
+		{blue, 2},
+	}
+	if len(anim.Image) != len(expected) {
+		t.Fatalf("GIF has %d frames, expected %d", len(anim.Image), len(expected))
+	}
+	for i, e := range expected {
+		if c := color.RGBAModel.Convert(anim.Image[i].At(5, 5)); c != e.c {
+			t.Errorf("Frame %d is %v, expected %v", i, c, e.c)
+		}
+		if anim.Delay[i] != e.delay {
+			t.Errorf("Frame %d delay is %d, expected %d", i, anim.Delay[i], e.delay)
+		}
+	}
+}
+
+func TestAPNG(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	r, err := New(&buf, APNG)
+	if err != nil {
+		t.Fatalf("Error creating recorder: %v", err)
+	}
+	record(t, r)
+	// The first frame is the default image
+	img, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Error decoding PNG: %v", err)
+	}
+	if c := color.RGBAModel.Convert(img.At(5, 5)); c != red {
+		t.Errorf("First frame is %v, expected %v", c, red)
+	}
+	var delays []uint16
+	chunks := buf.Bytes()[len(pngSignature):]
+	for len(chunks) > 0 {
+		length := binary.BigEndian.Uint32(chunks)
+		kind, data := string(chunks[4:8]), chunks[8:8+length]
+		if kind == "acTL" {
+			if frames := binary.BigEndian.Uint32(data); frames != 3 {
+				t.Errorf("acTL has %d frames, expected 3", frames)
+			}
+		}
+		if kind == "fcTL" {
+			delays = append(delays, binary.BigEndian.Uint16(data[20:]))
+		}
+		chunks = chunks[12+length:]
+	}
+	if len(delays) != 3 || delays[0] != 1 || delays[1] != 2 || delays[2] != 1 {
+		t.Errorf("Delays are %v tics, expected [1 2 1]", delays)
+	}
+}
+
+func TestAVI(t *testing.T) {
+	t.Parallel()
+	name := filepath.Join(t.TempDir(), "test.avi")
+	r, err := Create(name)
+	if err != nil {
+		t.Fatalf("Error creating recorder: %v", err)
+	}
+	record(t, r)
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("Error reading AVI: %v", err)
+	}
+	le := binary.LittleEndian
+	if size := le.Uint32(data[aviRIFFSize:]); int(size) != len(data)-8 {
+		t.Errorf("RIFF size is %d, expected %d", size, len(data)-8)
+	}
+	// Frames are repeated to make up the tics
+	if frames := le.Uint32(data[aviTotalFrames:]); frames != 4 {
+		t.Errorf("AVI has %d frames, expected 4", frames)
+	}
+	stride := (32*3 + 3) &^ 3
+	frameSize := 8 + stride*20
+	if string(data[aviMovi:aviMovi+4]) != "movi" || int(le.Uint32(data[aviMoviSize:])) != 4+4*frameSize {
+		t.Errorf("Bad movi list")
+	}
+	// The pixels are BGR
+	if pixel := data[aviHeaderLength+8 : aviHeaderLength+11]; !bytes.Equal(pixel, []byte{0, 0, 0xff}) {
+		t.Errorf("First pixel is %v, expected red", pixel)
+	}
+	index := data[aviMovi+4+4*frameSize:]
+	if string(index[:4]) != "idx1" || le.Uint32(index[4:]) != 4*aviIndexEntryBytes {
+		t.Errorf("Bad index")
+	}
+
+	if _, err := New(&bytes.Buffer{}, AVI); err == nil {
+		t.Errorf("AVI created with a writer which can't seek")
+	}
+}
+
+func TestY4M(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	r, err := New(&buf, Y4M)
+	if err != nil {
+		t.Fatalf("Error creating recorder: %v", err)
+	}
+	record(t, r)
+	header, frames, _ := strings.Cut(buf.String(), "\n")
+	if header != "YUV4MPEG2 W32 H20 F35:1 Ip C420jpeg XCOLORRANGE=FULL" {
+		t.Errorf("Unexpected header %q", header)
+	}
+	frameSize := len("FRAME\n") + 32*20 + 2*16*10
+	if len(frames) != 4*frameSize {
+		t.Errorf("Stream is %d bytes, expected 4 frames of %d", len(frames), frameSize)
+	}
+	// Red has a high Cr
+	if cr := frames[len("FRAME\n")+32*20+16*10]; cr < 0xf0 {
+		t.Errorf("Red has Cr %d", cr)
+	}
+}
+
+type testSource struct {
+	hook func(img *image.Paletted, tic int)
+}
+
+func (s *testSource) AddFrameHook(hook func(img *image.Paletted, tic int)) (remove func() error, err error) {
+	s.hook = hook
+	return func() error {
+		s.hook = nil
+		return nil
+	}, nil
+}
+
+func TestAttach(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	r, err := New(&buf, GIF)
+	if err != nil {
+		t.Fatalf("Error creating recorder: %v", err)
+	}
+	src := &testSource{}
+	if err := r.Attach(src); err != nil {
+		t.Fatalf("Error attaching recorder: %v", err)
+	}
+	src.hook(testFrame(red), 0)
+	src.hook(testFrame(green), 1)
+	if err := r.Close(); err != nil {
+		t.Fatalf("Error closing recorder: %v", err)
+	}
+	if src.hook != nil {
+		t.Errorf("Hook wasn't removed when closed")
+	}
+	if err := r.WriteFrame(testFrame(blue), 2); err != ErrClosed {
+		t.Errorf("Writing after closing returned %v, expected ErrClosed", err)
+	}
+	if err := r.Attach(src); err != ErrClosed || src.hook != nil {
+		t.Errorf("Attaching after closing returned %v, expected ErrClosed", err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatalf("Error decoding GIF: %v", err)
+	}
+	if len(anim.Image) != 2 {
+		t.Errorf("GIF has %d frames, expected 2", len(anim.Image))
+	}
+}
+
+func TestFormatForFile(t *testing.T) {
+	t.Parallel()
+	for name, expected := range map[string]Format{"a.gif": GIF, "b.PNG": APNG, "c.apng": APNG, "d.avi": AVI, "e.y4m": Y4M} {
+		if format, err := FormatForFile(name); err != nil || format != expected {
+			t.Errorf("Format for %q is %v, %v, expected %v", name, format, err, expected)
+		}
+	}
+	if _, err := FormatForFile("f.mp4"); err == nil {
+		t.Errorf("No error for an unknown format")
+	}
+}
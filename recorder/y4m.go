@@ -0,0 +1,81 @@
+package recorder
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// A YUV4MPEG2 stream is a one line text header, then each frame as
+// "FRAME\n" followed by the Y, Cb and Cr planes. The chroma planes are half
+// the width and height, each sample being the average of 2x2 pixels.
+
+type y4mEncoder struct {
+	w             *bufio.Writer
+	width, height int
+	frame         []byte
+}
+
+func newY4MEncoder(w io.Writer) *y4mEncoder {
+	return &y4mEncoder{w: bufio.NewWriter(w)}
+}
+
+func (e *y4mEncoder) writeFrame(img image.Image, tics int) error {
+	rgba := toRGBA(img)
+	if e.frame == nil {
+		e.width, e.height = rgba.Rect.Dx(), rgba.Rect.Dy()
+		cw, ch := (e.width+1)/2, (e.height+1)/2
+		e.frame = make([]byte, e.width*e.height+2*cw*ch)
+		if _, err := fmt.Fprintf(e.w, "YUV4MPEG2 W%d H%d F%d:1 Ip C420jpeg XCOLORRANGE=FULL\n", e.width, e.height, TicRate); err != nil {
+			return err
+		}
+	} else if rgba.Rect.Dx() != e.width || rgba.Rect.Dy() != e.height {
+		return errors.New("recorder: frame size changed")
+	}
+	e.convert(rgba)
+	for range tics {
+		if _, err := io.WriteString(e.w, "FRAME\n"); err != nil {
+			return err
+		}
+		if _, err := e.w.Write(e.frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convert fills in e.frame from img
+func (e *y4mEncoder) convert(img *image.RGBA) {
+	cw, ch := (e.width+1)/2, (e.height+1)/2
+	lum := e.frame[:e.width*e.height]
+	cb := e.frame[len(lum) : len(lum)+cw*ch]
+	cr := e.frame[len(lum)+cw*ch:]
+	for y := range e.height {
+		for x := range e.width {
+			c := img.RGBAAt(x, y)
+			lum[y*e.width+x], _, _ = color.RGBToYCbCr(c.R, c.G, c.B)
+		}
+	}
+	for cy := range ch {
+		for cx := range cw {
+			var r, g, b, n int
+			for y := cy * 2; y < min(cy*2+2, e.height); y++ {
+				for x := cx * 2; x < min(cx*2+2, e.width); x++ {
+					c := img.RGBAAt(x, y)
+					r += int(c.R)
+					g += int(c.G)
+					b += int(c.B)
+					n++
+				}
+			}
+			_, cb[cy*cw+cx], cr[cy*cw+cx] = color.RGBToYCbCr(uint8(r/n), uint8(g/n), uint8(b/n))
+		}
+	}
+}
+
+func (e *y4mEncoder) close() error {
+	return e.w.Flush()
+}
@@ -1,33 +1,20 @@
 #!/bin/bash
-# We're not clearing out the state properly on `Run` at the moment, so all the
-# tests need to be in a separate process
+# Each test runs in its own Game, so they can all share the one process
 
 set -e
 
-TESTS=$(go test -test.list '.*' | grep '^Test' | sed 's/\n/ /g')
-echo "All tests: $TESTS"
-
 if [ "$1" == "loop" ] ; then
 	# Run the tests forever until they crash
 	while : ; do
-		for f in $TESTS ; do
-			echo "Running $f..."
-			go test -v -count 1 -run "^${f}\$" .
-		done
+		go test -v -count 1 .
 	done
 elif [ -n "$1" ] ; then
-	echo "Running tests in $1"
 	COUNT=1
 	if [ -n "$2" ] ; then
 		COUNT=$2
 	fi
 	echo "Running Test $1 $COUNT times"
-	for i in $(seq $COUNT) ; do
-		go test -v -count 1 -run "^${1}\$" .
-	done
+	go test -v -count "$COUNT" -run "^${1}\$" .
 else
-	for f in $TESTS ; do
-		echo "Running $f..."
-		go test -v -count 1 -run "^${f}\$" .
-	done
+	go test -v -count 1 .
 fi
@@ -0,0 +1,193 @@
+package gore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SaveStore is where saved games are kept. Slots are numbered from 0, as
+// they appear in the save and load menus, which show the first 6.
+type SaveStore interface {
+	// List returns the slots which have games saved in them, in order
+	List() ([]int, error)
+	// Read returns the game saved in a slot. If the slot is empty, the error
+	// wraps fs.ErrNotExist.
+	Read(slot int) ([]byte, error)
+	// Write saves a game in a slot, replacing what was there. If it fails,
+	// what was there before must be left intact.
+	Write(slot int, data []byte) error
+	// Delete empties a slot. Deleting an empty slot is not an error.
+	Delete(slot int) error
+}
+
+// DirSaveStore keeps saved games as files in a directory, named as the
+// original names them (dgsave0.dsg etc)
+type DirSaveStore struct {
+	Dir string
+}
+
+// NewDirSaveStore returns a SaveStore which keeps saved games in dir
+func NewDirSaveStore(dir string) *DirSaveStore {
+	return &DirSaveStore{Dir: dir}
+}
+
+func (s *DirSaveStore) path(slot int) string {
+	return filepath.Join(s.Dir, fmt.Sprintf("dgsave%d.dsg", slot))
+}
+
+// List returns the slots which have files in the directory
+func (s *DirSaveStore) List() ([]int, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var slots []int
+	for _, entry := range entries {
+		name, ok := strings.CutPrefix(entry.Name(), "dgsave")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		name, ok = strings.CutSuffix(name, ".dsg")
+		// Only take the names Write would have used, ie: not dgsave01.dsg
+		if slot, err := strconv.Atoi(name); ok && err == nil && filepath.Base(s.path(slot)) == entry.Name() {
+			slots = append(slots, slot)
+		}
+	}
+	slices.Sort(slots)
+	return slots, nil
+}
+
+// Read reads the file for a slot
+func (s *DirSaveStore) Read(slot int) ([]byte, error) {
+	return os.ReadFile(s.path(slot))
+}
+
+// Write writes to a temporary file, which then replaces the file for the
+// slot, so a failed save doesn't destroy the old one
+func (s *DirSaveStore) Write(slot int, data []byte) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.Dir, "temp*.dsg")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), s.path(slot))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// Delete removes the file for a slot
+func (s *DirSaveStore) Delete(slot int) error {
+	err := os.Remove(s.path(slot))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// MemorySaveStore keeps saved games in memory, ie: for tests, or to be
+// copied to and from a database. It is safe for concurrent use.
+type MemorySaveStore struct {
+	lock  sync.Mutex
+	saves map[int][]byte
+}
+
+// NewMemorySaveStore returns an empty MemorySaveStore
+func NewMemorySaveStore() *MemorySaveStore {
+	return &MemorySaveStore{saves: map[int][]byte{}}
+}
+
+// List returns the slots which have been written
+func (s *MemorySaveStore) List() ([]int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return slices.Sorted(maps.Keys(s.saves)), nil
+}
+
+// Read returns a copy of what was written to a slot
+func (s *MemorySaveStore) Read(slot int) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	data, ok := s.saves[slot]
+	if !ok {
+		return nil, fmt.Errorf("gore: save slot %d: %w", slot, fs.ErrNotExist)
+	}
+	return slices.Clone(data), nil
+}
+
+// Write keeps a copy of data for a slot
+func (s *MemorySaveStore) Write(slot int, data []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.saves[slot] = slices.Clone(data)
+	return nil
+}
+
+// Delete forgets a slot
+func (s *MemorySaveStore) Delete(slot int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.saves, slot)
+	return nil
+}
+
+// save_store is where the game is saved, set from Options.SaveStore
+var save_store SaveStore
+
+// savestream_t is a savegame being read or written, in memory
+type savestream_t struct {
+	Fdata []byte
+	Fpos  int
+}
+
+func (s *savestream_t) Read(p []byte) (int, error) {
+	if s.Fpos >= len(s.Fdata) {
+		return 0, io.EOF
+	}
+	n := copy(p, s.Fdata[s.Fpos:])
+	s.Fpos += n
+	return n, nil
+}
+
+func (s *savestream_t) Write(p []byte) (int, error) {
+	if extra := s.Fpos + len(p) - len(s.Fdata); extra > 0 {
+		s.Fdata = append(s.Fdata, make([]byte, extra)...)
+	}
+	s.Fpos += copy(s.Fdata[s.Fpos:], p)
+	return len(p), nil
+}
+
+func (s *savestream_t) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += int64(s.Fpos)
+	case io.SeekEnd:
+		offset += int64(len(s.Fdata))
+	}
+	if offset < 0 {
+		return 0, errors.New("savestream: negative position")
+	}
+	s.Fpos = int(offset)
+	return offset, nil
+}
@@ -0,0 +1,59 @@
+package gore
+
+import (
+	"errors"
+	"image"
+)
+
+// KEY_PRTSCR is the Print Screen key, which saves a screenshot by default
+const KEY_PRTSCR = 0x80 + 0x59
+
+// DoomScreenshotFrontend can optionally be implemented by a DoomFrontend to
+// save the screenshots taken with the screenshot key. If the frontend doesn't
+// implement it, they aren't saved, as the engine has nowhere to write them.
+type DoomScreenshotFrontend interface {
+	// SaveScreenshot is passed a copy of the screen, with the palette it
+	// was drawn in. If it returns an error, the player is told that the
+	// screenshot failed.
+	SaveScreenshot(img *image.Paletted) error
+}
+
+// i_CopyScreen returns a copy of the screen as it was last drawn, with the
+// current palette
+func i_CopyScreen() *image.Paletted {
+	img := *pal_screen
+	img.Pix = append([]byte(nil), pal_screen.Pix...)
+	img.Palette = append(img.Palette[:0:0], pal_screen.Palette...)
+	return &img
+}
+
+// g_ScreenShot takes a screenshot at the start of the next tic, once the
+// current frame has been drawn
+func g_ScreenShot() {
+	gameaction = ga_screenshot
+}
+
+// v_ScreenShot passes a copy of the screen to the frontend to save
+func v_ScreenShot() error {
+	frontend, ok := dg_frontend.(DoomScreenshotFrontend)
+	if !ok {
+		return errors.New("the frontend doesn't save screenshots")
+	}
+	img := i_CopyScreen()
+	var err error
+	i_Callback(func() { err = frontend.SaveScreenshot(img) })
+	return err
+}
+
+// Screenshot returns a copy of the screen as it was last drawn, with the
+// palette it was drawn in
+func (g *Game) Screenshot() (*image.Paletted, error) {
+	if err := g.lock(); err != nil {
+		return nil, err
+	}
+	defer g.unlock()
+	if g.closed {
+		return nil, ErrClosed
+	}
+	return i_CopyScreen(), nil
+}
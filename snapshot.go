@@ -0,0 +1,345 @@
+package gore
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Snapshots are saved games kept in memory, followed by what vanilla
+// savegames leave out: the random number generator indexes, and the things
+// each mobj points to, which are otherwise cleared when loading. Mobjs are
+// referred to by their position in the thinker list, plus one so that zero
+// is nil, and keep their IDs, so that a restored game carries on as it did
+// the first time.
+
+// snapshot_mobjs returns the mobjs in the order they are archived, along
+// with their index plus one
+func snapshot_mobjs() ([]*mobj_t, map[*mobj_t]uint32) {
+	var mobjs []*mobj_t
+	index := map[*mobj_t]uint32{}
+	for th := thinkercap.Fnext; th != &thinkercap; th = th.Fnext {
+		if mo, ok := th.Ffunction.(*mobj_t); ok {
+			mobjs = append(mobjs, mo)
+			index[mo] = uint32(len(mobjs))
+		}
+	}
+	return mobjs, index
+}
+
+// snapshot_archived reports whether p_ArchiveThinkers or p_ArchiveSpecials
+// saves a thinker
+func snapshot_archived(th *thinker_t) bool {
+	switch th.Ffunction.(type) {
+	case *mobj_t, *ceiling_t, *vldoor_t, *floormove_t, *plat_t, *lightflash_t, *strobe_t, *glow_t:
+		return true
+	case nil:
+		// Ceilings in stasis
+		for _, c := range activeceilings {
+			if c != nil && &c.Fthinker == th {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// snapshot_thinkerpos returns the position of each mobj among the archived
+// thinkers. The savegame has the mobjs before the specials, so this is
+// needed to put them back in the order they run in.
+func snapshot_thinkerpos() map[*mobj_t]uint32 {
+	pos := map[*mobj_t]uint32{}
+	n := uint32(0)
+	for th := thinkercap.Fnext; th != &thinkercap; th = th.Fnext {
+		if !snapshot_archived(th) {
+			continue
+		}
+		if mo, ok := th.Ffunction.(*mobj_t); ok {
+			pos[mo] = n
+		}
+		n++
+	}
+	return pos
+}
+
+// snapshot_reorder puts the thinkers loaded from a savegame, which are the
+// mobjs followed by the specials, back in their original order
+func snapshot_reorder(mobjs []*mobj_t, pos []uint32) error {
+	var thinkers []*thinker_t
+	for th := thinkercap.Fnext; th != &thinkercap; th = th.Fnext {
+		thinkers = append(thinkers, th)
+	}
+	specials := thinkers[len(mobjs):]
+	p_InitThinkers()
+	for p := range uint32(len(thinkers)) {
+		if len(pos) > 0 && pos[0] == p {
+			p_AddThinker(&mobjs[0].Fthinker)
+			mobjs, pos = mobjs[1:], pos[1:]
+		} else if len(specials) > 0 {
+			p_AddThinker(specials[0])
+			specials = specials[1:]
+		} else {
+			return ErrSaveVersion
+		}
+	}
+	return nil
+}
+
+// p_ArchiveSnapshot appends what the savegame leaves out to data. Pointers
+// to mobjs which have been removed, which the game no longer uses, are
+// saved as nil.
+func p_ArchiveSnapshot(data []byte) []byte {
+	start := len(data)
+	put := func(v uint32) {
+		data = binary.LittleEndian.AppendUint32(data, v)
+	}
+	put(uint32(prndindex))
+	put(uint32(rndindex))
+	put(mobj_next_id)
+	mobjs, index := snapshot_mobjs()
+	pos := snapshot_thinkerpos()
+	put(uint32(len(mobjs)))
+	for _, mo := range mobjs {
+		put(pos[mo])
+		put(mo.Fid)
+		put(index[mo.Ftarget])
+		put(index[mo.Ftracer])
+		put(index[mo.Fsnext])
+		put(index[mo.Fsprev])
+		put(index[mo.Fbnext])
+		put(index[mo.Fbprev])
+		put(uint32(mo.Ffloorz))
+		put(uint32(mo.Fceilingz))
+	}
+	for i := range players {
+		put(index[players[i].Fattacker])
+	}
+	put(uint32(len(sectors)))
+	for i := range sectors {
+		put(index[sectors[i].Fsoundtarget])
+	}
+	put(uint32(numbraintargets))
+	put(uint32(braintargeton))
+	for _, mo := range braintargets {
+		put(index[mo])
+	}
+	// The length goes last, so that Restore can find the savegame
+	put(uint32(len(data) - start + 4))
+	return data
+}
+
+// snapshot_t is what p_ArchiveSnapshot saved, once it has been checked
+type snapshot_t struct {
+	Fprndindex       int32
+	Frndindex        int32
+	Fmobj_next_id    uint32
+	Fmobjs           [][10]uint32 // Position, ID, links, floorz and ceilingz
+	Fattackers       [MAXPLAYERS]uint32
+	Fsoundtargets    []uint32
+	Fnumbraintargets int32
+	Fbraintargeton   int32
+	Fbraintargets    [len(braintargets)]uint32
+}
+
+// p_ParseSnapshot checks all of what p_ArchiveSnapshot saved, so that
+// nothing needs to be changed to find out that a snapshot is corrupt
+func p_ParseSnapshot(data []byte) (*snapshot_t, error) {
+	corrupt := errors.New("gore: snapshot is corrupt")
+	get := func() uint32 {
+		v := binary.LittleEndian.Uint32(data)
+		data = data[4:]
+		return v
+	}
+	// Check the length before reading anything, using the counts
+	if len(data) < 4*4 {
+		return nil, corrupt
+	}
+	nummobjs := uint64(binary.LittleEndian.Uint32(data[3*4:]))
+	sectorsat := 4 * (4 + 10*nummobjs + MAXPLAYERS)
+	if uint64(len(data)) < sectorsat+4 {
+		return nil, corrupt
+	}
+	numsectors := uint64(binary.LittleEndian.Uint32(data[sectorsat:]))
+	if uint64(len(data)) != sectorsat+4*(1+numsectors+2+uint64(len(braintargets))+1) {
+		return nil, corrupt
+	}
+	s := &snapshot_t{}
+	s.Fprndindex = int32(get())
+	s.Frndindex = int32(get())
+	s.Fmobj_next_id = get()
+	get()
+	badmobj := false
+	mobj := func() uint32 {
+		i := get()
+		if uint64(i) > nummobjs {
+			badmobj = true
+		}
+		return i
+	}
+	s.Fmobjs = make([][10]uint32, nummobjs)
+	for i := range s.Fmobjs {
+		mo := &s.Fmobjs[i]
+		mo[0] = get()
+		mo[1] = get()
+		for j := 2; j < 8; j++ {
+			mo[j] = mobj()
+		}
+		mo[8] = get()
+		mo[9] = get()
+	}
+	for i := range s.Fattackers {
+		s.Fattackers[i] = mobj()
+	}
+	s.Fsoundtargets = make([]uint32, get())
+	for i := range s.Fsoundtargets {
+		s.Fsoundtargets[i] = mobj()
+	}
+	s.Fnumbraintargets = int32(get())
+	s.Fbraintargeton = int32(get())
+	for i := range s.Fbraintargets {
+		s.Fbraintargets[i] = mobj()
+	}
+	if badmobj {
+		return nil, errors.New("gore: snapshot refers to a mobj which doesn't exist")
+	}
+	if s.Fnumbraintargets < 0 || int(s.Fnumbraintargets) > len(braintargets) ||
+		s.Fbraintargeton < 0 || s.Fbraintargeton >= max(s.Fnumbraintargets, 1) {
+		return nil, errors.New("gore: snapshot has bad brain targets")
+	}
+	return s, nil
+}
+
+// p_UnArchiveSnapshot restores a snapshot from p_ParseSnapshot, once the
+// savegame before it has been loaded
+func p_UnArchiveSnapshot(s *snapshot_t) error {
+	mobjs, _ := snapshot_mobjs()
+	if len(s.Fmobjs) != len(mobjs) || len(s.Fsoundtargets) != len(sectors) {
+		return ErrSaveVersion
+	}
+	mobj := func(i uint32) *mobj_t {
+		if i == 0 {
+			return nil
+		}
+		return mobjs[i-1]
+	}
+	prndindex = s.Fprndindex
+	rndindex = s.Frndindex
+	mobj_next_id = s.Fmobj_next_id
+	// Relink the sectors and blockmap in the same order as before, as it
+	// affects which things are hit first
+	for i := range sectors {
+		sectors[i].Fthinglist = nil
+	}
+	clear(blocklinks)
+	pos := make([]uint32, len(mobjs))
+	for i, mo := range mobjs {
+		saved := &s.Fmobjs[i]
+		pos[i] = saved[0]
+		mo.Fid = saved[1]
+		mo.Ftarget = mobj(saved[2])
+		mo.Ftracer = mobj(saved[3])
+		mo.Fsnext = mobj(saved[4])
+		mo.Fsprev = mobj(saved[5])
+		mo.Fbnext = mobj(saved[6])
+		mo.Fbprev = mobj(saved[7])
+		mo.Ffloorz = fixed_t(saved[8])
+		mo.Fceilingz = fixed_t(saved[9])
+		if mo.Fflags&mf_NOSECTOR == 0 && mo.Fsprev == nil {
+			mo.Fsubsector.Fsector.Fthinglist = mo
+		}
+		if mo.Fflags&mf_NOBLOCKMAP == 0 && mo.Fbprev == nil {
+			blockx := (mo.Fx - bmaporgx) >> (FRACBITS + 7)
+			blocky := (mo.Fy - bmaporgy) >> (FRACBITS + 7)
+			if blockx >= 0 && blockx < bmapwidth && blocky >= 0 && blocky < bmapheight {
+				blocklinks[blocky*bmapwidth+blockx] = mo
+			}
+		}
+	}
+	for i := range players {
+		players[i].Fattacker = mobj(s.Fattackers[i])
+	}
+	for i := range sectors {
+		sectors[i].Fsoundtarget = mobj(s.Fsoundtargets[i])
+	}
+	numbraintargets = s.Fnumbraintargets
+	braintargeton = s.Fbraintargeton
+	for i := range braintargets {
+		braintargets[i] = mobj(s.Fbraintargets[i])
+	}
+	return snapshot_reorder(mobjs, pos)
+}
+
+// Snapshot returns the state of the current level, which can be returned to
+// at any time with Restore. It is quick enough to take every second or so,
+// ie: to offer a rewind button.
+func (g *Game) Snapshot() (data []byte, err error) {
+	if err := g.lock(); err != nil {
+		return nil, err
+	}
+	defer g.unlock()
+	if g.closed {
+		return nil, ErrClosed
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+	if gamestate != gs_LEVEL {
+		return nil, ErrNoLevel
+	}
+	defer g.catch(&err)
+	data, _, err = g_WriteSaveGame("snapshot")
+	if err != nil {
+		return nil, err
+	}
+	return p_ArchiveSnapshot(data), nil
+}
+
+// Restore returns the game to the state saved by Snapshot. The level is
+// reloaded immediately, without a screen wipe, and plays out the same as it
+// did after the snapshot was taken, given the same input. Snapshots can be
+// restored in any Game running the same WAD files, not just the one they
+// were taken in. Snapshots taken with PWADs loaded return a
+// *WADMismatchError if restored with different ones.
+func (g *Game) Restore(data []byte) (err error) {
+	if err := g.lock(); err != nil {
+		return err
+	}
+	defer g.unlock()
+	if g.closed {
+		return ErrClosed
+	}
+	if g.err != nil {
+		return g.err
+	}
+	if demorecording != 0 {
+		return ErrDemoRecording
+	}
+	if demoplayback != 0 {
+		return errors.New("gore: can't restore during demo playback")
+	}
+	if len(data) < 4 {
+		return errors.New("gore: snapshot is too short")
+	}
+	extra := int(binary.LittleEndian.Uint32(data[len(data)-4:]))
+	if extra < 4 || extra > len(data) {
+		return errors.New("gore: snapshot is corrupt")
+	}
+	snapshot, err := p_ParseSnapshot(data[len(data)-extra:])
+	if err != nil {
+		return err
+	}
+	defer g.catch(&err)
+	gameaction = ga_nothing
+	if err := g_ReadSaveGame(data[:len(data)-extra]); err != nil {
+		return err
+	}
+	// The level has been reloaded, so if the snapshot doesn't match it
+	// there's nothing sensible to go back to
+	if err := p_UnArchiveSnapshot(snapshot); err != nil {
+		g.err = err
+		return err
+	}
+	// Don't draw the next frame part way from where things were before
+	interp_tic = -1
+	wipegamestate = gamestate
+	return nil
+}
@@ -0,0 +1,206 @@
+package gore
+
+// Software sound effect mixer. This plays the same role as i_sdlsound.c in
+// Chocolate Doom, but rather than handing the sounds to SDL it mixes them
+// down itself, and passes the result to the frontend.
+
+// AudioSampleRate is the sample rate, in Hz, of the audio passed to
+// DoomSoundFrontend.PlayAudio
+const AudioSampleRate = 44100
+
+// DoomSoundFrontend can optionally be implemented by a DoomFrontend to receive
+// the game's audio. If the frontend doesn't implement it, no audio is mixed.
+type DoomSoundFrontend interface {
+	// PlayAudio is called after each frame with the audio for the tics
+	// which have run since the previous call (1/35th of a second per tic),
+	// as interleaved left/right signed 16-bit samples at AudioSampleRate.
+	// The slice is reused, so must be copied if it is to be retained.
+	PlayAudio(samples []int16)
+}
+
+const mixer_NUMCHANNELS = 16
+
+// Most audio that will be buffered up in a single update, in sample frames
+const mixer_MAXFRAMES = AudioSampleRate / 4
+
+// A sound effect, decoded from a DMX format lump
+type mixer_sound_t struct {
+	Fsamples []int16
+	Frate    uint32
+}
+
+type mixer_channel_t struct {
+	Fsound *mixer_sound_t
+	Fpos   uint64 // Position in the sound, as 16.16 fixed point
+	Fstep  uint64 // Amount to advance Fpos by for each output frame
+	Fleft  int32  // Left volume, 0-255
+	Fright int32  // Right volume, 0-255
+}
+
+var mixer_frontend DoomSoundFrontend
+var mixer_use_prefix boolean
+var mixer_channels [mixer_NUMCHANNELS]mixer_channel_t
+var mixer_sounds map[int32]*mixer_sound_t // Decoded sounds, by lump number
+var mixer_frames uint64                   // Number of sample frames sent to the frontend
+var mixer_start_tic int32                 // Value of gametic when the mixer started
+var mixer_buffer []int16
+
+var mixer_module = sound_module_t{
+	Fsound_devices:     []snddevice_t{SNDDEVICE_SB, SNDDEVICE_GUS},
+	Fnum_sound_devices: 2,
+	FInit:              mixerInit,
+	FShutdown:          mixerShutdown,
+	FGetSfxLumpNum:     mixerGetSfxLumpNum,
+	FUpdate:            mixerUpdate,
+	FUpdateSoundParams: mixerUpdateSoundParams,
+	FStartSound:        mixerStartSound,
+	FStopSound:         mixerStopSound,
+	FSoundIsPlaying:    mixerSoundIsPlaying,
+}
+
+func mixerInit(use_sfx_prefix boolean) boolean {
+	frontend, ok := dg_frontend.(DoomSoundFrontend)
+	if !ok {
+		return 0
+	}
+	mixer_frontend = frontend
+	mixer_use_prefix = use_sfx_prefix
+	mixer_channels = [mixer_NUMCHANNELS]mixer_channel_t{}
+	mixer_sounds = map[int32]*mixer_sound_t{}
+	mixer_frames = 0
+	mixer_start_tic = gametic
+	return 1
+}
+
+func mixerShutdown() {
+	mixer_frontend = nil
+	mixer_sounds = nil
+	mixer_buffer = nil
+}
+
+func mixerGetSfxLumpNum(sfx *sfxinfo_t) int32 {
+	// Linked sounds use the lump of the sound they're linked to
+	if sfx.Flink != nil {
+		sfx = sfx.Flink
+	}
+	name := sfx.Fname
+	if mixer_use_prefix != 0 {
+		name = "ds" + name
+	}
+	return w_GetNumForName(name)
+}
+
+// mixerCacheSound decodes the DMX sound in the given lump. Invalid sounds
+// return nil, and are silently ignored.
+func mixerCacheSound(lumpnum int32) *mixer_sound_t {
+	if sound, ok := mixer_sounds[lumpnum]; ok {
+		return sound
+	}
+	data := w_CacheLumpNumBytes(lumpnum)
+	var sound *mixer_sound_t
+	// Check the header, and that the length is sane. DMX pads the start and
+	// the end of the sound with 16 bytes, which aren't played.
+	if len(data) >= 8 && data[0] == 0x03 && data[1] == 0x00 {
+		rate := uint32(data[2]) | uint32(data[3])<<8
+		length := int(uint32(data[4]) | uint32(data[5])<<8 | uint32(data[6])<<16 | uint32(data[7])<<24)
+		if rate != 0 && length > 32 && length <= len(data)-8 {
+			raw := data[8+16 : 8+length-16]
+			sound = &mixer_sound_t{
+				Fsamples: make([]int16, len(raw)),
+				Frate:    rate,
+			}
+			for i, s := range raw {
+				sound.Fsamples[i] = (int16(s) - 128) << 8
+			}
+		}
+	}
+	mixer_sounds[lumpnum] = sound
+	return sound
+}
+
+func mixerUpdateSoundParams(channel int32, vol int32, sep int32) {
+	if channel < 0 || channel >= mixer_NUMCHANNELS {
+		return
+	}
+	c := &mixer_channels[channel]
+	c.Fleft = (254 - sep) * vol / 127
+	c.Fright = sep * vol / 127
+}
+
+func mixerStartSound(sfxinfo *sfxinfo_t, channel int32, vol int32, sep int32) int32 {
+	if channel < 0 || channel >= mixer_NUMCHANNELS {
+		return -1
+	}
+	mixerStopSound(channel)
+	sound := mixerCacheSound(sfxinfo.Flumpnum)
+	if sound == nil {
+		return -1
+	}
+	c := &mixer_channels[channel]
+	c.Fsound = sound
+	c.Fpos = 0
+	c.Fstep = uint64(sound.Frate) << 16 / AudioSampleRate
+	mixerUpdateSoundParams(channel, vol, sep)
+	return channel
+}
+
+func mixerStopSound(channel int32) {
+	if channel < 0 || channel >= mixer_NUMCHANNELS {
+		return
+	}
+	mixer_channels[channel].Fsound = nil
+}
+
+func mixerSoundIsPlaying(channel int32) boolean {
+	if channel < 0 || channel >= mixer_NUMCHANNELS {
+		return 0
+	}
+	return booluint32(mixer_channels[channel].Fsound != nil)
+}
+
+// mixerUpdate mixes all of the audio for the tics which have run since the
+// last update, and passes it to the frontend
+func mixerUpdate() {
+	// Audio follows the game tics rather than the clock, so that it stays
+	// in sync however fast the game is being run
+	target := uint64(gametic-mixer_start_tic) * AudioSampleRate / TICRATE
+	if target <= mixer_frames {
+		return
+	}
+	frames := min(target-mixer_frames, mixer_MAXFRAMES)
+	mixer_frames = target
+	if cap(mixer_buffer) < int(frames)*2 {
+		mixer_buffer = make([]int16, frames*2)
+	}
+	buffer := mixer_buffer[:frames*2]
+	music := oplMusicRender(int(frames))
+	for i := range frames {
+		var left, right int32
+		if music != nil {
+			left, right = int32(music[i*2]), int32(music[i*2+1])
+		}
+		for j := range mixer_channels {
+			c := &mixer_channels[j]
+			if c.Fsound == nil {
+				continue
+			}
+			pos := int(c.Fpos >> 16)
+			if pos >= len(c.Fsound.Fsamples) {
+				c.Fsound = nil
+				continue
+			}
+			// Linear interpolation between neighbouring samples
+			sample := int32(c.Fsound.Fsamples[pos])
+			if pos+1 < len(c.Fsound.Fsamples) {
+				frac := int64(c.Fpos & 0xffff)
+				sample += int32((int64(c.Fsound.Fsamples[pos+1]) - int64(sample)) * frac >> 16)
+			}
+			left += sample * c.Fleft / 255
+			right += sample * c.Fright / 255
+			c.Fpos += c.Fstep
+		}
+		buffer[i*2] = int16(min(max(left, -32768), 32767))
+		buffer[i*2+1] = int16(min(max(right, -32768), 32767))
+	}
+	i_Callback(func() { mixer_frontend.PlayAudio(buffer) })
+}
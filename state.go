@@ -0,0 +1,191 @@
+package gore
+
+// Read only snapshots of the engine state, for bots, tests and tools which
+// would otherwise have to scrape the rendered frame.
+
+// Weapon identifies one of the player's weapons
+type Weapon int
+
+const (
+	WeaponFist Weapon = iota
+	WeaponPistol
+	WeaponShotgun
+	WeaponChaingun
+	WeaponRocketLauncher
+	WeaponPlasmaRifle
+	WeaponBFG
+	WeaponChainsaw
+	WeaponSuperShotgun
+	NumWeapons
+)
+
+// Ammo identifies one of the types of ammunition
+type Ammo int
+
+const (
+	AmmoBullets Ammo = iota
+	AmmoShells
+	AmmoCells
+	AmmoRockets
+	NumAmmo
+)
+
+// Key identifies one of the keycards or skull keys
+type Key int
+
+const (
+	KeyBlueCard Key = iota
+	KeyYellowCard
+	KeyRedCard
+	KeyBlueSkull
+	KeyYellowSkull
+	KeyRedSkull
+	NumKeys
+)
+
+// Power identifies one of the powerups
+type Power int
+
+const (
+	PowerInvulnerability Power = iota
+	PowerStrength
+	PowerInvisibility
+	PowerRadiationSuit
+	PowerAllMap
+	PowerLightAmp
+	NumPowers
+)
+
+// Vec3 is a position or velocity, in map units
+type Vec3 struct {
+	X, Y, Z float64
+}
+
+// PlayerState is a snapshot of a player
+type PlayerState struct {
+	Alive     bool
+	Health    int
+	Armor     int
+	ArmorType int // 0 for none, 1 for green armor, 2 for blue armor
+
+	Ammo    [NumAmmo]int
+	MaxAmmo [NumAmmo]int
+	Weapons [NumWeapons]bool
+	Weapon  Weapon // The weapon currently in use
+	// The weapon being changed to, or the current weapon if it isn't changing
+	PendingWeapon Weapon
+	Keys          [NumKeys]bool
+	// Tics remaining for each power, or for strength, tics since it was
+	// picked up. The computer area map is 1 for the rest of the level.
+	Powers   [NumPowers]int
+	Backpack bool
+
+	// Position is the player's position, at their feet
+	Position Vec3
+	Momentum Vec3    // Momentum, in map units per tic
+	Angle    float64 // Direction the player is facing, in degrees anticlockwise from east
+	ViewZ    float64 // Height of the player's eyes
+
+	Kills   int
+	Items   int
+	Secrets int
+}
+
+// State is a snapshot of the game
+type State struct {
+	// InLevel is set when playing a level, rather than at the
+	// intermission, finale, or title screen
+	InLevel bool
+	Paused  bool
+	// Demo is set when a demo is being played back, such as on the title
+	// screen
+	Demo bool
+
+	Episode int // Always 1 for Doom II
+	Map     int
+	Skill   int // From 0 (I'm too young to die) to 4 (Nightmare!)
+
+	LevelTime int // Tics since the level started
+	GameTic   int
+
+	TotalKills   int
+	TotalItems   int
+	TotalSecrets int
+
+	// Player is the player being controlled by this game
+	Player PlayerState
+}
+
+// State returns a snapshot of the current game state
+func (g *Game) State() (State, error) {
+	if err := g.lock(); err != nil {
+		return State{}, err
+	}
+	defer g.unlock()
+	if g.closed {
+		return State{}, ErrClosed
+	}
+	if g.err != nil {
+		return State{}, g.err
+	}
+	return State{
+		InLevel:      gamestate == gs_LEVEL,
+		Paused:       paused != 0,
+		Demo:         demoplayback != 0,
+		Episode:      int(gameepisode),
+		Map:          int(gamemap),
+		Skill:        int(gameskill),
+		LevelTime:    int(leveltime),
+		GameTic:      int(gametic),
+		TotalKills:   int(totalkills),
+		TotalItems:   int(totalitems),
+		TotalSecrets: int(totalsecret),
+		Player:       playerState(&players[consoleplayer]),
+	}, nil
+}
+
+func playerState(p *player_t) PlayerState {
+	s := PlayerState{
+		Alive:         p.Fplayerstate == Pst_LIVE,
+		Health:        int(p.Fhealth),
+		Armor:         int(p.Farmorpoints),
+		ArmorType:     int(p.Farmortype),
+		Weapon:        Weapon(p.Freadyweapon),
+		PendingWeapon: Weapon(p.Freadyweapon),
+		Backpack:      p.Fbackpack != 0,
+		ViewZ:         fixedToFloat(p.Fviewz),
+		Kills:         int(p.Fkillcount),
+		Items:         int(p.Fitemcount),
+		Secrets:       int(p.Fsecretcount),
+	}
+	if p.Fpendingweapon != wp_nochange {
+		s.PendingWeapon = Weapon(p.Fpendingweapon)
+	}
+	for i := range s.Ammo {
+		s.Ammo[i] = int(p.Fammo[i])
+		s.MaxAmmo[i] = int(p.Fmaxammo[i])
+	}
+	for i := range s.Weapons {
+		s.Weapons[i] = p.Fweaponowned[i] != 0
+	}
+	for i := range s.Keys {
+		s.Keys[i] = p.Fcards[i] != 0
+	}
+	for i := range s.Powers {
+		s.Powers[i] = int(p.Fpowers[i])
+	}
+	if mo := p.Fmo; mo != nil {
+		s.Position = Vec3{fixedToFloat(mo.Fx), fixedToFloat(mo.Fy), fixedToFloat(mo.Fz)}
+		s.Momentum = Vec3{fixedToFloat(mo.Fmomx), fixedToFloat(mo.Fmomy), fixedToFloat(mo.Fmomz)}
+		s.Angle = angleToDegrees(mo.Fangle)
+	}
+	return s
+}
+
+func fixedToFloat(f fixed_t) float64 {
+	return float64(f) / FRACUNIT
+}
+
+func angleToDegrees(a angle_t) float64 {
+	return float64(a) * 360 / (1 << 32)
+}
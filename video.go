@@ -0,0 +1,277 @@
+package gore
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Rendering at resolutions other than the original 320x200. The 3D view and
+// automap are drawn at the full resolution, whilst the status bar, menus,
+// intermission screens and other 2D graphics are still laid out in the
+// original 320x200 coordinates, and scaled up as they are drawn.
+//
+// In widescreen, the 2D graphics are drawn in a 4:3 area in the middle of the
+// screen, and the 3D view's horizontal field of view is widened to fill the
+// rest.
+
+// Aspect is the aspect ratio the screen is displayed at, for Options.Aspect
+type Aspect int
+
+const (
+	// AspectVanilla is the original 4:3, with the 320x200 screen stretched
+	// vertically to fill it
+	AspectVanilla Aspect = iota
+	Aspect16x10
+	Aspect16x9
+)
+
+// ratio returns the width and height of the aspect ratio
+func (a Aspect) ratio() (num, den int32) {
+	switch a {
+	case Aspect16x10:
+		return 16, 10
+	case Aspect16x9:
+		return 16, 9
+	}
+	return 4, 3
+}
+
+// Size of the screen the 2D graphics are laid out for
+const ORIGWIDTH = 320
+const ORIGHEIGHT = 200
+
+// Largest supported resolution. The visplanes store rows as 16 bits, and the
+// fixed point maths used by the renderer overflows well before then.
+const MAXWIDTH = 3840
+const MAXHEIGHT = 2400
+
+// Size of the screen being rendered to
+var screenwidth int32 = ORIGWIDTH
+var screenheight int32 = ORIGHEIGHT
+var screenaspect Aspect
+
+// Width of the view which the 90 degree field of view covers. It is narrower
+// than the view in widescreen, widening the field of view.
+var fovwidth int32
+
+// i_SetResolution sets the size and aspect ratio of the screen, which must
+// happen before the engine starts. A size of 0 uses the original 200 rows,
+// widened to fill the aspect ratio.
+func i_SetResolution(width, height int, aspect Aspect) error {
+	if width == 0 && height == 0 {
+		// Widen the original screen to the aspect ratio
+		num, den := aspect.ratio()
+		width, height = int(ORIGWIDTH*3*num/(4*den))&^1, ORIGHEIGHT
+	}
+	if width < ORIGWIDTH || height < ORIGHEIGHT || width > MAXWIDTH || height > MAXHEIGHT {
+		return fmt.Errorf("gore: unsupported resolution %dx%d, must be between %dx%d and %dx%d",
+			width, height, ORIGWIDTH, ORIGHEIGHT, MAXWIDTH, MAXHEIGHT)
+	}
+	// The low detail mode and screen wipe work on pairs of columns
+	if width%2 != 0 {
+		return fmt.Errorf("gore: unsupported resolution %dx%d, the width must be even", width, height)
+	}
+	if aspect < AspectVanilla || aspect > Aspect16x9 {
+		return fmt.Errorf("gore: unsupported aspect ratio %d", aspect)
+	}
+	screenwidth = int32(width)
+	screenheight = int32(height)
+	screenaspect = aspect
+	return nil
+}
+
+// i_CheckResolutionParms applies the -width, -height and -aspect command line
+// parameters, which override those set by i_SetResolution
+func i_CheckResolutionParms() {
+	var p int32
+	width, height, aspect := int(screenwidth), int(screenheight), screenaspect
+	//!
+	// @arg <x>
+	//
+	// Specify the screen width, in pixels.
+	//
+	p = m_CheckParmWithArgs("-width", 1)
+	if p != 0 {
+		width, _ = strconv.Atoi(myargs[p+1])
+	}
+	//!
+	// @arg <y>
+	//
+	// Specify the screen height, in pixels.
+	//
+	p = m_CheckParmWithArgs("-height", 1)
+	if p != 0 {
+		height, _ = strconv.Atoi(myargs[p+1])
+	}
+	//!
+	// @arg <ratio>
+	//
+	// Specify the aspect ratio the screen is displayed at: 4:3 (the
+	// default), 16:10 or 16:9.
+	//
+	p = m_CheckParmWithArgs("-aspect", 1)
+	if p != 0 {
+		switch myargs[p+1] {
+		case "4:3":
+			aspect = AspectVanilla
+		case "16:10":
+			aspect = Aspect16x10
+		case "16:9":
+			aspect = Aspect16x9
+		default:
+			i_Error("Unsupported aspect ratio %s", myargs[p+1])
+		}
+	}
+	if err := i_SetResolution(width, height, aspect); err != nil {
+		i_Error("%v", err)
+	}
+}
+
+// i_InitResolution allocates the buffers which depend on the screen size
+func i_InitResolution() {
+	columnofs = make([]int32, screenwidth)
+	ylookup = make([]int32, screenheight)
+	clipbot = make([]int16, screenwidth)
+	cliptop = make([]int16, screenwidth)
+	ceilingclip = make([]int16, screenwidth)
+	floorclip = make([]int16, screenwidth)
+	negonearray = make([]int16, screenwidth)
+	screenheightarray = make([]int16, screenwidth)
+	distscale = make([]fixed_t, screenwidth)
+	xtoviewangle = make([]angle_t, screenwidth+1)
+	// The sprite clipping arrays point screenwidth before their part of
+	// openings, so it is padded either side to keep them within it
+	openings = make([]int16, screenwidth*66)
+	cacheddistance = make([]fixed_t, screenheight)
+	cachedheight = make([]fixed_t, screenheight)
+	cachedxstep = make([]fixed_t, screenheight)
+	cachedystep = make([]fixed_t, screenheight)
+	spanstart = make([]int32, screenheight)
+	yslope = make([]fixed_t, screenheight)
+	for i := range visplanes {
+		visplanes[i].Ftop = make([]uint16, screenwidth)
+		visplanes[i].Fbottom = make([]uint16, screenwidth)
+	}
+}
+
+// v_AreaWidth returns the width of the 4:3 area of the screen which the 2D
+// graphics are drawn in. It is rounded to the nearest column, as the 16:9
+// width of 320 columns is 426.67, which has to be rounded to be even.
+func v_AreaWidth() int32 {
+	num, den := screenaspect.ratio()
+	return (screenwidth*4*den + 3*num/2) / (3 * num)
+}
+
+// v_ScaleX converts an x coordinate from the original 320x200 layout to the
+// screen
+func v_ScaleX(x int32) int32 {
+	area := v_AreaWidth()
+	return (screenwidth-area)/2 + x*area/ORIGWIDTH
+}
+
+// v_ScaleY converts a y coordinate from the original 320x200 layout to the
+// screen
+func v_ScaleY(y int32) int32 {
+	return y * screenheight / ORIGHEIGHT
+}
+
+// v_UnscaleX converts an x coordinate on the screen to the original 320x200
+// layout
+func v_UnscaleX(x int32) int32 {
+	area := v_AreaWidth()
+	return (x - (screenwidth-area)/2) * ORIGWIDTH / area
+}
+
+// v_UnscaleY converts a y coordinate on the screen to the original 320x200
+// layout
+func v_UnscaleY(y int32) int32 {
+	return y * ORIGHEIGHT / screenheight
+}
+
+// v_StatusBarHeight returns the number of rows of the screen covered by the
+// status bar
+func v_StatusBarHeight() int32 {
+	return screenheight - v_ScaleY(ORIGHEIGHT-SBARHEIGHT)
+}
+
+// v_FillPillars fills the parts of rows y to y+height, in the original
+// 320x200 layout, which are outside the 4:3 area of a widescreen screen. If
+// source is set, they are copied from it; otherwise they are cleared to
+// black.
+func v_FillPillars(source []byte, y, height int32) {
+	left, right := v_ScaleX(0), v_ScaleX(ORIGWIDTH)
+	if left == 0 {
+		return
+	}
+	for sy := v_ScaleY(y); sy < v_ScaleY(y+height); sy++ {
+		row := sy * screenwidth
+		for _, span := range [2][2]int32{{row, row + left}, {row + right, row + screenwidth}} {
+			if source != nil {
+				copy(I_VideoBuffer[span[0]:span[1]], source[span[0]:span[1]])
+			} else {
+				clear(I_VideoBuffer[span[0]:span[1]])
+			}
+		}
+	}
+}
+
+// v_BackgroundFlat returns the flat drawn around the view and status bar
+func v_BackgroundFlat() []byte {
+	if gamemode == commercial {
+		return w_CacheLumpNameBytes("GRNROCK") // DOOM II border patch
+	}
+	return w_CacheLumpNameBytes("FLOOR7_2") // DOOM border patch
+}
+
+// v_TileFlat fills a buffer of whole screen rows with a 64x64 flat, scaled
+// to match the 2D graphics
+func v_TileFlat(dest []byte, flat []byte) {
+	for y := range int32(len(dest)) / screenwidth {
+		row := dest[y*screenwidth : (y+1)*screenwidth]
+		srcRow := flat[v_UnscaleY(y)&63<<6:]
+		for x := range row {
+			row[x] = srcRow[v_UnscaleX(int32(x))&63]
+		}
+	}
+}
+
+// v_DrawPatchColumn draws the posts of a patch column at x, with the top of
+// the patch at y, both in the original 320x200 layout
+func v_DrawPatchColumn(dest []byte, x, y int32, column *column_t) {
+	x0, x1 := v_ScaleX(x), v_ScaleX(x+1)
+	for int32(column.Ftopdelta) != 0xff {
+		source := column.Data()
+		top := y + int32(column.Ftopdelta)
+		length := int32(column.Flength)
+		y0, y1 := v_ScaleY(top), v_ScaleY(top+length)
+		for sy := y0; sy < y1; sy++ {
+			c := source[(sy-y0)*length/(y1-y0)]
+			row := dest[sy*screenwidth:]
+			for sx := x0; sx < x1; sx++ {
+				row[sx] = c
+			}
+		}
+		column = column.Next()
+	}
+}
+
+// v_FillRect fills a rectangle, given in the original 320x200 layout
+func v_FillRect(dest []byte, x, y, width, height int32, c uint8) {
+	x0, x1 := v_ScaleX(x), v_ScaleX(x+width)
+	for sy := v_ScaleY(y); sy < v_ScaleY(y+height); sy++ {
+		row := dest[sy*screenwidth:]
+		for sx := x0; sx < x1; sx++ {
+			row[sx] = c
+		}
+	}
+}
+
+// r_OrigViewWindow returns the position and size of the reduced view window
+// for the current setblocks, in the original 320x200 layout
+func r_OrigViewWindow() (x, y, width, height int32) {
+	width = setblocks * 32
+	height = setblocks * 168 / 10 & ^7
+	x = (ORIGWIDTH - width) >> 1
+	y = (ORIGHEIGHT - SBARHEIGHT - height) >> 1
+	return x, y, width, height
+}
@@ -0,0 +1,349 @@
+package wad
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+)
+
+// Palettes decodes PLAYPAL, the game's 14 palettes. The first is the normal
+// one, and the others are tinted for pain, bonus pickups and the radiation
+// suit.
+func (w *WAD) Palettes() ([]color.Palette, error) {
+	data, err := w.ReadNamed("PLAYPAL")
+	if err != nil {
+		return nil, err
+	}
+	return DecodePalettes(data)
+}
+
+// DecodePalettes decodes the palettes in a PLAYPAL lump
+func DecodePalettes(data []byte) ([]color.Palette, error) {
+	if len(data) == 0 || len(data)%(256*3) != 0 {
+		return nil, fmt.Errorf("wad: PLAYPAL is %d bytes, not a multiple of %d", len(data), 256*3)
+	}
+	palettes := make([]color.Palette, len(data)/(256*3))
+	for i := range palettes {
+		palettes[i] = make(color.Palette, 256)
+		for c := range palettes[i] {
+			rgb := data[(i*256+c)*3:]
+			palettes[i][c] = color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 0xff}
+		}
+	}
+	return palettes, nil
+}
+
+// Colormap maps each palette index to the one used at a light level
+type Colormap [256]uint8
+
+// Apply returns the palette as it looks through the colormap, ie: for
+// drawing a flat at a particular light level
+func (c *Colormap) Apply(pal color.Palette) color.Palette {
+	mapped := make(color.Palette, len(c))
+	for i, index := range c {
+		mapped[i] = pal[index]
+	}
+	return mapped
+}
+
+// Colormaps decodes COLORMAP. The first 32 colormaps go from full
+// brightness to darkest, followed by the invulnerability colormap and an
+// all black one.
+func (w *WAD) Colormaps() ([]Colormap, error) {
+	data, err := w.ReadNamed("COLORMAP")
+	if err != nil {
+		return nil, err
+	}
+	return DecodeColormaps(data)
+}
+
+// DecodeColormaps decodes the colormaps in a COLORMAP lump. Any bytes after
+// the last whole colormap are ignored.
+func DecodeColormaps(data []byte) ([]Colormap, error) {
+	if len(data) < 256 {
+		return nil, fmt.Errorf("wad: COLORMAP is only %d bytes", len(data))
+	}
+	colormaps := make([]Colormap, len(data)/256)
+	for i := range colormaps {
+		copy(colormaps[i][:], data[i*256:])
+	}
+	return colormaps, nil
+}
+
+// Flat decodes a floor or ceiling texture from the flats namespace
+func (w *WAD) Flat(name string, pal color.Palette) (*image.Paletted, error) {
+	l, ok := w.FindIn(Flats, name)
+	if !ok {
+		return nil, fmt.Errorf("%w: flat %s", ErrNotFound, name)
+	}
+	data, err := w.ReadLump(l)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeFlat(data, pal)
+}
+
+// DecodeFlat decodes a flat, which is 64 pixels wide. They are normally
+// 64x64, but some ports allow taller ones.
+func DecodeFlat(data []byte, pal color.Palette) (*image.Paletted, error) {
+	if len(data) == 0 || len(data)%64 != 0 {
+		return nil, fmt.Errorf("wad: flat is %d bytes, not a multiple of 64", len(data))
+	}
+	img := image.NewPaletted(image.Rect(0, 0, 64, len(data)/64), pal)
+	copy(img.Pix, data)
+	return img, nil
+}
+
+// maxPixels is the largest patch or texture which is decoded. The sizes are
+// read from the WAD, so this stops a corrupt one from using up all of the
+// memory. It is far bigger than any graphic Doom could draw.
+const maxPixels = 4096 * 4096
+
+// checkSize returns an error if a graphic's size is negative or too large
+func checkSize(what string, width, height int) error {
+	if width < 0 || height < 0 || width*height > maxPixels {
+		return fmt.Errorf("wad: %s is %dx%d, larger than the limit of %d pixels", what, width, height, maxPixels)
+	}
+	return nil
+}
+
+// Patch is a graphic in Doom's column based format, as used for sprites,
+// wall patches, and the menus and status bar
+type Patch struct {
+	// Image is transparent where the patch has no pixels
+	Image *image.NRGBA
+	// LeftOffset and TopOffset are how far the patch is drawn to the left
+	// of and above its position, ie: so a sprite's feet are on the ground
+	LeftOffset int
+	TopOffset  int
+}
+
+// Patch decodes a patch, looking in the patches namespace, then the sprites
+// namespace, and then any other lump
+func (w *WAD) Patch(name string, pal color.Palette) (*Patch, error) {
+	l, ok := w.FindIn(Patches, name)
+	if !ok {
+		l, ok = w.FindIn(Sprites, name)
+	}
+	if !ok {
+		l, ok = w.Find(name)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: patch %s", ErrNotFound, name)
+	}
+	data, err := w.ReadLump(l)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := DecodePatch(data, pal)
+	if err != nil {
+		return nil, fmt.Errorf("%w (%s)", err, name)
+	}
+	return patch, nil
+}
+
+// DecodePatch decodes a graphic in the patch format
+func DecodePatch(data []byte, pal color.Palette) (*Patch, error) {
+	if len(data) < 8 {
+		return nil, errors.New("wad: patch is too short")
+	}
+	if len(pal) < 256 {
+		return nil, fmt.Errorf("wad: palette only has %d colours", len(pal))
+	}
+	width := int(binary.LittleEndian.Uint16(data))
+	height := int(binary.LittleEndian.Uint16(data[2:]))
+	// Check the size and column offsets before allocating the image
+	if err := checkSize("patch", width, height); err != nil {
+		return nil, err
+	}
+	if len(data) < 8+width*4 {
+		return nil, errors.New("wad: patch column offsets are truncated")
+	}
+	columns := make([]int, width)
+	for x := range columns {
+		columns[x] = int(binary.LittleEndian.Uint32(data[8+x*4:]))
+		if columns[x] >= len(data) {
+			return nil, fmt.Errorf("wad: patch column %d is outside the patch", x)
+		}
+	}
+	p := &Patch{
+		Image:      image.NewNRGBA(image.Rect(0, 0, width, height)),
+		LeftOffset: int(int16(binary.LittleEndian.Uint16(data[4:]))),
+		TopOffset:  int(int16(binary.LittleEndian.Uint16(data[6:]))),
+	}
+	for x, pos := range columns {
+		top := -1
+		for {
+			if pos >= len(data) {
+				return nil, fmt.Errorf("wad: patch column %d is truncated", x)
+			}
+			topdelta := int(data[pos])
+			if topdelta == 0xff {
+				break
+			}
+			// Tall patches have posts starting at or above the last
+			// one, which are relative to it
+			if topdelta <= top {
+				topdelta += top
+			}
+			top = topdelta
+			if pos+4 > len(data) {
+				return nil, fmt.Errorf("wad: patch column %d is truncated", x)
+			}
+			length := int(data[pos+1])
+			pixels := data[pos+3:]
+			if len(pixels) < length {
+				return nil, fmt.Errorf("wad: patch column %d is truncated", x)
+			}
+			for y := range min(length, height-topdelta) {
+				p.Image.Set(x, topdelta+y, pal[pixels[y]])
+			}
+			pos += length + 4
+		}
+	}
+	return p, nil
+}
+
+// PNames decodes PNAMES, the list of patches the textures are made of
+func (w *WAD) PNames() ([]string, error) {
+	data, err := w.ReadNamed("PNAMES")
+	if err != nil {
+		return nil, err
+	}
+	return DecodePNames(data)
+}
+
+// DecodePNames decodes a PNAMES lump
+func DecodePNames(data []byte) ([]string, error) {
+	if len(data) < 4 {
+		return nil, errors.New("wad: PNAMES is too short")
+	}
+	count := int(int32(binary.LittleEndian.Uint32(data)))
+	if count < 0 || len(data) < 4+count*8 {
+		return nil, fmt.Errorf("wad: PNAMES is too short for %d names", count)
+	}
+	names := make([]string, count)
+	for i := range names {
+		names[i] = lumpName(data[4+i*8 : 12+i*8])
+	}
+	return names, nil
+}
+
+// lumpName returns a NUL padded, 8 character lump name in upper case, as the
+// game looks them up without regard to case
+func lumpName(b []byte) string {
+	name := string(b)
+	if n := strings.IndexByte(name, 0); n >= 0 {
+		name = name[:n]
+	}
+	return strings.ToUpper(name)
+}
+
+// TexturePatch is one of the patches a texture is made of
+type TexturePatch struct {
+	Patch   string // Name of the patch, from PNAMES
+	OriginX int
+	OriginY int
+}
+
+// Texture is a wall texture, made up of patches
+type Texture struct {
+	Name    string
+	Masked  bool
+	Width   int
+	Height  int
+	Patches []TexturePatch
+}
+
+// Textures decodes the wall textures in TEXTURE1 and TEXTURE2 (which only
+// the registered and commercial games have), in the order the game numbers
+// them
+func (w *WAD) Textures() ([]Texture, error) {
+	pnames, err := w.PNames()
+	if err != nil {
+		return nil, err
+	}
+	var textures []Texture
+	for _, lump := range []string{"TEXTURE1", "TEXTURE2"} {
+		data, err := w.ReadNamed(lump)
+		if errors.Is(err, ErrNotFound) && lump == "TEXTURE2" {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		t, err := DecodeTextures(data, pnames)
+		if err != nil {
+			return nil, fmt.Errorf("%w (%s)", err, lump)
+		}
+		textures = append(textures, t...)
+	}
+	return textures, nil
+}
+
+// DecodeTextures decodes a TEXTURE1 or TEXTURE2 lump, using the patch names
+// from PNAMES
+func DecodeTextures(data []byte, pnames []string) ([]Texture, error) {
+	if len(data) < 4 {
+		return nil, errors.New("wad: texture lump is too short")
+	}
+	count := int(int32(binary.LittleEndian.Uint32(data)))
+	if count < 0 || len(data) < 4+count*4 {
+		return nil, fmt.Errorf("wad: texture lump is too short for %d textures", count)
+	}
+	textures := make([]Texture, count)
+	for i := range textures {
+		pos := int(int32(binary.LittleEndian.Uint32(data[4+i*4:])))
+		if pos < 0 || pos+22 > len(data) {
+			return nil, fmt.Errorf("wad: texture %d is outside the lump", i)
+		}
+		mt := data[pos:]
+		t := &textures[i]
+		t.Name = lumpName(mt[:8])
+		t.Masked = binary.LittleEndian.Uint32(mt[8:]) != 0
+		t.Width = int(int16(binary.LittleEndian.Uint16(mt[12:])))
+		t.Height = int(int16(binary.LittleEndian.Uint16(mt[14:])))
+		// 4 obsolete bytes for the column directory
+		patchcount := int(int16(binary.LittleEndian.Uint16(mt[20:])))
+		if patchcount < 0 || 22+patchcount*10 > len(mt) {
+			return nil, fmt.Errorf("wad: texture %s has truncated patches", t.Name)
+		}
+		t.Patches = make([]TexturePatch, patchcount)
+		for j := range t.Patches {
+			mp := mt[22+j*10:]
+			patch := int(int16(binary.LittleEndian.Uint16(mp[4:])))
+			if patch < 0 || patch >= len(pnames) {
+				return nil, fmt.Errorf("wad: texture %s uses patch %d, but there are only %d", t.Name, patch, len(pnames))
+			}
+			t.Patches[j] = TexturePatch{
+				Patch:   pnames[patch],
+				OriginX: int(int16(binary.LittleEndian.Uint16(mp))),
+				OriginY: int(int16(binary.LittleEndian.Uint16(mp[2:]))),
+			}
+		}
+	}
+	return textures, nil
+}
+
+// TextureImage draws a texture from its patches. Areas no patch covers are
+// transparent.
+func (w *WAD) TextureImage(t Texture, pal color.Palette) (*image.NRGBA, error) {
+	if err := checkSize("texture "+t.Name, t.Width, t.Height); err != nil {
+		return nil, err
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, t.Width, t.Height))
+	for _, tp := range t.Patches {
+		patch, err := w.Patch(tp.Patch, pal)
+		if err != nil {
+			return nil, fmt.Errorf("%w in texture %s", err, t.Name)
+		}
+		// The patch offsets are ignored in textures
+		r := patch.Image.Bounds().Add(image.Pt(tp.OriginX, tp.OriginY))
+		draw.Draw(img, r, patch.Image, image.Point{}, draw.Over)
+	}
+	return img, nil
+}
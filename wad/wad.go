@@ -0,0 +1,278 @@
+// Package wad reads Doom WAD files, for tools which need to look inside them
+// without running a game. It lists the lumps, works out which are sprites,
+// flats and patches from their S_START, F_START and P_START markers, groups
+// the lumps of each map, and decodes the graphics into Go images.
+//
+//	data, err := os.ReadFile("doom1.wad")
+//	...
+//	w, err := wad.Open(bytes.NewReader(data), int64(len(data)))
+//	pal, err := w.Palettes()
+//	img, err := w.Flat("FLOOR4_8", pal[0])
+package wad
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is returned when a lump doesn't exist
+var ErrNotFound = errors.New("wad: lump not found")
+
+// Namespace is the part of a WAD a lump is in, according to the markers
+// around it
+type Namespace int
+
+const (
+	// Global lumps aren't between any markers
+	Global Namespace = iota
+	// Sprites are between S_START and S_END (or SS_START and SS_END)
+	Sprites
+	// Flats are between F_START and F_END (or FF_START and FF_END)
+	Flats
+	// Patches are between P_START and P_END (or PP_START and PP_END)
+	Patches
+)
+
+func (ns Namespace) String() string {
+	switch ns {
+	case Global:
+		return "global"
+	case Sprites:
+		return "sprites"
+	case Flats:
+		return "flats"
+	case Patches:
+		return "patches"
+	}
+	return fmt.Sprintf("Namespace(%d)", int(ns))
+}
+
+// Lump is an entry in a WAD's directory
+type Lump struct {
+	Name      string
+	Offset    int64 // From the start of the file
+	Size      int64
+	Namespace Namespace
+	// Marker is set for the namespace markers themselves (ie: F_START), and
+	// the F1_START style markers inside them
+	Marker bool
+}
+
+// Map is the group of lumps making up a level
+type Map struct {
+	Name string // ie: "E1M1" or "MAP01"
+	// Index is the position of the map's marker lump in the directory. The
+	// map's lumps follow it.
+	Index int
+	Lumps []Lump // THINGS, LINEDEFS, SIDEDEFS etc.
+}
+
+// Lump returns the map lump with a name, ie: "LINEDEFS"
+func (m *Map) Lump(name string) (Lump, bool) {
+	for _, l := range m.Lumps {
+		if strings.EqualFold(l.Name, name) {
+			return l, true
+		}
+	}
+	return Lump{}, false
+}
+
+// mapLumps are the lumps which can follow a map marker
+var mapLumps = map[string]bool{
+	"THINGS": true, "LINEDEFS": true, "SIDEDEFS": true, "VERTEXES": true,
+	"SEGS": true, "SSECTORS": true, "NODES": true, "SECTORS": true,
+	"REJECT": true, "BLOCKMAP": true, "BEHAVIOR": true,
+}
+
+// WAD is an opened WAD file
+type WAD struct {
+	// Type is "IWAD" for a game's main WAD, or "PWAD" for an add-on
+	Type  string
+	Lumps []Lump
+	r     io.ReaderAt
+}
+
+// Open reads the directory of a WAD, which is size bytes long. Lumps are only
+// read when they are asked for, so r must stay open for as long as the WAD is
+// used.
+func Open(r io.ReaderAt, size int64) (*WAD, error) {
+	var header [12]byte
+	if err := readAt(r, header[:], 0); err != nil {
+		return nil, fmt.Errorf("wad: reading header: %w", err)
+	}
+	w := &WAD{Type: string(header[:4]), r: r}
+	if w.Type != "IWAD" && w.Type != "PWAD" {
+		return nil, fmt.Errorf("wad: not a WAD file, starts with %q", w.Type)
+	}
+	numlumps := int64(int32(binary.LittleEndian.Uint32(header[4:])))
+	offset := int64(int32(binary.LittleEndian.Uint32(header[8:])))
+	// Check the directory fits in the file before allocating it
+	if numlumps < 0 || offset < 0 || offset+numlumps*16 > size {
+		return nil, fmt.Errorf("wad: bad directory of %d lumps at %d in a %d byte file", numlumps, offset, size)
+	}
+	dir := make([]byte, numlumps*16)
+	if err := readAt(r, dir, offset); err != nil {
+		return nil, fmt.Errorf("wad: reading directory: %w", err)
+	}
+	w.Lumps = make([]Lump, numlumps)
+	for i := range w.Lumps {
+		entry := dir[i*16:]
+		l := Lump{
+			Name:   lumpName(entry[8:16]),
+			Offset: int64(binary.LittleEndian.Uint32(entry)),
+			Size:   int64(int32(binary.LittleEndian.Uint32(entry[4:]))),
+		}
+		// Markers can have any offset, as long as they're empty
+		if l.Size < 0 || (l.Size > 0 && l.Offset+l.Size > size) {
+			return nil, fmt.Errorf("wad: lump %d (%s) of %d bytes at %d is outside the file", i, l.Name, l.Size, l.Offset)
+		}
+		w.Lumps[i] = l
+	}
+	w.setNamespaces()
+	return w, nil
+}
+
+// readAt fills p from r, which is allowed to return io.EOF along with the
+// last byte of the file
+func readAt(r io.ReaderAt, p []byte, off int64) error {
+	n, err := r.ReadAt(p, off)
+	if n == len(p) {
+		return nil
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// markerNamespace returns the namespace a marker lump starts or ends
+func markerNamespace(name string) (ns Namespace, start, ok bool) {
+	prefix, suffix, found := strings.Cut(name, "_")
+	if !found || (suffix != "START" && suffix != "END") {
+		return Global, false, false
+	}
+	start = suffix == "START"
+	switch prefix {
+	case "S", "SS":
+		return Sprites, start, true
+	case "F", "FF":
+		return Flats, start, true
+	case "P", "PP":
+		return Patches, start, true
+	case "F1", "F2", "F3", "P1", "P2", "P3":
+		// Markers within the namespace, which don't change it
+		return Global, start, true
+	}
+	return Global, false, false
+}
+
+func (w *WAD) setNamespaces() {
+	ns := Global
+	for i := range w.Lumps {
+		l := &w.Lumps[i]
+		marker, start, ok := markerNamespace(l.Name)
+		if !ok {
+			l.Namespace = ns
+			continue
+		}
+		l.Marker = true
+		if marker == Global {
+			l.Namespace = ns
+			continue
+		}
+		l.Namespace = marker
+		if start {
+			ns = marker
+		} else {
+			ns = Global
+		}
+	}
+}
+
+// Find returns the last lump with a name, which is the one the game uses
+func (w *WAD) Find(name string) (Lump, bool) {
+	for i := len(w.Lumps) - 1; i >= 0; i-- {
+		if strings.EqualFold(w.Lumps[i].Name, name) {
+			return w.Lumps[i], true
+		}
+	}
+	return Lump{}, false
+}
+
+// FindIn returns the last lump with a name in a namespace, ie: to find a
+// flat rather than a graphic with the same name
+func (w *WAD) FindIn(ns Namespace, name string) (Lump, bool) {
+	for i := len(w.Lumps) - 1; i >= 0; i-- {
+		l := w.Lumps[i]
+		if l.Namespace == ns && !l.Marker && strings.EqualFold(l.Name, name) {
+			return l, true
+		}
+	}
+	return Lump{}, false
+}
+
+// Namespace returns the lumps in a namespace, leaving out the markers
+func (w *WAD) Namespace(ns Namespace) []Lump {
+	var lumps []Lump
+	for _, l := range w.Lumps {
+		if l.Namespace == ns && !l.Marker {
+			lumps = append(lumps, l)
+		}
+	}
+	return lumps
+}
+
+// ReadLump returns the contents of a lump
+func (w *WAD) ReadLump(l Lump) ([]byte, error) {
+	data := make([]byte, l.Size)
+	if err := readAt(w.r, data, l.Offset); err != nil {
+		return nil, fmt.Errorf("wad: reading %s: %w", l.Name, err)
+	}
+	return data, nil
+}
+
+// ReadNamed returns the contents of the last lump with a name
+func (w *WAD) ReadNamed(name string) ([]byte, error) {
+	l, ok := w.Find(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return w.ReadLump(l)
+}
+
+// Maps returns the levels in the WAD, in the order they appear. A map is
+// any lump followed by THINGS, along with the map lumps after it.
+func (w *WAD) Maps() []Map {
+	var maps []Map
+	for i := 0; i+1 < len(w.Lumps); i++ {
+		if w.Lumps[i+1].Name != "THINGS" {
+			continue
+		}
+		m := Map{Name: w.Lumps[i].Name, Index: i}
+		seen := map[string]bool{}
+		for _, l := range w.Lumps[i+1:] {
+			if !mapLumps[l.Name] || seen[l.Name] {
+				break
+			}
+			seen[l.Name] = true
+			m.Lumps = append(m.Lumps, l)
+		}
+		maps = append(maps, m)
+		i += len(m.Lumps)
+	}
+	return maps
+}
+
+// Map returns the last level with a name, ie: "E1M1"
+func (w *WAD) Map(name string) (Map, bool) {
+	maps := w.Maps()
+	for i := len(maps) - 1; i >= 0; i-- {
+		if strings.EqualFold(maps[i].Name, name) {
+			return maps[i], true
+		}
+	}
+	return Map{}, false
+}
@@ -0,0 +1,277 @@
+package wad
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image/color"
+	"slices"
+	"testing"
+)
+
+type testLump struct {
+	name string
+	data []byte
+}
+
+// buildWAD returns a WAD containing lumps
+func buildWAD(typ string, lumps []testLump) []byte {
+	var data, dir bytes.Buffer
+	for _, l := range lumps {
+		binary.Write(&dir, binary.LittleEndian, []int32{int32(12 + data.Len()), int32(len(l.data))})
+		name := make([]byte, 8)
+		copy(name, l.name)
+		dir.Write(name)
+		data.Write(l.data)
+	}
+	var wad bytes.Buffer
+	wad.WriteString(typ)
+	binary.Write(&wad, binary.LittleEndian, []int32{int32(len(lumps)), int32(12 + data.Len())})
+	wad.Write(data.Bytes())
+	wad.Write(dir.Bytes())
+	return wad.Bytes()
+}
+
+// le returns the little-endian encoding of values
+func le(values ...any) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	return buf.Bytes()
+}
+
+// testPatch is 2x3, with an offset of (1, 2). The first column has pixels 1
+// and 2 at the top, and the second has 3 and 4 at the bottom.
+var testPatch = le(
+	uint16(2), uint16(3), int16(1), int16(2),
+	uint32(16), uint32(23),
+	[]byte{0, 2, 0, 1, 2, 0, 0xff},
+	[]byte{1, 2, 0, 3, 4, 0, 0xff},
+)
+
+func testWAD(t *testing.T) *WAD {
+	t.Helper()
+	playpal := make([]byte, 2*256*3)
+	for i := range 256 {
+		copy(playpal[i*3:], []byte{byte(i), 0, 0})
+		copy(playpal[(256+i)*3:], []byte{0, byte(i), 0})
+	}
+	colormap := make([]byte, 2*256)
+	for i := range 256 {
+		colormap[i] = byte(i)
+		colormap[256+i] = byte(255 - i)
+	}
+	flat := make([]byte, 64*64)
+	flat[64+2] = 7
+	mt := le([8]byte{'W', 'A', 'L', 'L'}, int32(0), int16(4), int16(4), int32(0), int16(1),
+		int16(1), int16(1), int16(0), int16(1), int16(0))
+	texture1 := slices.Concat(le(int32(1), int32(8)), mt)
+
+	lumps := []testLump{
+		{"PLAYPAL", playpal},
+		{"COLORMAP", colormap},
+		{"PNAMES", le(int32(1), [8]byte{'p', 'a', 't', 'c', 'h', '1'})},
+		{"TEXTURE1", texture1},
+		{"E1M1", nil},
+		{"THINGS", make([]byte, 10)},
+		{"LINEDEFS", make([]byte, 14)},
+		{"SIDEDEFS", nil},
+		{"VERTEXES", nil},
+		{"SEGS", nil},
+		{"SSECTORS", nil},
+		{"NODES", nil},
+		{"SECTORS", make([]byte, 26)},
+		{"REJECT", nil},
+		{"BLOCKMAP", nil},
+		{"E1M2", nil},
+		{"THINGS", nil},
+		{"LINEDEFS", nil},
+		{"S_START", nil},
+		{"TROOA1", testPatch},
+		{"S_END", nil},
+		{"P_START", nil},
+		{"P1_START", nil},
+		{"PATCH1", testPatch},
+		{"P1_END", nil},
+		{"P_END", nil},
+		{"FF_START", nil},
+		{"FLAT1", flat},
+		{"FF_END", nil},
+		{"ENDOOM", nil},
+	}
+	data := buildWAD("PWAD", lumps)
+	w, err := Open(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Error opening WAD: %v", err)
+	}
+	if w.Type != "PWAD" || len(w.Lumps) != len(lumps) {
+		t.Fatalf("Opened %s with %d lumps, want PWAD with %d", w.Type, len(w.Lumps), len(lumps))
+	}
+	return w
+}
+
+func names(lumps []Lump) []string {
+	var n []string
+	for _, l := range lumps {
+		n = append(n, l.Name)
+	}
+	return n
+}
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+	open := func(data []byte) error {
+		_, err := Open(bytes.NewReader(data), int64(len(data)))
+		return err
+	}
+	if err := open([]byte("JUNKJUNKJUNK")); err == nil {
+		t.Errorf("No error opening a file which isn't a WAD")
+	}
+	if err := open(le([]byte("IWAD"), int32(10), int32(12))); err == nil {
+		t.Errorf("No error opening a WAD with a truncated directory")
+	}
+	if err := open(le([]byte("IWAD"), int32(1<<30), int32(12))); err == nil {
+		t.Errorf("No error opening a WAD with a huge directory")
+	}
+	if err := open(le([]byte("IWAD"), int32(1), int32(12), int32(1000), int32(16), []byte("BIGLUMP\x00"))); err == nil {
+		t.Errorf("No error opening a WAD with a lump past the end")
+	}
+
+	w := testWAD(t)
+	l, ok := w.Find("sectors")
+	if !ok || l.Size != 26 {
+		t.Errorf("Found SECTORS as %+v, %v", l, ok)
+	}
+	data, err := w.ReadNamed("LINEDEFS")
+	if err != nil || len(data) != 0 {
+		t.Errorf("Read the last LINEDEFS as %d bytes, %v", len(data), err)
+	}
+	if _, err := w.ReadNamed("MISSING"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Reading a missing lump gave %v", err)
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	t.Parallel()
+	w := testWAD(t)
+	for ns, want := range map[Namespace][]string{
+		Sprites: {"TROOA1"},
+		Patches: {"PATCH1"},
+		Flats:   {"FLAT1"},
+	} {
+		if got := names(w.Namespace(ns)); !slices.Equal(got, want) {
+			t.Errorf("%v namespace has %v, want %v", ns, got, want)
+		}
+	}
+	if global := names(w.Namespace(Global)); !slices.Contains(global, "ENDOOM") || slices.Contains(global, "FLAT1") {
+		t.Errorf("Global namespace has %v", global)
+	}
+	if l, ok := w.Find("P1_START"); !ok || !l.Marker || l.Namespace != Patches {
+		t.Errorf("P1_START is %+v", l)
+	}
+	if _, ok := w.FindIn(Flats, "PATCH1"); ok {
+		t.Errorf("Found a patch as a flat")
+	}
+}
+
+func TestMaps(t *testing.T) {
+	t.Parallel()
+	w := testWAD(t)
+	maps := w.Maps()
+	if len(maps) != 2 || maps[0].Name != "E1M1" || maps[1].Name != "E1M2" {
+		t.Fatalf("Maps = %+v", maps)
+	}
+	if len(maps[0].Lumps) != 10 || len(maps[1].Lumps) != 2 {
+		t.Errorf("Maps have %d and %d lumps, want 10 and 2", len(maps[0].Lumps), len(maps[1].Lumps))
+	}
+	m, ok := w.Map("e1m1")
+	if !ok {
+		t.Fatalf("E1M1 not found")
+	}
+	if l, ok := m.Lump("SECTORS"); !ok || l.Size != 26 {
+		t.Errorf("E1M1 SECTORS is %+v, %v", l, ok)
+	}
+	if _, ok := w.Map("E1M3"); ok {
+		t.Errorf("Found a map which doesn't exist")
+	}
+}
+
+func TestGraphics(t *testing.T) {
+	t.Parallel()
+	w := testWAD(t)
+	pals, err := w.Palettes()
+	if err != nil {
+		t.Fatalf("Error reading palettes: %v", err)
+	}
+	if len(pals) != 2 || pals[0][5] != (color.RGBA{R: 5, A: 0xff}) || pals[1][5] != (color.RGBA{G: 5, A: 0xff}) {
+		t.Errorf("Palettes decoded incorrectly")
+	}
+	pal := pals[0]
+
+	colormaps, err := w.Colormaps()
+	if err != nil {
+		t.Fatalf("Error reading colormaps: %v", err)
+	}
+	if len(colormaps) != 2 || colormaps[1].Apply(pal)[0] != pal[255] {
+		t.Errorf("Colormaps decoded incorrectly")
+	}
+
+	flat, err := w.Flat("FLAT1", pal)
+	if err != nil {
+		t.Fatalf("Error reading flat: %v", err)
+	}
+	if flat.Bounds().Dx() != 64 || flat.Bounds().Dy() != 64 || flat.ColorIndexAt(2, 1) != 7 {
+		t.Errorf("Flat decoded incorrectly")
+	}
+
+	patch, err := w.Patch("TROOA1", pal)
+	if err != nil {
+		t.Fatalf("Error reading patch: %v", err)
+	}
+	if patch.LeftOffset != 1 || patch.TopOffset != 2 || patch.Image.Bounds().Dx() != 2 || patch.Image.Bounds().Dy() != 3 {
+		t.Errorf("Patch is %v with offsets %d,%d", patch.Image.Bounds(), patch.LeftOffset, patch.TopOffset)
+	}
+	for _, p := range []struct {
+		x, y  int
+		index int // -1 for transparent
+	}{{0, 0, 1}, {0, 1, 2}, {0, 2, -1}, {1, 0, -1}, {1, 1, 3}, {1, 2, 4}} {
+		want := color.NRGBA{}
+		if p.index >= 0 {
+			want = color.NRGBAModel.Convert(pal[p.index]).(color.NRGBA)
+		}
+		if got := patch.Image.NRGBAAt(p.x, p.y); got != want {
+			t.Errorf("Patch pixel %d,%d is %v, want %v", p.x, p.y, got, want)
+		}
+	}
+	if _, err := DecodePatch(testPatch[:20], pal); err == nil {
+		t.Errorf("No error decoding a truncated patch")
+	}
+	if _, err := DecodePatch(le(uint16(0xffff), uint16(0xffff), int16(0), int16(0)), pal); err == nil {
+		t.Errorf("No error decoding a huge patch")
+	}
+	if _, err := DecodePatch(le(uint16(1), uint16(1), int16(0), int16(0), uint32(1000)), pal); err == nil {
+		t.Errorf("No error decoding a patch with a column past the end")
+	}
+
+	textures, err := w.Textures()
+	if err != nil {
+		t.Fatalf("Error reading textures: %v", err)
+	}
+	want := []Texture{{Name: "WALL", Width: 4, Height: 4, Patches: []TexturePatch{{Patch: "PATCH1", OriginX: 1, OriginY: 1}}}}
+	if !slices.EqualFunc(textures, want, func(a, b Texture) bool {
+		return a.Name == b.Name && a.Width == b.Width && a.Height == b.Height && slices.Equal(a.Patches, b.Patches)
+	}) {
+		t.Fatalf("Textures = %+v, want %+v", textures, want)
+	}
+	img, err := w.TextureImage(textures[0], pal)
+	if err != nil {
+		t.Fatalf("Error drawing texture: %v", err)
+	}
+	if img.NRGBAAt(0, 0).A != 0 || img.NRGBAAt(1, 1) != color.NRGBAModel.Convert(pal[1]) || img.NRGBAAt(2, 3) != color.NRGBAModel.Convert(pal[4]) {
+		t.Errorf("Texture drawn incorrectly")
+	}
+	if _, err := w.TextureImage(Texture{Name: "HUGE", Width: 1 << 20, Height: 1 << 20}, pal); err == nil {
+		t.Errorf("No error drawing a huge texture")
+	}
+}